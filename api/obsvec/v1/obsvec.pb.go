@@ -0,0 +1,945 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: api/obsvec/v1/obsvec.proto
+
+package obsvecv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SearchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Query string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	// limit caps the results; 0 uses the server's default.
+	Limit         int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_api_obsvec_v1_obsvec_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type SearchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*Result              `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_api_obsvec_v1_obsvec_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SearchResponse) GetResults() []*Result {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type Result struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Rank       int32                  `protobuf:"varint,1,opt,name=rank,proto3" json:"rank,omitempty"`
+	Score      float64                `protobuf:"fixed64,2,opt,name=score,proto3" json:"score,omitempty"`
+	Path       string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	Heading    string                 `protobuf:"bytes,4,opt,name=heading,proto3" json:"heading,omitempty"`
+	Content    string                 `protobuf:"bytes,5,opt,name=content,proto3" json:"content,omitempty"`
+	StartLine  int32                  `protobuf:"varint,6,opt,name=start_line,json=startLine,proto3" json:"start_line,omitempty"`
+	EndLine    int32                  `protobuf:"varint,7,opt,name=end_line,json=endLine,proto3" json:"end_line,omitempty"`
+	DocId      int64                  `protobuf:"varint,8,opt,name=doc_id,json=docId,proto3" json:"doc_id,omitempty"`
+	ChunkId    int64                  `protobuf:"varint,9,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	ModifiedAt *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"`
+	// linked_from is set on results found by following links, to the path of
+	// the result linking to this note.
+	LinkedFrom    string `protobuf:"bytes,11,opt,name=linked_from,json=linkedFrom,proto3" json:"linked_from,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Result) Reset() {
+	*x = Result{}
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Result) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result) ProtoMessage() {}
+
+func (x *Result) ProtoReflect() protoreflect.Message {
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result.ProtoReflect.Descriptor instead.
+func (*Result) Descriptor() ([]byte, []int) {
+	return file_api_obsvec_v1_obsvec_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Result) GetRank() int32 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+func (x *Result) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Result) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Result) GetHeading() string {
+	if x != nil {
+		return x.Heading
+	}
+	return ""
+}
+
+func (x *Result) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Result) GetStartLine() int32 {
+	if x != nil {
+		return x.StartLine
+	}
+	return 0
+}
+
+func (x *Result) GetEndLine() int32 {
+	if x != nil {
+		return x.EndLine
+	}
+	return 0
+}
+
+func (x *Result) GetDocId() int64 {
+	if x != nil {
+		return x.DocId
+	}
+	return 0
+}
+
+func (x *Result) GetChunkId() int64 {
+	if x != nil {
+		return x.ChunkId
+	}
+	return 0
+}
+
+func (x *Result) GetModifiedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModifiedAt
+	}
+	return nil
+}
+
+func (x *Result) GetLinkedFrom() string {
+	if x != nil {
+		return x.LinkedFrom
+	}
+	return ""
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_api_obsvec_v1_obsvec_proto_rawDescGZIP(), []int{3}
+}
+
+type GetStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Documents     int64                  `protobuf:"varint,1,opt,name=documents,proto3" json:"documents,omitempty"`
+	Chunks        int64                  `protobuf:"varint,2,opt,name=chunks,proto3" json:"chunks,omitempty"`
+	Indexing      bool                   `protobuf:"varint,3,opt,name=indexing,proto3" json:"indexing,omitempty"`
+	LastIndex     *IndexRun              `protobuf:"bytes,4,opt,name=last_index,json=lastIndex,proto3" json:"last_index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusResponse) Reset() {
+	*x = GetStatusResponse{}
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusResponse) ProtoMessage() {}
+
+func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_api_obsvec_v1_obsvec_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetStatusResponse) GetDocuments() int64 {
+	if x != nil {
+		return x.Documents
+	}
+	return 0
+}
+
+func (x *GetStatusResponse) GetChunks() int64 {
+	if x != nil {
+		return x.Chunks
+	}
+	return 0
+}
+
+func (x *GetStatusResponse) GetIndexing() bool {
+	if x != nil {
+		return x.Indexing
+	}
+	return false
+}
+
+func (x *GetStatusResponse) GetLastIndex() *IndexRun {
+	if x != nil {
+		return x.LastIndex
+	}
+	return nil
+}
+
+type IndexRun struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Full      bool                   `protobuf:"varint,1,opt,name=full,proto3" json:"full,omitempty"`
+	Paths     []string               `protobuf:"bytes,2,rep,name=paths,proto3" json:"paths,omitempty"`
+	StartedAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	// finished_at is unset while the run is in progress.
+	FinishedAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexRun) Reset() {
+	*x = IndexRun{}
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexRun) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexRun) ProtoMessage() {}
+
+func (x *IndexRun) ProtoReflect() protoreflect.Message {
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexRun.ProtoReflect.Descriptor instead.
+func (*IndexRun) Descriptor() ([]byte, []int) {
+	return file_api_obsvec_v1_obsvec_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *IndexRun) GetFull() bool {
+	if x != nil {
+		return x.Full
+	}
+	return false
+}
+
+func (x *IndexRun) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *IndexRun) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *IndexRun) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+func (x *IndexRun) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GetDocumentRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// path is relative to the vault, e.g. "Projects/Plan.md".
+	Path          string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDocumentRequest) Reset() {
+	*x = GetDocumentRequest{}
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDocumentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDocumentRequest) ProtoMessage() {}
+
+func (x *GetDocumentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDocumentRequest.ProtoReflect.Descriptor instead.
+func (*GetDocumentRequest) Descriptor() ([]byte, []int) {
+	return file_api_obsvec_v1_obsvec_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetDocumentRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type Document struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Path       string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Title      string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	ModifiedAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"`
+	IndexedAt  *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=indexed_at,json=indexedAt,proto3" json:"indexed_at,omitempty"`
+	Tags       []string               `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	// metadata is the note's frontmatter.
+	Metadata      *structpb.Struct `protobuf:"bytes,6,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Chunks        []*Chunk         `protobuf:"bytes,7,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Document) Reset() {
+	*x = Document{}
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Document) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Document) ProtoMessage() {}
+
+func (x *Document) ProtoReflect() protoreflect.Message {
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Document.ProtoReflect.Descriptor instead.
+func (*Document) Descriptor() ([]byte, []int) {
+	return file_api_obsvec_v1_obsvec_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Document) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Document) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Document) GetModifiedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModifiedAt
+	}
+	return nil
+}
+
+func (x *Document) GetIndexedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.IndexedAt
+	}
+	return nil
+}
+
+func (x *Document) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Document) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Document) GetChunks() []*Chunk {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+type Chunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Heading       string                 `protobuf:"bytes,2,opt,name=heading,proto3" json:"heading,omitempty"`
+	StartLine     int32                  `protobuf:"varint,3,opt,name=start_line,json=startLine,proto3" json:"start_line,omitempty"`
+	EndLine       int32                  `protobuf:"varint,4,opt,name=end_line,json=endLine,proto3" json:"end_line,omitempty"`
+	Content       string                 `protobuf:"bytes,5,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Chunk) Reset() {
+	*x = Chunk{}
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Chunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Chunk) ProtoMessage() {}
+
+func (x *Chunk) ProtoReflect() protoreflect.Message {
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Chunk.ProtoReflect.Descriptor instead.
+func (*Chunk) Descriptor() ([]byte, []int) {
+	return file_api_obsvec_v1_obsvec_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Chunk) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Chunk) GetHeading() string {
+	if x != nil {
+		return x.Heading
+	}
+	return ""
+}
+
+func (x *Chunk) GetStartLine() int32 {
+	if x != nil {
+		return x.StartLine
+	}
+	return 0
+}
+
+func (x *Chunk) GetEndLine() int32 {
+	if x != nil {
+		return x.EndLine
+	}
+	return 0
+}
+
+func (x *Chunk) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+type IndexRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// full reindexes every note, not only those that changed.
+	Full bool `protobuf:"varint,1,opt,name=full,proto3" json:"full,omitempty"`
+	// paths reindexes only these vault-relative notes. It can't be combined
+	// with full.
+	Paths         []string `protobuf:"bytes,2,rep,name=paths,proto3" json:"paths,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexRequest) Reset() {
+	*x = IndexRequest{}
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexRequest) ProtoMessage() {}
+
+func (x *IndexRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexRequest.ProtoReflect.Descriptor instead.
+func (*IndexRequest) Descriptor() ([]byte, []int) {
+	return file_api_obsvec_v1_obsvec_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *IndexRequest) GetFull() bool {
+	if x != nil {
+		return x.Full
+	}
+	return false
+}
+
+func (x *IndexRequest) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+type IndexProgress struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// phase is Removing, Checking, Parsing or Embedding.
+	Phase   string `protobuf:"bytes,1,opt,name=phase,proto3" json:"phase,omitempty"`
+	Current int32  `protobuf:"varint,2,opt,name=current,proto3" json:"current,omitempty"`
+	Total   int32  `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	Path    string `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`
+	Message string `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	// chunks and tokens count what was embedded so far in the Embedding phase.
+	Chunks        int32 `protobuf:"varint,6,opt,name=chunks,proto3" json:"chunks,omitempty"`
+	Tokens        int32 `protobuf:"varint,7,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexProgress) Reset() {
+	*x = IndexProgress{}
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexProgress) ProtoMessage() {}
+
+func (x *IndexProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_api_obsvec_v1_obsvec_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexProgress.ProtoReflect.Descriptor instead.
+func (*IndexProgress) Descriptor() ([]byte, []int) {
+	return file_api_obsvec_v1_obsvec_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *IndexProgress) GetPhase() string {
+	if x != nil {
+		return x.Phase
+	}
+	return ""
+}
+
+func (x *IndexProgress) GetCurrent() int32 {
+	if x != nil {
+		return x.Current
+	}
+	return 0
+}
+
+func (x *IndexProgress) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *IndexProgress) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *IndexProgress) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *IndexProgress) GetChunks() int32 {
+	if x != nil {
+		return x.Chunks
+	}
+	return 0
+}
+
+func (x *IndexProgress) GetTokens() int32 {
+	if x != nil {
+		return x.Tokens
+	}
+	return 0
+}
+
+var File_api_obsvec_v1_obsvec_proto protoreflect.FileDescriptor
+
+const file_api_obsvec_v1_obsvec_proto_rawDesc = "" +
+	"\n" +
+	"\x1aapi/obsvec/v1/obsvec.proto\x12\tobsvec.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\";\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"=\n" +
+	"\x0eSearchResponse\x12+\n" +
+	"\aresults\x18\x01 \x03(\v2\x11.obsvec.v1.ResultR\aresults\"\xc4\x02\n" +
+	"\x06Result\x12\x12\n" +
+	"\x04rank\x18\x01 \x01(\x05R\x04rank\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x01R\x05score\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x18\n" +
+	"\aheading\x18\x04 \x01(\tR\aheading\x12\x18\n" +
+	"\acontent\x18\x05 \x01(\tR\acontent\x12\x1d\n" +
+	"\n" +
+	"start_line\x18\x06 \x01(\x05R\tstartLine\x12\x19\n" +
+	"\bend_line\x18\a \x01(\x05R\aendLine\x12\x15\n" +
+	"\x06doc_id\x18\b \x01(\x03R\x05docId\x12\x19\n" +
+	"\bchunk_id\x18\t \x01(\x03R\achunkId\x12;\n" +
+	"\vmodified_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"modifiedAt\x12\x1f\n" +
+	"\vlinked_from\x18\v \x01(\tR\n" +
+	"linkedFrom\"\x12\n" +
+	"\x10GetStatusRequest\"\x99\x01\n" +
+	"\x11GetStatusResponse\x12\x1c\n" +
+	"\tdocuments\x18\x01 \x01(\x03R\tdocuments\x12\x16\n" +
+	"\x06chunks\x18\x02 \x01(\x03R\x06chunks\x12\x1a\n" +
+	"\bindexing\x18\x03 \x01(\bR\bindexing\x122\n" +
+	"\n" +
+	"last_index\x18\x04 \x01(\v2\x13.obsvec.v1.IndexRunR\tlastIndex\"\xc2\x01\n" +
+	"\bIndexRun\x12\x12\n" +
+	"\x04full\x18\x01 \x01(\bR\x04full\x12\x14\n" +
+	"\x05paths\x18\x02 \x03(\tR\x05paths\x129\n" +
+	"\n" +
+	"started_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12;\n" +
+	"\vfinished_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"(\n" +
+	"\x12GetDocumentRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"\x9f\x02\n" +
+	"\bDocument\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12;\n" +
+	"\vmodified_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"modifiedAt\x129\n" +
+	"\n" +
+	"indexed_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tindexedAt\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\x123\n" +
+	"\bmetadata\x18\x06 \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12(\n" +
+	"\x06chunks\x18\a \x03(\v2\x10.obsvec.v1.ChunkR\x06chunks\"\x85\x01\n" +
+	"\x05Chunk\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x18\n" +
+	"\aheading\x18\x02 \x01(\tR\aheading\x12\x1d\n" +
+	"\n" +
+	"start_line\x18\x03 \x01(\x05R\tstartLine\x12\x19\n" +
+	"\bend_line\x18\x04 \x01(\x05R\aendLine\x12\x18\n" +
+	"\acontent\x18\x05 \x01(\tR\acontent\"8\n" +
+	"\fIndexRequest\x12\x12\n" +
+	"\x04full\x18\x01 \x01(\bR\x04full\x12\x14\n" +
+	"\x05paths\x18\x02 \x03(\tR\x05paths\"\xb3\x01\n" +
+	"\rIndexProgress\x12\x14\n" +
+	"\x05phase\x18\x01 \x01(\tR\x05phase\x12\x18\n" +
+	"\acurrent\x18\x02 \x01(\x05R\acurrent\x12\x14\n" +
+	"\x05total\x18\x03 \x01(\x05R\x05total\x12\x12\n" +
+	"\x04path\x18\x04 \x01(\tR\x04path\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\x12\x16\n" +
+	"\x06chunks\x18\x06 \x01(\x05R\x06chunks\x12\x16\n" +
+	"\x06tokens\x18\a \x01(\x05R\x06tokens2\x90\x02\n" +
+	"\x06Obsvec\x12=\n" +
+	"\x06Search\x12\x18.obsvec.v1.SearchRequest\x1a\x19.obsvec.v1.SearchResponse\x12F\n" +
+	"\tGetStatus\x12\x1b.obsvec.v1.GetStatusRequest\x1a\x1c.obsvec.v1.GetStatusResponse\x12A\n" +
+	"\vGetDocument\x12\x1d.obsvec.v1.GetDocumentRequest\x1a\x13.obsvec.v1.Document\x12<\n" +
+	"\x05Index\x12\x17.obsvec.v1.IndexRequest\x1a\x18.obsvec.v1.IndexProgress0\x01B1Z/github.com/mgomes/obsvec/api/obsvec/v1;obsvecv1b\x06proto3"
+
+var (
+	file_api_obsvec_v1_obsvec_proto_rawDescOnce sync.Once
+	file_api_obsvec_v1_obsvec_proto_rawDescData []byte
+)
+
+func file_api_obsvec_v1_obsvec_proto_rawDescGZIP() []byte {
+	file_api_obsvec_v1_obsvec_proto_rawDescOnce.Do(func() {
+		file_api_obsvec_v1_obsvec_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_obsvec_v1_obsvec_proto_rawDesc), len(file_api_obsvec_v1_obsvec_proto_rawDesc)))
+	})
+	return file_api_obsvec_v1_obsvec_proto_rawDescData
+}
+
+var file_api_obsvec_v1_obsvec_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_api_obsvec_v1_obsvec_proto_goTypes = []any{
+	(*SearchRequest)(nil),         // 0: obsvec.v1.SearchRequest
+	(*SearchResponse)(nil),        // 1: obsvec.v1.SearchResponse
+	(*Result)(nil),                // 2: obsvec.v1.Result
+	(*GetStatusRequest)(nil),      // 3: obsvec.v1.GetStatusRequest
+	(*GetStatusResponse)(nil),     // 4: obsvec.v1.GetStatusResponse
+	(*IndexRun)(nil),              // 5: obsvec.v1.IndexRun
+	(*GetDocumentRequest)(nil),    // 6: obsvec.v1.GetDocumentRequest
+	(*Document)(nil),              // 7: obsvec.v1.Document
+	(*Chunk)(nil),                 // 8: obsvec.v1.Chunk
+	(*IndexRequest)(nil),          // 9: obsvec.v1.IndexRequest
+	(*IndexProgress)(nil),         // 10: obsvec.v1.IndexProgress
+	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),       // 12: google.protobuf.Struct
+}
+var file_api_obsvec_v1_obsvec_proto_depIdxs = []int32{
+	2,  // 0: obsvec.v1.SearchResponse.results:type_name -> obsvec.v1.Result
+	11, // 1: obsvec.v1.Result.modified_at:type_name -> google.protobuf.Timestamp
+	5,  // 2: obsvec.v1.GetStatusResponse.last_index:type_name -> obsvec.v1.IndexRun
+	11, // 3: obsvec.v1.IndexRun.started_at:type_name -> google.protobuf.Timestamp
+	11, // 4: obsvec.v1.IndexRun.finished_at:type_name -> google.protobuf.Timestamp
+	11, // 5: obsvec.v1.Document.modified_at:type_name -> google.protobuf.Timestamp
+	11, // 6: obsvec.v1.Document.indexed_at:type_name -> google.protobuf.Timestamp
+	12, // 7: obsvec.v1.Document.metadata:type_name -> google.protobuf.Struct
+	8,  // 8: obsvec.v1.Document.chunks:type_name -> obsvec.v1.Chunk
+	0,  // 9: obsvec.v1.Obsvec.Search:input_type -> obsvec.v1.SearchRequest
+	3,  // 10: obsvec.v1.Obsvec.GetStatus:input_type -> obsvec.v1.GetStatusRequest
+	6,  // 11: obsvec.v1.Obsvec.GetDocument:input_type -> obsvec.v1.GetDocumentRequest
+	9,  // 12: obsvec.v1.Obsvec.Index:input_type -> obsvec.v1.IndexRequest
+	1,  // 13: obsvec.v1.Obsvec.Search:output_type -> obsvec.v1.SearchResponse
+	4,  // 14: obsvec.v1.Obsvec.GetStatus:output_type -> obsvec.v1.GetStatusResponse
+	7,  // 15: obsvec.v1.Obsvec.GetDocument:output_type -> obsvec.v1.Document
+	10, // 16: obsvec.v1.Obsvec.Index:output_type -> obsvec.v1.IndexProgress
+	13, // [13:17] is the sub-list for method output_type
+	9,  // [9:13] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_api_obsvec_v1_obsvec_proto_init() }
+func file_api_obsvec_v1_obsvec_proto_init() {
+	if File_api_obsvec_v1_obsvec_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_obsvec_v1_obsvec_proto_rawDesc), len(file_api_obsvec_v1_obsvec_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_obsvec_v1_obsvec_proto_goTypes,
+		DependencyIndexes: file_api_obsvec_v1_obsvec_proto_depIdxs,
+		MessageInfos:      file_api_obsvec_v1_obsvec_proto_msgTypes,
+	}.Build()
+	File_api_obsvec_v1_obsvec_proto = out.File
+	file_api_obsvec_v1_obsvec_proto_goTypes = nil
+	file_api_obsvec_v1_obsvec_proto_depIdxs = nil
+}
@@ -0,0 +1,259 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: api/obsvec/v1/obsvec.proto
+
+package obsvecv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Obsvec_Search_FullMethodName      = "/obsvec.v1.Obsvec/Search"
+	Obsvec_GetStatus_FullMethodName   = "/obsvec.v1.Obsvec/GetStatus"
+	Obsvec_GetDocument_FullMethodName = "/obsvec.v1.Obsvec/GetDocument"
+	Obsvec_Index_FullMethodName       = "/obsvec.v1.Obsvec/Index"
+)
+
+// ObsvecClient is the client API for Obsvec service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Obsvec searches and reindexes an Obsidian vault. ofind daemon and ofind
+// serve offer it on localhost with -grpc-port.
+type ObsvecClient interface {
+	// Search returns the chunks that best match a query.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// GetStatus reports the size of the index and the last reindex started
+	// through the API.
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
+	// GetDocument returns a note as indexed. It fails with NOT_FOUND for a
+	// note that isn't in the index.
+	GetDocument(ctx context.Context, in *GetDocumentRequest, opts ...grpc.CallOption) (*Document, error)
+	// Index reindexes the vault and streams its progress until it finishes.
+	// It fails with ABORTED while another reindex is running.
+	Index(ctx context.Context, in *IndexRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[IndexProgress], error)
+}
+
+type obsvecClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewObsvecClient(cc grpc.ClientConnInterface) ObsvecClient {
+	return &obsvecClient{cc}
+}
+
+func (c *obsvecClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, Obsvec_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *obsvecClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetStatusResponse)
+	err := c.cc.Invoke(ctx, Obsvec_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *obsvecClient) GetDocument(ctx context.Context, in *GetDocumentRequest, opts ...grpc.CallOption) (*Document, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Document)
+	err := c.cc.Invoke(ctx, Obsvec_GetDocument_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *obsvecClient) Index(ctx context.Context, in *IndexRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[IndexProgress], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Obsvec_ServiceDesc.Streams[0], Obsvec_Index_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[IndexRequest, IndexProgress]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Obsvec_IndexClient = grpc.ServerStreamingClient[IndexProgress]
+
+// ObsvecServer is the server API for Obsvec service.
+// All implementations must embed UnimplementedObsvecServer
+// for forward compatibility.
+//
+// Obsvec searches and reindexes an Obsidian vault. ofind daemon and ofind
+// serve offer it on localhost with -grpc-port.
+type ObsvecServer interface {
+	// Search returns the chunks that best match a query.
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// GetStatus reports the size of the index and the last reindex started
+	// through the API.
+	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
+	// GetDocument returns a note as indexed. It fails with NOT_FOUND for a
+	// note that isn't in the index.
+	GetDocument(context.Context, *GetDocumentRequest) (*Document, error)
+	// Index reindexes the vault and streams its progress until it finishes.
+	// It fails with ABORTED while another reindex is running.
+	Index(*IndexRequest, grpc.ServerStreamingServer[IndexProgress]) error
+	mustEmbedUnimplementedObsvecServer()
+}
+
+// UnimplementedObsvecServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedObsvecServer struct{}
+
+func (UnimplementedObsvecServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedObsvecServer) GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedObsvecServer) GetDocument(context.Context, *GetDocumentRequest) (*Document, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetDocument not implemented")
+}
+func (UnimplementedObsvecServer) Index(*IndexRequest, grpc.ServerStreamingServer[IndexProgress]) error {
+	return status.Error(codes.Unimplemented, "method Index not implemented")
+}
+func (UnimplementedObsvecServer) mustEmbedUnimplementedObsvecServer() {}
+func (UnimplementedObsvecServer) testEmbeddedByValue()                {}
+
+// UnsafeObsvecServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ObsvecServer will
+// result in compilation errors.
+type UnsafeObsvecServer interface {
+	mustEmbedUnimplementedObsvecServer()
+}
+
+func RegisterObsvecServer(s grpc.ServiceRegistrar, srv ObsvecServer) {
+	// If the following call panics, it indicates UnimplementedObsvecServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Obsvec_ServiceDesc, srv)
+}
+
+func _Obsvec_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObsvecServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Obsvec_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObsvecServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Obsvec_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObsvecServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Obsvec_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObsvecServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Obsvec_GetDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObsvecServer).GetDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Obsvec_GetDocument_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObsvecServer).GetDocument(ctx, req.(*GetDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Obsvec_Index_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(IndexRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ObsvecServer).Index(m, &grpc.GenericServerStream[IndexRequest, IndexProgress]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Obsvec_IndexServer = grpc.ServerStreamingServer[IndexProgress]
+
+// Obsvec_ServiceDesc is the grpc.ServiceDesc for Obsvec service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Obsvec_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "obsvec.v1.Obsvec",
+	HandlerType: (*ObsvecServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Search",
+			Handler:    _Obsvec_Search_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _Obsvec_GetStatus_Handler,
+		},
+		{
+			MethodName: "GetDocument",
+			Handler:    _Obsvec_GetDocument_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Index",
+			Handler:       _Obsvec_Index_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/obsvec/v1/obsvec.proto",
+}
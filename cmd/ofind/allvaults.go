@@ -0,0 +1,124 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mgomes/obsvec/internal/config"
+	"github.com/mgomes/obsvec/internal/vectorstore"
+	"github.com/mgomes/obsvec/pkg/obsvec/cohere"
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+	"github.com/mgomes/obsvec/pkg/obsvec/indexer"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+// runSearchAllVaults runs the queries against the default vault and every
+// named one, each with its own index and settings, and merges the results
+// by score with each labeled by its vault. excludePaths are the -exclude-path
+// globs, applied in every vault on top of its own exclusions.
+func runSearchAllVaults(cfg *config.Config, queries, excludePaths []string, opts search.Options, out outputOptions) error {
+	if len(queries) == 0 {
+		return fmt.Errorf("usage: ofind search -all-vaults <query>")
+	}
+
+	// Notes of different vaults can't be opened from one results view
+	if !out.printsResults() {
+		out.plain = true
+	}
+
+	var merged []search.Result
+	for _, name := range append([]string{""}, slices.Sorted(maps.Keys(cfg.Vaults))...) {
+		vaultCfg := *cfg
+		if name != "" {
+			if err := vaultCfg.UseVault(name); err != nil {
+				return err
+			}
+		}
+		label := cmp.Or(name, config.DefaultVaultName)
+
+		results, err := searchVault(&vaultCfg, queries, excludePaths, opts, out)
+		if err != nil {
+			return fmt.Errorf("vault %s: %w", label, err)
+		}
+		for i := range results {
+			results[i].Vault = label
+		}
+		merged = append(merged, results...)
+	}
+
+	return presentResults(cfg, strings.Join(queries, " | "), "", mergeVaultResults(merged, opts.Limit), out)
+}
+
+// mergeVaultResults orders the results of several vaults by score, keeping
+// the best limit of them.
+func mergeVaultResults(results []search.Result, limit int) []search.Result {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+	return results
+}
+
+// searchVault searches one vault of an -all-vaults search. A vault that was
+// never indexed is skipped with a warning.
+func searchVault(cfg *config.Config, queries, excludePaths []string, opts search.Options, out outputOptions) ([]search.Result, error) {
+	dbPath, err := config.DBPath(cfg.Vault)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Skipping vault %s: not indexed yet\n", cmp.Or(cfg.Vault, config.DefaultVaultName))
+		return nil, nil
+	}
+
+	var vectors db.VectorStore
+	if cfg.VectorStore != nil {
+		vectors, err = vectorstore.Open(*cfg.VectorStore, cfg.Vault, cfg.EmbedDim)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open vector store: %w", err)
+		}
+	}
+	database, err := openDBWithRebuild(cfg, dbPath, false, vectors)
+	if err != nil {
+		return nil, err
+	}
+	defer database.Close() //nolint:errcheck
+
+	opts.ExcludePaths = slices.Concat(excludePaths, cfg.ExcludePaths, cfg.ExcludedPaths())
+	if opts.BookmarkedOnly || opts.BookmarkBoost > 0 {
+		opts.Bookmarks, err = indexer.Bookmarks(cfg.ObsidianDir)
+		if err != nil && opts.BookmarkedOnly {
+			return nil, err
+		}
+		if opts.BookmarkedOnly && len(opts.Bookmarks) == 0 {
+			return nil, nil
+		}
+	}
+
+	cohereClient := cohere.NewClient(cfg.CohereAPIKey, cfg.EmbedModel, cfg.RerankModel, cfg.ChatModel, cfg.EmbedDim)
+	searcher := search.New(database, cohereClient)
+	searcher.SetWarningHandler(func(msg string) {
+		fmt.Fprintln(os.Stderr, msg)
+	})
+	results, err := searcher.SearchMulti(context.Background(), queries, opts)
+	if err != nil {
+		return nil, err
+	}
+	if n := contextSize(out); n > 0 {
+		if err := searcher.AddContext(results, n); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+func TestMergeVaultResults(t *testing.T) {
+	results := []search.Result{
+		{Vault: "default", Path: "a.md", Score: 0.4},
+		{Vault: "default", Path: "b.md", Score: 0.1},
+		{Vault: "work", Path: "c.md", Score: 0.9},
+		{Vault: "work", Path: "d.md", Score: 0.3},
+	}
+
+	merged := mergeVaultResults(results, 3)
+	if len(merged) != 3 {
+		t.Fatalf("expected the limit kept, got %d results", len(merged))
+	}
+	for i, want := range []string{"work:c.md", "default:a.md", "work:d.md"} {
+		if got := merged[i].Vault + ":" + merged[i].Path; got != want || merged[i].Rank != i+1 {
+			t.Errorf("result %d: expected %s at rank %d, got %s at %d", i, want, i+1, got, merged[i].Rank)
+		}
+	}
+}
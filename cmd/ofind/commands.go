@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+)
+
+// commands are the subcommands ofind takes as its first argument.
+var commands = []string{
+	"search", "similar", "explore", "index", "watch", "daemon", "serve", "service", "setup",
+	"ask", "chat", "open", "eval", "stats", "clusters", "check-vault", "config", "token", "purge", "version", "db", "sync",
+}
+
+// legacyCommands are the flags that picked what ofind did before it had
+// subcommands, in the order they took precedence. They still work as
+// aliases for their subcommand.
+var legacyCommands = []struct {
+	flag    string
+	command string
+}{
+	{"index", "index"},
+	{"watch", "watch"},
+	{"q", "search"},
+	{"similar", "similar"},
+	{"explore", "explore"},
+	{"setup", "setup"},
+}
+
+// parseCommand parses args with fs and returns the subcommand and its
+// arguments. Flags may go before or after the subcommand. Without one, a
+// legacy flag picks it, and with neither the command is empty.
+func parseCommand(fs *flag.FlagSet, args []string) (string, []string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", nil, err
+	}
+	if name := fs.Arg(0); slices.Contains(commands, name) {
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return "", nil, err
+		}
+		return name, fs.Args(), nil
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, legacy := range legacyCommands {
+		if set[legacy.flag] {
+			return legacy.command, fs.Args(), nil
+		}
+	}
+
+	if fs.NArg() > 0 {
+		return "", nil, fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+	return "", nil, nil
+}
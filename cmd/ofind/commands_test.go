@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"slices"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		args        []string
+		wantCommand string
+		wantArgs    []string
+		wantLimit   int
+	}{
+		{[]string{"search", "-n", "5", "weekly", "review"}, "search", []string{"weekly", "review"}, 5},
+		{[]string{"-n", "5", "search", "weekly review"}, "search", []string{"weekly review"}, 5},
+		{[]string{"-q", "weekly review"}, "search", nil, 10},
+		{[]string{"-index", "-full"}, "index", nil, 10},
+		{[]string{"-setup", "-q", "x"}, "search", nil, 10},
+		{[]string{"db", "verify", "-fix"}, "db", []string{"verify", "-fix"}, 10},
+		{[]string{"-n", "3"}, "", nil, 3},
+	}
+
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("ofind", flag.ContinueOnError)
+		limit := fs.Int("n", 10, "")
+		fs.String("q", "", "")
+		fs.Bool("index", false, "")
+		fs.Bool("full", false, "")
+		fs.Bool("setup", false, "")
+
+		command, args, err := parseCommand(fs, tt.args)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.args, err)
+			continue
+		}
+		if command != tt.wantCommand || !slices.Equal(args, tt.wantArgs) || *limit != tt.wantLimit {
+			t.Errorf("%v: got %q %q n=%d, want %q %q n=%d", tt.args, command, args, *limit, tt.wantCommand, tt.wantArgs, tt.wantLimit)
+		}
+	}
+
+	fs := flag.NewFlagSet("ofind", flag.ContinueOnError)
+	if _, _, err := parseCommand(fs, []string{"serach", "x"}); err == nil {
+		t.Error("expected an error for an unknown command")
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mgomes/obsvec/internal/ask"
+	"github.com/mgomes/obsvec/internal/config"
+	"github.com/mgomes/obsvec/internal/server"
+	"github.com/mgomes/obsvec/internal/tui"
+	"github.com/mgomes/obsvec/pkg/obsvec/cohere"
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+	"github.com/mgomes/obsvec/pkg/obsvec/events"
+	"github.com/mgomes/obsvec/pkg/obsvec/indexer"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+	"google.golang.org/grpc"
+)
+
+// defaultPort is where the daemon's API listens unless -port says otherwise.
+const defaultPort = 7700
+
+// queryCacheSize is how many query embeddings the API keeps, so repeated
+// searches skip the embedding request.
+const queryCacheSize = 256
+
+// shutdownTimeout is how long in-flight requests get to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
+// runDaemon keeps the index current with the watcher and serves the HTTP API
+// on localhost until SIGINT or SIGTERM. Everything is logged to stderr, for
+// launchd or systemd to collect.
+func runDaemon(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, opts search.Options, port, grpcPort int) error {
+	debounce, err := cfg.WatchDebounceDuration()
+	if err != nil {
+		return err
+	}
+	batchWindow, err := cfg.WatchBatchWindowDuration()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	bus := events.NewBus()
+	idx, closeIndexer := newVaultIndexer(database, cohereClient, cfg, bus)
+	defer closeIndexer()
+	api, err := startAPI(database, cohereClient, cfg, idx, bus, opts, port, grpcPort)
+	if err != nil {
+		return err
+	}
+
+	// Catch up on changes made while the daemon wasn't running
+	slog.Info("indexing changes since last run", "vault", cfg.ObsidianDir)
+	err = idx.Index(ctx, false, nil)
+	var skippedErr *indexer.SkippedFilesError
+	switch {
+	case errors.As(err, &skippedErr):
+		for _, file := range skippedErr.Files {
+			slog.Warn("skipped file", "path", file.Path, "error", file.Err)
+		}
+	case err != nil && ctx.Err() == nil:
+		slog.Error("indexing failed", "error", err)
+	}
+
+	watcher, err := indexer.NewWatcher(idx)
+	if err != nil {
+		return errors.Join(err, api.shutdown())
+	}
+	defer watcher.Stop()
+	watcher.SetMessageHandler(func(msg string) {
+		slog.Info(msg)
+	})
+	watcher.SetDebounce(debounce)
+	watcher.SetBatchWindow(batchWindow)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- watcher.Start(ctx)
+	}()
+
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down")
+	case err = <-api.serveErr:
+		stop()
+	case err = <-watchErr:
+		stop()
+	}
+
+	if shutdownErr := api.shutdown(); shutdownErr != nil && err == nil {
+		err = shutdownErr
+	}
+	return err
+}
+
+// runServe serves the HTTP API on localhost until SIGINT or SIGTERM, without
+// watching the vault. Reindexing happens when a client asks for it.
+func runServe(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, opts search.Options, port, grpcPort int) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	bus := events.NewBus()
+	idx, closeIndexer := newVaultIndexer(database, cohereClient, cfg, bus)
+	defer closeIndexer()
+	api, err := startAPI(database, cohereClient, cfg, idx, bus, opts, port, grpcPort)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down")
+	case err = <-api.serveErr:
+	}
+
+	if shutdownErr := api.shutdown(); shutdownErr != nil && err == nil {
+		err = shutdownErr
+	}
+	return err
+}
+
+// apiServer is the HTTP API, and the gRPC one when asked for, as run by the
+// daemon and serve commands.
+type apiServer struct {
+	http     *http.Server
+	grpc     *grpc.Server
+	api      *server.Server
+	serveErr chan error
+}
+
+// startAPI starts serving the HTTP API on localhost port, on api_listen if
+// set, and on the vault's unix socket in the background, and the gRPC API on
+// grpcPort unless it is 0. All of them require the configured API token. The
+// events published to bus, and the API's own searches, are streamed to
+// WebSocket clients.
+func startAPI(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, idx *indexer.Indexer, bus *events.Bus, opts search.Options, port, grpcPort int) (*apiServer, error) {
+	token, err := apiToken(cfg)
+	if err != nil {
+		return nil, err
+	}
+	socketPath, err := config.SocketPath(cfg.Vault)
+	if err != nil {
+		return nil, err
+	}
+
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return nil, err
+	}
+	listeners = append(listeners, listener)
+	socket, err := server.ListenSocket(socketPath)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	listeners = append(listeners, socket)
+	if cfg.APIListen != "" {
+		remote, err := net.Listen("tcp", cfg.APIListen)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("api_listen: %w", err)
+		}
+		listeners = append(listeners, remote)
+	}
+	var grpcListener net.Listener
+	if grpcPort != 0 {
+		grpcListener, err = net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", grpcPort))
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+	}
+
+	searcher := search.New(database, cohereClient)
+	searcher.SetWarningHandler(func(msg string) {
+		slog.Warn(msg)
+	})
+	searcher.SetQueryCache(queryCacheSize)
+	searcher.SetEventBus(bus)
+
+	api := server.New(database, searcher, idx, opts)
+	api.SetToken(token)
+	api.SetClientTokens(newConfigTokens(cfg).get)
+	api.SetEventBus(bus)
+	api.SetAsker(ask.New(searcher, cohereClient))
+	api.SetNoteURL(func(path, heading string, line int) string {
+		return tui.ObsidianURL(cfg.ObsidianDir, path, heading, line)
+	})
+	if cfg.APIListen != "" {
+		host, _, err := net.SplitHostPort(cfg.APIListen)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("api_listen: %w", err)
+		}
+		api.AllowHost(host)
+	}
+	a := &apiServer{
+		http: &http.Server{
+			Handler:           api.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		api:      api,
+		serveErr: make(chan error, len(listeners)+1),
+	}
+	for _, l := range listeners {
+		go func() {
+			a.serveErr <- a.http.Serve(l)
+		}()
+	}
+	slog.Info("serving API", "addr", "http://"+listener.Addr().String(), "socket", socketPath)
+	if cfg.APIListen != "" {
+		slog.Info("serving API", "addr", "http://"+cfg.APIListen)
+	}
+
+	if grpcListener != nil {
+		a.grpc = grpc.NewServer(api.GRPCOptions()...)
+		api.RegisterGRPC(a.grpc)
+		go func() {
+			a.serveErr <- a.grpc.Serve(grpcListener)
+		}()
+		slog.Info("serving gRPC API", "addr", grpcListener.Addr().String())
+	}
+	return a, nil
+}
+
+// apiToken returns the configured API token, generating and saving one for
+// configs made before tokens were.
+func apiToken(cfg *config.Config) (string, error) {
+	if cfg.APIToken != "" {
+		return cfg.APIToken, nil
+	}
+
+	// Saved from a fresh load, so flags applied to cfg don't end up in it
+	saved, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+	if saved.APIToken == "" {
+		saved.APIToken = rand.Text()
+		if err := saved.Save(); err != nil {
+			return "", err
+		}
+		slog.Info("generated an API token; ofind config get api_token shows it")
+	}
+	cfg.APIToken = saved.APIToken
+	return cfg.APIToken, nil
+}
+
+// configTokens hands out the client tokens of the saved config, reading it
+// again whenever it changes, so tokens created or revoked with ofind token
+// apply to a running API.
+type configTokens struct {
+	mu      sync.Mutex
+	modTime time.Time
+	hashes  map[string]string
+}
+
+func newConfigTokens(cfg *config.Config) *configTokens {
+	t := &configTokens{hashes: cfg.Tokens}
+	if path, err := config.Path(); err == nil {
+		if info, err := os.Stat(path); err == nil {
+			t.modTime = info.ModTime()
+		}
+	}
+	return t
+}
+
+func (t *configTokens) get() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path, err := config.Path()
+	if err != nil {
+		return t.hashes
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.ModTime().Equal(t.modTime) {
+		return t.hashes
+	}
+	saved, err := config.Load()
+	if err != nil {
+		slog.Warn("reloading client tokens failed", "error", err)
+		return t.hashes
+	}
+	t.modTime = info.ModTime()
+	t.hashes = saved.Tokens
+	return t.hashes
+}
+
+// runToken creates, revokes or lists the client tokens the API accepts
+// besides api_token, one per client so each can be revoked on its own.
+func runToken(cfg *config.Config, args []string) error {
+	switch {
+	case len(args) == 0 || args[0] == "list" && len(args) == 1:
+		if len(cfg.Tokens) == 0 {
+			fmt.Println("No client tokens; ofind token create <name> makes one")
+			return nil
+		}
+		for _, name := range slices.Sorted(maps.Keys(cfg.Tokens)) {
+			fmt.Println(name)
+		}
+		return nil
+
+	case args[0] == "create" && len(args) == 2:
+		token := rand.Text()
+		if err := cfg.AddToken(args[1], token); err != nil {
+			return err
+		}
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		statusf(os.Stderr, "Created token %s. It is shown only once; keep it somewhere safe:\n", args[1])
+		fmt.Println(token)
+		return nil
+
+	case args[0] == "revoke" && len(args) == 2:
+		if err := cfg.RevokeToken(args[1]); err != nil {
+			return err
+		}
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		statusf(os.Stdout, "Revoked token %s\n", args[1])
+		return nil
+	}
+	return fmt.Errorf("usage: ofind token [list] | create <name> | revoke <name>")
+}
+
+// shutdown gives in-flight requests up to shutdownTimeout to finish, then
+// stops a reindex started through the API.
+func (a *apiServer) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if a.grpc != nil {
+		// Streams still open when the time is up are cut off
+		stop := context.AfterFunc(ctx, a.grpc.Stop)
+		defer stop()
+		a.grpc.GracefulStop()
+	}
+	err := a.http.Shutdown(ctx)
+	a.api.Close()
+	return err
+}
+
+// forwardSearch runs a search on the vault's daemon and shows its results,
+// reporting false, without an error, when no daemon is there to run it.
+func forwardSearch(cfg *config.Config, queries []string, opts search.Options, out outputOptions) (bool, error) {
+	socketPath, err := config.SocketPath(cfg.Vault)
+	if err != nil {
+		return false, nil
+	}
+	if _, err := os.Stat(socketPath); err != nil {
+		return false, nil
+	}
+
+	results, err := server.Query(context.Background(), socketPath, cfg.APIToken, server.QueryRequest{
+		Queries: queries,
+		Options: opts,
+		Context: contextSize(out),
+	})
+	if errors.Is(err, server.ErrNoDaemon) {
+		slog.Debug("daemon socket not answering, searching here", "socket", socketPath)
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	slog.Debug("search answered by the daemon", "socket", socketPath)
+	return true, presentResults(cfg, strings.Join(queries, " | "), "", results, out)
+}
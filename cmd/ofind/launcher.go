@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mgomes/obsvec/internal/tui"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+// outputFormats are the values -format accepts, empty meaning unset.
+var outputFormats = []string{"", "alfred", "raycast", "grep"}
+
+// launcherSubtitleLen caps the snippet shown under each launcher item, which
+// has room for one line.
+const launcherSubtitleLen = 120
+
+// alfredItem is an entry of Alfred's script filter JSON.
+type alfredItem struct {
+	UID          string     `json:"uid"`
+	Title        string     `json:"title"`
+	Subtitle     string     `json:"subtitle"`
+	Arg          string     `json:"arg"`
+	Autocomplete string     `json:"autocomplete"`
+	QuickLookURL string     `json:"quicklookurl,omitempty"`
+	Text         alfredText `json:"text"`
+}
+
+type alfredText struct {
+	Copy      string `json:"copy"`
+	LargeType string `json:"largetype"`
+}
+
+// raycastItem is a result for a Raycast extension's list. Arg opens the
+// result, as in Alfred.
+type raycastItem struct {
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	Subtitle string  `json:"subtitle"`
+	Arg      string  `json:"arg"`
+	Path     string  `json:"path"`
+	Score    float64 `json:"score"`
+}
+
+// printResultsLauncher prints results as JSON for the launcher named by
+// out.format, each opening its note in Obsidian at the matching section.
+func printResultsLauncher(results []search.Result, vaultDir string, out outputOptions) error {
+	if out.redact {
+		results = redactResults(results)
+	}
+
+	if out.format == "alfred" {
+		items := make([]alfredItem, len(results))
+		for i, r := range results {
+			items[i] = alfredItem{
+				UID:          r.Path,
+				Title:        launcherTitle(r),
+				Subtitle:     launcherSubtitle(r.Content),
+				Arg:          tui.ObsidianURL(vaultDir, r.Path, r.Heading, r.StartLine),
+				Autocomplete: strings.TrimSuffix(path.Base(r.Path), ".md"),
+				Text: alfredText{
+					Copy:      tui.Wikilink(r.Path, r.Heading),
+					LargeType: r.Content,
+				},
+			}
+			if !out.redact {
+				items[i].QuickLookURL = filepath.Join(vaultDir, filepath.FromSlash(r.Path))
+			}
+		}
+		return writeLauncherJSON(struct {
+			Items []alfredItem `json:"items"`
+		}{items})
+	}
+
+	items := make([]raycastItem, len(results))
+	for i, r := range results {
+		items[i] = raycastItem{
+			ID:       strconv.FormatInt(r.ChunkID, 10),
+			Title:    launcherTitle(r),
+			Subtitle: launcherSubtitle(r.Content),
+			Arg:      tui.ObsidianURL(vaultDir, r.Path, r.Heading, r.StartLine),
+			Path:     r.Path,
+			Score:    r.Score,
+		}
+	}
+	return writeLauncherJSON(struct {
+		Items []raycastItem `json:"items"`
+	}{items})
+}
+
+func writeLauncherJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// launcherTitle names a result by its note and innermost heading, e.g.
+// "Taxes › Deductions".
+func launcherTitle(r search.Result) string {
+	title := strings.TrimSuffix(path.Base(r.Path), ".md")
+	if r.Heading == "" {
+		return title
+	}
+	parts := strings.Split(r.Heading, " > ")
+	return title + " › " + strings.TrimSpace(parts[len(parts)-1])
+}
+
+func launcherSubtitle(content string) string {
+	subtitle := plainField(content)
+	if runes := []rune(subtitle); len(runes) > launcherSubtitleLen {
+		subtitle = string(runes[:launcherSubtitleLen-1]) + "…"
+	}
+	return subtitle
+}
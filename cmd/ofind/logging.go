@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/mgomes/obsvec/internal/config"
+)
+
+// logFileName is the log -v and -vv write to, in the data directory.
+const logFileName = "obsvec.log"
+
+// quiet is set by -quiet to drop progress and status messages, leaving
+// results, warnings and errors.
+var quiet bool
+
+// statusf prints a progress or status message to w unless -quiet is set.
+func statusf(w io.Writer, format string, args ...any) {
+	if !quiet {
+		fmt.Fprintf(w, format, args...)
+	}
+}
+
+// setupLogging sends log output to the log file, at info level for -v (API
+// calls and embed batches) and debug level for -vv (SQL timings too).
+// Without either, logs are discarded. The daemon and serve always log, to
+// stderr.
+func setupLogging(verbose, debug bool, command string) error {
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+
+	if command == "daemon" || command == "serve" {
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+		slog.Info("ofind started", "command", command, "version", version)
+		return nil
+	}
+	if !verbose && !debug {
+		slog.SetDefault(slog.New(slog.DiscardHandler))
+		return nil
+	}
+
+	dir, err := config.DataDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, logFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(f, &slog.HandlerOptions{Level: level})))
+	slog.Info("ofind started", "command", command, "version", version)
+	return nil
+}
@@ -1,29 +1,114 @@
 package main
 
 import (
+	"bufio"
+	"cmp"
+	"compress/gzip"
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/mgomes/obsvec/internal/cohere"
+	"github.com/mgomes/obsvec/internal/ask"
+	"github.com/mgomes/obsvec/internal/cluster"
 	"github.com/mgomes/obsvec/internal/config"
-	"github.com/mgomes/obsvec/internal/db"
-	"github.com/mgomes/obsvec/internal/indexer"
-	"github.com/mgomes/obsvec/internal/search"
+	"github.com/mgomes/obsvec/internal/embedfile"
+	"github.com/mgomes/obsvec/internal/eval"
+	"github.com/mgomes/obsvec/internal/export"
+	"github.com/mgomes/obsvec/internal/fuzzy"
+	"github.com/mgomes/obsvec/internal/history"
+	"github.com/mgomes/obsvec/internal/keychain"
+	"github.com/mgomes/obsvec/internal/redact"
 	"github.com/mgomes/obsvec/internal/tui"
+	"github.com/mgomes/obsvec/internal/vectorstore"
+	"github.com/mgomes/obsvec/internal/webhook"
+	"github.com/mgomes/obsvec/pkg/obsvec/cohere"
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+	"github.com/mgomes/obsvec/pkg/obsvec/events"
+	"github.com/mgomes/obsvec/pkg/obsvec/indexer"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
 )
 
 func main() {
-	query := flag.String("q", "", "search query")
-	doIndex := flag.Bool("index", false, "index the obsidian vault")
+	var queries stringList
+	flag.Var(&queries, "q", "search query (repeat to fuse several queries, - reads it from stdin)")
+	flag.Bool("explore", false, "show the nearest neighbors of a random recent note")
+	similar := flag.String("similar", "", "find notes related to this note (vault-relative path)")
+	limit := flag.Int("n", 10, "number of search results")
+	redactPaths := flag.Bool("redact-paths", false, "demo mode: hash paths and show only the first snippet line")
+	var tags stringList
+	flag.Var(&tags, "tag", "only search notes with this tag (repeatable)")
+	var not stringList
+	flag.Var(&not, "not", "down-rank results similar to this term (repeatable, or -term in the query)")
+	var paths, excludePaths stringList
+	flag.Var(&paths, "path", "only search notes matching this glob, e.g. \"Projects/**\" (repeatable)")
+	flag.Var(&excludePaths, "exclude-path", "skip notes matching this glob (repeatable)")
+	since := flag.String("since", "", "only notes modified on/after this date (YYYY-MM-DD or 30d, 2w, 6m, 1y)")
+	until := flag.String("until", "", "only notes modified on/before this date (YYYY-MM-DD or relative)")
+	followLinks := flag.Bool("links", false, "also show notes linked from the results (one wikilink hop)")
+	onePerNote := flag.Bool("one-per-note", false, "return only the best chunk of each note")
+	contextChunks := flag.Int("context", 0, "include this many neighboring chunks around each result")
+	exportNote := flag.String("export-note", "", "write the results into the vault as a note at this path; <query> is replaced with the query")
+	summarize := flag.Bool("summarize", false, "summarize the top results with the chat model above the result list")
+	explain := flag.Bool("explain", false, "show how each result was scored: vector distance, rerank score, boosts and filters")
+	group := flag.Bool("group", false, "group results by note with chunk hits nested")
+	jsonOutput := flag.Bool("json", false, "print results as JSON instead of opening the TUI")
+	fzfOutput := flag.Bool("fzf", false, "print path:line:snippet lines for fzf or telescope, with the note's full path")
+	format := flag.String("format", "", "print results for a launcher or editor: alfred, raycast or grep")
+	plainOutput := flag.Bool("plain", false, "print one tab-separated result per line (score, path, heading, snippet) instead of opening the TUI; the default when stdout isn't a terminal")
+	recency := flag.String("recency", "", "boost recently modified notes; the boost halves every half-life (e.g. 7d, 0 disables)")
+	expand := flag.Bool("expand", false, "ask the chat model for query paraphrases and search with all of them")
+	bookmarked := flag.Bool("bookmarked", false, "search only the notes and folders bookmarked in Obsidian")
+	mmrLambda := flag.Float64("mmr", -1, "diversify results with MMR; lambda in (0,1], lower is more diverse (0 disables)")
+	asOf := flag.String("as-of", "", "search a git-tracked vault as it was on this date (YYYY-MM-DD or relative)")
+	flag.Bool("index", false, "index the obsidian vault")
 	fullReindex := flag.Bool("full", false, "full reindex (use with -index)")
-	doWatch := flag.Bool("watch", false, "watch for file changes and auto-index")
+	onlyFiles := flag.Bool("files", false, "reindex only the notes given as arguments (use with index)")
+	ref := flag.String("ref", "", "index a git-tracked vault as of this branch or commit instead of the working tree (use with index)")
+	flag.Bool("watch", false, "watch for file changes and auto-index")
+	port := flag.Int("port", defaultPort, "port the daemon's and serve's API listens on, on localhost")
+	grpcPort := flag.Int("grpc-port", 0, "also serve the gRPC API on this localhost port (daemon and serve; 0 disables)")
 	doSetup := flag.Bool("setup", false, "run setup wizard")
-	flag.Parse()
+	assumeYes := flag.Bool("yes", false, "rebuild an index made with other embedding settings, or purge, without asking")
+	dbOnly := flag.Bool("db-only", false, "purge only the index, keeping the settings")
+	vault := flag.String("vault", "", "use this named vault from config.json instead of the default")
+	allVaults := flag.Bool("all-vaults", false, "search the default vault and every named one, merging the results labeled by vault")
+	ephemeralDir := flag.String("dir", "", "index this directory in memory for one search, leaving the saved index untouched")
+	verbose := flag.Bool("v", false, "write API latencies and batch sizes to obsvec.log in the data directory")
+	debug := flag.Bool("vv", false, "like -v, adding SQL timings")
+	flag.BoolVar(&quiet, "quiet", false, "print only results, warnings and errors, no progress")
+	flag.Usage = printUsage
+	command, args, err := parseCommand(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		printUsage()
+		os.Exit(2)
+	}
+
+	// Until they are moved, settings and indexes are used where they are
+	if err := config.Migrate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Not moving settings to their new location yet: %v\n", err)
+	}
+
+	if err := setupLogging(*verbose, *debug, command); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open log: %v\n", err)
+		os.Exit(1)
+	}
 
 	cfg, err := config.Load()
 	if err != nil {
@@ -31,51 +116,459 @@ func main() {
 		os.Exit(1)
 	}
 
-	if *doSetup || cfg.CohereAPIKey == "" {
+	if command == "version" {
+		runOrExit("Version failed", func() error {
+			return runVersion(cfg, *jsonOutput)
+		})
+		return
+	}
+
+	// Settings are edited as saved, before -vault or -dir change them
+	if command == "config" {
+		runOrExit("Config failed", func() error {
+			return runConfig(cfg, args)
+		})
+		return
+	}
+
+	if command == "token" {
+		runOrExit("Token command failed", func() error {
+			return runToken(cfg, args)
+		})
+		return
+	}
+
+	if command == "purge" {
+		runOrExit("Purge failed", func() error {
+			return runPurge(cfg, *vault, *dbOnly, *assumeYes)
+		})
+		return
+	}
+
+	// default_vault stands in for -vault, except for setup and -dir
+	selected := *vault
+	if selected == "" && command != "setup" && !*doSetup && *ephemeralDir == "" && !*allVaults && cfg.CohereAPIKey != "" {
+		selected = cfg.DefaultVault
+	}
+	if selected != "" {
+		if command == "setup" || cfg.CohereAPIKey == "" {
+			fmt.Fprintln(os.Stderr, "-vault can't be combined with setup; run ofind setup first")
+			os.Exit(1)
+		}
+		if err := cfg.UseVault(selected); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -vault: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *allVaults && (command != "search" || *vault != "" || *ephemeralDir != "" || *asOf != "" || *summarize || *exportNote != "" || *fzfOutput || *format != "") {
+		fmt.Fprintln(os.Stderr, "-all-vaults only works with ofind search, without -vault, -dir, -as-of, -summarize, -export-note, -fzf or -format")
+		os.Exit(1)
+	}
+
+	if command == "check-vault" {
+		runOrExit("Vault check failed", func() error {
+			return runCheckVault(cfg, firstArg(args))
+		})
+		return
+	}
+
+	if command == "setup" || *doSetup || cfg.CohereAPIKey == "" {
 		runOrExit("Setup failed", func() error {
 			return runSetup(cfg)
 		})
+		if command == "setup" {
+			return
+		}
+	}
+
+	if command == "service" {
+		runOrExit("Service command failed", func() error {
+			return runService(cfg.Vault, *port, *grpcPort, args)
+		})
+		return
 	}
 
-	if cfg.CohereAPIKey == "" || cfg.ObsidianDir == "" {
-		fmt.Fprintln(os.Stderr, "Please run setup first: ofind -setup")
+	// Bare ofind in a terminal opens the live search screen
+	if command == "" {
+		if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) || *jsonOutput || *plainOutput || *fzfOutput || *format != "" {
+			printUsage()
+			return
+		}
+		command = "search"
+	}
+
+	if *ephemeralDir != "" {
+		if *vault != "" || *asOf != "" || slices.Contains([]string{"index", "watch", "daemon", "serve", "db", "sync"}, command) {
+			fmt.Fprintln(os.Stderr, "-dir can't be combined with -vault, -as-of or the index, watch, daemon, serve, db and sync commands")
+			os.Exit(1)
+		}
+		dir, err := filepath.Abs(*ephemeralDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -dir: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.ObsidianDir = dir
+		// Nothing is written to disk, so there is nothing to encrypt
+		cfg.Encrypt = false
+	}
+
+	if *plainOutput && *jsonOutput || *fzfOutput && (*plainOutput || *jsonOutput) || *format != "" && (*plainOutput || *jsonOutput || *fzfOutput) {
+		fmt.Fprintln(os.Stderr, "-plain, -fzf, -json and -format can't be combined")
+		os.Exit(1)
+	}
+	if !slices.Contains(outputFormats, *format) {
+		fmt.Fprintf(os.Stderr, "Invalid -format %q (known: %s)\n", *format, strings.Join(outputFormats[1:], ", "))
 		os.Exit(1)
 	}
 
-	dbPath, err := config.DBPath()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get database path: %v\n", err)
+	// Results piped or redirected elsewhere can't drive the TUI
+	plain := *plainOutput || !*jsonOutput && !*fzfOutput && *format == "" && !isTerminal(os.Stdout)
+
+	if *ref != "" && (command != "index" || *onlyFiles || len(args) > 0) {
+		fmt.Fprintln(os.Stderr, "-ref only works with ofind index, without -files or note paths")
+		os.Exit(1)
+	}
+
+	if *asOf != "" && slices.Contains([]string{"index", "watch", "daemon", "serve"}, command) {
+		fmt.Fprintln(os.Stderr, "-as-of can't be combined with the index, watch, daemon or serve commands")
+		os.Exit(1)
+	}
+
+	if cfg.CohereAPIKey == "" || cfg.ObsidianDir == "" {
+		fmt.Fprintln(os.Stderr, "Please run setup first: ofind setup")
 		os.Exit(1)
 	}
 
-	database, err := db.Open(dbPath, cfg.EmbedDim)
+	searchOpts := search.Options{
+		Limit:          *limit,
+		Tags:           tags,
+		Paths:          paths,
+		ExcludePaths:   slices.Concat(excludePaths, cfg.ExcludePaths, cfg.ExcludedPaths()),
+		Not:            not,
+		Expand:         *expand || cfg.ExpandQueries,
+		OnePerDocument: *onePerNote,
+		FollowLinks:    *followLinks,
+		MMRLambda:      cfg.MMRLambda,
+		BookmarkedOnly: *bookmarked,
+		BookmarkBoost:  cfg.BookmarkBoost,
+		Explain:        *explain,
+	}
+	searches := slices.Contains([]string{"search", "similar", "explore", "ask", "chat", "eval", "daemon", "serve"}, command)
+	if *bookmarked || cfg.BookmarkBoost > 0 && searches {
+		searchOpts.Bookmarks, err = indexer.Bookmarks(cfg.ObsidianDir)
+		if err != nil && *bookmarked {
+			fmt.Fprintf(os.Stderr, "Failed to read Obsidian bookmarks: %v\n", err)
+			os.Exit(1)
+		}
+		// Without -bookmarked they only reorder results, which can do without
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ignoring Obsidian bookmarks: %v\n", err)
+		}
+		if *bookmarked && len(searchOpts.Bookmarks) == 0 {
+			fmt.Fprintln(os.Stderr, "-bookmarked: no notes are bookmarked in .obsidian/bookmarks.json")
+			os.Exit(1)
+		}
+	}
+	if *mmrLambda >= 0 {
+		searchOpts.MMRLambda = *mmrLambda
+	}
+	if halfLife := cmp.Or(*recency, cfg.RecencyHalfLife); halfLife != "" {
+		searchOpts.RecencyHalfLife, err = search.ParseHalfLife(halfLife)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -recency: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *since != "" {
+		searchOpts.Since, err = search.ParseSince(*since, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -since: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *until != "" {
+		searchOpts.Until, err = search.ParseUntil(*until, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -until: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	searchOut := outputOptions{
+		redact:    *redactPaths || cfg.RedactPaths,
+		group:     *group,
+		json:      *jsonOutput,
+		plain:     plain,
+		fzf:       *fzfOutput,
+		format:    *format,
+		context:   *contextChunks,
+		summarize: *summarize,
+		export:    *exportNote,
+	}
+
+	if *allVaults {
+		if len(args) > 0 {
+			queries = append(queries, strings.Join(args, " "))
+		}
+		runOrExit("Search failed", func() error {
+			queries, err := readStdinQueries(queries)
+			if err != nil {
+				return err
+			}
+			return runSearchAllVaults(cfg, queries, excludePaths, searchOpts, searchOut)
+		})
+		return
+	}
+
+	// A running daemon has the index open and recent query embeddings
+	// cached, so searches it can answer skip opening the database here
+	if command == "search" && *ephemeralDir == "" && *asOf == "" && !*summarize && *exportNote == "" {
+		if len(args) > 0 {
+			queries = append(queries, strings.Join(args, " "))
+			args = nil
+		}
+		forwarded := false
+		runOrExit("Search failed", func() error {
+			queries, err = readStdinQueries(queries)
+			if err != nil || len(queries) == 0 {
+				return err
+			}
+			forwarded, err = forwardSearch(cfg, queries, searchOpts, searchOut)
+			return err
+		})
+		if forwarded {
+			return
+		}
+	}
+
+	dbPath := ":memory:"
+	if *ephemeralDir == "" {
+		dbPath, err = config.DBPath(cfg.Vault)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get database path: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0700); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create database directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	openIndex := func(rebuild bool) (*db.DB, error) {
+		// Only the vault's own index uses an external vector store; -dir
+		// and -as-of indexes are local and short-lived
+		var vectors db.VectorStore
+		if cfg.VectorStore != nil && *ephemeralDir == "" {
+			var err error
+			vectors, err = vectorstore.Open(*cfg.VectorStore, cfg.Vault, cfg.EmbedDim)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open vector store: %w", err)
+			}
+		}
+		return openDBWithRebuild(cfg, dbPath, rebuild, vectors)
+	}
+
+	// A full reindex replaces every vector, so it may also clear an index
+	// built with a different embedding model or size
+	database, err := openIndex(command == "index" && *fullReindex)
+	var mismatch *db.MismatchError
+	rebuilt := false
+	if errors.As(err, &mismatch) && confirmRebuild(mismatch, *assumeYes) {
+		database, err = openIndex(true)
+		rebuilt = true
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to open database: %v\n", err)
 		os.Exit(1)
 	}
 	defer database.Close() //nolint:errcheck
 
-	cohereClient := cohere.NewClient(cfg.CohereAPIKey, cfg.EmbedModel, cfg.RerankModel, cfg.EmbedDim)
+	cohereClient := cohere.NewClient(cfg.CohereAPIKey, cfg.EmbedModel, cfg.RerankModel, cfg.ChatModel, cfg.EmbedDim)
 
-	switch {
-	case *doIndex:
+	// The cleared index is filled again before anything searches it
+	if rebuilt && command != "index" {
+		runOrExit("Indexing failed", func() error {
+			return runIndex(database, cohereClient, cfg, true, "")
+		})
+	}
+
+	if *ephemeralDir != "" {
 		runOrExit("Indexing failed", func() error {
-			return runIndex(database, cohereClient, cfg, *fullReindex)
+			return indexEphemeral(database, cohereClient, cfg.ObsidianDir)
 		})
+	}
+
+	switch command {
+	case "open":
+		runOrExit("Open failed", func() error {
+			return runOpen(database, cfg, strings.Join(args, " "))
+		})
+		return
+
+	case "db":
+		runOrExit("Database command failed", func() error {
+			return runDB(database, cohereClient, cfg, dbPath, args)
+		})
+		return
+
+	case "sync":
+		runOrExit("Sync failed", func() error {
+			return runSync(database, args)
+		})
+		return
+
+	case "stats":
+		runOrExit("Stats failed", func() error {
+			return runStats(database, dbPath, *jsonOutput)
+		})
+		return
+
+	case "clusters":
+		runOrExit("Clustering failed", func() error {
+			return runClusters(database, firstArg(args))
+		})
+		return
+
+	case "index":
+		if *onlyFiles || len(args) > 0 {
+			runOrExit("Indexing failed", func() error {
+				return runIndexFiles(database, cohereClient, cfg, args)
+			})
+			return
+		}
+		runOrExit("Indexing failed", func() error {
+			return runIndex(database, cohereClient, cfg, *fullReindex, *ref)
+		})
+		return
 
-	case *doWatch:
+	case "watch":
 		runOrExit("Watch mode failed", func() error {
 			return runWatch(database, cohereClient, cfg)
 		})
+		return
+	}
+
+	if *asOf != "" {
+		historical, err := openAsOf(cfg, cohereClient, *asOf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open vault history: %v\n", err)
+			os.Exit(1)
+		}
+		defer historical.Close() //nolint:errcheck
+		database = historical
+	}
+
+	switch command {
+	case "ask":
+		runOrExit("Ask failed", func() error {
+			return runAsk(database, cohereClient, strings.Join(args, " "), searchOpts, outputOptions{
+				redact: *redactPaths || cfg.RedactPaths,
+				json:   *jsonOutput,
+			})
+		})
+
+	case "eval":
+		runOrExit("Eval failed", func() error {
+			return runEval(database, cohereClient, firstArg(args), searchOpts, *jsonOutput)
+		})
+
+	case "chat":
+		runOrExit("Chat failed", func() error {
+			return runChat(database, cohereClient, cfg, searchOpts, *redactPaths || cfg.RedactPaths)
+		})
+
+	case "daemon":
+		runOrExit("Daemon failed", func() error {
+			return runDaemon(database, cohereClient, cfg, searchOpts, *port, *grpcPort)
+		})
+
+	case "serve":
+		runOrExit("Serve failed", func() error {
+			return runServe(database, cohereClient, cfg, searchOpts, *port, *grpcPort)
+		})
 
-	case *query != "":
+	case "search":
+		if len(args) > 0 {
+			queries = append(queries, strings.Join(args, " "))
+		}
 		runOrExit("Search failed", func() error {
-			return runSearch(database, cohereClient, cfg, *query)
+			queries, err := readStdinQueries(queries)
+			if err != nil {
+				return err
+			}
+			return runSearch(database, cohereClient, cfg, queries, searchOpts, searchOut)
 		})
 
-	default:
-		printUsage()
+	case "similar":
+		notePath := cmp.Or(strings.Join(args, " "), *similar)
+		runOrExit("Search failed", func() error {
+			return runSimilar(database, cohereClient, cfg, notePath, searchOpts, outputOptions{
+				redact:  *redactPaths || cfg.RedactPaths,
+				group:   *group,
+				json:    *jsonOutput,
+				plain:   plain,
+				fzf:     *fzfOutput,
+				format:  *format,
+				context: *contextChunks,
+			})
+		})
+
+	case "explore":
+		runOrExit("Search failed", func() error {
+			return runExplore(database, cohereClient, cfg, searchOpts, outputOptions{
+				redact:  *redactPaths || cfg.RedactPaths,
+				group:   *group,
+				json:    *jsonOutput,
+				plain:   plain,
+				fzf:     *fzfOutput,
+				format:  *format,
+				context: *contextChunks,
+			})
+		})
+	}
+}
+
+// stringList is a flag.Value collecting repeated string flags.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// readStdinQueries replaces a "-" query with the text on stdin, so long or
+// multi-line queries can be piped in without quoting.
+func readStdinQueries(queries []string) ([]string, error) {
+	i := slices.Index(queries, "-")
+	if i < 0 {
+		return queries, nil
+	}
+	if slices.Contains(queries[i+1:], "-") {
+		return nil, fmt.Errorf("only one query can be read from stdin")
 	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query from stdin: %w", err)
+	}
+	query := strings.TrimSpace(string(data))
+	if query == "" {
+		return nil, fmt.Errorf("empty query on stdin")
+	}
+	return slices.Replace(slices.Clone(queries), i, i+1, query), nil
+}
+
+// firstArg returns the first of args, or "" if there are none.
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
 }
 
 func runOrExit(prefix string, fn func() error) {
@@ -96,6 +589,10 @@ func runSetup(cfg *config.Config) error {
 		if runner.apiKey != "" && runner.obsidianDir != "" {
 			cfg.CohereAPIKey = runner.apiKey
 			cfg.ObsidianDir = runner.obsidianDir
+			cfg.KeychainAPIKey = runner.keychain
+			if cfg.APIToken == "" {
+				cfg.APIToken = rand.Text()
+			}
 			return cfg.Save()
 		}
 	}
@@ -108,11 +605,16 @@ type setupRunner struct {
 	cfg         *config.Config
 	apiKey      string
 	obsidianDir string
+	keychain    bool
 }
 
 func newSetupRunner(cfg *config.Config) setupRunner {
+	model := tui.NewSetupModel().WithVaults(config.DetectVaults())
+	if keychain.Available() {
+		model = model.WithKeychain()
+	}
 	return setupRunner{
-		setupModel: tui.NewSetupModel(),
+		setupModel: model,
 		cfg:        cfg,
 	}
 }
@@ -125,7 +627,7 @@ func (m setupRunner) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tui.SetupSubmitMsg:
 		ctx := context.Background()
-		client := cohere.NewClient(msg.APIKey, m.cfg.EmbedModel, m.cfg.RerankModel, m.cfg.EmbedDim)
+		client := cohere.NewClient(msg.APIKey, m.cfg.EmbedModel, m.cfg.RerankModel, m.cfg.ChatModel, m.cfg.EmbedDim)
 		if err := client.ValidateAPIKey(ctx); err != nil {
 			newModel, _ := m.setupModel.Update(tui.SetupErrorMsg{Error: "Invalid API key: " + err.Error()})
 			if sm, ok := newModel.(tui.SetupModel); ok {
@@ -144,6 +646,7 @@ func (m setupRunner) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		m.apiKey = msg.APIKey
 		m.obsidianDir = msg.ObsidianDir
+		m.keychain = msg.Keychain
 		return m, tea.Quit
 
 	default:
@@ -159,43 +662,302 @@ func (m setupRunner) View() string {
 	return m.setupModel.View()
 }
 
-func runIndex(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, fullReindex bool) error {
-	idx := indexer.New(database, cohereClient, cfg.ObsidianDir)
+// runIndex indexes the vault, or with ref the vault's notes as of that git
+// branch or commit, leaving uncommitted edits out.
+func runIndex(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, fullReindex bool, ref string) error {
+	dir := cfg.ObsidianDir
+	if ref != "" {
+		commit, err := history.ResolveRef(cfg.ObsidianDir, ref)
+		if err != nil {
+			return err
+		}
+		snapshot, err := os.MkdirTemp("", "obsvec-ref")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(snapshot) //nolint:errcheck
 
-	progress := func(p indexer.Progress) {
-		if p.Total > 0 {
-			// Clear line and print progress (truncate long messages)
-			msg := p.Message
-			if len(msg) > 60 {
-				msg = msg[:57] + "..."
-			}
-			fmt.Printf("\r\033[K[%d/%d] %s", p.Current, p.Total, msg)
-		} else if p.Message != "" {
-			fmt.Println(p.Message)
+		if err := history.Extract(cfg.ObsidianDir, commit, snapshot); err != nil {
+			return err
 		}
+		statusf(os.Stdout, "Indexing %s at commit %s\n", ref, commit[:12])
+		dir = snapshot
 	}
 
-	ctx := context.Background()
-	if err := idx.Index(ctx, fullReindex, progress); err != nil {
+	idx, closeIndexer := newIndexerAt(database, cohereClient, cfg, dir, nil)
+	defer closeIndexer()
+	// Every file of a snapshot has the commit time, which says nothing
+	// about whether it differs from what was indexed
+	idx.SetCompareContent(ref != "")
+
+	timer, err := indexWithProgress(func(ctx context.Context, progress indexer.ProgressFunc) error {
+		return idx.Index(ctx, fullReindex, progress)
+	})
+	var skippedErr *indexer.SkippedFilesError
+	if err != nil && !errors.As(err, &skippedErr) {
 		return err
 	}
 
-	fmt.Println()
+	if !quiet {
+		docCount, _ := database.DocumentCount()
+		chunkCount, _ := database.ChunkCount()
+		fmt.Printf("Index complete: %d documents, %d chunks\n", docCount, chunkCount)
+		timer.print(os.Stdout)
+	}
+
+	if skippedErr != nil {
+		fmt.Fprintf(os.Stderr, "Skipped %d file(s) that could not be indexed:\n", len(skippedErr.Files))
+		for _, fileErr := range skippedErr.Files {
+			fmt.Fprintf(os.Stderr, "  %s\n", fileErr.Error())
+		}
+	}
+
+	return nil
+}
+
+// runIndexFiles reindexes the given notes without scanning the vault. Paths
+// may be absolute, relative to the working directory inside the vault, or
+// relative to the vault.
+func runIndexFiles(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, paths []string) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("usage: ofind index -files <note>...")
+	}
+
+	relPaths := make([]string, len(paths))
+	for i, path := range paths {
+		relPaths[i] = vaultRelPath(cfg.ObsidianDir, path)
+	}
+
+	idx, closeIndexer := newVaultIndexer(database, cohereClient, cfg, nil)
+	defer closeIndexer()
+	err := idx.IndexFiles(context.Background(), relPaths)
+	var skippedErr *indexer.SkippedFilesError
+	if err != nil && !errors.As(err, &skippedErr) {
+		return err
+	}
+
+	if skippedErr != nil {
+		statusf(os.Stdout, "Reindexed %d of %d note(s)\n", len(paths)-len(skippedErr.Files), len(paths))
+		return skippedErr
+	}
+	statusf(os.Stdout, "Reindexed %d note(s)\n", len(paths))
+	return nil
+}
+
+// vaultRelPath returns path relative to the vault. A path that doesn't
+// resolve to a file inside the vault is taken to be relative to it already.
+func vaultRelPath(vaultDir, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(vaultDir, abs)
+	if err != nil || !filepath.IsLocal(rel) {
+		return path
+	}
+	if _, err := os.Stat(abs); err != nil && !filepath.IsAbs(path) {
+		return path
+	}
+	return rel
+}
+
+// dbKeyAccount is the keychain entry holding the database encryption key,
+// shared by every vault.
+const dbKeyAccount = "database-key"
+
+// openDB opens the index at path with the configured embedding model, size
+// and quantization.
+func openDB(cfg *config.Config, path string) (*db.DB, error) {
+	return openDBWithRebuild(cfg, path, false, nil)
+}
+
+// openDBWithRebuild is openDB that, if rebuild is set, clears an index built
+// with other embedding settings instead of failing. A non-nil vectors keeps
+// the embeddings there instead of in the database.
+func openDBWithRebuild(cfg *config.Config, path string, rebuild bool, vectors db.VectorStore) (*db.DB, error) {
+	quantization, err := db.ParseQuantization(cfg.Quantization)
+	if err != nil {
+		return nil, err
+	}
+	opts := db.Options{
+		EmbedDim:     cfg.EmbedDim,
+		EmbedModel:   cfg.EmbedModel,
+		Quantization: quantization,
+		Rebuild:      rebuild,
+		VectorStore:  vectors,
+	}
+	if cfg.Encrypt {
+		// A new key would never open an existing index, so only make one
+		// for a new database
+		create := db.NewKey
+		if _, err := os.Stat(path); err == nil {
+			create = func() (string, error) {
+				return "", fmt.Errorf("no key for %s in the keychain; unlock the keychain and try again", path)
+			}
+		}
+		opts.Key, err = keychain.GetOrCreate(dbKeyAccount, create)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get database key: %w", err)
+		}
+	}
+	return db.OpenWithOptions(path, opts)
+}
+
+// confirmRebuild asks whether to clear an index built with other embedding
+// settings so it can be indexed again.
+func confirmRebuild(mismatch *db.MismatchError, assumeYes bool) bool {
+	return confirm(fmt.Sprintf("The index holds %s.\nRebuild it now? Every note will be embedded again.", mismatch.Reason), assumeYes)
+}
+
+// confirm asks a yes/no question on the terminal. Without a terminal to ask
+// on, only -yes agrees.
+func confirm(question string, assumeYes bool) bool {
+	if assumeYes {
+		return true
+	}
+	if !isTerminal(os.Stdin) {
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// webhookFlushTimeout is how long ofind waits on exit for webhooks still
+// being delivered.
+const webhookFlushTimeout = 5 * time.Second
+
+// newVaultIndexer returns an indexer for the configured vault, publishing
+// its events to bus, if not nil, and sending them to the configured webhooks.
+// Call the returned function when done indexing to deliver those still
+// queued.
+func newVaultIndexer(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, bus *events.Bus) (*indexer.Indexer, func()) {
+	return newIndexerAt(database, cohereClient, cfg, cfg.ObsidianDir, bus)
+}
+
+// newIndexerAt is newVaultIndexer reading the notes from dir, a copy of the
+// vault.
+func newIndexerAt(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, dir string, bus *events.Bus) (*indexer.Indexer, func()) {
+	idx := indexer.New(database, cohereClient, dir)
+	idx.SetTrashRetention(cfg.TrashRetention())
+	idx.SetExcludePaths(cfg.ExcludePaths)
+	if len(cfg.Webhooks) == 0 {
+		idx.SetEventBus(bus)
+		return idx, func() {}
+	}
+
+	notifier := webhook.New(slices.Sorted(maps.Values(cfg.Webhooks)), cfg.ObsidianDir)
+	if bus == nil {
+		bus = events.NewBus()
+	}
+	bus.Subscribe(notifier.Handle)
+	idx.SetEventBus(bus)
+	return idx, func() { notifier.Close(webhookFlushTimeout) }
+}
 
-	docCount, _ := database.DocumentCount()
-	chunkCount, _ := database.ChunkCount()
-	fmt.Printf("Index complete: %d documents, %d chunks\n", docCount, chunkCount)
+// indexEphemeral indexes dir into an in-memory database for -dir. Progress
+// goes to stderr so -json output stays clean.
+func indexEphemeral(database *db.DB, cohereClient *cohere.Client, dir string) error {
+	statusf(os.Stderr, "Indexing %s in memory...\n", dir)
 
+	err := indexer.New(database, cohereClient, dir).Index(context.Background(), true, nil)
+	var skippedErr *indexer.SkippedFilesError
+	if err != nil && !errors.As(err, &skippedErr) {
+		return err
+	}
+	if skippedErr != nil {
+		fmt.Fprintf(os.Stderr, "Skipped %d file(s) that could not be indexed\n", len(skippedErr.Files))
+	}
 	return nil
 }
 
+// openAsOf opens an index of the vault as of the last git commit on or
+// before value, building it on first use. Indexes are kept per commit under
+// config.HistoryDir so later searches of the same date are free.
+func openAsOf(cfg *config.Config, cohereClient *cohere.Client, value string) (*db.DB, error) {
+	t, err := search.ParseUntil(value, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	commit, err := history.CommitAt(cfg.ObsidianDir, t)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := config.HistoryDir(cfg.Vault)
+	if err != nil {
+		return nil, err
+	}
+	dbPath := filepath.Join(dir, commit[:12]+".db")
+	if _, err := os.Stat(dbPath); err == nil {
+		return openDB(cfg, dbPath)
+	}
+
+	statusf(os.Stderr, "Indexing vault as of commit %s (first search of this date)...\n", commit[:12])
+
+	snapshot, err := os.MkdirTemp("", "obsvec-as-of")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(snapshot) //nolint:errcheck
+
+	if err := history.Extract(cfg.ObsidianDir, commit, snapshot); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	// Build under a temporary name so an interrupted run isn't mistaken for
+	// a finished index
+	tmpPath := dbPath + ".tmp"
+	_ = os.Remove(tmpPath)
+	database, err := openDB(cfg, tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	err = indexer.New(database, cohereClient, snapshot).Index(context.Background(), true, nil)
+	_ = database.Close()
+
+	var skippedErr *indexer.SkippedFilesError
+	if err != nil && !errors.As(err, &skippedErr) {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		return nil, err
+	}
+	return openDB(cfg, dbPath)
+}
+
 func runWatch(database *db.DB, cohereClient *cohere.Client, cfg *config.Config) error {
-	idx := indexer.New(database, cohereClient, cfg.ObsidianDir)
+	idx, closeIndexer := newVaultIndexer(database, cohereClient, cfg, nil)
+	defer closeIndexer()
+
+	debounce, err := cfg.WatchDebounceDuration()
+	if err != nil {
+		return err
+	}
+	batchWindow, err := cfg.WatchBatchWindowDuration()
+	if err != nil {
+		return err
+	}
 
 	watcher, err := indexer.NewWatcher(idx)
 	if err != nil {
 		return err
 	}
+	watcher.SetQuiet(quiet)
+	watcher.SetDebounce(debounce)
+	watcher.SetBatchWindow(batchWindow)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -205,58 +967,1440 @@ func runWatch(database *db.DB, cohereClient *cohere.Client, cfg *config.Config)
 
 	go func() {
 		<-sigCh
-		fmt.Println("\nStopping watcher...")
+		statusf(os.Stdout, "\nStopping watcher...\n")
 		cancel()
 	}()
 
 	return watcher.Start(ctx)
 }
 
-func runSearch(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, query string) error {
-	searcher := search.New(database, cohereClient)
-
-	ctx := context.Background()
-	results, err := searcher.Search(ctx, query)
-	if err != nil {
-		return err
+// runPurge deletes what ofind keeps for a vault after asking: with -db-only
+// its index, and otherwise also its entry in the config. Without -vault or
+// -db-only everything goes: the settings, every vault's index and the keys
+// kept in the keychain.
+func runPurge(cfg *config.Config, vault string, dbOnly, assumeYes bool) error {
+	if vault != "" && (vault == "." || vault == ".." || filepath.Base(vault) != vault) {
+		return fmt.Errorf("invalid vault name %q", vault)
 	}
+	everything := vault == "" && !dbOnly
 
-	model := tui.NewSearchModel(query, cfg.ObsidianDir)
-
-	tuiResults := make([]tui.SearchResult, len(results))
-	for i, r := range results {
-		tuiResults[i] = tui.SearchResult{
-			Rank:    r.Rank,
-			Score:   r.Score,
-			Path:    r.Path,
-			Heading: r.Heading,
-			Snippet: r.Content,
-			DocID:   r.DocID,
-			ChunkID: r.ChunkID,
+	var targets []string
+	if everything {
+		configDir, err := config.ConfigDir()
+		if err != nil {
+			return err
+		}
+		dataDir, err := config.DataDir()
+		if err != nil {
+			return err
+		}
+		targets = slices.Compact([]string{configDir, dataDir})
+	} else {
+		var err error
+		if targets, err = indexFiles(vault); err != nil {
+			return err
 		}
 	}
+	targets = slices.DeleteFunc(targets, func(path string) bool {
+		_, err := os.Lstat(path)
+		return err != nil
+	})
+	_, inConfig := cfg.Vaults[vault]
+	forget := vault != "" && !dbOnly && inConfig
 
-	initCmd := func() tea.Msg {
-		return tui.SearchResultsMsg{Results: tuiResults}
+	if len(targets) == 0 && !forget {
+		fmt.Println("Nothing to purge")
+		return nil
 	}
-	_, err = runTeaProgram(model, initCmd)
+
+	fmt.Println("This deletes:")
+	for _, path := range targets {
+		fmt.Printf("  %s\n", path)
+	}
+	if forget {
+		fmt.Printf("  vault %q from the config\n", vault)
+	}
+	if everything && cfg.Encrypt {
+		fmt.Println("  the database key in the keychain")
+	}
+	if everything && cfg.KeychainAPIKey {
+		fmt.Println("  the API key in the keychain")
+	}
+	if cfg.VectorStore != nil {
+		fmt.Printf("Vectors kept in %s are not deleted.\n", cfg.VectorStore.Type)
+	}
+	if !confirm("Purge?", assumeYes) {
+		return fmt.Errorf("cancelled; pass -yes to purge without a terminal")
+	}
+
+	for _, path := range targets {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	if forget {
+		cfg.RemoveVault(vault)
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+	}
+	if everything && cfg.Encrypt {
+		if err := keychain.Delete(dbKeyAccount); err != nil && !errors.Is(err, keychain.ErrNotFound) {
+			return fmt.Errorf("failed to delete the database key: %w", err)
+		}
+	}
+	if everything && cfg.KeychainAPIKey {
+		if err := cfg.DeleteAPIKeys(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Purged")
+	return nil
+}
+
+// indexFiles returns the paths holding a vault's index: the database, its
+// WAL files and the indexes built for -as-of searches.
+func indexFiles(vault string) ([]string, error) {
+	dbPath, err := config.DBPath(vault)
+	if err != nil {
+		return nil, err
+	}
+	historyDir, err := config.HistoryDir(vault)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{dbPath, dbPath + "-wal", dbPath + "-shm"}
+	if vault != "" {
+		return append(paths, historyDir), nil
+	}
+
+	// The default vault's history directory also holds the named vaults'
+	entries, err := os.ReadDir(historyDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Name() != "vaults" {
+			paths = append(paths, filepath.Join(historyDir, entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// secretKeys are settings shown masked when listing the config.
+var secretKeys = []string{"cohere_api_key", "vector_store.api_key", "api_token"}
+
+func runConfig(cfg *config.Config, args []string) error {
+	switch {
+	case len(args) == 0 || args[0] == "list":
+		vaults := make(map[string]string)
+		for name, profile := range cfg.Vaults {
+			vaults[name] = profile.Dir
+			for setting, value := range profile.Settings() {
+				if setting == "cohere_api_key" {
+					value = "(set)"
+				}
+				vaults[name+"."+setting] = value
+			}
+		}
+		for _, key := range config.Keys() {
+			if entries, ok := map[string]map[string]string{
+				"vaults.<name>":      vaults,
+				"keybindings.<name>": cfg.Keybindings,
+				"webhooks.<name>":    cfg.Webhooks,
+				"tokens.<name>":      cfg.Tokens,
+			}[key]; ok {
+				prefix := strings.TrimSuffix(key, "<name>")
+				for _, name := range slices.Sorted(maps.Keys(entries)) {
+					value := entries[name]
+					if key == "tokens.<name>" {
+						value = "(set)"
+					}
+					fmt.Printf("%s%s = %s\n", prefix, name, value)
+				}
+				continue
+			}
+			value, err := cfg.Get(key)
+			if err != nil {
+				return err
+			}
+			if value != "" && slices.Contains(secretKeys, key) {
+				value = "(set)"
+			}
+			fmt.Printf("%s = %s\n", key, value)
+		}
+		return nil
+
+	case args[0] == "get" && len(args) == 2:
+		value, err := cfg.Get(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+
+	case args[0] == "set" && len(args) == 3:
+		return setConfig(cfg, args[1], args[2])
+	}
+	return fmt.Errorf("usage: ofind config [list] | get <key> | set <key> <value>")
+}
+
+// setConfig changes one setting, checks it and saves the config, warning
+// when the index has to be rebuilt for the change to take effect.
+func setConfig(cfg *config.Config, key, value string) error {
+	before, err := cfg.Get(key)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Set(key, value); err != nil {
+		return err
+	}
+
+	// These are parsed where they're used
+	switch key {
+	case "quantization":
+		if _, err := db.ParseQuantization(cfg.Quantization); err != nil {
+			return err
+		}
+	case "recency_half_life":
+		if cfg.RecencyHalfLife != "" {
+			if _, err := search.ParseHalfLife(cfg.RecencyHalfLife); err != nil {
+				return err
+			}
+		}
+	}
+	if strings.HasPrefix(key, "keybindings.") {
+		if _, err := tui.DefaultKeyMap().Rebind(cfg.Keybindings); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+
+	after, _ := cfg.Get(key)
+	if after == before || !config.RequiresReindex(key) {
+		return nil
+	}
+	if key == "encrypt" {
+		fmt.Fprintln(os.Stderr, "The existing index can't be converted: delete it and run ofind index to rebuild it.")
+	} else {
+		fmt.Fprintf(os.Stderr, "Changing %s invalidates the existing index: run ofind index -full to rebuild it.\n", key)
+	}
+	return nil
+}
+
+func runCheckVault(cfg *config.Config, dir string) error {
+	if dir == "" {
+		dir = cfg.ObsidianDir
+	}
+	if dir == "" {
+		return fmt.Errorf("no vault directory configured; pass one as an argument or run ofind setup")
+	}
+
+	report, err := indexer.CheckVault(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Vault: %s\n", report.Dir)
+	if report.IsObsidianVault {
+		fmt.Println("  Obsidian vault:   yes (.obsidian found)")
+	} else {
+		fmt.Println("  Obsidian vault:   no .obsidian directory found (plain markdown folder?)")
+	}
+	fmt.Printf("  Markdown files:   %d (%s)\n", report.MarkdownFiles, formatBytes(report.TotalBytes))
+	fmt.Printf("  Estimated chunks: %d\n", report.EstimatedChunks)
+	fmt.Printf("  Estimated tokens: %d\n", report.EstimatedTokens)
+	fmt.Printf("  Estimated index:  %s\n", formatBytes(report.EstimatedIndexBytes(cfg.EmbedDim)))
+	fmt.Printf("  Estimated cost:   $%.2f to embed once\n", report.EstimatedCost())
+
+	if len(report.LargeFiles) > 0 {
+		fmt.Printf("\nLarge files (%d):\n", len(report.LargeFiles))
+		for _, f := range report.LargeFiles {
+			fmt.Printf("  %s (%s)\n", f.Path, formatBytes(f.Bytes))
+		}
+	}
+
+	if len(report.Unreadable) > 0 {
+		fmt.Printf("\nUnreadable paths (%d):\n", len(report.Unreadable))
+		for _, fileErr := range report.Unreadable {
+			fmt.Printf("  %s\n", fileErr.Error())
+		}
+	}
+
+	if report.MarkdownFiles == 0 {
+		return fmt.Errorf("no markdown files found in %s", report.Dir)
+	}
+
+	return nil
+}
+
+// openAlternativesShown is how many runner-up matches ofind open lists.
+const openAlternativesShown = 4
+
+func runOpen(database *db.DB, cfg *config.Config, query string) error {
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("usage: ofind open <title>")
+	}
+
+	titles, err := database.GetTitles(db.TitleNote, db.TitleFile, db.TitleAlias)
+	if err != nil {
+		return err
+	}
+
+	// Titles come ordered by path, so each note's names are adjacent
+	var candidates []fuzzy.Candidate
+	for _, title := range titles {
+		if n := len(candidates); n > 0 && candidates[n-1].Path == title.Path {
+			candidates[n-1].Names = append(candidates[n-1].Names, title.Name)
+			continue
+		}
+		candidates = append(candidates, fuzzy.Candidate{Path: title.Path, Names: []string{title.Name}})
+	}
+
+	matches := fuzzy.Find(query, candidates)
+	if len(matches) == 0 {
+		return fmt.Errorf("no note matches %q", query)
+	}
+
+	statusf(os.Stdout, "Opening %s\n", matches[0].Path)
+	if editor := cfg.EditorCommand(); cfg.OpenInEditor && editor != "" {
+		if err := tui.OpenInEditor(editor, cfg.ObsidianDir, matches[0].Path, 1); err != nil {
+			return fmt.Errorf("editor: %w", err)
+		}
+	} else {
+		tui.OpenInObsidian(cfg.ObsidianDir, matches[0].Path, "", 0)
+	}
+
+	if len(matches) > 1 {
+		fmt.Println("Other matches:")
+		for _, m := range matches[1:min(len(matches), openAlternativesShown+1)] {
+			fmt.Printf("  %s\n", m.Path)
+		}
+	}
+	return nil
+}
+
+// clusterNotesShown is how many representative notes are listed per cluster.
+const clusterNotesShown = 5
+
+func runClusters(database *db.DB, kArg string) error {
+	embeddings, err := database.GetDocumentEmbeddings()
+	if err != nil {
+		return err
+	}
+	if len(embeddings) == 0 {
+		return fmt.Errorf("no indexed notes; run ofind index first")
+	}
+
+	docs, err := database.GetAllDocuments()
+	if err != nil {
+		return err
+	}
+
+	var notes []cluster.Note
+	for _, doc := range docs {
+		if vector, ok := embeddings[doc.ID]; ok {
+			notes = append(notes, cluster.Note{DocID: doc.ID, Path: doc.Path, Title: doc.Title, Vector: vector})
+		}
+	}
+	// Stable input order keeps the seeded clustering reproducible
+	sort.Slice(notes, func(i, j int) bool { return notes[i].DocID < notes[j].DocID })
+
+	k := cluster.DefaultK(len(notes))
+	if kArg != "" {
+		k, err = strconv.Atoi(kArg)
+		if err != nil || k < 1 {
+			return fmt.Errorf("invalid cluster count %q", kArg)
+		}
+	}
+
+	for i, c := range cluster.Build(notes, k, 1) {
+		fmt.Printf("%d. %s (%d notes)\n", i+1, c.Label(), len(c.Notes))
+		for _, note := range c.Notes[:min(clusterNotesShown, len(c.Notes))] {
+			fmt.Printf("     %s\n", note.Path)
+		}
+		if len(c.Notes) > clusterNotesShown {
+			fmt.Printf("     ... and %d more\n", len(c.Notes)-clusterNotesShown)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// statsLargestShown is how many of the biggest notes ofind stats lists.
+const statsLargestShown = 5
+
+// statsBarWidth is the length of the longest bar in the per-month chart.
+const statsBarWidth = 40
+
+func runStats(database *db.DB, dbPath string, jsonOutput bool) error {
+	stats, err := database.Stats(statsLargestShown)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	if info, err := os.Stat(dbPath); err == nil {
+		size = info.Size()
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			*db.Stats
+			DatabaseBytes int64 `json:"database_bytes"`
+		}{stats, size})
+	}
+
+	fmt.Printf("Notes:     %d\n", stats.Documents)
+	fmt.Printf("Chunks:    %d\n", stats.Chunks)
+	fmt.Printf("Words:     %d (%.0f per note)\n", stats.Words, stats.WordsPerNote())
+	fmt.Printf("Tokens:    ~%d (%.0f per note)\n", stats.Tokens, stats.TokensPerNote())
+	if size > 0 {
+		fmt.Printf("Database:  %s\n", formatBytes(size))
+	}
+
+	if len(stats.Largest) > 0 {
+		fmt.Println()
+		fmt.Println("Largest notes:")
+		for _, note := range stats.Largest {
+			fmt.Printf("  %7d words  %s\n", note.Words, note.Path)
+		}
+	}
+
+	if len(stats.Months) > 0 {
+		most := 0
+		for _, month := range stats.Months {
+			most = max(most, month.Notes)
+		}
+		fmt.Println()
+		fmt.Println("Notes by month last modified:")
+		for _, month := range stats.Months {
+			bar := strings.Repeat("█", max(1, month.Notes*statsBarWidth/most))
+			fmt.Printf("  %s  %5d  %s\n", month.Month, month.Notes, bar)
+		}
+	}
+	return nil
+}
+
+func runDB(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, dbPath string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: ofind db vacuum|prune|verify [-fix]|export <file>|import <file>|export-embeddings <file>|import-embeddings <file>")
+	}
+
+	switch args[0] {
+	case "vacuum":
+		return runVacuum(database, dbPath)
+	case "prune":
+		return runPrune(database, cfg.ObsidianDir)
+	case "verify":
+		fix := len(args) > 1 && (args[1] == "-fix" || args[1] == "--fix")
+		return runVerify(database, cohereClient, cfg, fix)
+	case "export", "import":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: ofind db %s <file>", args[0])
+		}
+		if args[0] == "export" {
+			return runExportIndex(database, args[1])
+		}
+		return runImportIndex(database, args[1])
+	case "export-embeddings":
+		return runExportEmbeddings(database, args[1:])
+	case "import-embeddings":
+		return runImportEmbeddings(database, args[1:])
+	}
+	return fmt.Errorf("unknown db command %q", args[0])
+}
+
+func runPrune(database *db.DB, vaultDir string) error {
+	// An unmounted or moved vault would otherwise look like every note was
+	// deleted
+	if info, err := os.Stat(vaultDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("vault directory %s is not available", vaultDir)
+	}
+
+	result, err := database.Prune(func(path string) bool {
+		_, err := os.Stat(filepath.Join(vaultDir, path))
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed %d deleted notes, %d orphaned chunks and %d orphaned vectors\n", result.Documents, result.Chunks, result.Vectors)
+	return nil
+}
+
+// verifyPathsShown caps how many notes each category of a verify report
+// lists.
+const verifyPathsShown = 20
+
+// runVerify checks the index against the vault and reports which notes need
+// reindexing. With fix it also repairs the index.
+func runVerify(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, fix bool) error {
+	if info, err := os.Stat(cfg.ObsidianDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("vault directory %s is not available", cfg.ObsidianDir)
+	}
+
+	idx, closeIndexer := newVaultIndexer(database, cohereClient, cfg, nil)
+	defer closeIndexer()
+	report, err := idx.Verify()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Checked %d notes\n", report.Files)
+	printVerifyPaths("Not indexed", report.NotIndexed)
+	printVerifyPaths("Deleted from the vault", report.Deleted)
+	printVerifyPaths("Changed since indexing", report.Stale)
+	printVerifyPaths("Missing embeddings", report.MissingEmbeddings)
+	printVerifyPaths("Indexed without a checksum", report.Unhashed)
+	if len(report.Unreadable) > 0 {
+		fmt.Printf("Unreadable (%d):\n", len(report.Unreadable))
+		for _, fileErr := range report.Unreadable {
+			fmt.Printf("  %s\n", fileErr.Error())
+		}
+	}
+
+	if report.OK() {
+		fmt.Println("Index matches the vault")
+		return nil
+	}
+	if !fix {
+		return fmt.Errorf("%d notes need reindexing; run ofind db verify -fix", len(report.NeedsReindex()))
+	}
+
+	err = idx.Repair(context.Background(), report)
+	var skippedErr *indexer.SkippedFilesError
+	if err != nil && !errors.As(err, &skippedErr) {
+		return err
+	}
+	fmt.Printf("Reindexed %d notes and removed %d deleted ones\n", len(report.NeedsReindex()), len(report.Deleted))
+	if skippedErr != nil {
+		fmt.Fprintf(os.Stderr, "Skipped %d file(s) that could not be indexed:\n", len(skippedErr.Files))
+		for _, fileErr := range skippedErr.Files {
+			fmt.Fprintf(os.Stderr, "  %s\n", fileErr.Error())
+		}
+	}
+	return nil
+}
+
+func printVerifyPaths(label string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", label, len(paths))
+	for _, path := range paths[:min(len(paths), verifyPathsShown)] {
+		fmt.Printf("  %s\n", path)
+	}
+	if len(paths) > verifyPathsShown {
+		fmt.Printf("  ... and %d more\n", len(paths)-verifyPathsShown)
+	}
+}
+
+// runExportIndex writes the index to path, gzipped if it ends in .gz.
+func runExportIndex(database *db.DB, path string) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+
+	var w io.Writer = f
+	if strings.HasSuffix(path, ".gz") {
+		gz := gzip.NewWriter(f)
+		defer func() {
+			if closeErr := gz.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = gz
+	}
+
+	buf := bufio.NewWriter(w)
+	if err := database.Export(buf); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+
+	docs, err := database.DocumentCount()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d notes to %s\n", docs, path)
+	return nil
+}
+
+// runExportEmbeddings writes every chunk's embedding, with its id, path,
+// heading, lines and content hash, to a file for notebooks. The format is
+// -format, or else taken from the file's extension.
+func runExportEmbeddings(database *db.DB, args []string) (err error) {
+	format, path, err := formatAndFile(args, embedfile.Formats, "usage: ofind db export-embeddings [-format parquet|npz] <file>")
+	if err != nil {
+		return err
+	}
+
+	chunks, err := database.ChunkEmbeddings()
+	if err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return fmt.Errorf("no embeddings to export; run ofind index first")
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+
+	buf := bufio.NewWriter(f)
+	if err := embedfile.Write(buf, format, chunks); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d embeddings of %d dimensions to %s\n", len(chunks), len(chunks[0].Embedding), path)
+	return nil
+}
+
+// runImportEmbeddings loads embeddings computed elsewhere, keyed by the
+// SHA-256 of each chunk's text, applying them to matching chunks now and
+// to matching chunks indexed later instead of calling the embedding API.
+func runImportEmbeddings(database *db.DB, args []string) error {
+	format, path, err := formatAndFile(args, embedfile.ReadFormats, "usage: ofind db import-embeddings [-format parquet|jsonl] <file>")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+
+	vectors, err := embedfile.Read(f, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	applied, err := database.ImportEmbeddings(vectors)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d embeddings from %s; %d chunks in the index use them\n", len(vectors), path, applied)
+	return nil
+}
+
+// formatAndFile parses the [-format <format>] <file> arguments of the
+// embedding file commands, taking the format from the file's extension when
+// not given.
+func formatAndFile(args, formats []string, usage string) (format, path string, err error) {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case (arg == "-format" || arg == "--format") && i+1 < len(args):
+			format = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "-format=") || strings.HasPrefix(arg, "--format="):
+			format = arg[strings.Index(arg, "=")+1:]
+		case path == "" && !strings.HasPrefix(arg, "-"):
+			path = arg
+		default:
+			return "", "", errors.New(usage)
+		}
+	}
+	if path == "" {
+		return "", "", errors.New(usage)
+	}
+
+	if format == "" {
+		if format = embedfile.FormatFromPath(path, formats); format == "" {
+			return "", "", fmt.Errorf("can't tell the format from %s; pass -format %s", path, strings.Join(formats, " or -format "))
+		}
+	}
+	if !slices.Contains(formats, format) {
+		return "", "", fmt.Errorf("unknown format %q (want %s)", format, strings.Join(formats, " or "))
+	}
+	return format, path, nil
+}
+
+func runImportIndex(database *db.DB, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close() //nolint:errcheck
+		r = gz
+	}
+
+	count, err := database.Import(r)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d notes from %s\n", count, path)
+	return nil
+}
+
+func runVacuum(database *db.DB, dbPath string) error {
+	before, err := os.Stat(dbPath)
+	if err != nil {
+		return err
+	}
+
+	if err := database.Vacuum(); err != nil {
+		return err
+	}
+
+	after, err := os.Stat(dbPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s -> %s (saved %s)\n", dbPath, formatBytes(before.Size()), formatBytes(after.Size()), formatBytes(max(0, before.Size()-after.Size())))
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// outputOptions controls how search results are presented.
+type outputOptions struct {
+	redact bool
+	group  bool
+	json   bool
+
+	// plain prints tab-separated lines with no styling, for piping into
+	// other tools.
+	plain bool
+
+	// fzf prints path:line:snippet lines, the format fzf and editor pickers
+	// read from grep.
+	fzf bool
+
+	// format is one of outputFormats, printing results for another program
+	// to display. Empty uses the flags above.
+	format string
+
+	// context is the number of neighboring chunks to include on each side
+	// of a result.
+	context int
+
+	// summarize prints a chat model synthesis of the top results above them.
+	summarize bool
+
+	// export is a vault-relative note path to write the results to instead
+	// of showing them.
+	export string
+}
+
+// printsResults reports whether results are printed to stdout rather than
+// shown in the TUI.
+func (o outputOptions) printsResults() bool {
+	return o.json || o.plain || o.fzf || o.format != ""
+}
+
+func runSearch(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, queries []string, opts search.Options, out outputOptions) error {
+	if len(queries) == 0 {
+		if out.printsResults() || out.export != "" {
+			return fmt.Errorf("usage: ofind search <query>")
+		}
+		return runLiveSearch(database, cohereClient, cfg, opts, out)
+	}
+
+	searcher := search.New(database, cohereClient)
+	searcher.SetWarningHandler(func(msg string) {
+		fmt.Fprintln(os.Stderr, msg)
+	})
+
+	ctx := context.Background()
+	results, err := searcher.SearchMulti(ctx, queries, opts)
+	if err != nil {
+		return err
+	}
+
+	if out.export != "" {
+		return exportResults(cfg, strings.Join(queries, ", "), results, out.export)
+	}
+
+	var summary string
+	if out.summarize {
+		summary, err = ask.New(searcher, cohereClient).Summarize(ctx, strings.Join(queries, " | "), results)
+		if err != nil {
+			return err
+		}
+	}
+
+	return showResults(searcher, cfg, strings.Join(queries, " | "), summary, results, out)
+}
+
+// runLiveSearch opens the search screen with a query input, searching again
+// as the query is typed. Warnings go to the log instead of the screen.
+func runLiveSearch(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, opts search.Options, out outputOptions) error {
+	searcher := search.New(database, cohereClient)
+	searcher.SetWarningHandler(func(msg string) {
+		slog.Warn(msg)
+	})
+
+	model := tui.NewLiveSearchModel(cfg.ObsidianDir, func(query string) ([]tui.SearchResult, error) {
+		results, err := searcher.SearchMulti(context.Background(), []string{query}, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := searcher.AddContext(results, max(out.context, 1)); err != nil {
+			return nil, err
+		}
+		return tuiResults(results), nil
+	})
+	model, err := searchModelOptions(model, cfg, out)
+	if err != nil {
+		return err
+	}
+	_, err = runTeaProgram(model, nil)
+	return err
+}
+
+func exportResults(cfg *config.Config, query string, results []search.Result, pattern string) error {
+	notePath, err := export.NotePath(cfg.ObsidianDir, pattern, query)
+	if err != nil {
+		return err
+	}
+	if err := export.Write(notePath, query, results, time.Now()); err != nil {
+		return err
+	}
+
+	rel, _ := filepath.Rel(cfg.ObsidianDir, notePath)
+	fmt.Printf("Wrote %d results to %s\n", len(results), filepath.ToSlash(rel))
+	return nil
+}
+
+func runSimilar(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, notePath string, opts search.Options, out outputOptions) error {
+	if notePath == "" {
+		return fmt.Errorf("usage: ofind similar <note>")
+	}
+	if filepath.IsAbs(notePath) {
+		rel, err := filepath.Rel(cfg.ObsidianDir, notePath)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("%s is outside the vault", notePath)
+		}
+		notePath = rel
+	}
+	notePath = filepath.ToSlash(notePath)
+
+	searcher := search.New(database, cohereClient)
+	results, err := searcher.SearchSimilarTo(context.Background(), notePath, opts)
+	if err != nil {
+		return err
+	}
+
+	title := notePath
+	if out.redact {
+		title = redact.Path(title)
+	}
+	return showResults(searcher, cfg, "similar to "+title, "", results, out)
+}
+
+func runExplore(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, opts search.Options, out outputOptions) error {
+	searcher := search.New(database, cohereClient)
+	seed, results, err := searcher.Explore(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+
+	if out.redact {
+		seed = redact.Path(seed)
+	}
+	if out.printsResults() {
+		statusf(os.Stderr, "Exploring from %s\n", seed)
+	}
+	return showResults(searcher, cfg, "exploring from "+seed, "", results, out)
+}
+
+func runAsk(database *db.DB, cohereClient *cohere.Client, question string, opts search.Options, out outputOptions) error {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return fmt.Errorf("usage: ofind ask \"question\"")
+	}
+
+	searcher := search.New(database, cohereClient)
+	answer, err := ask.New(searcher, cohereClient).Ask(context.Background(), question, opts)
+	if err != nil {
+		return err
+	}
+
+	text, footnotes := answer.Footnotes()
+	if out.redact {
+		for i := range footnotes {
+			footnotes[i].Path = redact.Path(footnotes[i].Path)
+		}
+	}
+
+	if out.json {
+		if footnotes == nil {
+			footnotes = []ask.Footnote{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Answer  string         `json:"answer"`
+			Sources []ask.Footnote `json:"sources"`
+		}{text, footnotes})
+	}
+
+	fmt.Println(text)
+	if len(footnotes) > 0 {
+		fmt.Println()
+	}
+	for _, f := range footnotes {
+		if f.Heading != "" {
+			fmt.Printf("[%d] %s (%s)\n", f.Number, f.Path, f.Heading)
+		} else {
+			fmt.Printf("[%d] %s\n", f.Number, f.Path)
+		}
+	}
+	return nil
+}
+
+func runEval(database *db.DB, cohereClient *cohere.Client, casesPath string, opts search.Options, jsonOutput bool) error {
+	if casesPath == "" {
+		return fmt.Errorf("usage: ofind eval cases.yaml")
+	}
+
+	f, err := os.Open(casesPath)
+	if err != nil {
+		return err
+	}
+	cases, err := eval.ParseCases(f)
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", casesPath, err)
+	}
+
+	searcher := search.New(database, cohereClient)
+	report, err := eval.Run(context.Background(), cases, opts.Limit, func(ctx context.Context, query string) ([]search.Result, error) {
+		return searcher.Search(ctx, query, opts)
+	})
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	for _, c := range report.Cases {
+		status := "miss   "
+		if c.Rank > 0 {
+			status = fmt.Sprintf("hit@%-3d", c.Rank)
+		}
+		fmt.Printf("%s %s\n", status, c.Query)
+		for _, missed := range c.Missed {
+			fmt.Printf("        missing %s\n", missed)
+		}
+	}
+	fmt.Println()
+	fmt.Printf("recall@%d: %.3f  MRR: %.3f  (%d queries)\n", report.K, report.Recall, report.MRR, len(report.Cases))
+	return nil
+}
+
+func runChat(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, opts search.Options, redactPaths bool) error {
+	model := tui.NewChatModel(cfg.ObsidianDir)
+	if redactPaths {
+		model = model.WithRedaction()
+	}
+
+	asker := ask.New(search.New(database, cohereClient), cohereClient)
+	if cfg.ChatMemoryDir != "" {
+		asker = asker.WithMemory(cfg.ChatMemoryDir)
+	}
+
+	runner := chatRunner{
+		chatModel: model,
+		asker:     asker,
+		opts:      opts,
+	}
+	finalModel, err := runTeaProgram(runner, nil)
+	if err != nil {
+		return err
+	}
+
+	if runner, ok := finalModel.(chatRunner); ok && cfg.ChatMemoryDir != "" && len(runner.history) > 0 {
+		return saveChatMemory(database, cohereClient, cfg, asker, runner.history)
+	}
+	return nil
+}
+
+// saveChatMemory distills a chat session into a note in chat_memory_dir and
+// indexes it, so later sessions retrieve it along with the vault's notes.
+func saveChatMemory(database *db.DB, cohereClient *cohere.Client, cfg *config.Config, asker *ask.Asker, history []cohere.ChatMessage) error {
+	dir := filepath.FromSlash(cfg.ChatMemoryDir)
+	if !filepath.IsLocal(dir) {
+		return fmt.Errorf("chat_memory_dir %s must be a folder inside the vault", cfg.ChatMemoryDir)
+	}
+
+	ctx := context.Background()
+	memory, err := asker.Distill(ctx, history)
+	if err != nil {
+		return fmt.Errorf("failed to distill the chat into a memory: %w", err)
+	}
+	if memory == "" {
+		return nil
+	}
+
+	now := time.Now()
+	rel := filepath.Join(dir, now.Format("2006-01-02 150405")+".md")
+	notePath := filepath.Join(cfg.ObsidianDir, rel)
+	if err := os.MkdirAll(filepath.Dir(notePath), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(notePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(ask.MemoryNote(memory, history, now)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	idx, closeIndexer := newVaultIndexer(database, cohereClient, cfg, nil)
+	defer closeIndexer()
+	if err := idx.IndexFiles(ctx, []string{rel}); err != nil {
+		return fmt.Errorf("failed to index the chat memory: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Saved the chat to %s\n", filepath.ToSlash(rel))
+	return nil
+}
+
+// chatRunner drives a tui.ChatModel, keeping the conversation history and
+// answering each question from freshly retrieved notes.
+type chatRunner struct {
+	chatModel tui.ChatModel
+	asker     *ask.Asker
+	opts      search.Options
+	history   []cohere.ChatMessage
+}
+
+// chatReplyMsg carries the result of answering the last question back to
+// the runner.
+type chatReplyMsg struct {
+	answer *ask.Answer
+	err    error
+}
+
+func (m chatRunner) Init() tea.Cmd {
+	return m.chatModel.Init()
+}
+
+func (m chatRunner) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tui.ChatSubmitMsg:
+		m.history = append(m.history, cohere.ChatMessage{Role: "user", Content: msg.Question})
+		history := slices.Clone(m.history)
+		asker, opts := m.asker, m.opts
+		return m, func() tea.Msg {
+			answer, err := asker.Converse(context.Background(), history, opts)
+			return chatReplyMsg{answer: answer, err: err}
+		}
+
+	case chatReplyMsg:
+		if msg.err != nil {
+			// Drop the unanswered question so the next turn starts clean
+			m.history = m.history[:len(m.history)-1]
+			return m.updateChat(tui.ChatErrorMsg{Error: msg.err.Error()})
+		}
+
+		m.history = append(m.history, cohere.ChatMessage{Role: "assistant", Content: msg.answer.Text})
+		text, footnotes := msg.answer.Footnotes()
+		sources := make([]tui.ChatSource, len(footnotes))
+		for i, f := range footnotes {
+			sources[i] = tui.ChatSource{Number: f.Number, Path: f.Path, Heading: f.Heading}
+		}
+		return m.updateChat(tui.ChatAnswerMsg{Text: text, Sources: sources})
+
+	default:
+		return m.updateChat(msg)
+	}
+}
+
+func (m chatRunner) updateChat(msg tea.Msg) (tea.Model, tea.Cmd) {
+	newModel, cmd := m.chatModel.Update(msg)
+	if cm, ok := newModel.(tui.ChatModel); ok {
+		m.chatModel = cm
+	}
+	return m, cmd
+}
+
+func (m chatRunner) View() string {
+	return m.chatModel.View()
+}
+
+// showResults prints results as JSON or opens them in the TUI. A non-empty
+// summary is shown above the results, or on stderr with JSON output.
+func showResults(searcher *search.Searcher, cfg *config.Config, title, summary string, results []search.Result, out outputOptions) error {
+	if n := contextSize(out); n > 0 {
+		if err := searcher.AddContext(results, n); err != nil {
+			return err
+		}
+	}
+	return presentResults(cfg, title, summary, results, out)
+}
+
+// contextSize is how many neighboring chunks results are shown with.
+func contextSize(out outputOptions) int {
+	if !out.printsResults() {
+		// Always fetched for the TUI so c can toggle it
+		return max(out.context, 1)
+	}
+	return out.context
+}
+
+// presentResults prints results, or opens the results view on them.
+func presentResults(cfg *config.Config, title, summary string, results []search.Result, out outputOptions) error {
+	if out.printsResults() {
+		if out.redact {
+			summary = redact.Snippet(summary)
+		}
+		if summary != "" {
+			fmt.Fprintln(os.Stderr, summary)
+		}
+		switch {
+		case out.plain:
+			return printResultsPlain(results, out)
+		case out.fzf:
+			return printResultsFzf(results, cfg.ObsidianDir, out)
+		case out.format == "grep":
+			return printResultsGrep(results, out)
+		case out.format != "":
+			return printResultsLauncher(results, cfg.ObsidianDir, out)
+		}
+		return printResultsJSON(results, out)
+	}
+
+	model, err := searchModelOptions(tui.NewSearchModel(title, cfg.ObsidianDir), cfg, out)
+	if err != nil {
+		return err
+	}
+	initCmd := func() tea.Msg {
+		return tui.SearchResultsMsg{Results: tuiResults(results), Summary: summary}
+	}
+	_, err = runTeaProgram(model, initCmd)
 	return err
 }
 
+// searchModelOptions applies the display flags and settings to a results
+// view.
+func searchModelOptions(model tui.SearchModel, cfg *config.Config, out outputOptions) (tui.SearchModel, error) {
+	keys, err := tui.DefaultKeyMap().Rebind(cfg.Keybindings)
+	if err != nil {
+		return model, err
+	}
+	model = model.WithKeyMap(keys)
+	if out.context > 0 {
+		model = model.WithContext()
+	}
+	if out.redact {
+		model = model.WithRedaction()
+	}
+	if out.group {
+		model = model.WithGrouping()
+	}
+	model = model.WithExclude(func(path string) error {
+		return excludeNote(filepath.Join(cfg.ObsidianDir, filepath.FromSlash(path)))
+	})
+	return model.WithEditor(cfg.EditorCommand(), cfg.OpenInEditor), nil
+}
+
+// excludeNote adds a note to excluded_notes. The config is loaded afresh
+// since this run's copy may point at another vault.
+func excludeNote(notePath string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(cfg.ExcludedNotes, notePath) {
+		return nil
+	}
+	cfg.ExcludedNotes = append(cfg.ExcludedNotes, notePath)
+	return cfg.Save()
+}
+
+func tuiResults(results []search.Result) []tui.SearchResult {
+	converted := make([]tui.SearchResult, len(results))
+	for i, r := range results {
+		converted[i] = tui.SearchResult{
+			Rank:       r.Rank,
+			Score:      r.Score,
+			Path:       r.Path,
+			Heading:    r.Heading,
+			Snippet:    r.Content,
+			DocID:      r.DocID,
+			ChunkID:    r.ChunkID,
+			StartLine:  r.StartLine,
+			Before:     contextSnippets(r.Before),
+			After:      contextSnippets(r.After),
+			LinkedFrom: r.LinkedFrom,
+		}
+		if r.Explanation != nil {
+			converted[i].Explain = r.Explanation.String()
+		}
+	}
+	return converted
+}
+
+func contextSnippets(chunks []search.ContextChunk) []string {
+	snippets := make([]string, len(chunks))
+	for i, c := range chunks {
+		snippets[i] = c.Content
+	}
+	return snippets
+}
+
+func printResultsJSON(results []search.Result, out outputOptions) error {
+	if out.redact {
+		results = redactResults(results)
+	}
+	if results == nil {
+		results = []search.Result{}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if out.group {
+		groups := search.GroupByDocument(results)
+		if groups == nil {
+			groups = []search.DocumentGroup{}
+		}
+		return enc.Encode(groups)
+	}
+	return enc.Encode(results)
+}
+
+// printResultsPlain prints each result as score, path, heading and snippet
+// separated by tabs, the path prefixed with "vault:" in a search across
+// vaults. Whitespace inside the heading and snippet, newlines and
+// tabs included, is collapsed to single spaces so every result is one line.
+func printResultsPlain(results []search.Result, out outputOptions) error {
+	if out.redact {
+		results = redactResults(results)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, r := range results {
+		path := r.Path
+		if r.Vault != "" {
+			path = r.Vault + ":" + path
+		}
+		fmt.Fprintf(w, "%.4f\t%s\t%s\t%s\n", r.Score, path, plainField(r.Heading), plainField(r.Content))
+	}
+	return w.Flush()
+}
+
+// printResultsFzf prints each result as path:line:snippet, grep's format, so
+// fzf can preview the line and editors can jump to it. Paths are absolute
+// unless redacted.
+func printResultsFzf(results []search.Result, vaultDir string, out outputOptions) error {
+	if out.redact {
+		results = redactResults(results)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, r := range results {
+		path := r.Path
+		if !out.redact {
+			path = filepath.Join(vaultDir, r.Path)
+		}
+		fmt.Fprintf(w, "%s:%d:%s\n", path, r.StartLine, plainField(r.Content))
+	}
+	return w.Flush()
+}
+
+// printResultsGrep prints each result as path:line: snippet with the path
+// relative to the vault, the error format vim, Emacs and VS Code tasks parse.
+func printResultsGrep(results []search.Result, out outputOptions) error {
+	if out.redact {
+		results = redactResults(results)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, r := range results {
+		fmt.Fprintf(w, "%s:%d: %s\n", r.Path, max(r.StartLine, 1), plainField(r.Content))
+	}
+	return w.Flush()
+}
+
+func plainField(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func redactResults(results []search.Result) []search.Result {
+	redacted := make([]search.Result, len(results))
+	for i, r := range results {
+		r.Path = redact.Path(r.Path)
+		if r.LinkedFrom != "" {
+			r.LinkedFrom = redact.Path(r.LinkedFrom)
+		}
+		r.Content = redact.Snippet(r.Content)
+		r.Before = redactContext(r.Before)
+		r.After = redactContext(r.After)
+		redacted[i] = r
+	}
+	return redacted
+}
+
+func redactContext(chunks []search.ContextChunk) []search.ContextChunk {
+	if len(chunks) == 0 {
+		return nil
+	}
+	redacted := make([]search.ContextChunk, len(chunks))
+	for i, c := range chunks {
+		c.Content = redact.Snippet(c.Content)
+		redacted[i] = c
+	}
+	return redacted
+}
+
 func printUsage() {
 	fmt.Println("obsvec - Obsidian Vector Search")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  ofind -q \"search query\"   Search your Obsidian vault")
-	fmt.Println("  ofind -index              Index your Obsidian vault")
-	fmt.Println("  ofind -index -full        Full reindex (ignore cache)")
-	fmt.Println("  ofind -watch              Watch for changes and auto-index")
-	fmt.Println("  ofind -setup              Run setup wizard")
+	fmt.Println("  ofind                     Search as you type")
+	fmt.Println("  ofind search \"query\"      Search your Obsidian vault")
+	fmt.Println("  ofind explore             Resurface notes related to a random recent note")
+	fmt.Println("  ofind similar Projects/Idea.md")
+	fmt.Println("                            Find notes related to a note")
+	fmt.Println("  ofind index               Index your Obsidian vault")
+	fmt.Println("  ofind index -full         Full reindex (ignore cache)")
+	fmt.Println("  ofind index -files a.md b.md")
+	fmt.Println("                            Reindex only these notes, changed or not")
+	fmt.Println("  ofind index -ref main     Index the notes as committed on a git branch or commit")
+	fmt.Println("  ofind watch               Watch for changes and auto-index")
+	fmt.Println("  ofind daemon -port 7700   Watch and serve the HTTP API, logging to stderr")
+	fmt.Println("  ofind serve -port 7700    Serve the HTTP API without watching")
+	fmt.Println("  ofind service install     Run the daemon at login with launchd or systemd")
+	fmt.Println("                            (also service uninstall, service status)")
+	fmt.Println("  ofind setup               Run setup wizard")
+	fmt.Println("  ofind ask \"question\"      Answer a question from your notes, with citations")
+	fmt.Println("  ofind chat                Chat with your notes; tab opens cited notes")
+	fmt.Println("  ofind check-vault [dir]   Validate a vault and estimate index size/cost")
+	fmt.Println("  ofind config              List settings (config get <key>, config set <key> <value>)")
+	fmt.Println("  ofind token create phone  Make an API token for one client (token revoke, token list)")
+	fmt.Println("  ofind purge -db-only      Delete the index after asking; without -db-only, the settings too")
+	fmt.Println("  ofind version             Show the version, build details and configured models")
+	fmt.Println("  ofind eval cases.yaml     Report recall@k and MRR for queries with known answers")
+	fmt.Println("  ofind open <title>        Open the note best matching a fuzzy title or alias")
+	fmt.Println("  ofind db vacuum           Compact the database and report the space saved")
+	fmt.Println("  ofind db prune            Remove deleted notes and leftovers from interrupted indexing")
+	fmt.Println("  ofind db verify [-fix]    Check the index against the vault; -fix reindexes what drifted")
+	fmt.Println("  ofind db export index.jsonl.gz")
+	fmt.Println("                            Save the index, embeddings included, to a file")
+	fmt.Println("  ofind db import index.jsonl.gz")
+	fmt.Println("                            Load an exported index without re-embedding")
+	fmt.Println("  ofind db export-embeddings vectors.parquet")
+	fmt.Println("                            Save chunk ids, paths and vectors for notebooks (or .npz)")
+	fmt.Println("  ofind db import-embeddings vectors.jsonl")
+	fmt.Println("                            Load vectors computed elsewhere, by chunk text hash (or .parquet)")
+	fmt.Println("  ofind sync export sync/laptop.jsonl.gz")
+	fmt.Println("                            Save the notes indexed since the last export (-full: all)")
+	fmt.Println("  ofind sync apply sync/laptop.jsonl.gz")
+	fmt.Println("                            Load a bundle from another machine without re-embedding")
+	fmt.Println("  ofind stats               Show vault size, largest notes and notes per month")
+	fmt.Println("  ofind clusters [k]        Group notes into k topics (default: picked from vault size)")
+	fmt.Println()
+	fmt.Println("Search options, for search, similar, explore, ask, chat and eval:")
+	fmt.Println("  -n 50                     Return more results (default 10)")
+	fmt.Println("  -q \"a\" -q \"b\"             Fuse results from several queries")
+	fmt.Println("  -q -                      Read the query from stdin (or ofind search -)")
+	fmt.Println("  -redact-paths             Demo mode: hide paths and note contents")
+	fmt.Println("  -tag work                 Only search notes tagged #work (or tag:work in the query)")
+	fmt.Println("  \"kubernetes -helm\"        Down-rank results about a term (or -not helm)")
+	fmt.Println("  -path \"Projects/**\" -exclude-path \"Daily/**\"")
+	fmt.Println("                            Only search part of the vault")
+	fmt.Println("  -since 30d -until 2024-06-30")
+	fmt.Println("                            Only search notes modified in a date range")
+	fmt.Println("  -recency 14d              Favor recently modified notes")
+	fmt.Println("  -bookmarked               Search only notes bookmarked in Obsidian")
+	fmt.Println("  -expand                   Also search LLM-generated paraphrases of the query")
+	fmt.Println("  -mmr 0.7                  Diversify results so one note doesn't dominate")
+	fmt.Println("  -one-per-note             Only the best chunk of each note")
+	fmt.Println("  -context 1                Show neighboring chunks around each result")
+	fmt.Println("  -links                    Add notes linked from the results")
+	fmt.Println("  -summarize                Summarize the top results above the list")
+	fmt.Println("  -explain                  Show how each result was scored")
+	fmt.Println("  -group                    Group results by note")
+	fmt.Println("  -as-of 2023-12-01         Search a git-tracked vault as it was on a date")
+	fmt.Println("  -json                     Print results as JSON")
+	fmt.Println("  -plain                    Print results as score, path, heading, snippet lines")
+	fmt.Println("  -fzf                      Print path:line:snippet lines for fzf (see README)")
+	fmt.Println("  -format alfred|raycast    Print results as launcher JSON (see README)")
+	fmt.Println("  -format grep              Print path:line: snippet lines for editors")
+	fmt.Println("  -export-note \"Research/<query>\"")
+	fmt.Println("                            Save the results as a note in the vault")
+	fmt.Println()
+	fmt.Println("Options for any command:")
+	fmt.Println("  -yes                      Rebuild an index made with other embedding settings, or purge, without asking")
+	fmt.Println("  -vault work               Use a named vault (and its own index) for any command")
+	fmt.Println("  -all-vaults               Search every vault and merge the results, labeled by vault")
+	fmt.Println("  -dir ./docs               Search any directory with a throwaway in-memory index")
+	fmt.Println("  -quiet                    Print only results, warnings and errors")
+	fmt.Println("  -v, -vv                   Log API latencies and batch sizes (-vv: SQL timings too)")
+	fmt.Println("                            to obsvec.log in the data directory")
+	fmt.Println()
+	fmt.Println("Options go before or after the command. The old -q, -similar, -explore,")
+	fmt.Println("-index, -watch and -setup flags still work in place of their commands.")
 	fmt.Println()
 }
 
 func runTeaProgram(model tea.Model, initCmd tea.Cmd) (tea.Model, error) {
-	program := tea.NewProgram(model)
+	var opts []tea.ProgramOption
+	if !isTerminal(os.Stdin) {
+		// Stdin held piped input, such as the query; read keys from the
+		// terminal instead
+		opts = append(opts, tea.WithInputTTY())
+	}
+	program := tea.NewProgram(model, opts...)
 	if initCmd != nil {
 		go func() {
 			program.Send(initCmd())
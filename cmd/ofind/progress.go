@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mgomes/obsvec/internal/tui"
+	"github.com/mgomes/obsvec/pkg/obsvec/indexer"
+)
+
+// phaseTimer follows indexing progress reports to time each phase, for the
+// breakdown printed when indexing finishes.
+type phaseTimer struct {
+	start  time.Time
+	phase  indexer.Phase
+	since  time.Time
+	phases []phaseTime
+
+	chunks int
+	tokens int
+}
+
+type phaseTime struct {
+	phase indexer.Phase
+	took  time.Duration
+}
+
+func newPhaseTimer() *phaseTimer {
+	return &phaseTimer{start: time.Now()}
+}
+
+func (t *phaseTimer) observe(p indexer.Progress) {
+	if p.Phase != "" && p.Phase != t.phase {
+		now := time.Now()
+		t.end(now)
+		t.phase, t.since = p.Phase, now
+	}
+	if p.Phase == indexer.PhaseEmbed {
+		t.chunks, t.tokens = p.Chunks, p.Tokens
+	}
+}
+
+// end closes the running phase, if any.
+func (t *phaseTimer) end(now time.Time) {
+	if t.phase != "" {
+		t.phases = append(t.phases, phaseTime{t.phase, now.Sub(t.since)})
+		t.phase = ""
+	}
+}
+
+func (t *phaseTimer) print(w io.Writer) {
+	now := time.Now()
+	t.end(now)
+
+	if t.chunks > 0 {
+		fmt.Fprintf(w, "Embedded %d chunks, ~%d tokens\n", t.chunks, t.tokens)
+	}
+	for _, p := range t.phases {
+		fmt.Fprintf(w, "  %-10s %8s\n", p.phase, roundDuration(p.took))
+	}
+	fmt.Fprintf(w, "  %-10s %8s\n", "Total", roundDuration(now.Sub(t.start)))
+}
+
+// roundDuration keeps a tenth of a second once d passes a second.
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(100 * time.Millisecond)
+}
+
+// indexWithProgress runs index, showing its progress as a bar on a terminal,
+// as lines otherwise and not at all with -quiet. Ctrl+c in the progress view
+// cancels the context index runs with.
+func indexWithProgress(index func(context.Context, indexer.ProgressFunc) error) (*phaseTimer, error) {
+	timer := newPhaseTimer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	switch {
+	case quiet:
+		return timer, index(ctx, timer.observe)
+	case !isTerminal(os.Stdout):
+		err := index(ctx, func(p indexer.Progress) {
+			timer.observe(p)
+			if p.Total == 0 && p.Message != "" {
+				fmt.Println(p.Message)
+			}
+		})
+		return timer, err
+	}
+
+	var opts []tea.ProgramOption
+	if !isTerminal(os.Stdin) {
+		opts = append(opts, tea.WithInputTTY())
+	}
+	program := tea.NewProgram(tui.NewIndexModel(), opts...)
+
+	done := make(chan error, 1)
+	go func() {
+		err := index(ctx, func(p indexer.Progress) {
+			timer.observe(p)
+			program.Send(tui.IndexProgressMsg{
+				Phase:   string(p.Phase),
+				Current: p.Current,
+				Total:   p.Total,
+				Message: p.Message,
+				Chunks:  p.Chunks,
+				Tokens:  p.Tokens,
+			})
+		})
+		program.Send(tui.IndexDoneMsg{})
+		done <- err
+	}()
+
+	model, err := program.Run()
+	if err != nil {
+		cancel()
+		<-done
+		return timer, err
+	}
+	if m, ok := model.(tui.IndexModel); ok && m.Interrupted() {
+		cancel()
+	}
+	err = <-done
+	if errors.Is(err, context.Canceled) {
+		return timer, fmt.Errorf("indexing interrupted")
+	}
+	return timer, err
+}
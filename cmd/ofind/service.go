@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/mgomes/obsvec/internal/config"
+)
+
+// launchdLabel names the launchd job of the default vault; named vaults
+// append .<vault>.
+const launchdLabel = "com.mgomes.obsvec"
+
+// errNoServiceManager is returned on systems other than macOS and Linux.
+var errNoServiceManager = errors.New("ofind service needs launchd (macOS) or systemd (Linux)")
+
+// service is the daemon of one vault as a launchd job or systemd user unit.
+type service struct {
+	// name is the launchd label or systemd unit name.
+	name string
+
+	// path is where the plist or unit file goes.
+	path string
+
+	// args run the daemon, the absolute path of this binary first.
+	args []string
+
+	// logPath is where launchd sends the daemon's output. systemd sends it
+	// to the journal.
+	logPath string
+}
+
+// runService installs, uninstalls or reports on the daemon of the vault as
+// a service started at login and restarted when it exits.
+func runService(vault string, port, grpcPort int, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: ofind service install|uninstall|status")
+	}
+
+	svc, err := newService(vault, port, grpcPort)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "install":
+		return svc.install()
+	case "uninstall":
+		return svc.uninstall()
+	case "status":
+		return svc.status()
+	}
+	return fmt.Errorf("unknown service command %q", args[0])
+}
+
+func newService(vault string, port, grpcPort int) (*service, error) {
+	if runtime.GOOS != "darwin" && runtime.GOOS != "linux" {
+		return nil, errNoServiceManager
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return nil, err
+	}
+	args := []string{exe, "daemon"}
+	if vault != "" {
+		args = append(args, "-vault", vault)
+	}
+	if port != defaultPort {
+		args = append(args, "-port", strconv.Itoa(port))
+	}
+	if grpcPort != 0 {
+		args = append(args, "-grpc-port", strconv.Itoa(grpcPort))
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	svc := &service{args: args}
+	if runtime.GOOS == "darwin" {
+		svc.name = launchdLabel
+		if vault != "" {
+			svc.name += "." + vault
+		}
+		svc.path = filepath.Join(home, "Library", "LaunchAgents", svc.name+".plist")
+
+		dir, err := config.DataDir()
+		if err != nil {
+			return nil, err
+		}
+		svc.logPath = filepath.Join(dir, "daemon.log")
+		if vault != "" {
+			svc.logPath = filepath.Join(dir, "vaults", vault+".daemon.log")
+		}
+		return svc, nil
+	}
+
+	svc.name = "obsvec.service"
+	if vault != "" {
+		svc.name = "obsvec-" + vault + ".service"
+	}
+	unitDir := filepath.Join(home, ".config")
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		unitDir = xdg
+	}
+	svc.path = filepath.Join(unitDir, "systemd", "user", svc.name)
+	return svc, nil
+}
+
+// install writes the plist or unit and starts the daemon, replacing one
+// installed before, so installing again picks up new flags or a moved
+// binary.
+func (s *service) install() error {
+	definition := systemdUnit(s.args)
+	if runtime.GOOS == "darwin" {
+		definition = launchdPlist(s.name, s.args, s.logPath)
+		if err := os.MkdirAll(filepath.Dir(s.logPath), 0700); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path, []byte(definition), 0644); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "darwin" {
+		// Unloading fails when the job isn't loaded yet, which is fine
+		_ = exec.Command("launchctl", "bootout", launchdDomain(), s.path).Run()
+		if err := runQuiet("launchctl", "bootstrap", launchdDomain(), s.path); err != nil {
+			return err
+		}
+	} else {
+		if err := runQuiet("systemctl", "--user", "daemon-reload"); err != nil {
+			return err
+		}
+		if err := runQuiet("systemctl", "--user", "enable", s.name); err != nil {
+			return err
+		}
+		if err := runQuiet("systemctl", "--user", "restart", s.name); err != nil {
+			return err
+		}
+	}
+
+	statusf(os.Stdout, "Installed %s, running %s\n", s.path, strings.Join(s.args, " "))
+	if runtime.GOOS == "darwin" {
+		statusf(os.Stdout, "Logs go to %s\n", s.logPath)
+	} else {
+		statusf(os.Stdout, "Logs: journalctl --user -u %s\n", s.name)
+	}
+	return nil
+}
+
+// uninstall stops the daemon and removes the plist or unit.
+func (s *service) uninstall() error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return fmt.Errorf("%s is not installed", s.name)
+	}
+
+	if runtime.GOOS == "darwin" {
+		_ = exec.Command("launchctl", "bootout", launchdDomain(), s.path).Run()
+	} else {
+		_ = exec.Command("systemctl", "--user", "disable", "--now", s.name).Run()
+	}
+	if err := os.Remove(s.path); err != nil {
+		return err
+	}
+	if runtime.GOOS == "linux" {
+		if err := runQuiet("systemctl", "--user", "daemon-reload"); err != nil {
+			return err
+		}
+	}
+
+	statusf(os.Stdout, "Uninstalled %s\n", s.name)
+	return nil
+}
+
+// status prints what launchd or systemd reports about the daemon.
+func (s *service) status() error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		fmt.Printf("%s is not installed; ofind service install sets it up\n", s.name)
+		return nil
+	}
+
+	fmt.Printf("Installed at %s\n\n", s.path)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("launchctl", "print", launchdDomain()+"/"+s.name)
+	} else {
+		cmd = exec.Command("systemctl", "--user", "status", "--no-pager", s.name)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Both exit nonzero for a service that isn't running, which status
+	// has just reported
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); err != nil && !errors.As(err, &exitErr) {
+		return err
+	}
+	return nil
+}
+
+// launchdDomain is the launchd domain of the logged-in user's agents.
+func launchdDomain() string {
+	return "gui/" + strconv.Itoa(os.Getuid())
+}
+
+// runQuiet runs a command, returning its output in the error if it fails.
+func runQuiet(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// launchdPlist is a launchd job running args at login and again whenever
+// it exits, with its output appended to logPath.
+func launchdPlist(label string, args []string, logPath string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	fmt.Fprintf(&b, "\t<key>Label</key>\n\t<string>%s</string>\n", xmlEscape(label))
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range args {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", xmlEscape(arg))
+	}
+	b.WriteString("\t</array>\n")
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	b.WriteString("\t<key>KeepAlive</key>\n\t<true/>\n")
+	b.WriteString("\t<key>ProcessType</key>\n\t<string>Background</string>\n")
+	fmt.Fprintf(&b, "\t<key>StandardOutPath</key>\n\t<string>%s</string>\n", xmlEscape(logPath))
+	fmt.Fprintf(&b, "\t<key>StandardErrorPath</key>\n\t<string>%s</string>\n", xmlEscape(logPath))
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+// systemdUnit is a systemd user unit running args at login, restarted
+// whenever it fails.
+func systemdUnit(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = systemdQuote(arg)
+	}
+
+	return fmt.Sprintf(`[Unit]
+Description=obsvec daemon: keeps the Obsidian index current and serves its API
+
+[Service]
+ExecStart=%s
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=default.target
+`, strings.Join(quoted, " "))
+}
+
+// systemdQuote escapes the specifiers and variables systemd would expand in
+// arg, quoting it when it has spaces or quotes.
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// xmlEscape escapes s for a plist string.
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLaunchdPlist(t *testing.T) {
+	plist := launchdPlist("com.mgomes.obsvec.work", []string{"/Users/me/Apps & Tools/ofind", "daemon", "-vault", "work"}, "/Users/me/.config/obsvec/vaults/work.daemon.log")
+
+	for _, want := range []string{
+		"<string>com.mgomes.obsvec.work</string>",
+		"<string>/Users/me/Apps &amp; Tools/ofind</string>\n\t\t<string>daemon</string>",
+		"<key>KeepAlive</key>\n\t<true/>",
+		"<key>StandardErrorPath</key>\n\t<string>/Users/me/.config/obsvec/vaults/work.daemon.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("expected %q in the plist:\n%s", want, plist)
+		}
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	unit := systemdUnit([]string{"/home/me/my bin/ofind", "daemon", "-vault", "100%$notes"})
+
+	want := `ExecStart="/home/me/my bin/ofind" daemon -vault 100%%$$notes` + "\n"
+	if !strings.Contains(unit, want) {
+		t.Errorf("expected %q in the unit:\n%s", want, unit)
+	}
+	if !strings.Contains(unit, "WantedBy=default.target") {
+		t.Errorf("expected the unit to start at login:\n%s", unit)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+const syncUsage = "usage: ofind sync export [-full] <file>|apply <file>"
+
+// runSync moves an index between machines in bundles of what changed:
+// export writes the notes indexed since the last export, apply loads a
+// bundle into this index. Files ending in .gz are gzipped.
+func runSync(database *db.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(syncUsage)
+	}
+
+	switch args[0] {
+	case "export":
+		full := false
+		var path string
+		for _, arg := range args[1:] {
+			switch {
+			case arg == "-full" || arg == "--full":
+				full = true
+			case path == "" && !strings.HasPrefix(arg, "-"):
+				path = arg
+			default:
+				return errors.New(syncUsage)
+			}
+		}
+		if path == "" {
+			return errors.New(syncUsage)
+		}
+		return runSyncExport(database, path, full)
+	case "apply":
+		if len(args) != 2 {
+			return errors.New(syncUsage)
+		}
+		return runSyncApply(database, args[1])
+	}
+	return fmt.Errorf("unknown sync command %q", args[0])
+}
+
+func runSyncExport(database *db.DB, path string, full bool) (err error) {
+	var since time.Time
+	if !full {
+		if since, err = database.SyncExported(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	stats, err := writeSyncBundle(database, f, path, since)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	// Only once the bundle is saved, so a failed export is simply redone
+	if err := database.SetSyncExported(stats.Until); err != nil {
+		return err
+	}
+	if stats.Since.IsZero() {
+		fmt.Printf("Exported all %d notes to %s\n", stats.Documents, path)
+	} else {
+		fmt.Printf("Exported %d notes changed since %s to %s\n", stats.Documents, stats.Since.Format(time.DateTime), path)
+	}
+	return nil
+}
+
+func writeSyncBundle(database *db.DB, f *os.File, path string, since time.Time) (stats db.SyncStats, err error) {
+	var w io.Writer = f
+	if strings.HasSuffix(path, ".gz") {
+		gz := gzip.NewWriter(f)
+		defer func() {
+			if closeErr := gz.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = gz
+	}
+
+	buf := bufio.NewWriter(w)
+	if stats, err = database.ExportSync(buf, since); err != nil {
+		return stats, err
+	}
+	return stats, buf.Flush()
+}
+
+func runSyncApply(database *db.DB, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close() //nolint:errcheck
+		r = gz
+	}
+
+	stats, err := database.ApplySync(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	fmt.Printf("Applied %s: %d notes updated, %d removed\n", path, stats.Documents, stats.Removed)
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/mgomes/obsvec/internal/config"
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+// Build metadata, set by make with -ldflags "-X main.version=...". Builds
+// without it fall back to what the Go toolchain recorded, where the date is
+// the commit's rather than the build's.
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+// buildInfo is what ofind version reports.
+type buildInfo struct {
+	Version     string    `json:"version"`
+	Commit      string    `json:"commit,omitempty"`
+	Date        string    `json:"date,omitempty"`
+	Go          string    `json:"go"`
+	Platform    string    `json:"platform"`
+	Engine      db.Engine `json:"engine"`
+	EmbedModel  string    `json:"embed_model"`
+	EmbedDim    int       `json:"embed_dim"`
+	RerankModel string    `json:"rerank_model"`
+	ChatModel   string    `json:"chat_model"`
+}
+
+func currentBuild(cfg *config.Config) (buildInfo, error) {
+	info := buildInfo{
+		Version:     version,
+		Commit:      commit,
+		Date:        date,
+		Go:          runtime.Version(),
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+		EmbedModel:  cfg.EmbedModel,
+		EmbedDim:    cfg.EmbedDim,
+		RerankModel: cfg.RerankModel,
+		ChatModel:   cfg.ChatModel,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		// go install records the module version; go build records the
+		// checkout
+		if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		modified := false
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+
+	var err error
+	info.Engine, err = db.EngineInfo()
+	return info, err
+}
+
+func runVersion(cfg *config.Config, jsonOutput bool) error {
+	info, err := currentBuild(cfg)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	fmt.Printf("ofind %s\n", info.Version)
+	if info.Commit != "" {
+		fmt.Printf("  Commit:       %s\n", info.Commit)
+	}
+	if info.Date != "" {
+		fmt.Printf("  Date:         %s\n", info.Date)
+	}
+	fmt.Printf("  Go:           %s %s\n", info.Go, info.Platform)
+	fmt.Printf("  SQLite:       %s\n", info.Engine.SQLite)
+	fmt.Printf("  sqlite-vec:   %s\n", cmp.Or(info.Engine.SQLiteVec, "none (pure-Go build)"))
+	fmt.Printf("  SQLCipher:    %s\n", cmp.Or(info.Engine.SQLCipher, "none"))
+	fmt.Printf("  FTS5:         %t\n", info.Engine.FTS5)
+	fmt.Printf("  Embed model:  %s (%d dimensions)\n", info.EmbedModel, info.EmbedDim)
+	fmt.Printf("  Rerank model: %s\n", info.RerankModel)
+	fmt.Printf("  Chat model:   %s\n", info.ChatModel)
+	return nil
+}
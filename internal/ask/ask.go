@@ -0,0 +1,273 @@
+package ask
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/cohere"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+// defaultSources is how many retrieved chunks are passed to the chat model
+// when Options.Limit is unset.
+const defaultSources = 8
+
+// maxHistoryMessages and maxHistoryRunes cap how much of a conversation is
+// sent with each turn, keeping the most recent messages, so a long chat
+// stays within the chat model's context window.
+const (
+	maxHistoryMessages = 20
+	maxHistoryRunes    = 24000
+)
+
+const systemPrompt = `You answer questions using the user's personal notes, which are provided as documents.
+Answer only from the documents. If they do not contain the answer, say so briefly instead of guessing.
+Be concise and quote dates, numbers and names exactly as they appear in the notes.`
+
+const summarizePrompt = `The user searched their personal notes. The top search results are provided as documents.
+Write a short synthesis of what the notes say about the search, in two to four sentences.
+Combine information spread across several notes, point out where they disagree, and do not add facts that are not in the documents.`
+
+type Asker struct {
+	searcher *search.Searcher
+	cohere   *cohere.Client
+
+	// memoryDir is the vault-relative folder of chat memories set by
+	// WithMemory, empty for none.
+	memoryDir string
+}
+
+// Answer is the chat model's reply together with the notes it was given.
+// Citation document IDs are indexes into Sources.
+type Answer struct {
+	Text      string
+	Citations []cohere.Citation
+	Sources   []search.Result
+}
+
+// Footnote is a cited note section, numbered in order of first citation.
+type Footnote struct {
+	Number  int    `json:"number"`
+	Path    string `json:"path"`
+	Heading string `json:"heading,omitempty"`
+}
+
+func New(searcher *search.Searcher, cohereClient *cohere.Client) *Asker {
+	return &Asker{
+		searcher: searcher,
+		cohere:   cohereClient,
+	}
+}
+
+// Ask retrieves the notes most relevant to question and has the chat model
+// answer from them.
+func (a *Asker) Ask(ctx context.Context, question string, opts search.Options) (*Answer, error) {
+	return a.Converse(ctx, []cohere.ChatMessage{{Role: "user", Content: question}}, opts)
+}
+
+// AskStream is Ask, passing each piece of the answer text to onText as the
+// chat model generates it. Citations come with the returned Answer.
+func (a *Asker) AskStream(ctx context.Context, question string, opts search.Options, onText func(string)) (*Answer, error) {
+	return a.converse(ctx, []cohere.ChatMessage{{Role: "user", Content: question}}, opts, onText)
+}
+
+// Converse answers the last user message of a conversation. Notes are
+// retrieved fresh for every turn using the latest question and the one before
+// it, so short follow-ups still find the notes the conversation is about.
+func (a *Asker) Converse(ctx context.Context, history []cohere.ChatMessage, opts search.Options) (*Answer, error) {
+	return a.converse(ctx, history, opts, nil)
+}
+
+// converse answers like Converse, streaming the reply to onText if set.
+func (a *Asker) converse(ctx context.Context, history []cohere.ChatMessage, opts search.Options, onText func(string)) (*Answer, error) {
+	queries := retrievalQueries(history)
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("no question to answer")
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultSources
+	}
+
+	system := systemPrompt
+	if a.memoryDir != "" {
+		// Memories are retrieved on their own so they don't crowd out notes
+		opts.ExcludePaths = append(slices.Clone(opts.ExcludePaths), a.memoryGlob())
+		system += fmt.Sprintf(memoryPrompt, a.memoryDir)
+	}
+
+	sources, err := a.searcher.SearchMulti(ctx, queries, opts)
+	if err != nil {
+		return nil, err
+	}
+	if a.memoryDir != "" {
+		memories, err := a.retrieveMemories(ctx, queries)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, memories...)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no notes found for %q", queries[0])
+	}
+
+	history = recentHistory(history)
+
+	var reply *cohere.ChatReply
+	if onText != nil {
+		reply, err = a.cohere.ChatWithDocumentsStream(ctx, system, history, chatDocuments(sources), onText)
+	} else {
+		reply, err = a.cohere.ChatWithDocuments(ctx, system, history, chatDocuments(sources))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Answer{
+		Text:      reply.Text,
+		Citations: reply.Citations,
+		Sources:   sources,
+	}, nil
+}
+
+// recentHistory returns the end of history that fits maxHistoryMessages and
+// maxHistoryRunes, starting with a user message. The last message is always
+// kept.
+func recentHistory(history []cohere.ChatMessage) []cohere.ChatMessage {
+	start := len(history)
+	runes := 0
+	for start > 0 && len(history)-start < maxHistoryMessages {
+		runes += utf8.RuneCountInString(history[start-1].Content)
+		if runes > maxHistoryRunes && start < len(history) {
+			break
+		}
+		start--
+	}
+	for start < len(history)-1 && history[start].Role != "user" {
+		start++
+	}
+	return history[start:]
+}
+
+// retrievalQueries returns the latest user message followed by the previous
+// one, if any.
+func retrievalQueries(history []cohere.ChatMessage) []string {
+	var queries []string
+	for i := len(history) - 1; i >= 0 && len(queries) < 2; i-- {
+		if history[i].Role != "user" {
+			continue
+		}
+		if q := strings.TrimSpace(history[i].Content); q != "" {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
+// Summarize has the chat model synthesize the top results of a search for
+// query into a few sentences.
+func (a *Asker) Summarize(ctx context.Context, query string, results []search.Result) (string, error) {
+	if len(results) == 0 {
+		return "", nil
+	}
+	results = results[:min(defaultSources, len(results))]
+
+	reply, err := a.cohere.ChatWithDocuments(ctx, summarizePrompt, []cohere.ChatMessage{
+		{Role: "user", Content: "Search: " + query},
+	}, chatDocuments(results))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(reply.Text), nil
+}
+
+func chatDocuments(sources []search.Result) []cohere.ChatDocument {
+	docs := make([]cohere.ChatDocument, len(sources))
+	for i, r := range sources {
+		title := strings.TrimSuffix(r.Path, ".md")
+		if r.Heading != "" {
+			title += " > " + r.Heading
+		}
+		docs[i] = cohere.ChatDocument{
+			ID:    strconv.Itoa(i),
+			Title: title,
+			Text:  r.Content,
+		}
+	}
+	return docs
+}
+
+// Footnotes returns the answer text with [n] markers after each cited span,
+// and the notes those markers refer to. Chunks from the same note section
+// share a number.
+func (a *Answer) Footnotes() (string, []Footnote) {
+	type marker struct {
+		end     int
+		numbers []int
+	}
+
+	var footnotes []Footnote
+	numbers := make(map[string]int)
+	var markers []marker
+
+	// Number sources in the order they are first cited in the text
+	citations := slices.Clone(a.Citations)
+	sort.SliceStable(citations, func(i, j int) bool { return citations[i].End < citations[j].End })
+
+	for _, c := range citations {
+		m := marker{end: c.End}
+		for _, id := range c.DocumentIDs {
+			i, err := strconv.Atoi(id)
+			if err != nil || i < 0 || i >= len(a.Sources) {
+				continue
+			}
+			src := a.Sources[i]
+			key := src.Path + "#" + src.Heading
+			n, ok := numbers[key]
+			if !ok {
+				n = len(footnotes) + 1
+				numbers[key] = n
+				footnotes = append(footnotes, Footnote{Number: n, Path: src.Path, Heading: src.Heading})
+			}
+			if !slices.Contains(m.numbers, n) {
+				m.numbers = append(m.numbers, n)
+			}
+		}
+		if len(m.numbers) > 0 {
+			markers = append(markers, m)
+		}
+	}
+
+	text := []rune(a.Text)
+	var b strings.Builder
+	pos := 0
+	for i := 0; i < len(markers); {
+		end := min(max(markers[i].end, pos), len(text))
+
+		// Citations ending at the same offset share one marker
+		var nums []int
+		for ; i < len(markers) && min(max(markers[i].end, pos), len(text)) == end; i++ {
+			for _, n := range markers[i].numbers {
+				if !slices.Contains(nums, n) {
+					nums = append(nums, n)
+				}
+			}
+		}
+		sort.Ints(nums)
+
+		b.WriteString(string(text[pos:end]))
+		labels := make([]string, len(nums))
+		for j, n := range nums {
+			labels[j] = strconv.Itoa(n)
+		}
+		b.WriteString("[" + strings.Join(labels, ",") + "]")
+		pos = end
+	}
+	b.WriteString(string(text[pos:]))
+
+	return b.String(), footnotes
+}
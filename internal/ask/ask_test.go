@@ -0,0 +1,123 @@
+package ask
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/cohere"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+func TestFootnotes(t *testing.T) {
+	answer := &Answer{
+		Text: "Your passport expires in März 2026. Renew it at the post office.",
+		Citations: []cohere.Citation{
+			{Start: 36, End: 63, DocumentIDs: []string{"2"}},
+			{Start: 25, End: 34, DocumentIDs: []string{"0", "1"}},
+			{Start: 25, End: 34, DocumentIDs: []string{"2"}},
+			{Start: 0, End: 5, DocumentIDs: []string{"9"}},
+		},
+		Sources: []search.Result{
+			{Path: "Travel/Passport.md", Heading: "Expiry"},
+			{Path: "Travel/Passport.md", Heading: "Expiry"},
+			{Path: "Errands.md"},
+		},
+	}
+
+	text, footnotes := answer.Footnotes()
+
+	want := "Your passport expires in März 2026[1,2]. Renew it at the post office[2]."
+	if text != want {
+		t.Errorf("expected %q, got %q", want, text)
+	}
+
+	if len(footnotes) != 2 {
+		t.Fatalf("expected 2 footnotes, got %+v", footnotes)
+	}
+	if footnotes[0].Path != "Travel/Passport.md" || footnotes[0].Heading != "Expiry" || footnotes[0].Number != 1 {
+		t.Errorf("unexpected first footnote: %+v", footnotes[0])
+	}
+	if footnotes[1].Path != "Errands.md" || footnotes[1].Number != 2 {
+		t.Errorf("unexpected second footnote: %+v", footnotes[1])
+	}
+}
+
+func TestRetrievalQueries(t *testing.T) {
+	history := []cohere.ChatMessage{
+		{Role: "user", Content: "where did we stay in Lisbon?"},
+		{Role: "assistant", Content: "Hotel Avenida."},
+		{Role: "user", Content: "how much was it?"},
+		{Role: "assistant", Content: "About 120 EUR a night."},
+		{Role: "user", Content: "  and the check-in time? "},
+	}
+
+	got := retrievalQueries(history)
+
+	if len(got) != 2 || got[0] != "and the check-in time?" || got[1] != "how much was it?" {
+		t.Errorf("expected the last two questions, newest first, got %v", got)
+	}
+}
+
+func TestMemoryNote(t *testing.T) {
+	history := []cohere.ChatMessage{
+		{Role: "user", Content: "where did we stay\nin Lisbon?"},
+		{Role: "assistant", Content: "Hotel Avenida."},
+		{Role: "user", Content: "book it again?"},
+	}
+
+	note := MemoryNote("- Wants to go back to Hotel Avenida", history, time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC))
+
+	for _, want := range []string{
+		"tags: [" + MemoryTag + "]",
+		"# Chat on 2026-03-14 09:30",
+		"- Wants to go back to Hotel Avenida\n",
+		"## Questions\n\n- where did we stay in Lisbon?\n- book it again?\n",
+	} {
+		if !strings.Contains(note, want) {
+			t.Errorf("expected the note to contain %q:\n%s", want, note)
+		}
+	}
+}
+
+func TestWithMemory(t *testing.T) {
+	a := New(nil, nil).WithMemory("/Chat Memory/")
+	if got := a.memoryGlob(); got != "Chat Memory/**" {
+		t.Errorf("expected a glob for the memory folder, got %q", got)
+	}
+}
+
+func TestRecentHistory(t *testing.T) {
+	var history []cohere.ChatMessage
+	for i := range 30 {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		history = append(history, cohere.ChatMessage{Role: role, Content: strconv.Itoa(i)})
+	}
+
+	got := recentHistory(history)
+	if len(got) != maxHistoryMessages || got[0].Content != "10" || got[len(got)-1].Content != "29" {
+		t.Errorf("expected the last %d messages, got %v", maxHistoryMessages, got)
+	}
+
+	// A long answer pushes out everything before it, starting on a question
+	long := []cohere.ChatMessage{
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: strings.Repeat("x", maxHistoryRunes)},
+		{Role: "user", Content: "second"},
+		{Role: "assistant", Content: "short"},
+		{Role: "user", Content: "third"},
+	}
+	got = recentHistory(long)
+	if len(got) != 3 || got[0].Content != "second" {
+		t.Errorf("expected the turns after the long answer, got %d messages starting %q", len(got), got[0].Content)
+	}
+
+	huge := []cohere.ChatMessage{{Role: "user", Content: strings.Repeat("x", 2*maxHistoryRunes)}}
+	if got := recentHistory(huge); len(got) != 1 {
+		t.Errorf("expected the last message kept whatever its length, got %d", len(got))
+	}
+}
@@ -0,0 +1,102 @@
+package ask
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/cohere"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+// memorySources is how many memory notes are retrieved for each turn, on top
+// of the vault's notes.
+const memorySources = 3
+
+// MemoryTag marks the notes written by Distill, so they can be told apart
+// from the user's own notes.
+const MemoryTag = "ofind-chat-memory"
+
+const memoryPrompt = `
+Documents under %s are memories of your earlier conversations with the user. Use them for continuity, but prefer the user's own notes where they disagree.`
+
+const distillPrompt = `You are given a conversation between the user and an assistant that answers from the user's personal notes.
+Write down what is worth remembering in later conversations: what the user wanted to know or decide, the conclusions reached, and preferences or plans the user mentioned.
+Write short Markdown bullet points, at most ten, with no heading or introduction. Do not repeat facts that are only restated from the notes.
+If nothing is worth remembering, reply with just NONE.`
+
+// WithMemory has the asker also retrieve the notes under dir, a
+// vault-relative folder of memories from earlier chat sessions, and give
+// them to the chat model alongside the vault's notes.
+func (a *Asker) WithMemory(dir string) *Asker {
+	a.memoryDir = strings.Trim(path.Clean("/"+dir), "/")
+	return a
+}
+
+// memoryGlob matches the notes in the memory folder.
+func (a *Asker) memoryGlob() string {
+	return a.memoryDir + "/**"
+}
+
+// retrieveMemories returns the memory notes most relevant to queries.
+func (a *Asker) retrieveMemories(ctx context.Context, queries []string) ([]search.Result, error) {
+	return a.searcher.SearchMulti(ctx, queries, search.Options{
+		Limit: memorySources,
+		Paths: []string{a.memoryGlob()},
+	})
+}
+
+// Distill condenses a conversation into what is worth remembering in later
+// sessions, as Markdown bullet points. It returns "" when there is nothing.
+func (a *Asker) Distill(ctx context.Context, history []cohere.ChatMessage) (string, error) {
+	var transcript strings.Builder
+	for _, m := range recentHistory(history) {
+		role := "User"
+		if m.Role == "assistant" {
+			role = "Assistant"
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n\n", role, strings.TrimSpace(m.Content))
+	}
+	if transcript.Len() == 0 {
+		return "", nil
+	}
+
+	memory, err := a.cohere.Chat(ctx, distillPrompt, transcript.String())
+	if err != nil {
+		return "", err
+	}
+	memory = strings.TrimSpace(memory)
+	if strings.EqualFold(memory, "NONE") {
+		return "", nil
+	}
+	return memory, nil
+}
+
+// MemoryNote renders a distilled memory as a note for the memory folder,
+// tagged with MemoryTag and listing the questions of the session.
+func MemoryNote(memory string, history []cohere.ChatMessage, now time.Time) string {
+	var b strings.Builder
+
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "created: %s\n", now.Format("2006-01-02"))
+	fmt.Fprintf(&b, "tags: [%s]\n", MemoryTag)
+	b.WriteString("---\n\n")
+	fmt.Fprintf(&b, "# Chat on %s\n\n", now.Format("2006-01-02 15:04"))
+	b.WriteString(memory + "\n")
+
+	var questions []string
+	for _, m := range history {
+		if m.Role == "user" {
+			questions = append(questions, strings.Join(strings.Fields(m.Content), " "))
+		}
+	}
+	if len(questions) > 0 {
+		b.WriteString("\n## Questions\n\n")
+		for _, q := range questions {
+			fmt.Fprintf(&b, "- %s\n", q)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,280 @@
+// Package cluster groups notes into topics by running k-means over their
+// embeddings and labels each topic with the words that set its note titles
+// apart from the rest of the vault.
+package cluster
+
+import (
+	"cmp"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxIterations = 50
+	labelTerms    = 3
+	minTermLen    = 3
+)
+
+// Note is a document to cluster. Vector is its document-level embedding.
+type Note struct {
+	DocID  int64
+	Path   string
+	Title  string
+	Vector []float32
+}
+
+// Cluster is one topic. Notes are ordered from most to least representative,
+// i.e. by similarity to the cluster centroid.
+type Cluster struct {
+	Terms []string
+	Notes []Note
+}
+
+// Label joins the cluster's distinctive terms for display.
+func (c Cluster) Label() string {
+	if len(c.Terms) == 0 {
+		return "(unlabeled)"
+	}
+	return strings.Join(c.Terms, ", ")
+}
+
+// DefaultK picks a cluster count for n notes: roughly sqrt(n/2), kept between
+// 2 and 20 so the output stays readable.
+func DefaultK(n int) int {
+	return min(max(int(math.Sqrt(float64(n)/2)), 2), 20)
+}
+
+// Build clusters notes into at most k topics, largest first. The seed makes
+// runs reproducible.
+func Build(notes []Note, k int, seed uint64) []Cluster {
+	if len(notes) == 0 || k <= 0 {
+		return nil
+	}
+	k = min(k, len(notes))
+
+	vectors := make([][]float32, len(notes))
+	for i, n := range notes {
+		vectors[i] = normalize(n.Vector)
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed))
+	assignments, centroids := KMeans(vectors, k, rng)
+
+	members := make([][]int, k)
+	for i, c := range assignments {
+		members[c] = append(members[c], i)
+	}
+
+	var clusters []Cluster
+	for c, idxs := range members {
+		if len(idxs) == 0 {
+			continue
+		}
+		slices.SortStableFunc(idxs, func(a, b int) int {
+			return cmp.Compare(dot(vectors[b], centroids[c]), dot(vectors[a], centroids[c]))
+		})
+
+		cluster := Cluster{}
+		for _, i := range idxs {
+			cluster.Notes = append(cluster.Notes, notes[i])
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	labelClusters(clusters)
+
+	slices.SortStableFunc(clusters, func(a, b Cluster) int {
+		return cmp.Compare(len(b.Notes), len(a.Notes))
+	})
+	return clusters
+}
+
+// KMeans assigns each unit-length vector to one of k clusters using cosine
+// similarity, seeding centroids with k-means++. It returns the assignment of
+// each vector and the final unit-length centroids.
+func KMeans(vectors [][]float32, k int, rng *rand.Rand) ([]int, [][]float32) {
+	centroids := seedCentroids(vectors, k, rng)
+	assignments := make([]int, len(vectors))
+
+	for iter := 0; iter < maxIterations; iter++ {
+		changed := iter == 0
+		for i, v := range vectors {
+			if best := nearest(v, centroids); best != assignments[i] {
+				assignments[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		sums := make([][]float64, k)
+		for c := range sums {
+			sums[c] = make([]float64, len(vectors[0]))
+		}
+		for i, v := range vectors {
+			for d, x := range v {
+				sums[assignments[i]][d] += float64(x)
+			}
+		}
+		for c, sum := range sums {
+			// Keep the old centroid for an empty cluster
+			if norm := vectorNorm(sum); norm > 0 {
+				for d, x := range sum {
+					centroids[c][d] = float32(x / norm)
+				}
+			}
+		}
+	}
+
+	return assignments, centroids
+}
+
+// seedCentroids picks initial centroids with k-means++: each next centroid is
+// drawn with probability proportional to its distance from the nearest
+// centroid chosen so far.
+func seedCentroids(vectors [][]float32, k int, rng *rand.Rand) [][]float32 {
+	centroids := [][]float32{slices.Clone(vectors[rng.IntN(len(vectors))])}
+	distances := make([]float64, len(vectors))
+
+	for len(centroids) < k {
+		var total float64
+		for i, v := range vectors {
+			d := 1 - dot(v, centroids[nearest(v, centroids)])
+			distances[i] = d * d
+			total += distances[i]
+		}
+
+		next := rng.IntN(len(vectors))
+		if total > 0 {
+			target := rng.Float64() * total
+			for i, d := range distances {
+				target -= d
+				if target <= 0 {
+					next = i
+					break
+				}
+			}
+		}
+		centroids = append(centroids, slices.Clone(vectors[next]))
+	}
+	return centroids
+}
+
+func nearest(v []float32, centroids [][]float32) int {
+	best, bestSim := 0, math.Inf(-1)
+	for c, centroid := range centroids {
+		if sim := dot(v, centroid); sim > bestSim {
+			best, bestSim = c, sim
+		}
+	}
+	return best
+}
+
+// labelClusters picks each cluster's terms by TF-IDF over note titles, treating
+// every cluster as one document so words common to the whole vault score low.
+func labelClusters(clusters []Cluster) {
+	counts := make([]map[string]int, len(clusters))
+	df := make(map[string]int)
+	for c, cluster := range clusters {
+		counts[c] = make(map[string]int)
+		for _, note := range cluster.Notes {
+			for _, term := range titleTerms(note) {
+				counts[c][term]++
+			}
+		}
+		for term := range counts[c] {
+			df[term]++
+		}
+	}
+
+	for c := range clusters {
+		type scored struct {
+			term  string
+			score float64
+		}
+		var terms []scored
+		for term, n := range counts[c] {
+			idf := math.Log(float64(len(clusters)+1) / float64(df[term]))
+			terms = append(terms, scored{term, float64(n) * idf})
+		}
+		slices.SortFunc(terms, func(a, b scored) int {
+			if a.score != b.score {
+				return cmp.Compare(b.score, a.score)
+			}
+			return strings.Compare(a.term, b.term)
+		})
+
+		for _, t := range terms[:min(labelTerms, len(terms))] {
+			clusters[c].Terms = append(clusters[c].Terms, t.term)
+		}
+	}
+}
+
+var stopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true,
+	"that": true, "this": true, "are": true, "was": true, "not": true,
+	"notes": true, "note": true,
+}
+
+// titleTerms splits a note's title, or its file name when untitled, into
+// lowercase words, dropping short words, stopwords and numbers.
+func titleTerms(note Note) []string {
+	title := note.Title
+	if title == "" {
+		title = note.Path
+	}
+
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) < minTermLen || stopwords[word] || isNumber(word) || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+func isNumber(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) }) < 0
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	norm := math.Sqrt(sum)
+
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+func vectorNorm(v []float64) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestKMeans_SeparatesGroups(t *testing.T) {
+	vectors := [][]float32{
+		{1, 0, 0}, {0.9, 0.1, 0}, {0.95, 0, 0.05},
+		{0, 1, 0}, {0.1, 0.9, 0}, {0, 0.95, 0.05},
+	}
+	for i := range vectors {
+		vectors[i] = normalize(vectors[i])
+	}
+
+	assignments, _ := KMeans(vectors, 2, rand.New(rand.NewPCG(1, 1)))
+
+	if assignments[0] != assignments[1] || assignments[0] != assignments[2] {
+		t.Errorf("expected the first three vectors together, got %v", assignments)
+	}
+	if assignments[3] != assignments[4] || assignments[3] != assignments[5] {
+		t.Errorf("expected the last three vectors together, got %v", assignments)
+	}
+	if assignments[0] == assignments[3] {
+		t.Errorf("expected two distinct clusters, got %v", assignments)
+	}
+}
+
+func TestBuild_LabelsAndOrder(t *testing.T) {
+	notes := []Note{
+		{DocID: 1, Title: "Kubernetes deploy notes", Vector: []float32{1, 0}},
+		{DocID: 2, Title: "Kubernetes helm charts", Vector: []float32{0.9, 0.1}},
+		{DocID: 3, Title: "Helm upgrade", Vector: []float32{0.8, 0.2}},
+		{DocID: 4, Title: "Sourdough bread", Vector: []float32{0, 1}},
+		{DocID: 5, Title: "Bread starter", Vector: []float32{0.1, 0.9}},
+	}
+
+	clusters := Build(notes, 2, 1)
+
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %d", len(clusters))
+	}
+	if len(clusters[0].Notes) != 3 || clusters[0].Notes[0].DocID != 2 {
+		t.Errorf("expected the kubernetes cluster first, led by note 2 nearest its centroid, got %+v", clusters[0].Notes)
+	}
+	if clusters[0].Label() != "helm, kubernetes, charts" {
+		t.Errorf("unexpected label %q", clusters[0].Label())
+	}
+	if clusters[1].Terms[0] != "bread" {
+		t.Errorf("expected 'bread' to label the second cluster, got %v", clusters[1].Terms)
+	}
+}
+
+func TestDefaultK(t *testing.T) {
+	tests := map[int]int{1: 2, 50: 5, 10000: 20}
+	for n, want := range tests {
+		if got := DefaultK(n); got != want {
+			t.Errorf("DefaultK(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
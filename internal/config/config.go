@@ -1,9 +1,14 @@
 package config
 
 import (
+	"cmp"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -11,18 +16,202 @@ type Config struct {
 	ObsidianDir  string `json:"obsidian_dir"`
 	EmbedModel   string `json:"embed_model"`
 	RerankModel  string `json:"rerank_model"`
+	ChatModel    string `json:"chat_model"`
 	EmbedDim     int    `json:"embed_dim"`
+
+	// KeychainAPIKey keeps cohere_api_key in the OS keychain instead of this
+	// file. Setup offers it when a keychain is available.
+	KeychainAPIKey bool `json:"keychain_api_key,omitempty"`
+
+	// WatchDebounce is how long a file must be quiet before watch mode
+	// reindexes it, and WatchBatchWindow how often settled files are
+	// flushed into one embed batch. Both are Go duration strings.
+	WatchDebounce    string `json:"watch_debounce"`
+	WatchBatchWindow string `json:"watch_batch_window"`
+
+	// RedactPaths always runs searches in demo mode, as if -redact-paths
+	// were passed.
+	RedactPaths bool `json:"redact_paths,omitempty"`
+
+	// MMRLambda is the default for -mmr; 0 leaves diversification off.
+	MMRLambda float64 `json:"mmr_lambda,omitempty"`
+
+	// RecencyHalfLife is the default for -recency, e.g. "30d"; empty leaves
+	// recency boosting off.
+	RecencyHalfLife string `json:"recency_half_life,omitempty"`
+
+	// BookmarkBoost scales the scores of notes bookmarked in Obsidian by
+	// 1 + the boost; 0 leaves them ranked like any other note.
+	BookmarkBoost float64 `json:"bookmark_boost,omitempty"`
+
+	// ExpandQueries always asks the chat model for query paraphrases, as if
+	// -expand were passed.
+	ExpandQueries bool `json:"expand_queries,omitempty"`
+
+	// ChatMemoryDir is a vault folder, such as "Chat Memory", that ofind chat
+	// saves a distilled note of each session to and retrieves them from in
+	// later sessions. Empty leaves chat memory off.
+	ChatMemoryDir string `json:"chat_memory_dir,omitempty"`
+
+	// Editor is the command results are opened with by the e key in the
+	// results view, e.g. "nvim" or "code --wait". Empty uses $VISUAL or
+	// $EDITOR.
+	Editor string `json:"editor,omitempty"`
+
+	// OpenInEditor makes enter, and ofind open, use the editor instead of
+	// Obsidian.
+	OpenInEditor bool `json:"open_in_editor,omitempty"`
+
+	// Keybindings remaps keys in the results view by action name (up, down,
+	// open, edit, copy_path, copy_link, copy_text, quit), each to
+	// space-separated keys such as "k ctrl+p". They replace that action's
+	// default keys.
+	Keybindings map[string]string `json:"keybindings,omitempty"`
+
+	// ExcludedNotes are the absolute paths of notes left out of every
+	// search, added from the results view's action menu.
+	ExcludedNotes []string `json:"excluded_notes,omitempty"`
+
+	// Quantization stores embeddings as "int8" or "bit" vectors instead of
+	// float32, rescoring the top candidates at full precision. Empty means
+	// float.
+	Quantization string `json:"quantization,omitempty"`
+
+	// Encrypt stores the index encrypted with SQLCipher under a key kept in
+	// the OS keychain. It needs a binary built with make build-encrypted.
+	Encrypt bool `json:"encrypt,omitempty"`
+
+	// TrashRetentionDays is how long notes moved to the vault's .trash
+	// folder are kept in the index, out of search results, so restoring one
+	// doesn't need new embeddings. 0 means 30 days; negative removes them
+	// like any deleted note.
+	TrashRetentionDays int `json:"trash_retention_days,omitempty"`
+
+	// Vaults names additional vaults, each a directory with optional
+	// settings of its own. Each has its own database and is selected with
+	// -vault; obsidian_dir stays the default unless DefaultVault names one.
+	Vaults map[string]Profile `json:"vaults,omitempty"`
+
+	// DefaultVault is the vault used without -vault; empty uses
+	// obsidian_dir.
+	DefaultVault string `json:"default_vault,omitempty"`
+
+	// ExcludePaths are vault-relative globs ("Archive/**") of notes left out
+	// of the index and of searches.
+	ExcludePaths []string `json:"exclude_paths,omitempty"`
+
+	// VectorStore keeps embeddings on a Qdrant or pgvector server instead
+	// of in the index database. Nil uses the built-in sqlite-vec table.
+	VectorStore *VectorStoreConfig `json:"vector_store,omitempty"`
+
+	// Webhooks names URLs that every index event (a note indexed or
+	// removed, a run completed or failed) is POSTed to as JSON.
+	Webhooks map[string]string `json:"webhooks,omitempty"`
+
+	// APIToken must be sent as a bearer token to the HTTP and gRPC APIs of
+	// ofind daemon and ofind serve. It is generated at setup, or the first
+	// time either runs.
+	APIToken string `json:"api_token,omitempty"`
+
+	// APIListen is another address, as host:port, that the HTTP API of
+	// ofind daemon and ofind serve listens on besides localhost, such as
+	// the machine's Tailscale address to reach it from a phone. Requests
+	// for its host are accepted along with localhost ones.
+	APIListen string `json:"api_listen,omitempty"`
+
+	// Tokens are further API tokens, one per client, so each can be revoked
+	// on its own. They are kept as SHA-256 hashes in hex by client name, and
+	// managed with ofind token.
+	Tokens map[string]string `json:"tokens,omitempty"`
+
+	// Vault is the named vault selected for this run, empty for the
+	// default one. It is set by UseVault and never saved.
+	Vault string `json:"-"`
+
+	// base holds the top-level settings UseVault replaced, for Save.
+	base *Profile
+
+	// keychainKeys are the API keys last read from or stored in the
+	// keychain, by account.
+	keychainKeys map[string]string
 }
 
+// VectorStoreConfig selects an external vector store.
+type VectorStoreConfig struct {
+	// Type is "qdrant" or "pgvector".
+	Type string `json:"type"`
+
+	// URL is the Qdrant base URL or the PostgreSQL connection string.
+	URL string `json:"url"`
+
+	// APIKey authenticates to Qdrant.
+	APIKey string `json:"api_key,omitempty"`
+
+	// Collection is the Qdrant collection or PostgreSQL table, obsvec by
+	// default. Named vaults append _<vault>.
+	Collection string `json:"collection,omitempty"`
+}
+
+// ConfigDir holds config.json: $XDG_CONFIG_HOME/obsvec, ~/.config/obsvec by
+// default, or %APPDATA%\obsvec on Windows. It stays ~/.config/obsvec while
+// Migrate hasn't moved the config from there.
 func ConfigDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return unmigrated(dir, "config.json"), nil
+}
+
+func configDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "obsvec"), nil
+	}
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir holds the indexes, the daemon's sockets and the logs:
+// $XDG_DATA_HOME/obsvec, ~/.local/share/obsvec by default, or
+// %LOCALAPPDATA%\obsvec on Windows. It stays ~/.config/obsvec while Migrate
+// hasn't moved the indexes from there.
+func DataDir() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return unmigrated(dir, "obsvec.db", "vaults", "history"), nil
+}
+
+func dataDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, "obsvec"), nil
+		}
+		return configDir()
+	}
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// xdgDir is obsvec's directory under the XDG base directory in env, or under
+// fallback in the home directory when env is unset. The spec says to ignore
+// relative paths.
+func xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "obsvec"), nil
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(home, ".config", "obsvec"), nil
+	return filepath.Join(home, fallback, "obsvec"), nil
 }
 
-func configPath() (string, error) {
+// Path is the config file, config.json in ConfigDir.
+func Path() (string, error) {
 	dir, err := ConfigDir()
 	if err != nil {
 		return "", err
@@ -30,16 +219,47 @@ func configPath() (string, error) {
 	return filepath.Join(dir, "config.json"), nil
 }
 
-func DBPath() (string, error) {
-	dir, err := ConfigDir()
+// DBPath is the database of the named vault, or of the default vault when
+// vault is empty.
+func DBPath(vault string) (string, error) {
+	dir, err := DataDir()
 	if err != nil {
 		return "", err
 	}
+	if vault != "" {
+		return filepath.Join(dir, "vaults", vault+".db"), nil
+	}
 	return filepath.Join(dir, "obsvec.db"), nil
 }
 
+// SocketPath is where the daemon of the named vault, or of the default vault
+// when vault is empty, takes searches from the CLI.
+func SocketPath(vault string) (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	if vault != "" {
+		return filepath.Join(dir, "vaults", vault+".sock"), nil
+	}
+	return filepath.Join(dir, "obsvec.sock"), nil
+}
+
+// HistoryDir holds the per-commit indexes built for searches with -as-of,
+// kept apart per vault since two vaults can share a git repository.
+func HistoryDir(vault string) (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	if vault != "" {
+		return filepath.Join(dir, "history", "vaults", vault), nil
+	}
+	return filepath.Join(dir, "history"), nil
+}
+
 func Load() (*Config, error) {
-	path, err := configPath()
+	path, err := Path()
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +278,9 @@ func Load() (*Config, error) {
 	}
 
 	cfg.ApplyDefaults()
+	if err := cfg.loadAPIKey(); err != nil {
+		return nil, err
+	}
 
 	return &cfg, nil
 }
@@ -72,18 +295,32 @@ func (c *Config) Save() error {
 		return err
 	}
 
-	path, err := configPath()
+	path, err := Path()
 	if err != nil {
 		return err
 	}
 
-	data, err := json.MarshalIndent(c, "", "  ")
+	// A selected vault's settings stay in its profile
+	saved := c
+	if c.base != nil {
+		unswitched := *c
+		unswitched.setProfile(*c.base, false)
+		saved = &unswitched
+	}
+	if saved, err = c.storeAPIKey(saved); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
 	if err != nil {
 		return err
 	}
 
 	data = append(data, '\n')
-	return os.WriteFile(path, data, 0600)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return c.forgetAPIKey()
 }
 
 func defaultConfig() *Config {
@@ -99,7 +336,65 @@ func (c *Config) ApplyDefaults() {
 	if c.RerankModel == "" {
 		c.RerankModel = "rerank-v3.5"
 	}
+	if c.ChatModel == "" {
+		c.ChatModel = "command-a-03-2025"
+	}
 	if c.EmbedDim == 0 {
 		c.EmbedDim = 1024
 	}
+	if c.WatchDebounce == "" {
+		c.WatchDebounce = "2s"
+	}
+	if c.WatchBatchWindow == "" {
+		c.WatchBatchWindow = "500ms"
+	}
+}
+
+func (c *Config) WatchDebounceDuration() (time.Duration, error) {
+	return parsePositiveDuration("watch_debounce", c.WatchDebounce)
+}
+
+func (c *Config) WatchBatchWindowDuration() (time.Duration, error) {
+	return parsePositiveDuration("watch_batch_window", c.WatchBatchWindow)
+}
+
+func (c *Config) TrashRetention() time.Duration {
+	switch {
+	case c.TrashRetentionDays < 0:
+		return 0
+	case c.TrashRetentionDays == 0:
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(c.TrashRetentionDays) * 24 * time.Hour
+}
+
+// ExcludedPaths returns the excluded notes inside the current vault, as
+// vault-relative paths.
+func (c *Config) ExcludedPaths() []string {
+	var paths []string
+	for _, note := range c.ExcludedNotes {
+		rel, err := filepath.Rel(c.ObsidianDir, note)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	return paths
+}
+
+// EditorCommand returns the editor to open results in: Editor, else $VISUAL,
+// else $EDITOR.
+func (c *Config) EditorCommand() string {
+	return cmp.Or(c.Editor, os.Getenv("VISUAL"), os.Getenv("EDITOR"))
+}
+
+func parsePositiveDuration(key, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", key, value, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be positive", key, value)
+	}
+	return d, nil
 }
@@ -2,11 +2,28 @@ package config
 
 import (
 	"encoding/json"
+	"net"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/mgomes/obsvec/internal/keychain"
 )
 
+// tempHome points the home directory at a new temporary one, without XDG
+// overrides, and returns it.
+func tempHome(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	return home
+}
+
 func TestDefaultConfig(t *testing.T) {
 	cfg := defaultConfig()
 
@@ -95,3 +112,521 @@ func TestConfigDefaultsApplied(t *testing.T) {
 		t.Errorf("expected default embed dim 1024, got %d", cfg.EmbedDim)
 	}
 }
+
+func TestWatchDurations(t *testing.T) {
+	cfg := defaultConfig()
+
+	debounce, err := cfg.WatchDebounceDuration()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if debounce != 2*time.Second {
+		t.Errorf("expected default debounce 2s, got %v", debounce)
+	}
+
+	cfg.WatchBatchWindow = "250ms"
+	window, err := cfg.WatchBatchWindowDuration()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if window != 250*time.Millisecond {
+		t.Errorf("expected batch window 250ms, got %v", window)
+	}
+
+	cfg.WatchDebounce = "soon"
+	if _, err := cfg.WatchDebounceDuration(); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+
+	cfg.WatchDebounce = "-1s"
+	if _, err := cfg.WatchDebounceDuration(); err == nil {
+		t.Error("expected error for negative duration")
+	}
+}
+
+func TestDirs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows uses %APPDATA% and %LOCALAPPDATA%")
+	}
+	home := tempHome(t)
+
+	configDir, _ := ConfigDir()
+	dataDir, _ := DataDir()
+	if configDir != filepath.Join(home, ".config", "obsvec") || dataDir != filepath.Join(home, ".local", "share", "obsvec") {
+		t.Errorf("expected the XDG defaults, got %s and %s", configDir, dataDir)
+	}
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(xdg, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(xdg, "data"))
+	if path, _ := Path(); path != filepath.Join(xdg, "config", "obsvec", "config.json") {
+		t.Errorf("expected the config under XDG_CONFIG_HOME, got %s", path)
+	}
+	if path, _ := DBPath("work"); path != filepath.Join(xdg, "data", "obsvec", "vaults", "work.db") {
+		t.Errorf("expected databases under XDG_DATA_HOME, got %s", path)
+	}
+
+	t.Setenv("XDG_DATA_HOME", "relative")
+	if dir, _ := DataDir(); dir != dataDir {
+		t.Errorf("expected a relative XDG_DATA_HOME to be ignored, got %s", dir)
+	}
+}
+
+// writeFiles creates each named file under dir with its name as content.
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows uses %APPDATA% and %LOCALAPPDATA%")
+	}
+	home := tempHome(t)
+	legacy := filepath.Join(home, ".config", "obsvec")
+	writeFiles(t, legacy, "config.json", "obsvec.db", "obsvec.db-wal", "vaults/work.db", "vaults/work.db-wal")
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(xdg, "config"))
+
+	// Until Migrate runs, everything is used where it is
+	if dbPath, _ := DBPath(""); dbPath != filepath.Join(legacy, "obsvec.db") {
+		t.Errorf("expected the unmoved index used in place, got %s", dbPath)
+	}
+
+	for range 2 {
+		if err := Migrate(); err != nil {
+			t.Fatalf("Migrate failed: %v", err)
+		}
+	}
+
+	path, _ := Path()
+	dbPath, _ := DBPath("")
+	workPath, _ := DBPath("work")
+	for _, p := range []string{path, dbPath, dbPath + "-wal", workPath, workPath + "-wal"} {
+		if _, err := os.Stat(p); err != nil || strings.HasPrefix(p, legacy) {
+			t.Errorf("expected %s to be moved: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("expected the emptied legacy directory removed, got %v", err)
+	}
+}
+
+func TestMigrateKeepsDatabaseFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows uses %APPDATA% and %LOCALAPPDATA%")
+	}
+	home := tempHome(t)
+	legacy := filepath.Join(home, ".config", "obsvec")
+	data := filepath.Join(home, ".local", "share", "obsvec")
+	writeFiles(t, legacy, "obsvec.db", "obsvec.db-wal", "obsvec.db-shm", "vaults/work.db", "vaults/work.db-wal", "vaults/home.db", "vaults/home.db-wal")
+	writeFiles(t, data, "obsvec.db", "vaults/work.db")
+
+	if err := Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// A WAL never lands next to another database
+	for _, name := range []string{"obsvec.db-wal", "obsvec.db-shm", "vaults/work.db-wal"} {
+		if _, err := os.Stat(filepath.Join(data, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s left behind with its database, got %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(legacy, name)); err != nil {
+			t.Errorf("expected %s kept in the legacy directory: %v", name, err)
+		}
+	}
+	for _, name := range []string{"vaults/home.db", "vaults/home.db-wal"} {
+		if _, err := os.Stat(filepath.Join(data, name)); err != nil {
+			t.Errorf("expected %s merged into the existing vaults directory: %v", name, err)
+		}
+	}
+}
+
+func TestMigrateWithDaemon(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows uses %APPDATA% and %LOCALAPPDATA%")
+	}
+	home := tempHome(t)
+	legacy := filepath.Join(home, ".config", "obsvec")
+	writeFiles(t, legacy, "config.json", "obsvec.db")
+
+	listener, err := net.Listen("unix", filepath.Join(legacy, "obsvec.sock"))
+	if err != nil {
+		t.Skipf("no unix sockets: %v", err)
+	}
+	defer listener.Close()
+
+	if err := Migrate(); err == nil {
+		t.Error("expected Migrate to wait for the daemon to stop")
+	}
+	if dbPath, _ := DBPath(""); dbPath != filepath.Join(legacy, "obsvec.db") {
+		t.Errorf("expected the daemon's index kept in use, got %s", dbPath)
+	}
+}
+
+func TestCopyEntry(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, "history/abc.db", "history/vaults/work/def.db")
+
+	dst := filepath.Join(t.TempDir(), "history")
+	if err := copyEntry(filepath.Join(src, "history"), dst); err != nil {
+		t.Fatalf("copyEntry failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "vaults", "work", "def.db"))
+	if err != nil || string(data) != "history/vaults/work/def.db" {
+		t.Errorf("expected the nested file copied, got %q (%v)", data, err)
+	}
+}
+
+func TestMoveAllFailure(t *testing.T) {
+	from, to := t.TempDir(), t.TempDir()
+	writeFiles(t, from, "obsvec.db")
+	writeFiles(t, to, "keep.db")
+
+	// The second entry can be neither renamed nor copied
+	if err := moveAll(from, to, []string{"obsvec.db", "obsvec.db-wal"}); err == nil {
+		t.Fatal("expected moveAll to fail")
+	}
+	if _, err := os.Stat(filepath.Join(from, "obsvec.db")); err != nil {
+		t.Errorf("expected the database put back: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(to, "obsvec.db")); !os.IsNotExist(err) {
+		t.Errorf("expected the partial copy removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(to, "keep.db")); err != nil {
+		t.Errorf("expected files already at the destination kept: %v", err)
+	}
+}
+
+func TestUseVault(t *testing.T) {
+	tempHome(t)
+
+	cfg := &Config{
+		ObsidianDir: "/vaults/personal",
+		Vaults:      map[string]Profile{"work": {Dir: "/vaults/work"}, "../evil": {Dir: "/tmp"}},
+		ExcludedNotes: []string{
+			filepath.FromSlash("/vaults/personal/diary.md"),
+			filepath.FromSlash("/vaults/work/old/plan.md"),
+			filepath.FromSlash("/vaults/workshop/notes.md"),
+		},
+	}
+
+	if err := cfg.UseVault("missing"); err == nil {
+		t.Error("expected an error for an unknown vault")
+	}
+	if err := cfg.UseVault("../evil"); err == nil {
+		t.Error("expected an error for a vault name that isn't a plain file name")
+	}
+
+	defaultPath, _ := DBPath(cfg.Vault)
+	if err := cfg.UseVault("work"); err != nil {
+		t.Fatalf("UseVault failed: %v", err)
+	}
+	if cfg.ObsidianDir != "/vaults/work" {
+		t.Errorf("expected the work vault directory, got %s", cfg.ObsidianDir)
+	}
+	if got := cfg.ExcludedPaths(); len(got) != 1 || got[0] != "old/plan.md" {
+		t.Errorf("expected only the work vault's excluded note, got %v", got)
+	}
+
+	workPath, _ := DBPath(cfg.Vault)
+	if workPath == defaultPath || filepath.Base(workPath) != "work.db" {
+		t.Errorf("expected a separate database for the vault, got %s (default %s)", workPath, defaultPath)
+	}
+}
+
+func TestVaultProfiles(t *testing.T) {
+	tempHome(t)
+
+	data := `{
+		"cohere_api_key": "main-key",
+		"obsidian_dir": "/vaults/personal",
+		"vaults": {
+			"work": {"dir": "/vaults/work", "cohere_api_key": "work-key", "embed_dim": 1536, "exclude_paths": ["Archive/**"]},
+			"old": "/vaults/old"
+		},
+		"default_vault": "work"
+	}`
+	var cfg Config
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	cfg.ApplyDefaults()
+	if cfg.Vaults["old"].Dir != "/vaults/old" {
+		t.Errorf("expected a vault given as a directory to load, got %+v", cfg.Vaults["old"])
+	}
+
+	if err := cfg.UseVault("work"); err != nil {
+		t.Fatalf("UseVault failed: %v", err)
+	}
+	if cfg.ObsidianDir != "/vaults/work" || cfg.CohereAPIKey != "work-key" || cfg.EmbedDim != 1536 || len(cfg.ExcludePaths) != 1 {
+		t.Errorf("expected the work vault's settings, got %+v", cfg)
+	}
+	if cfg.EmbedModel != "embed-v4.0" {
+		t.Errorf("expected unset settings to fall back, got %s", cfg.EmbedModel)
+	}
+
+	// Saving while a vault is selected leaves the top-level settings alone
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	saved, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if saved.ObsidianDir != "/vaults/personal" || saved.CohereAPIKey != "main-key" || saved.EmbedDim != 1024 {
+		t.Errorf("expected the top-level settings saved, got %+v", saved)
+	}
+	path, _ := Path()
+	raw, _ := os.ReadFile(path)
+	if !strings.Contains(string(raw), `"old": "/vaults/old"`) {
+		t.Errorf("expected a vault with only a directory saved as one, got %s", raw)
+	}
+
+	if err := saved.UseVault(DefaultVaultName); err != nil || saved.ObsidianDir != "/vaults/personal" {
+		t.Errorf("expected %q to keep the top-level vault, got %s (%v)", DefaultVaultName, saved.ObsidianDir, err)
+	}
+}
+
+func TestKeychainAPIKey(t *testing.T) {
+	tempHome(t)
+
+	stored := map[string]string{}
+	origGet, origSet, origDelete := keychainGet, keychainSet, keychainDelete
+	defer func() { keychainGet, keychainSet, keychainDelete = origGet, origSet, origDelete }()
+	keychainGet = func(account string) (string, error) {
+		if secret, ok := stored[account]; ok {
+			return secret, nil
+		}
+		return "", keychain.ErrNotFound
+	}
+	keychainSet = func(account, secret string) error {
+		stored[account] = secret
+		return nil
+	}
+	keychainDelete = func(account string) error {
+		delete(stored, account)
+		return nil
+	}
+
+	cfg := defaultConfig()
+	cfg.CohereAPIKey = "secret-key"
+	cfg.KeychainAPIKey = true
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	path, _ := Path()
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), "secret-key") {
+		t.Errorf("expected the API key kept out of the file, got %s", raw)
+	}
+	if stored[APIKeyAccount] != "secret-key" {
+		t.Errorf("expected the API key in the keychain, got %v", stored)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.CohereAPIKey != "secret-key" {
+		t.Errorf("expected the API key read from the keychain, got %q", loaded.CohereAPIKey)
+	}
+
+	// Turning it off writes the key back to the file and clears the keychain
+	loaded.KeychainAPIKey = false
+	if err := loaded.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	raw, _ = os.ReadFile(path)
+	if !strings.Contains(string(raw), "secret-key") || len(stored) != 0 {
+		t.Errorf("expected the API key moved back to the file, got %s and %v", raw, stored)
+	}
+
+	// A vault's own key goes to the keychain too, under its own entry
+	loaded.KeychainAPIKey = true
+	loaded.Vaults = map[string]Profile{
+		"work": {Dir: "/vaults/work", CohereAPIKey: "work-key"},
+		"old":  {Dir: "/vaults/old"},
+	}
+	if err := loaded.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	raw, _ = os.ReadFile(path)
+	if strings.Contains(string(raw), "secret-key") || strings.Contains(string(raw), "work-key") {
+		t.Errorf("expected the vault's API key kept out of the file, got %s", raw)
+	}
+	if stored[VaultAPIKeyAccount("work")] != "work-key" || len(stored) != 2 {
+		t.Errorf("expected the vault's API key in the keychain, got %v", stored)
+	}
+
+	loaded, err = Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := loaded.UseVault("work"); err != nil || loaded.CohereAPIKey != "work-key" {
+		t.Errorf("expected the vault's API key read from the keychain, got %q (%v)", loaded.CohereAPIKey, err)
+	}
+
+	// Removing the vault deletes its entry
+	loaded.RemoveVault("work")
+	if err := loaded.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, ok := stored[VaultAPIKeyAccount("work")]; ok || stored[APIKeyAccount] != "secret-key" {
+		t.Errorf("expected only the removed vault's API key deleted, got %v", stored)
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	cfg := defaultConfig()
+
+	if err := cfg.Set("embed_dim", "1536"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got, _ := cfg.Get("embed_dim"); got != "1536" {
+		t.Errorf("expected embed_dim 1536, got %q", got)
+	}
+	if !RequiresReindex("embed_dim") || RequiresReindex("mmr_lambda") {
+		t.Error("expected only embed_dim to require a reindex")
+	}
+
+	if err := cfg.Set("embed_dim", ""); err != nil || cfg.EmbedDim != 1024 {
+		t.Errorf("expected an empty value to restore the default, got %d (%v)", cfg.EmbedDim, err)
+	}
+
+	for key, value := range map[string]string{
+		"embed_dim":          "-1",
+		"mmr_lambda":         "1.5",
+		"bookmark_boost":     "-0.5",
+		"default_vault":      "nowhere",
+		"chat_memory_dir":    "../Elsewhere",
+		"watch_debounce":     "soon",
+		"expand_queries":     "maybe",
+		"vector_store.type":  "redis",
+		"obsidian_dir":       filepath.Join(t.TempDir(), "missing"),
+		"no_such_setting":    "1",
+		"embed_model.nested": "x",
+	} {
+		if err := cfg.Set(key, value); err == nil {
+			t.Errorf("expected %s=%q to be rejected", key, value)
+		}
+	}
+
+	if err := cfg.Set("vector_store.url", "http://localhost:6333"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if cfg.VectorStore == nil || cfg.VectorStore.URL != "http://localhost:6333" {
+		t.Errorf("expected the vector store to be created, got %+v", cfg.VectorStore)
+	}
+	if err := cfg.Set("vector_store.type", ""); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := cfg.Set("vector_store.url", ""); err != nil || cfg.VectorStore != nil {
+		t.Errorf("expected clearing every field to remove the vector store, got %+v (%v)", cfg.VectorStore, err)
+	}
+
+	dir := t.TempDir()
+	if err := cfg.Set("vaults.work", dir); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got, _ := cfg.Get("vaults.work"); got != dir {
+		t.Errorf("expected vault %s, got %q", dir, got)
+	}
+	if err := cfg.Set("vaults.work.embed_model", "embed-v3.0"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got, _ := cfg.Get("vaults.work.embed_model"); got != "embed-v3.0" || cfg.Vaults["work"].Dir != dir {
+		t.Errorf("expected the vault's model to be stored, got %q (%+v)", got, cfg.Vaults["work"])
+	}
+	if err := cfg.Set("vaults.home.embed_model", "embed-v3.0"); err == nil {
+		t.Error("expected an error setting a model for an unknown vault")
+	}
+	if err := cfg.Set("default_vault", "work"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := cfg.Set("vaults.work", ""); err != nil || len(cfg.Vaults) != 0 || cfg.DefaultVault != "" {
+		t.Errorf("expected the vault to be removed, got %v (%v)", cfg.Vaults, err)
+	}
+
+	if err := cfg.Set("keybindings.down", "j ctrl+n"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got, _ := cfg.Get("keybindings.down"); got != "j ctrl+n" {
+		t.Errorf("expected the keybinding to be stored, got %q", got)
+	}
+	if err := cfg.Set("keybindings.down", ""); err != nil || len(cfg.Keybindings) != 0 {
+		t.Errorf("expected the keybinding to be removed, got %v (%v)", cfg.Keybindings, err)
+	}
+
+	if err := cfg.Set("webhooks.home", "ftp://example.com"); err == nil {
+		t.Error("expected a non-http webhook to be rejected")
+	}
+	if err := cfg.Set("webhooks.home", "http://homeassistant.local:8123/api/webhook/notes"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got, _ := cfg.Get("webhooks.home"); got != "http://homeassistant.local:8123/api/webhook/notes" {
+		t.Errorf("expected the webhook to be stored, got %q", got)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	cfg := defaultConfig()
+
+	if err := cfg.AddToken("phone", "secret"); err != nil {
+		t.Fatalf("AddToken failed: %v", err)
+	}
+	if cfg.Tokens["phone"] != HashToken("secret") || cfg.Tokens["phone"] == "secret" {
+		t.Errorf("expected the token to be kept as its hash, got %q", cfg.Tokens["phone"])
+	}
+	if err := cfg.AddToken("phone", "other"); err == nil {
+		t.Error("expected a taken name to be rejected")
+	}
+	if err := cfg.AddToken("../phone", "other"); err == nil {
+		t.Error("expected an invalid name to be rejected")
+	}
+	if err := cfg.Set("tokens.phone", "plaintext"); err == nil {
+		t.Error("expected setting a token directly to be rejected")
+	}
+
+	if err := cfg.Set("tokens.phone", ""); err != nil || len(cfg.Tokens) != 0 {
+		t.Errorf("expected the token to be revoked, got %v (%v)", cfg.Tokens, err)
+	}
+	if err := cfg.RevokeToken("phone"); err == nil {
+		t.Error("expected revoking a missing token to fail")
+	}
+}
+
+func TestDetectVaults(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	for _, dir := range []string{
+		"Documents/Notes/.obsidian",
+		"Documents/Photos",
+		"Library/Mobile Documents/iCloud~md~obsidian/Documents/Work/.obsidian",
+		"Obsidian/.obsidian",
+	} {
+		if err := os.MkdirAll(filepath.Join(home, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		filepath.Join(home, "Documents", "Notes"),
+		filepath.Join(home, "Library", "Mobile Documents", "iCloud~md~obsidian", "Documents", "Work"),
+		filepath.Join(home, "Obsidian"),
+	}
+	if got := DetectVaults(); !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// vaultLocations are where Obsidian vaults are usually kept, relative to the
+// home directory. Each is checked itself and one level down.
+var vaultLocations = []string{
+	"Documents",
+	filepath.Join("Library", "Mobile Documents", "iCloud~md~obsidian", "Documents"),
+	"Obsidian",
+}
+
+// DetectVaults returns the vaults found in the usual locations under the home
+// directory, recognized by their .obsidian folder.
+func DetectVaults() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	var vaults []string
+	for _, location := range vaultLocations {
+		dir := filepath.Join(home, location)
+		if isVault(dir) {
+			vaults = append(vaults, dir)
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			sub := filepath.Join(dir, entry.Name())
+			if entry.IsDir() && isVault(sub) {
+				vaults = append(vaults, sub)
+			}
+		}
+	}
+	return vaults
+}
+
+func isVault(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, ".obsidian"))
+	return err == nil && info.IsDir()
+}
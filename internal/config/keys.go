@@ -0,0 +1,353 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// reindexKeys are the settings that change what is stored for each chunk,
+// so the index has to be rebuilt after they change.
+var reindexKeys = []string{"embed_model", "embed_dim", "quantization", "encrypt", "vector_store"}
+
+// RequiresReindex reports whether changing key leaves the existing index
+// unusable until it is rebuilt.
+func RequiresReindex(key string) bool {
+	top, _, _ := strings.Cut(key, ".")
+	return slices.Contains(reindexKeys, top)
+}
+
+// Keys lists the settings Get and Set accept, as their config.json names.
+// Nested settings are joined with dots, and each vault, keybinding, webhook
+// or client token is vaults.<name>, keybindings.<name>, webhooks.<name> or
+// tokens.<name>.
+func Keys() []string {
+	var keys []string
+	for _, f := range jsonFields(reflect.TypeOf(Config{})) {
+		switch f.Type.Kind() {
+		case reflect.Pointer:
+			for _, sub := range jsonFields(f.Type.Elem()) {
+				keys = append(keys, jsonName(f)+"."+jsonName(sub))
+			}
+		case reflect.Map:
+			keys = append(keys, jsonName(f)+".<name>")
+		default:
+			keys = append(keys, jsonName(f))
+		}
+	}
+	return keys
+}
+
+// Get returns the value of key, one of Keys. Vaults and the vector store
+// may also be read whole, as JSON.
+func (c *Config) Get(key string) (string, error) {
+	top, sub, nested := strings.Cut(key, ".")
+	v, f, err := c.field(top)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case top == "vaults" && nested:
+		return c.vaultSetting(key, sub)
+	case f.Type.Kind() == reflect.Map && nested:
+		if value := v.MapIndex(reflect.ValueOf(sub)); value.IsValid() {
+			return value.String(), nil
+		}
+		return "", nil
+	case f.Type.Kind() == reflect.Pointer && nested:
+		if v.IsNil() {
+			return "", nil
+		}
+		v, _, err = structField(v.Elem(), key, sub)
+		if err != nil {
+			return "", err
+		}
+	case f.Type.Kind() == reflect.Pointer || f.Type.Kind() == reflect.Map:
+		data, err := json.Marshal(v.Interface())
+		return string(data), err
+	case nested:
+		return "", fmt.Errorf("unknown setting %q", key)
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
+
+// Set parses value for key, one of Keys, checks it and stores it in c. An
+// empty value resets the setting to its default, or removes a vault.
+func (c *Config) Set(key, value string) error {
+	top, sub, nested := strings.Cut(key, ".")
+	v, f, err := c.field(top)
+	if err != nil {
+		return err
+	}
+
+	switch f.Type.Kind() {
+	case reflect.Map:
+		switch {
+		case !nested:
+			return fmt.Errorf("set one entry at a time with %s.<name>", top)
+		case top == "keybindings":
+			c.setKeybinding(sub, value)
+			return nil
+		case top == "webhooks":
+			return c.setWebhook(sub, value)
+		case top == "tokens":
+			if value != "" {
+				return fmt.Errorf("create tokens with ofind token create %s", sub)
+			}
+			return c.RevokeToken(sub)
+		}
+		return c.setVault(sub, value)
+	case reflect.Pointer:
+		if !nested {
+			return fmt.Errorf("set %s one field at a time, e.g. %s.url", top, top)
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(f.Type.Elem()))
+		}
+		field, _, err := structField(v.Elem(), key, sub)
+		if err != nil {
+			return err
+		}
+		if err := setValue(field, key, value); err != nil {
+			return err
+		}
+		if v.Elem().IsZero() {
+			v.SetZero()
+		}
+		return c.validate(key)
+	}
+
+	if nested {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	if err := setValue(v, key, value); err != nil {
+		return err
+	}
+	if key == "obsidian_dir" && value != "" {
+		if c.ObsidianDir, err = checkDir(value); err != nil {
+			return err
+		}
+	}
+	c.ApplyDefaults()
+	return c.validate(key)
+}
+
+// RemoveVault forgets the named vault, and makes obsidian_dir the default
+// again if it was the default.
+func (c *Config) RemoveVault(name string) {
+	delete(c.Vaults, name)
+	if c.DefaultVault == name {
+		c.DefaultVault = ""
+	}
+}
+
+// vaultSetting returns vaults.<name>, the vault's directory, or one of its
+// settings as vaults.<name>.<setting>.
+func (c *Config) vaultSetting(key, sub string) (string, error) {
+	name, setting, _ := strings.Cut(sub, ".")
+	p := c.Vaults[name]
+	if setting == "" {
+		return p.Dir, nil
+	}
+	v, _, err := structField(reflect.ValueOf(&p).Elem(), key, setting)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
+
+// setVault sets vaults.<name> to a directory, adding the vault, or removes
+// it when dir is empty. vaults.<name>.<setting> sets one of an existing
+// vault's settings.
+func (c *Config) setVault(sub, value string) error {
+	name, setting, nested := strings.Cut(sub, ".")
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return fmt.Errorf("invalid vault name %q", name)
+	}
+	p, ok := c.Vaults[name]
+
+	switch {
+	case !nested && value == "":
+		c.RemoveVault(name)
+		return nil
+	case !nested || setting == "dir":
+		if value == "" {
+			return fmt.Errorf("vaults.%s needs a directory; remove the vault with vaults.%s", sub, name)
+		}
+		dir, err := checkDir(value)
+		if err != nil {
+			return err
+		}
+		p.Dir = dir
+	case !ok:
+		return fmt.Errorf("unknown vault %q; add it with ofind config set vaults.%s <dir>", name, name)
+	default:
+		v, _, err := structField(reflect.ValueOf(&p).Elem(), "vaults."+sub, setting)
+		if err != nil {
+			return err
+		}
+		if err := setValue(v, "vaults."+sub, value); err != nil {
+			return err
+		}
+		if p.EmbedDim < 0 {
+			return fmt.Errorf("vaults.%s must be positive", sub)
+		}
+	}
+
+	if c.Vaults == nil {
+		c.Vaults = make(map[string]Profile)
+	}
+	c.Vaults[name] = p
+	return nil
+}
+
+func (c *Config) setKeybinding(action, keys string) {
+	if strings.TrimSpace(keys) == "" {
+		delete(c.Keybindings, action)
+		return
+	}
+	if c.Keybindings == nil {
+		c.Keybindings = make(map[string]string)
+	}
+	c.Keybindings[action] = keys
+}
+
+func (c *Config) setWebhook(name, rawURL string) error {
+	if rawURL == "" {
+		delete(c.Webhooks, name)
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhooks.%s must be an http or https URL", name)
+	}
+	if c.Webhooks == nil {
+		c.Webhooks = make(map[string]string)
+	}
+	c.Webhooks[name] = rawURL
+	return nil
+}
+
+// validate checks the settings that take more than a well-formed value.
+func (c *Config) validate(key string) error {
+	switch key {
+	case "embed_dim":
+		if c.EmbedDim <= 0 {
+			return fmt.Errorf("embed_dim must be positive")
+		}
+	case "mmr_lambda":
+		if c.MMRLambda < 0 || c.MMRLambda > 1 {
+			return fmt.Errorf("mmr_lambda must be between 0 and 1")
+		}
+	case "default_vault":
+		if _, ok := c.Vaults[c.DefaultVault]; !ok && c.DefaultVault != "" && c.DefaultVault != DefaultVaultName {
+			return fmt.Errorf("default_vault must name a vault in vaults")
+		}
+	case "chat_memory_dir":
+		if c.ChatMemoryDir != "" && !filepath.IsLocal(filepath.FromSlash(c.ChatMemoryDir)) {
+			return fmt.Errorf("chat_memory_dir must be a folder inside the vault")
+		}
+	case "bookmark_boost":
+		if c.BookmarkBoost < 0 {
+			return fmt.Errorf("bookmark_boost can't be negative")
+		}
+	case "watch_debounce":
+		_, err := c.WatchDebounceDuration()
+		return err
+	case "watch_batch_window":
+		_, err := c.WatchBatchWindowDuration()
+		return err
+	case "vector_store.type":
+		if c.VectorStore != nil && c.VectorStore.Type != "" && c.VectorStore.Type != "qdrant" && c.VectorStore.Type != "pgvector" {
+			return fmt.Errorf("vector_store.type must be qdrant or pgvector")
+		}
+	}
+	return nil
+}
+
+// field returns the top-level setting named key.
+func (c *Config) field(key string) (reflect.Value, reflect.StructField, error) {
+	return structField(reflect.ValueOf(c).Elem(), key, key)
+}
+
+func structField(v reflect.Value, key, name string) (reflect.Value, reflect.StructField, error) {
+	for _, f := range jsonFields(v.Type()) {
+		if jsonName(f) == name {
+			return v.FieldByIndex(f.Index), f, nil
+		}
+	}
+	return reflect.Value{}, reflect.StructField{}, fmt.Errorf("unknown setting %q", key)
+}
+
+func setValue(v reflect.Value, key, value string) error {
+	if value == "" {
+		v.SetZero()
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number", key)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", key)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false", key)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("%s can't be set from the command line", key)
+	}
+	return nil
+}
+
+// checkDir returns the absolute form of dir after making sure it is a
+// directory.
+func checkDir(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return dir, nil
+}
+
+// jsonFields returns the fields of t that are saved in config.json.
+func jsonFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := range t.NumField() {
+		if f := t.Field(i); f.IsExported() && jsonName(f) != "-" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// legacyEntries are what earlier versions kept in ~/.config/obsvec besides
+// config.json, all of which now belong in DataDir.
+var legacyEntries = []string{"obsvec.db", "vaults", "history", "obsvec.log", "daemon.log"}
+
+// sqliteSuffixes name the files SQLite keeps next to a database while it is
+// open. They only make sense with their own database, so they move with it.
+var sqliteSuffixes = []string{"-wal", "-shm", "-journal"}
+
+// legacyDir is where earlier versions kept both config and data.
+func legacyDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "obsvec"), nil
+}
+
+// unmigrated returns the legacy directory instead of dir while the legacy
+// one still holds any of names and dir holds none of them, so a failed or
+// postponed Migrate leaves everything where it was.
+func unmigrated(dir string, names ...string) string {
+	legacy, err := legacyDir()
+	if err != nil || legacy == dir {
+		return dir
+	}
+	exists := func(dir string) bool {
+		return slices.ContainsFunc(names, func(name string) bool {
+			_, err := os.Lstat(filepath.Join(dir, name))
+			return err == nil
+		})
+	}
+	if !exists(dir) && exists(legacy) {
+		return legacy
+	}
+	return dir
+}
+
+// Migrate moves the config and data that earlier versions kept together in
+// ~/.config/obsvec to ConfigDir and DataDir. Anything already at the new
+// location is left alone, so it is safe to run every time. Indexes aren't
+// moved while a daemon is serving them from the old location; until they
+// are, DataDir keeps pointing there.
+func Migrate() error {
+	legacy, err := legacyDir()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+
+	configDir, err := configDir()
+	if err != nil {
+		return err
+	}
+	dataDir, err := dataDir()
+	if err != nil {
+		return err
+	}
+
+	if configDir != legacy {
+		if err := migrateEntry(legacy, configDir, "config.json"); err != nil {
+			return err
+		}
+	}
+	if dataDir != legacy {
+		if daemonRunning(legacy) {
+			return fmt.Errorf("a daemon is serving the index from %s; stop it and run ofind again to move the index to %s", legacy, dataDir)
+		}
+		for _, name := range legacyEntries {
+			if err := migrateEntry(legacy, dataDir, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Only removed once nothing is left in it
+	if configDir != legacy && dataDir != legacy {
+		_ = os.Remove(legacy)
+	}
+	return nil
+}
+
+// daemonRunning reports whether a daemon answers on any socket in dir, the
+// default vault's or a named vault's.
+func daemonRunning(dir string) bool {
+	sockets, _ := filepath.Glob(filepath.Join(dir, "vaults", "*.sock"))
+	sockets = append(sockets, filepath.Join(dir, "obsvec.sock"))
+	for _, socket := range sockets {
+		if _, err := os.Lstat(socket); err != nil {
+			continue
+		}
+		conn, err := net.DialTimeout("unix", socket, time.Second)
+		if err == nil {
+			_ = conn.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// migrateEntry moves name from one directory to the other unless it is
+// already there. A database moves together with its -wal and -shm files,
+// and a directory that already exists at the destination is merged entry
+// by entry.
+func migrateEntry(from, to, name string) error {
+	src := filepath.Join(from, name)
+	dst := filepath.Join(to, name)
+	info, err := os.Lstat(src)
+	if err != nil {
+		return nil
+	}
+
+	if _, err := os.Lstat(dst); err == nil {
+		if !info.IsDir() {
+			return nil
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if isSQLiteSidecar(entry.Name()) {
+				continue
+			}
+			if err := migrateEntry(src, dst, entry.Name()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	names := []string{name}
+	if strings.HasSuffix(name, ".db") {
+		for _, suffix := range sqliteSuffixes {
+			if _, err := os.Lstat(src + suffix); err == nil {
+				names = append(names, name+suffix)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(to, 0700); err != nil {
+		return err
+	}
+	if err := moveAll(from, to, names); err != nil {
+		return fmt.Errorf("failed to move %s to %s: %w", src, to, err)
+	}
+	return nil
+}
+
+// isSQLiteSidecar reports whether name is a file SQLite keeps next to a
+// database, which migrateEntry moves with the database itself.
+func isSQLiteSidecar(name string) bool {
+	return slices.ContainsFunc(sqliteSuffixes, func(suffix string) bool {
+		return strings.HasSuffix(name, ".db"+suffix)
+	})
+}
+
+// moveAll moves the named entries from one directory to the other, copying
+// them when the two are on different filesystems. Either all of them move
+// or, as far as possible, none do.
+func moveAll(from, to string, names []string) error {
+	var moved []string
+	for _, name := range names {
+		if err := os.Rename(filepath.Join(from, name), filepath.Join(to, name)); err != nil {
+			break
+		}
+		moved = append(moved, name)
+	}
+	if len(moved) == len(names) {
+		return nil
+	}
+
+	// Put back what was renamed, then copy the whole group instead. If
+	// something can't be put back, the group stays split rather than risk
+	// the copy touching the only remaining file.
+	for _, name := range moved {
+		if err := os.Rename(filepath.Join(to, name), filepath.Join(from, name)); err != nil {
+			return err
+		}
+	}
+
+	// Only what this copy created is removed when it fails
+	var created []string
+	undo := func(err error) error {
+		errs := []error{err}
+		for _, name := range created {
+			errs = append(errs, os.RemoveAll(filepath.Join(to, name)))
+		}
+		return errors.Join(errs...)
+	}
+	for _, name := range names {
+		dst := filepath.Join(to, name)
+		if _, err := os.Lstat(dst); !os.IsNotExist(err) {
+			return undo(fmt.Errorf("%s already exists", dst))
+		}
+		created = append(created, name)
+		if err := copyEntry(filepath.Join(from, name), dst); err != nil {
+			return undo(err)
+		}
+	}
+	var errs []error
+	for _, name := range names {
+		errs = append(errs, os.RemoveAll(filepath.Join(from, name)))
+	}
+	return errors.Join(errs...)
+}
+
+// copyEntry copies a file, or a directory with everything in it. Sockets
+// and other special files are left behind.
+func copyEntry(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.IsDir():
+		if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyEntry(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	case !info.Mode().IsRegular():
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() //nolint:errcheck
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// DefaultVaultName selects the top-level obsidian_dir and settings with
+// -vault, for when default_vault names another vault.
+const DefaultVaultName = "default"
+
+// Profile is a named vault: its directory and, optionally, its own Cohere
+// key, models and excluded paths. Unset fields fall back to the top-level
+// settings. A profile with only a directory is saved as the directory alone,
+// the form vaults had before they took settings.
+type Profile struct {
+	Dir          string   `json:"dir"`
+	CohereAPIKey string   `json:"cohere_api_key,omitempty"`
+	EmbedModel   string   `json:"embed_model,omitempty"`
+	RerankModel  string   `json:"rerank_model,omitempty"`
+	ChatModel    string   `json:"chat_model,omitempty"`
+	EmbedDim     int      `json:"embed_dim,omitempty"`
+	ExcludePaths []string `json:"exclude_paths,omitempty"`
+}
+
+// profileJSON is Profile without its JSON methods.
+type profileJSON Profile
+
+func (p *Profile) UnmarshalJSON(data []byte) error {
+	var dir string
+	if err := json.Unmarshal(data, &dir); err == nil {
+		*p = Profile{Dir: dir}
+		return nil
+	}
+	return json.Unmarshal(data, (*profileJSON)(p))
+}
+
+func (p Profile) MarshalJSON() ([]byte, error) {
+	if len(p.Settings()) == 0 {
+		return json.Marshal(p.Dir)
+	}
+	return json.Marshal(profileJSON(p))
+}
+
+// Settings returns the profile's settings besides its directory that are
+// set, by their config.json names.
+func (p Profile) Settings() map[string]string {
+	settings := make(map[string]string)
+	v := reflect.ValueOf(p)
+	for _, f := range jsonFields(v.Type()) {
+		if name := jsonName(f); name != "dir" && !v.FieldByIndex(f.Index).IsZero() {
+			settings[name] = fmt.Sprint(v.FieldByIndex(f.Index).Interface())
+		}
+	}
+	return settings
+}
+
+// profile returns the top-level settings a profile can override.
+func (c *Config) profile() Profile {
+	return Profile{
+		Dir:          c.ObsidianDir,
+		CohereAPIKey: c.CohereAPIKey,
+		EmbedModel:   c.EmbedModel,
+		RerankModel:  c.RerankModel,
+		ChatModel:    c.ChatModel,
+		EmbedDim:     c.EmbedDim,
+		ExcludePaths: c.ExcludePaths,
+	}
+}
+
+// setProfile replaces the top-level settings with p's. With fallback, p's
+// unset fields keep the current values.
+func (c *Config) setProfile(p Profile, fallback bool) {
+	pick := func(value, current string) string {
+		if fallback && value == "" {
+			return current
+		}
+		return value
+	}
+	c.ObsidianDir = pick(p.Dir, c.ObsidianDir)
+	c.CohereAPIKey = pick(p.CohereAPIKey, c.CohereAPIKey)
+	c.EmbedModel = pick(p.EmbedModel, c.EmbedModel)
+	c.RerankModel = pick(p.RerankModel, c.RerankModel)
+	c.ChatModel = pick(p.ChatModel, c.ChatModel)
+	if !fallback || p.EmbedDim != 0 {
+		c.EmbedDim = p.EmbedDim
+	}
+	if !fallback || len(p.ExcludePaths) > 0 {
+		c.ExcludePaths = p.ExcludePaths
+	}
+}
+
+// UseVault switches this run to the named vault from Vaults, taking its
+// settings in place of the top-level ones. DefaultVaultName keeps the
+// top-level settings unless a vault has that name.
+func (c *Config) UseVault(name string) error {
+	p, ok := c.Vaults[name]
+	if !ok && name == DefaultVaultName {
+		return nil
+	}
+	if !ok {
+		names := slices.Sorted(maps.Keys(c.Vaults))
+		if len(names) == 0 {
+			return fmt.Errorf("unknown vault %q: no vaults are configured in config.json", name)
+		}
+		return fmt.Errorf("unknown vault %q (configured: %s)", name, strings.Join(names, ", "))
+	}
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return fmt.Errorf("invalid vault name %q", name)
+	}
+
+	if c.base == nil {
+		base := c.profile()
+		c.base = &base
+	} else {
+		c.setProfile(*c.base, false)
+	}
+	c.Vault = name
+	c.setProfile(p, true)
+	return nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/mgomes/obsvec/internal/keychain"
+)
+
+// APIKeyAccount is the keychain entry holding cohere_api_key when
+// keychain_api_key is set. A vault's own key is kept under
+// VaultAPIKeyAccount instead.
+const APIKeyAccount = "cohere-api-key"
+
+// VaultAPIKeyAccount is the keychain entry holding the named vault's own
+// cohere_api_key when keychain_api_key is set.
+func VaultAPIKeyAccount(vault string) string {
+	return APIKeyAccount + "/" + vault
+}
+
+// The credential store the API key is kept in; tests replace these.
+var (
+	keychainGet    = keychain.Get
+	keychainSet    = keychain.Set
+	keychainDelete = keychain.Delete
+)
+
+// loadAPIKey reads the API keys, the top-level one and each vault's, from the
+// keychain when the config keeps them there. A missing entry leaves the key
+// empty: setup asks for the top-level one again, and a vault falls back to it.
+func (c *Config) loadAPIKey() error {
+	if !c.KeychainAPIKey {
+		return nil
+	}
+	c.keychainKeys = make(map[string]string)
+
+	get := func(account, key string) (string, error) {
+		if key != "" {
+			return key, nil
+		}
+		key, err := keychainGet(account)
+		if errors.Is(err, keychain.ErrNotFound) {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read the API key from the keychain: %w", err)
+		}
+		c.keychainKeys[account] = key
+		return key, nil
+	}
+
+	key, err := get(APIKeyAccount, c.CohereAPIKey)
+	if err != nil {
+		return err
+	}
+	c.CohereAPIKey = key
+	for name, p := range c.Vaults {
+		if p.CohereAPIKey, err = get(VaultAPIKeyAccount(name), p.CohereAPIKey); err != nil {
+			return err
+		}
+		c.Vaults[name] = p
+	}
+	return nil
+}
+
+// storeAPIKey moves the API keys of saved into the keychain when the config
+// keeps them there, returning the config to write without them. Entries of
+// keys that were cleared, or of vaults that were removed, are deleted.
+func (c *Config) storeAPIKey(saved *Config) (*Config, error) {
+	if !saved.KeychainAPIKey {
+		return saved, nil
+	}
+
+	stripped := *saved
+	stripped.CohereAPIKey = ""
+	stripped.Vaults = maps.Clone(saved.Vaults)
+	keys := make(map[string]string)
+	if saved.CohereAPIKey != "" {
+		keys[APIKeyAccount] = saved.CohereAPIKey
+	}
+	for name, p := range saved.Vaults {
+		if p.CohereAPIKey != "" {
+			keys[VaultAPIKeyAccount(name)] = p.CohereAPIKey
+		}
+		p.CohereAPIKey = ""
+		stripped.Vaults[name] = p
+	}
+
+	for _, account := range slices.Sorted(maps.Keys(keys)) {
+		if keys[account] == c.keychainKeys[account] {
+			continue
+		}
+		if err := keychainSet(account, keys[account]); err != nil {
+			return nil, fmt.Errorf("failed to store the API key in the keychain: %w", err)
+		}
+	}
+	for _, account := range slices.Sorted(maps.Keys(c.keychainKeys)) {
+		if _, ok := keys[account]; ok {
+			continue
+		}
+		if err := keychainDelete(account); err != nil && !errors.Is(err, keychain.ErrNotFound) {
+			return nil, fmt.Errorf("failed to delete the API key from the keychain: %w", err)
+		}
+	}
+	c.keychainKeys = keys
+	return &stripped, nil
+}
+
+// forgetAPIKey removes the API keys from the keychain once the config no
+// longer keeps them there and they have been written back to the file.
+func (c *Config) forgetAPIKey() error {
+	if c.KeychainAPIKey {
+		return nil
+	}
+	for _, account := range slices.Sorted(maps.Keys(c.keychainKeys)) {
+		if err := keychainDelete(account); err != nil && !errors.Is(err, keychain.ErrNotFound) {
+			return fmt.Errorf("failed to delete the API key from the keychain: %w", err)
+		}
+		delete(c.keychainKeys, account)
+	}
+	return nil
+}
+
+// DeleteAPIKeys removes the top-level API key and every vault's from the
+// keychain, for purging everything.
+func (c *Config) DeleteAPIKeys() error {
+	accounts := []string{APIKeyAccount}
+	for name := range c.Vaults {
+		accounts = append(accounts, VaultAPIKeyAccount(name))
+	}
+	for _, account := range accounts {
+		if err := keychainDelete(account); err != nil && !errors.Is(err, keychain.ErrNotFound) {
+			return fmt.Errorf("failed to delete the API key from the keychain: %w", err)
+		}
+	}
+	c.keychainKeys = nil
+	return nil
+}
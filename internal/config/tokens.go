@@ -0,0 +1,43 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+// tokenName is what a client token may be named: a word, dashes and dots
+// allowed, such as "phone" or "obsidian-plugin".
+var tokenName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// HashToken is how client tokens are kept in Tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AddToken keeps the hash of token as the client token of name, which must
+// not be taken.
+func (c *Config) AddToken(name, token string) error {
+	if !tokenName.MatchString(name) {
+		return fmt.Errorf("invalid token name %q: use letters, digits, dashes and dots", name)
+	}
+	if _, ok := c.Tokens[name]; ok {
+		return fmt.Errorf("a token named %s already exists; revoke it first", name)
+	}
+	if c.Tokens == nil {
+		c.Tokens = make(map[string]string)
+	}
+	c.Tokens[name] = HashToken(token)
+	return nil
+}
+
+// RevokeToken removes the client token of name.
+func (c *Config) RevokeToken(name string) error {
+	if _, ok := c.Tokens[name]; !ok {
+		return fmt.Errorf("no token named %s", name)
+	}
+	delete(c.Tokens, name)
+	return nil
+}
@@ -0,0 +1,243 @@
+// Package embedfile writes chunk embeddings to files that notebooks read
+// directly, Parquet for pandas, Polars or DuckDB and NumPy's .npz, and reads
+// embeddings computed elsewhere from Parquet or JSON lines.
+package embedfile
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Formats are the file formats Write supports.
+var Formats = []string{"parquet", "npz"}
+
+// ReadFormats are the file formats Read supports.
+var ReadFormats = []string{"parquet", "jsonl"}
+
+// Write writes chunks to w in the named format.
+func Write(w io.Writer, format string, chunks []db.ChunkEmbedding) error {
+	switch format {
+	case "parquet":
+		return WriteParquet(w, chunks)
+	case "npz":
+		return WriteNPZ(w, chunks)
+	}
+	return fmt.Errorf("unknown embeddings format %q (want parquet or npz)", format)
+}
+
+// parquetRow is one chunk in a Parquet file, the embedding a list of
+// float32.
+type parquetRow struct {
+	ChunkID   int64     `parquet:"chunk_id"`
+	Path      string    `parquet:"path,dict"`
+	Heading   string    `parquet:"heading"`
+	StartLine int64     `parquet:"start_line"`
+	EndLine   int64     `parquet:"end_line"`
+	Hash      string    `parquet:"hash"`
+	Embedding []float32 `parquet:"embedding,list"`
+}
+
+// WriteParquet writes chunks as a Parquet file with one row per chunk.
+func WriteParquet(w io.Writer, chunks []db.ChunkEmbedding) error {
+	pw := parquet.NewGenericWriter[parquetRow](w, parquet.Compression(&parquet.Zstd))
+	rows := make([]parquetRow, len(chunks))
+	for i, c := range chunks {
+		rows[i] = parquetRow{
+			ChunkID:   c.ChunkID,
+			Path:      c.Path,
+			Heading:   c.Heading,
+			StartLine: int64(c.StartLine),
+			EndLine:   int64(c.EndLine),
+			Hash:      c.Hash,
+			Embedding: c.Embedding,
+		}
+	}
+	if _, err := pw.Write(rows); err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// WriteNPZ writes chunks as a NumPy .npz archive of parallel arrays:
+// embedding, an n×dim float32 matrix, and chunk_id, path, heading,
+// start_line, end_line and hash, one entry per row of it. Strings are
+// fixed-width unicode arrays, so np.load needs no allow_pickle.
+func WriteNPZ(w io.Writer, chunks []db.ChunkEmbedding) error {
+	dim := 0
+	if len(chunks) > 0 {
+		dim = len(chunks[0].Embedding)
+	}
+	for _, c := range chunks {
+		if len(c.Embedding) != dim {
+			return fmt.Errorf("chunk %d has %d dimensions, expected %d", c.ChunkID, len(c.Embedding), dim)
+		}
+	}
+
+	column := func(get func(db.ChunkEmbedding) string) []string {
+		values := make([]string, len(chunks))
+		for i, c := range chunks {
+			values[i] = get(c)
+		}
+		return values
+	}
+	ints := func(get func(db.ChunkEmbedding) int64) []int64 {
+		values := make([]int64, len(chunks))
+		for i, c := range chunks {
+			values[i] = get(c)
+		}
+		return values
+	}
+
+	arrays := []struct {
+		name string
+		data []byte
+	}{
+		{"embedding", npyFloat32(chunks, dim)},
+		{"chunk_id", npyInt64(ints(func(c db.ChunkEmbedding) int64 { return c.ChunkID }))},
+		{"path", npyStrings(column(func(c db.ChunkEmbedding) string { return c.Path }))},
+		{"heading", npyStrings(column(func(c db.ChunkEmbedding) string { return c.Heading }))},
+		{"start_line", npyInt64(ints(func(c db.ChunkEmbedding) int64 { return int64(c.StartLine) }))},
+		{"end_line", npyInt64(ints(func(c db.ChunkEmbedding) int64 { return int64(c.EndLine) }))},
+		{"hash", npyStrings(column(func(c db.ChunkEmbedding) string { return c.Hash }))},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, a := range arrays {
+		f, err := zw.Create(a.name + ".npy")
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(a.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// npyHeader starts a .npy file (format version 1.0) holding an array of
+// descr with the given shape, padded so the data is 64-byte aligned.
+func npyHeader(descr, shape string) []byte {
+	dict := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': %s, }", descr, shape)
+	// Magic, version and length take 10 bytes; the dict ends in a newline
+	padded := (10 + len(dict) + 1 + 63) / 64 * 64
+	dict += string(bytes.Repeat([]byte(" "), padded-10-len(dict)-1)) + "\n"
+
+	var b bytes.Buffer
+	b.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(&b, binary.LittleEndian, uint16(len(dict))) //nolint:errcheck
+	b.WriteString(dict)
+	return b.Bytes()
+}
+
+func npyFloat32(chunks []db.ChunkEmbedding, dim int) []byte {
+	b := npyHeader("<f4", fmt.Sprintf("(%d, %d)", len(chunks), dim))
+	for _, c := range chunks {
+		for _, x := range c.Embedding {
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(x))
+		}
+	}
+	return b
+}
+
+func npyInt64(values []int64) []byte {
+	b := npyHeader("<i8", fmt.Sprintf("(%d,)", len(values)))
+	for _, v := range values {
+		b = binary.LittleEndian.AppendUint64(b, uint64(v))
+	}
+	return b
+}
+
+// npyStrings encodes values as NumPy's fixed-width unicode, UTF-32 padded
+// with zeros to the longest.
+func npyStrings(values []string) []byte {
+	width := 1
+	for _, v := range values {
+		width = max(width, utf8.RuneCountInString(v))
+	}
+
+	b := npyHeader(fmt.Sprintf("<U%d", width), fmt.Sprintf("(%d,)", len(values)))
+	for _, v := range values {
+		runes := []rune(v)
+		for _, r := range runes {
+			b = binary.LittleEndian.AppendUint32(b, uint32(r))
+		}
+		b = append(b, make([]byte, 4*(width-len(runes)))...)
+	}
+	return b
+}
+
+// FormatFromPath guesses the format from a file name's extension, returning
+// "" when it is none of formats.
+func FormatFromPath(path string, formats []string) string {
+	format := strings.TrimPrefix(filepath.Ext(path), ".")
+	if !slices.Contains(formats, format) {
+		return ""
+	}
+	return format
+}
+
+// hashVector is an embedding of the chunk text with SHA-256 Hash, in hex,
+// as db.ChunkHash computes it. It is the line of a JSON lines file, and
+// the columns read from a Parquet one, such as those WriteParquet writes.
+type hashVector struct {
+	Hash      string    `json:"hash" parquet:"hash"`
+	Embedding []float32 `json:"embedding" parquet:"embedding,list"`
+}
+
+// Read returns the embeddings in the file by chunk hash, in the named
+// format.
+func Read(f *os.File, format string) (map[string][]float32, error) {
+	var rows []hashVector
+	switch format {
+	case "parquet":
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if rows, err = parquet.Read[hashVector](f, info.Size()); err != nil {
+			return nil, err
+		}
+	case "jsonl":
+		dec := json.NewDecoder(bufio.NewReader(f))
+		for line := 1; ; line++ {
+			var row hashVector
+			err := dec.Decode(&row)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rows = append(rows, row)
+		}
+	default:
+		return nil, fmt.Errorf("unknown embeddings format %q (want parquet or jsonl)", format)
+	}
+
+	vectors := make(map[string][]float32, len(rows))
+	for i, row := range rows {
+		hash := strings.ToLower(row.Hash)
+		if len(hash) != 64 || strings.Trim(hash, "0123456789abcdef") != "" {
+			return nil, fmt.Errorf("row %d: hash %q is not a SHA-256 in hex", i+1, row.Hash)
+		}
+		if len(row.Embedding) == 0 {
+			return nil, fmt.Errorf("row %d: no embedding", i+1)
+		}
+		vectors[hash] = row.Embedding
+	}
+	return vectors, nil
+}
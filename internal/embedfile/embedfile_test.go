@@ -0,0 +1,145 @@
+package embedfile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+	"github.com/parquet-go/parquet-go"
+)
+
+var testChunks = []db.ChunkEmbedding{
+	{ChunkID: 7, Path: "Health/Sleep.md", Heading: "Sleep > Routine", StartLine: 3, EndLine: 9, Hash: "ab", Embedding: []float32{0.5, -1, 2}},
+	{ChunkID: 9, Path: "Café.md", StartLine: 1, EndLine: 2, Hash: "cd", Embedding: []float32{1, 0, 0}},
+}
+
+func TestWriteParquet(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, testChunks); err != nil {
+		t.Fatalf("WriteParquet failed: %v", err)
+	}
+
+	rows, err := parquet.Read[parquetRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read the file back: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if rows[0].ChunkID != 7 || rows[0].Path != "Health/Sleep.md" || rows[0].Heading != "Sleep > Routine" || rows[0].EndLine != 9 {
+		t.Errorf("unexpected first row %+v", rows[0])
+	}
+	if got := rows[0].Embedding; len(got) != 3 || got[1] != -1 {
+		t.Errorf("expected the embedding back, got %v", got)
+	}
+}
+
+func TestWriteNPZ(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteNPZ(&buf, testChunks); err != nil {
+		t.Fatalf("WriteNPZ failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+	arrays := make(map[string][]byte)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		arrays[f.Name], _ = io.ReadAll(r)
+	}
+
+	header, data := splitNPY(t, arrays["embedding.npy"])
+	if !strings.Contains(header, "'descr': '<f4'") || !strings.Contains(header, "'shape': (2, 3)") {
+		t.Errorf("unexpected embedding header %q", header)
+	}
+	if x := math.Float32frombits(binary.LittleEndian.Uint32(data[4:])); x != -1 {
+		t.Errorf("expected -1 as the second value, got %v", x)
+	}
+
+	header, data = splitNPY(t, arrays["path.npy"])
+	if !strings.Contains(header, "'descr': '<U15'") || !strings.Contains(header, "'shape': (2,)") {
+		t.Errorf("unexpected path header %q", header)
+	}
+	if r := rune(binary.LittleEndian.Uint32(data[15*4+3*4:])); r != 'é' {
+		t.Errorf("expected é in the second path, got %q", r)
+	}
+
+	header, data = splitNPY(t, arrays["chunk_id.npy"])
+	if !strings.Contains(header, "'descr': '<i8'") || binary.LittleEndian.Uint64(data[8:]) != 9 {
+		t.Errorf("unexpected chunk ids %q %v", header, data)
+	}
+}
+
+// splitNPY checks a .npy file's preamble, returning its header and data.
+func splitNPY(t *testing.T, b []byte) (string, []byte) {
+	t.Helper()
+	if len(b) < 10 || string(b[:8]) != "\x93NUMPY\x01\x00" {
+		t.Fatalf("not a .npy file: %q", b)
+	}
+	n := 10 + int(binary.LittleEndian.Uint16(b[8:]))
+	if n%64 != 0 {
+		t.Errorf("expected the data 64-byte aligned, starts at %d", n)
+	}
+	return string(b[10:n]), b[n:]
+}
+
+func TestFormatFromPath(t *testing.T) {
+	for path, want := range map[string]string{"v.parquet": "parquet", "out/v.npz": "npz", "v.npy": "", "parquet": ""} {
+		if got := FormatFromPath(path, Formats); got != want {
+			t.Errorf("FormatFromPath(%q) = %q, expected %q", path, got, want)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	hash := db.ChunkHash("text")
+	chunks := []db.ChunkEmbedding{{ChunkID: 1, Path: "a.md", Hash: hash, Embedding: []float32{1, 2}}}
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, chunks); err != nil {
+		t.Fatalf("WriteParquet failed: %v", err)
+	}
+	files := map[string]string{
+		"parquet": buf.String(),
+		"jsonl":   `{"hash": "` + strings.ToUpper(hash) + `", "embedding": [1, 2]}` + "\n",
+	}
+	for format, data := range files {
+		path := filepath.Join(dir, "v."+format)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", path, err)
+		}
+		vectors, err := Read(f, format)
+		f.Close()
+		if err != nil {
+			t.Fatalf("Read %s failed: %v", format, err)
+		}
+		if got := vectors[hash]; len(got) != 2 || got[1] != 2 {
+			t.Errorf("%s: expected the vector by hash, got %v", format, vectors)
+		}
+	}
+
+	path := filepath.Join(dir, "bad.jsonl")
+	os.WriteFile(path, []byte(`{"hash": "abc", "embedding": [1]}`), 0644)
+	f, _ := os.Open(path)
+	defer f.Close()
+	if _, err := Read(f, "jsonl"); err == nil || !strings.Contains(err.Error(), "SHA-256") {
+		t.Errorf("expected a bad hash to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Package eval measures retrieval quality against a set of queries with known
+// relevant notes.
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+	"gopkg.in/yaml.v3"
+)
+
+// Case is a query and the notes a good search should return for it.
+type Case struct {
+	Query    string   `json:"query"`
+	Expected []string `json:"expected"`
+}
+
+// CaseResult is how one case fared. Rank is the position of the first
+// expected note among the distinct notes returned, or 0 if none was found.
+type CaseResult struct {
+	Query  string   `json:"query"`
+	Rank   int      `json:"rank"`
+	Found  int      `json:"found"`
+	Missed []string `json:"missed,omitempty"`
+}
+
+type Report struct {
+	K      int          `json:"k"`
+	Recall float64      `json:"recall"`
+	MRR    float64      `json:"mrr"`
+	Cases  []CaseResult `json:"cases"`
+}
+
+// SearchFunc runs one query through the pipeline under test.
+type SearchFunc func(ctx context.Context, query string) ([]search.Result, error)
+
+// Run searches every case and reports recall@k, the mean fraction of
+// expected notes found, and MRR over the first expected note found.
+func Run(ctx context.Context, cases []Case, k int, searchFn SearchFunc) (*Report, error) {
+	report := &Report{K: k}
+	for _, c := range cases {
+		results, err := searchFn(ctx, c.Query)
+		if err != nil {
+			return nil, fmt.Errorf("query %q: %w", c.Query, err)
+		}
+
+		result := score(c, results)
+		report.Cases = append(report.Cases, result)
+		if len(c.Expected) > 0 {
+			report.Recall += float64(result.Found) / float64(len(c.Expected))
+		}
+		if result.Rank > 0 {
+			report.MRR += 1 / float64(result.Rank)
+		}
+	}
+
+	if n := len(report.Cases); n > 0 {
+		report.Recall /= float64(n)
+		report.MRR /= float64(n)
+	}
+	return report, nil
+}
+
+func score(c Case, results []search.Result) CaseResult {
+	// Rank by note rather than chunk so several chunks of one note don't
+	// push the others down
+	ranks := make(map[string]int)
+	for _, r := range results {
+		key := notePathKey(r.Path)
+		if _, ok := ranks[key]; !ok {
+			ranks[key] = len(ranks) + 1
+		}
+	}
+
+	result := CaseResult{Query: c.Query}
+	for _, expected := range c.Expected {
+		rank, ok := ranks[notePathKey(expected)]
+		if !ok {
+			result.Missed = append(result.Missed, expected)
+			continue
+		}
+		result.Found++
+		if result.Rank == 0 || rank < result.Rank {
+			result.Rank = rank
+		}
+	}
+	return result
+}
+
+func notePathKey(p string) string {
+	p = strings.Trim(strings.ReplaceAll(p, "\\", "/"), "/")
+	return strings.ToLower(strings.TrimSuffix(p, ".md"))
+}
+
+// caseYAML is a case as written in a cases file, where the expected notes
+// can be given as "expect" or "expected".
+type caseYAML struct {
+	Query    string     `yaml:"query"`
+	Expect   stringList `yaml:"expect"`
+	Expected stringList `yaml:"expected"`
+}
+
+// stringList is a list of strings that can also be written as a single one.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = stringList{value.Value}
+		return nil
+	}
+	return value.Decode((*[]string)(l))
+}
+
+// ParseCases reads cases from a YAML list of mappings with a "query" string
+// and an "expect" note path or list of paths. Being YAML, the same list can
+// also be written as JSON.
+func ParseCases(r io.Reader) ([]Case, error) {
+	var parsed []caseYAML
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&parsed); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	cases := make([]Case, 0, len(parsed))
+	for i, c := range parsed {
+		if c.Query == "" {
+			return nil, fmt.Errorf("case %d has no query", i+1)
+		}
+		expected := slices.Concat(c.Expect, c.Expected)
+		if len(expected) == 0 {
+			return nil, fmt.Errorf("case %q has no expected notes", c.Query)
+		}
+		cases = append(cases, Case{Query: c.Query, Expected: expected})
+	}
+	return cases, nil
+}
@@ -0,0 +1,86 @@
+package eval
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+func TestParseCases(t *testing.T) {
+	input := `# retrieval benchmarks
+- query: when is my passport renewal due
+  expect: Travel/Passport.md
+- query: "sleep: experiments"
+  expect:
+    - Health/Sleep.md
+    - 'Journal/2024-03-02'
+- query: taxes
+  expected: [Finance/Taxes.md, "Finance/2024 Return.md"]
+`
+
+	cases, err := ParseCases(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cases) != 3 {
+		t.Fatalf("expected 3 cases, got %+v", cases)
+	}
+	if cases[0].Query != "when is my passport renewal due" || len(cases[0].Expected) != 1 || cases[0].Expected[0] != "Travel/Passport.md" {
+		t.Errorf("unexpected first case: %+v", cases[0])
+	}
+	if cases[1].Query != "sleep: experiments" || len(cases[1].Expected) != 2 || cases[1].Expected[1] != "Journal/2024-03-02" {
+		t.Errorf("unexpected second case: %+v", cases[1])
+	}
+	if len(cases[2].Expected) != 2 || cases[2].Expected[1] != "Finance/2024 Return.md" {
+		t.Errorf("unexpected third case: %+v", cases[2])
+	}
+
+	if _, err := ParseCases(strings.NewReader("- query: no expectations\n")); err == nil {
+		t.Error("expected error for a case without expected notes")
+	}
+	if _, err := ParseCases(strings.NewReader("- query: typo\n  expcet: Notes.md\n")); err == nil {
+		t.Error("expected error for an unknown key")
+	}
+
+	// A JSON list is YAML too
+	cases, err = ParseCases(strings.NewReader(`[{"query": "taxes", "expect": ["Finance/Taxes.md"]}]`))
+	if err != nil || len(cases) != 1 || cases[0].Expected[0] != "Finance/Taxes.md" {
+		t.Errorf("expected a JSON case, got %+v (%v)", cases, err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	results := map[string][]search.Result{
+		"passport": {{Path: "Errands.md"}, {Path: "Errands.md"}, {Path: "Travel/Passport.md"}},
+		"sleep":    {{Path: "Health/Sleep.md"}, {Path: "Other.md"}},
+	}
+	cases := []Case{
+		{Query: "passport", Expected: []string{"travel/passport"}},
+		{Query: "sleep", Expected: []string{"Health/Sleep.md", "Journal/Missing.md"}},
+	}
+
+	report, err := Run(context.Background(), cases, 10, func(_ context.Context, query string) ([]search.Result, error) {
+		return results[query], nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Errands.md appears twice but counts as one note, so Passport is rank 2
+	if report.Cases[0].Rank != 2 {
+		t.Errorf("expected rank 2 for passport, got %d", report.Cases[0].Rank)
+	}
+	if len(report.Cases[1].Missed) != 1 || report.Cases[1].Missed[0] != "Journal/Missing.md" {
+		t.Errorf("expected Journal/Missing.md to be missed, got %v", report.Cases[1].Missed)
+	}
+	if math.Abs(report.Recall-0.75) > 1e-9 {
+		t.Errorf("expected recall 0.75, got %v", report.Recall)
+	}
+	if math.Abs(report.MRR-0.75) > 1e-9 {
+		t.Errorf("expected MRR 0.75, got %v", report.MRR)
+	}
+}
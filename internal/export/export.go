@@ -0,0 +1,115 @@
+// Package export writes search results back into the vault as Markdown notes.
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+// QueryPlaceholder in an export path is replaced with the search query.
+const QueryPlaceholder = "<query>"
+
+// maxSnippetLen is the longest snippet quoted for each hit, in runes.
+const maxSnippetLen = 280
+
+// NotePath resolves an export path relative to the vault, substituting the
+// query for QueryPlaceholder. The result must stay inside the vault and is
+// given a .md extension if it has none.
+func NotePath(vaultDir, pattern, query string) (string, error) {
+	name := strings.ReplaceAll(pattern, QueryPlaceholder, sanitizeFileName(query))
+	if filepath.Ext(name) != ".md" {
+		name += ".md"
+	}
+
+	rel := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("export path %s must be inside the vault", pattern)
+	}
+	return filepath.Join(vaultDir, rel), nil
+}
+
+// Write renders results as a note at path, creating parent directories. An
+// existing note is never overwritten.
+func Write(path, query string, results []search.Result, now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%s already exists", path)
+		}
+		return err
+	}
+
+	if _, err := f.WriteString(Markdown(query, results, now)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Markdown renders results as a note with one entry per source note, linking
+// to it and to each matching section with a quoted snippet.
+func Markdown(query string, results []search.Result, now time.Time) string {
+	var b strings.Builder
+
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "query: %q\n", query)
+	fmt.Fprintf(&b, "created: %s\n", now.Format("2006-01-02"))
+	b.WriteString("---\n\n")
+	fmt.Fprintf(&b, "# %s\n\n", query)
+
+	if len(results) == 0 {
+		b.WriteString("No results found.\n")
+		return b.String()
+	}
+
+	for _, group := range search.GroupByDocument(results) {
+		target := strings.TrimSuffix(group.Path, ".md")
+		fmt.Fprintf(&b, "- [[%s]] (%.2f)\n", target, group.Score)
+
+		for _, hit := range group.Hits {
+			if hit.Heading != "" {
+				fmt.Fprintf(&b, "  - [[%s#%s|%s]]\n", target, lastHeading(hit.Heading), hit.Heading)
+			}
+			if snippet := snippet(hit.Content); snippet != "" {
+				fmt.Fprintf(&b, "    > %s\n", snippet)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// lastHeading returns the innermost heading of a "A > B" breadcrumb, which is
+// what Obsidian heading links refer to.
+func lastHeading(heading string) string {
+	parts := strings.Split(heading, " > ")
+	return strings.TrimSpace(parts[len(parts)-1])
+}
+
+func snippet(content string) string {
+	s := strings.Join(strings.Fields(content), " ")
+	runes := []rune(s)
+	if len(runes) > maxSnippetLen {
+		s = strings.TrimSpace(string(runes[:maxSnippetLen])) + "..."
+	}
+	return s
+}
+
+func sanitizeFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|#^[]`, r) {
+			return '-'
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+func TestMarkdown(t *testing.T) {
+	results := []search.Result{
+		{Score: 0.91, Path: "Health/Sleep.md", Heading: "Sleep > Experiments", Content: "Tried magnesium\nfor two weeks.", DocID: 1},
+		{Score: 0.80, Path: "Journal/2024-03-02.md", Content: "Slept badly again.", DocID: 2},
+		{Score: 0.75, Path: "Health/Sleep.md", Heading: "Sleep > Routine", Content: "No screens after 10.", DocID: 1},
+	}
+
+	md := Markdown("sleep problems", results, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	for _, want := range []string{
+		"query: \"sleep problems\"\ncreated: 2024-06-01\n",
+		"# sleep problems\n",
+		"- [[Health/Sleep]] (0.91)\n  - [[Health/Sleep#Experiments|Sleep > Experiments]]\n    > Tried magnesium for two weeks.\n  - [[Health/Sleep#Routine|Sleep > Routine]]\n",
+		"- [[Journal/2024-03-02]] (0.80)\n    > Slept badly again.\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("expected note to contain %q, got:\n%s", want, md)
+		}
+	}
+}
+
+func TestNotePath(t *testing.T) {
+	vault := t.TempDir()
+
+	got, err := NotePath(vault, "Research/<query>", "tax: 2024/2025?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(vault, "Research", "tax- 2024-2025-.md"); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	if _, err := NotePath(vault, "../outside.md", "q"); err == nil {
+		t.Error("expected error for a path outside the vault")
+	}
+}
+
+func TestWrite_DoesNotOverwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Research", "note.md")
+
+	if err := Write(path, "q", nil, time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected note to be written: %v", err)
+	}
+	if err := Write(path, "q", nil, time.Now()); err == nil {
+		t.Error("expected error when the note already exists")
+	}
+}
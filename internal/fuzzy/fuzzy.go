@@ -0,0 +1,116 @@
+// Package fuzzy ranks note names against a loosely typed query, in the style
+// of an editor's quick switcher.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	matchScore       = 10
+	consecutiveBonus = 15
+	wordStartBonus   = 20
+	prefixBonus      = 25
+	exactBonus       = 100
+	gapPenalty       = 1
+)
+
+// Candidate is a note and the names it can be found by: its title, aliases
+// and file name.
+type Candidate struct {
+	Path  string
+	Names []string
+}
+
+// Match is a candidate that matched, with the name that scored best.
+type Match struct {
+	Path  string
+	Name  string
+	Score int
+}
+
+// Find returns the candidates matching query, best first. Ties go to the
+// shorter name, then the path.
+func Find(query string, candidates []Candidate) []Match {
+	var matches []Match
+	for _, c := range candidates {
+		best := Match{Path: c.Path, Score: -1}
+		for _, name := range c.Names {
+			score, ok := Score(query, name)
+			if ok && (score > best.Score || score == best.Score && len(name) < len(best.Name)) {
+				best.Score = score
+				best.Name = name
+			}
+		}
+		if best.Score >= 0 {
+			matches = append(matches, best)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if len(matches[i].Name) != len(matches[j].Name) {
+			return len(matches[i].Name) < len(matches[j].Name)
+		}
+		return matches[i].Path < matches[j].Path
+	})
+	return matches
+}
+
+// Score reports whether every non-space character of pattern appears in s in
+// order, ignoring case, and how well: consecutive runs, matches at the start
+// of words and an exact or prefix match score higher, and skipped characters
+// cost a little.
+func Score(pattern, s string) (int, bool) {
+	p := []rune(strings.ToLower(strings.Join(strings.Fields(pattern), "")))
+	if len(p) == 0 {
+		return 0, false
+	}
+	text := []rune(strings.ToLower(s))
+
+	score := 0
+	pi := 0
+	last := -1
+	for i, r := range text {
+		if pi == len(p) {
+			break
+		}
+		if r != p[pi] {
+			continue
+		}
+
+		score += matchScore
+		switch {
+		case last >= 0 && i == last+1:
+			score += consecutiveBonus
+		case last >= 0:
+			score -= gapPenalty * (i - last - 1)
+		}
+		if i == 0 || isSeparator(text[i-1]) {
+			score += wordStartBonus
+		}
+		last = i
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+
+	normalized := strings.ToLower(strings.TrimSpace(pattern))
+	lower := string(text)
+	switch {
+	case lower == normalized:
+		score += exactBonus
+	case strings.HasPrefix(lower, normalized):
+		score += prefixBonus
+	}
+	return max(score, 0), true
+}
+
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r)
+}
@@ -0,0 +1,44 @@
+package fuzzy
+
+import "testing"
+
+func TestScore(t *testing.T) {
+	if _, ok := Score("pspt", "Passport Renewal"); !ok {
+		t.Error("expected a subsequence to match")
+	}
+	if _, ok := Score("tpss", "Passport"); ok {
+		t.Error("expected out-of-order characters not to match")
+	}
+	if _, ok := Score("  ", "Passport"); ok {
+		t.Error("expected an empty pattern not to match")
+	}
+
+	exact, _ := Score("passport", "Passport")
+	prefix, _ := Score("pass", "Passport")
+	scattered, _ := Score("pass", "Project Archive Summary Sheet")
+	if exact <= prefix || prefix <= scattered {
+		t.Errorf("expected exact > prefix > scattered, got %d, %d, %d", exact, prefix, scattered)
+	}
+}
+
+func TestFind(t *testing.T) {
+	candidates := []Candidate{
+		{Path: "Archive/Old Meeting Notes.md", Names: []string{"Old Meeting Notes"}},
+		{Path: "Travel/Passport.md", Names: []string{"Passport", "Travel documents"}},
+		{Path: "Weekly Review.md", Names: []string{"Weekly Review", "wr"}},
+	}
+
+	matches := Find("travel docs", candidates)
+	if len(matches) != 1 || matches[0].Path != "Travel/Passport.md" || matches[0].Name != "Travel documents" {
+		t.Errorf("expected the alias to match, got %+v", matches)
+	}
+
+	matches = Find("wr", candidates)
+	if len(matches) == 0 || matches[0].Path != "Weekly Review.md" || matches[0].Name != "wr" {
+		t.Errorf("expected the exact alias first, got %+v", matches)
+	}
+
+	if matches := Find("zzz", candidates); len(matches) != 0 {
+		t.Errorf("expected no matches, got %+v", matches)
+	}
+}
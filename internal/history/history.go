@@ -0,0 +1,133 @@
+// Package history reads past versions of a git-tracked vault.
+package history
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CommitAt returns the hash of the last commit on HEAD made at or before t in
+// the git repository containing dir.
+func CommitAt(dir string, t time.Time) (string, error) {
+	out, err := git(dir, "rev-list", "-1", "--before="+t.Format(time.RFC3339), "HEAD")
+	if err != nil {
+		return "", err
+	}
+	commit := strings.TrimSpace(string(out))
+	if commit == "" {
+		return "", fmt.Errorf("no commit in %s before %s", dir, t.Format("2006-01-02"))
+	}
+	return commit, nil
+}
+
+// ResolveRef returns the hash of the commit a branch, tag or commit names in
+// the git repository containing dir.
+func ResolveRef(dir, ref string) (string, error) {
+	// Anything starting with a dash would be taken for an option
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid ref %q", ref)
+	}
+	out, err := git(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		if _, repoErr := git(dir, "rev-parse", "--git-dir"); repoErr != nil {
+			return "", repoErr
+		}
+		return "", fmt.Errorf("no branch or commit %q in %s", ref, dir)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Extract writes the Markdown files under dir as of commit into dest, keeping
+// their paths relative to dir and using the commit time as their
+// modification time. dir may be a subdirectory of the repository.
+func Extract(dir, commit, dest string) error {
+	out, err := git(dir, "rev-parse", "--show-toplevel", "--show-prefix")
+	if err != nil {
+		return err
+	}
+	// The prefix line is empty when dir is the repository root
+	toplevel, prefix, _ := strings.Cut(strings.TrimRight(string(out), "\n"), "\n")
+
+	// Archive from the top level; inside a subdirectory git would restrict
+	// the tree to that path a second time
+	cmd := exec.Command("git", "-C", toplevel, "archive", "--format=tar", commit+":"+prefix)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run git: %w", err)
+	}
+
+	extractErr := extractMarkdown(tar.NewReader(stdout), dest)
+	// Drain so git doesn't block on a full pipe if extraction stopped early
+	_, _ = io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git archive failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return extractErr
+}
+
+func extractMarkdown(tr *tar.Reader, dest string) error {
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(strings.ToLower(hdr.Name), ".md") {
+			continue
+		}
+
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Chtimes(target, hdr.ModTime, hdr.ModTime); err != nil {
+			return err
+		}
+	}
+}
+
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
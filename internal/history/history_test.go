@@ -0,0 +1,104 @@
+package history
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func gitCommit(t *testing.T, repo, date string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", "update"}} {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date,
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func TestCommitAtAndExtract(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	gitCommit(t, repo, "2023-11-01T12:00:00Z", map[string]string{
+		"vault/Plan.md":     "old plan",
+		"vault/diagram.png": "binary",
+		"outside.md":        "not in the vault",
+	})
+	gitCommit(t, repo, "2024-02-01T12:00:00Z", map[string]string{"vault/Plan.md": "new plan"})
+
+	vault := filepath.Join(repo, "vault")
+	commit, err := CommitAt(vault, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("CommitAt failed: %v", err)
+	}
+
+	dest := t.TempDir()
+	if err := Extract(vault, commit, dest); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dest, "Plan.md"))
+	if err != nil || string(content) != "old plan" {
+		t.Errorf("expected the old version of Plan.md, got %q (%v)", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "diagram.png")); !os.IsNotExist(err) {
+		t.Error("expected non-Markdown files to be skipped")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "outside.md")); !os.IsNotExist(err) {
+		t.Error("expected files outside the vault directory to be skipped")
+	}
+
+	if _, err := CommitAt(vault, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("expected an error before the first commit")
+	}
+}
+
+func TestResolveRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	gitCommit(t, repo, "2024-02-01T12:00:00Z", map[string]string{"Plan.md": "plan"})
+
+	commit, err := ResolveRef(repo, "main")
+	if err != nil || len(commit) != 40 {
+		t.Fatalf("expected main to resolve to a commit, got %q (%v)", commit, err)
+	}
+	if short, err := ResolveRef(repo, commit[:12]); err != nil || short != commit {
+		t.Errorf("expected an abbreviated hash to resolve to %s, got %q (%v)", commit, short, err)
+	}
+
+	for _, ref := range []string{"nope", "-h", ""} {
+		if _, err := ResolveRef(repo, ref); err == nil {
+			t.Errorf("expected an error resolving %q", ref)
+		}
+	}
+	if _, err := ResolveRef(t.TempDir(), "main"); err == nil {
+		t.Error("expected an error outside a git repository")
+	}
+}
@@ -0,0 +1,167 @@
+// Package keychain stores secrets in the OS credential store: the login
+// keychain on macOS (via security), the Secret Service on Linux (via
+// secret-tool) and the Credential Manager on Windows.
+package keychain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// service groups obsvec's entries in the credential store.
+const service = "obsvec"
+
+// ErrNotFound is returned by Get when no secret is stored for the account.
+var ErrNotFound = errors.New("secret not found in keychain")
+
+// run executes a credential store command; tests replace it.
+var run = func(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", &commandError{name: name, code: exitErr.ExitCode(), stderr: strings.TrimSpace(stderr.String())}
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+type commandError struct {
+	name   string
+	code   int
+	stderr string
+}
+
+func (e *commandError) Error() string {
+	if e.stderr == "" {
+		return fmt.Sprintf("%s exited with status %d", e.name, e.code)
+	}
+	return fmt.Sprintf("%s: %s", e.name, e.stderr)
+}
+
+// Available reports whether there is a credential store to keep secrets in.
+func Available() bool {
+	switch runtime.GOOS {
+	case "darwin":
+		_, err := exec.LookPath("security")
+		return err == nil
+	case "linux":
+		_, err := exec.LookPath("secret-tool")
+		return err == nil
+	case "windows":
+		return true
+	}
+	return false
+}
+
+// Get returns the secret stored for account.
+func Get(account string) (string, error) {
+	var (
+		secret string
+		err    error
+	)
+	switch runtime.GOOS {
+	case "darwin":
+		secret, err = run("", "security", "find-generic-password", "-s", service, "-a", account, "-w")
+	case "linux":
+		secret, err = run("", "secret-tool", "lookup", "service", service, "account", account)
+	case "windows":
+		return credRead(account)
+	default:
+		return "", fmt.Errorf("no supported keychain on %s", runtime.GOOS)
+	}
+
+	if isNotFound(err) || (err == nil && secret == "") {
+		return "", ErrNotFound
+	}
+	return secret, err
+}
+
+// isNotFound reports whether err is how the credential store's tool says
+// there is no such entry: security exits with errSecItemNotFound (44), and
+// secret-tool with 1 and nothing on stderr. Anything else, such as a locked
+// keychain, a denied prompt or no D-Bus session, is a real error.
+func isNotFound(err error) bool {
+	var cmdErr *commandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	switch cmdErr.name {
+	case "security":
+		return cmdErr.code == 44
+	case "secret-tool":
+		return cmdErr.code == 1 && cmdErr.stderr == ""
+	}
+	return false
+}
+
+// Set stores secret for account, replacing any existing one.
+func Set(account, secret string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		// Given on stdin, as arguments would show the secret in ps
+		command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", quote(service), quote(account), quote(secret))
+		_, err := run(command, "security", "-i")
+		return err
+	case "linux":
+		_, err := run(secret, "secret-tool", "store", "--label", service+" "+account, "service", service, "account", account)
+		return err
+	case "windows":
+		return credWrite(account, secret)
+	}
+	return fmt.Errorf("no supported keychain on %s", runtime.GOOS)
+}
+
+// quote makes s one argument to security -i, which splits its commands on
+// spaces and takes double-quoted strings with backslash escapes.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Delete removes the secret stored for account. It returns ErrNotFound if
+// there is none.
+func Delete(account string) error {
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		_, err = run("", "security", "delete-generic-password", "-s", service, "-a", account)
+	case "linux":
+		_, err = run("", "secret-tool", "clear", "service", service, "account", account)
+	case "windows":
+		return credDelete(account)
+	default:
+		return fmt.Errorf("no supported keychain on %s", runtime.GOOS)
+	}
+
+	if isNotFound(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// GetOrCreate returns the secret stored for account, storing one made by
+// create first if there is none.
+func GetOrCreate(account string, create func() (string, error)) (string, error) {
+	secret, err := Get(account)
+	if !errors.Is(err, ErrNotFound) {
+		return secret, err
+	}
+
+	secret, err = create()
+	if err != nil {
+		return "", err
+	}
+	if err := Set(account, secret); err != nil {
+		return "", fmt.Errorf("failed to store secret in keychain: %w", err)
+	}
+	return secret, nil
+}
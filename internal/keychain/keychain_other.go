@@ -0,0 +1,19 @@
+//go:build !windows
+
+package keychain
+
+import "errors"
+
+var errNoCredentialManager = errors.New("the Credential Manager is only on Windows")
+
+func credRead(account string) (string, error) {
+	return "", errNoCredentialManager
+}
+
+func credWrite(account, secret string) error {
+	return errNoCredentialManager
+}
+
+func credDelete(account string) error {
+	return errNoCredentialManager
+}
@@ -0,0 +1,106 @@
+package keychain
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// missing is the error each tool exits with for an entry that isn't there.
+func missing(name string) error {
+	if name == "security" {
+		return &commandError{name: name, code: 44}
+	}
+	return &commandError{name: name, code: 1}
+}
+
+func TestGetOrCreate(t *testing.T) {
+	if runtime.GOOS != "darwin" && runtime.GOOS != "linux" {
+		t.Skip("no supported keychain")
+	}
+
+	stored := map[string]string{}
+	orig := run
+	defer func() { run = orig }()
+	run = func(stdin string, name string, args ...string) (string, error) {
+		account := args[len(args)-1]
+		switch args[0] {
+		case "find-generic-password":
+			account = args[4]
+			fallthrough
+		case "lookup":
+			if secret, ok := stored[account]; ok {
+				return secret, nil
+			}
+			return "", missing(name)
+		case "-i":
+			fields := strings.Fields(stdin)
+			account, _ := strconv.Unquote(fields[5])
+			stored[account], _ = strconv.Unquote(fields[7])
+		case "store":
+			stored[account] = stdin
+		case "delete-generic-password":
+			account = args[4]
+			fallthrough
+		case "clear":
+			if _, ok := stored[account]; !ok {
+				return "", missing(name)
+			}
+			delete(stored, account)
+		}
+		return "", nil
+	}
+
+	calls := 0
+	create := func() (string, error) {
+		calls++
+		return "secret", nil
+	}
+
+	for range 2 {
+		secret, err := GetOrCreate("database", create)
+		if err != nil {
+			t.Fatalf("GetOrCreate failed: %v", err)
+		}
+		if secret != "secret" {
+			t.Errorf("expected the created secret, got %q", secret)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the secret to be created once, got %d", calls)
+	}
+	if err := Delete("database"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := Delete("database"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound deleting a missing secret, got %v", err)
+	}
+	if _, err := GetOrCreate("database", create); err != nil || calls != 2 {
+		t.Errorf("expected a new secret after deleting, got %d calls (%v)", calls, err)
+	}
+}
+
+func TestGetLocked(t *testing.T) {
+	if runtime.GOOS != "darwin" && runtime.GOOS != "linux" {
+		t.Skip("no supported keychain")
+	}
+
+	orig := run
+	defer func() { run = orig }()
+	run = func(stdin string, name string, args ...string) (string, error) {
+		if name == "security" {
+			return "", &commandError{name: name, code: 36, stderr: "User interaction is not allowed."}
+		}
+		return "", &commandError{name: name, code: 1, stderr: "Cannot autolaunch D-Bus without X11 $DISPLAY"}
+	}
+
+	if _, err := Get("database"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a locked keychain to be an error, not a missing entry: %v", err)
+	}
+	created := false
+	if _, err := GetOrCreate("database", func() (string, error) { created = true; return "new", nil }); err == nil || created {
+		t.Errorf("expected no new secret while the keychain is locked, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+// Package redact masks note paths and contents for screenshots and screen
+// sharing. Redaction is deterministic so the same note always maps to the
+// same placeholder within and across outputs.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+)
+
+const hashLen = 8
+
+// Path replaces every segment of a slash-separated path with a short hash,
+// keeping the extension and directory depth.
+func Path(p string) string {
+	if p == "" {
+		return ""
+	}
+
+	segments := strings.Split(path.Clean(strings.ReplaceAll(p, "\\", "/")), "/")
+	for i, segment := range segments {
+		ext := path.Ext(segment)
+		segments[i] = hash(strings.TrimSuffix(segment, ext)) + ext
+	}
+	return strings.Join(segments, "/")
+}
+
+// Snippet keeps only the first non-empty line of s.
+func Snippet(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:hashLen]
+}
@@ -0,0 +1,33 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	redacted := Path("Projects/Secret Plan.md")
+
+	if strings.Contains(redacted, "Secret") || strings.Contains(redacted, "Projects") {
+		t.Errorf("expected path segments to be hidden, got '%s'", redacted)
+	}
+
+	if !strings.HasSuffix(redacted, ".md") {
+		t.Errorf("expected extension to be kept, got '%s'", redacted)
+	}
+
+	if strings.Count(redacted, "/") != 1 {
+		t.Errorf("expected directory depth to be kept, got '%s'", redacted)
+	}
+
+	if Path("Projects/Secret Plan.md") != redacted {
+		t.Error("expected redaction to be deterministic")
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	got := Snippet("\n## Heading\nprivate details\nmore details")
+	if got != "## Heading" {
+		t.Errorf("expected first line only, got '%s'", got)
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/mgomes/obsvec/internal/ask"
+)
+
+// SetAsker answers questions on /api/ask with a. Without one the route
+// answers 404.
+func (s *Server) SetAsker(a *ask.Asker) {
+	s.asker = a
+}
+
+// askResponse is the body of an answer, and the final event of a streamed
+// one: the answer with [n] markers after cited spans, and the notes they
+// refer to.
+type askResponse struct {
+	Answer  string         `json:"answer"`
+	Sources []ask.Footnote `json:"sources"`
+}
+
+// tokenEvent is a piece of a streamed answer, in the order generated.
+type tokenEvent struct {
+	Text string `json:"text"`
+}
+
+// handleAsk answers the question in q from the n most relevant notes.
+// Clients accepting text/event-stream get the answer as Server-Sent Events:
+// token events as the chat model writes, then a citations event with the
+// whole answer and its sources, or an error event. Other clients get the
+// answer as one JSON object once it's complete.
+func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	if s.asker == nil {
+		writeError(w, http.StatusNotFound, "asking is not available")
+		return
+	}
+	question := strings.TrimSpace(r.URL.Query().Get("q"))
+	if question == "" {
+		writeError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+	opts, err := s.searchOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		answer, err := s.asker.Ask(r.Context(), question, opts)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, newAskResponse(answer))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	events := newEventWriter(w)
+	events.flush()
+
+	answer, err := s.asker.AskStream(r.Context(), question, opts, func(text string) {
+		events.send("token", tokenEvent{Text: text})
+	})
+	if err != nil {
+		events.send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	events.send("citations", newAskResponse(answer))
+}
+
+func newAskResponse(answer *ask.Answer) askResponse {
+	text, footnotes := answer.Footnotes()
+	if footnotes == nil {
+		footnotes = []ask.Footnote{}
+	}
+	return askResponse{Answer: text, Sources: footnotes}
+}
+
+// eventWriter writes Server-Sent Events, flushing each one to the client
+// right away.
+type eventWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func newEventWriter(w http.ResponseWriter) *eventWriter {
+	return &eventWriter{w: w, rc: http.NewResponseController(w)}
+}
+
+// send writes an event named name with v as its JSON data.
+func (e *eventWriter) send(name string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Warn("encoding event failed", "event", name, "error", err)
+		return
+	}
+	if _, err := fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		slog.Debug("writing event failed", "event", name, "error", err)
+		return
+	}
+	e.flush()
+}
+
+func (e *eventWriter) flush() {
+	if err := e.rc.Flush(); err != nil {
+		slog.Debug("flushing events failed", "error", err)
+	}
+}
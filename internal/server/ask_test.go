@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mgomes/obsvec/internal/ask"
+	"github.com/mgomes/obsvec/pkg/obsvec/cohere"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+func TestServer_AskRequests(t *testing.T) {
+	s := newTestServer(t, t.TempDir())
+	handler := s.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest("GET", "/api/ask?q=taxes"))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without an asker, got %d", rec.Code)
+	}
+
+	s.SetAsker(ask.New(s.searcher, nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest("GET", "/api/ask"))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "missing query") {
+		t.Errorf("expected 400 without a question, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestEventWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	events := newEventWriter(rec)
+	events.send("token", tokenEvent{Text: "Taxes are"})
+	events.send("citations", newAskResponse(&ask.Answer{
+		Text:      "Taxes are due in April.",
+		Citations: []cohere.Citation{{Start: 13, End: 22, DocumentIDs: []string{"0"}}},
+		Sources:   []search.Result{{Path: "Finance/Taxes.md", Heading: "Deadlines"}},
+	}))
+
+	want := "event: token\ndata: {\"text\":\"Taxes are\"}\n\n" +
+		"event: citations\ndata: {\"answer\":\"Taxes are due in April[1].\",\"sources\":[{\"number\":1,\"path\":\"Finance/Taxes.md\",\"heading\":\"Deadlines\"}]}\n\n"
+	if rec.Body.String() != want {
+		t.Errorf("expected events\n%q\ngot\n%q", want, rec.Body.String())
+	}
+	if !rec.Flushed {
+		t.Error("expected the events to be flushed")
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/mgomes/obsvec/pkg/obsvec/events"
+)
+
+// eventBuffer is how many events can wait for a slow client of the event
+// stream before new ones are dropped.
+const eventBuffer = 64
+
+// eventWriteTimeout bounds sending one event to a client.
+const eventWriteTimeout = 10 * time.Second
+
+// SetEventBus streams the events published to bus to clients of /api/events.
+// Without a bus the route answers 404.
+func (s *Server) SetEventBus(bus *events.Bus) {
+	s.events = bus
+}
+
+// handleEvents upgrades the request to a WebSocket and sends each event
+// published from then on as a JSON message, until either side closes. Kind
+// parameters limit the stream to those kinds. Messages from the client are
+// ignored.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		writeError(w, http.StatusNotFound, "no event stream")
+		return
+	}
+	kinds := r.URL.Query()["kind"]
+
+	// Subscribed before the upgrade, so nothing published once the client is
+	// connected is missed
+	queue := make(chan events.Event, eventBuffer)
+	unsubscribe := s.events.Subscribe(func(e events.Event) {
+		if len(kinds) > 0 && !slices.Contains(kinds, string(e.Kind)) {
+			return
+		}
+		select {
+		case queue <- e:
+		default:
+			slog.Debug("event stream client too slow, dropping event", "kind", e.Kind)
+		}
+	})
+	defer unsubscribe()
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: allowedOrigins})
+	if err != nil {
+		// Accept has already answered the request
+		slog.Debug("websocket upgrade failed", "error", err)
+		return
+	}
+	defer conn.CloseNow() //nolint:errcheck
+
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	// ctx ends when the client closes the connection
+	ctx := conn.CloseRead(r.Context())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.ctx.Done():
+			_ = conn.Close(websocket.StatusGoingAway, "server shutting down")
+			return
+		case e := <-queue:
+			writeCtx, cancel := context.WithTimeout(ctx, eventWriteTimeout)
+			err := wsjson.Write(writeCtx, conn, e)
+			cancel()
+			if err != nil {
+				slog.Debug("event stream closed", "error", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/mgomes/obsvec/pkg/obsvec/events"
+)
+
+func TestServer_Events(t *testing.T) {
+	s := newTestServer(t, t.TempDir())
+	s.SetToken("secret")
+	bus := events.NewBus()
+	s.SetEventBus(bus)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	url := "ws://" + ts.Listener.Addr().String() + "/api/events"
+
+	_, resp, err := websocket.Dial(ctx, url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without the token, got %v", err)
+	}
+
+	conn, _, err := websocket.Dial(ctx, url+"?token=secret&kind=index_completed", nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.CloseNow()
+
+	bus.Publish(events.Event{Kind: events.SearchExecuted, Query: "taxes"})
+	bus.Publish(events.Event{Kind: events.IndexCompleted, Duration: time.Second})
+
+	var e events.Event
+	if err := wsjson.Read(ctx, conn, &e); err != nil {
+		t.Fatalf("failed to read event: %v", err)
+	}
+	if e.Kind != events.IndexCompleted || e.Duration != time.Second {
+		t.Errorf("expected the index completed event, got %+v", e)
+	}
+
+	// Closing the server ends the stream, once the client answers the close
+	go s.Close()
+	if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusGoingAway {
+		t.Errorf("expected the server to close with going away, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	obsvecv1 "github.com/mgomes/obsvec/api/obsvec/v1"
+	"github.com/mgomes/obsvec/pkg/obsvec/indexer"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RegisterGRPC adds the Obsvec gRPC service to g, answering from the same
+// index as the HTTP routes. A reindex started over gRPC and one started over
+// HTTP never run at once.
+func (s *Server) RegisterGRPC(g *grpc.Server) {
+	obsvecv1.RegisterObsvecServer(g, grpcService{s: s})
+}
+
+// GRPCOptions returns the options to create the gRPC server with, which make
+// calls carry the token set with SetToken as "authorization: Bearer <token>"
+// metadata.
+func (s *Server) GRPCOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := s.checkToken(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.checkToken(stream.Context()); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	}
+}
+
+func (s *Server) checkToken(ctx context.Context) error {
+	var header string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		header = values[0]
+	}
+	if !s.authorized(header) {
+		return status.Error(codes.Unauthenticated, "missing or wrong API token")
+	}
+	return nil
+}
+
+type grpcService struct {
+	obsvecv1.UnimplementedObsvecServer
+	s *Server
+}
+
+func (g grpcService) Search(ctx context.Context, req *obsvecv1.SearchRequest) (*obsvecv1.SearchResponse, error) {
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	if req.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit can't be negative")
+	}
+
+	opts := g.s.opts
+	if req.GetLimit() > 0 {
+		opts.Limit = min(int(req.GetLimit()), maxLimit)
+	}
+	results, err := g.s.searcher.Search(ctx, req.GetQuery(), opts)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &obsvecv1.SearchResponse{Results: make([]*obsvecv1.Result, len(results))}
+	for i, r := range results {
+		resp.Results[i] = resultProto(r)
+	}
+	return resp, nil
+}
+
+func (g grpcService) GetStatus(ctx context.Context, req *obsvecv1.GetStatusRequest) (*obsvecv1.GetStatusResponse, error) {
+	st, err := g.s.status()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &obsvecv1.GetStatusResponse{
+		Documents: int64(st.Documents),
+		Chunks:    int64(st.Chunks),
+		Indexing:  st.Indexing,
+	}
+	if run := st.LastIndex; run != nil {
+		resp.LastIndex = &obsvecv1.IndexRun{
+			Full:       run.Full,
+			Paths:      run.Paths,
+			StartedAt:  timestamppb.New(run.StartedAt),
+			FinishedAt: timestampProto(run.FinishedAt),
+			Error:      run.Error,
+		}
+	}
+	return resp, nil
+}
+
+func (g grpcService) GetDocument(ctx context.Context, req *obsvecv1.GetDocumentRequest) (*obsvecv1.Document, error) {
+	doc, err := g.s.document(req.GetPath())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if doc == nil {
+		return nil, status.Errorf(codes.NotFound, "%s is not indexed", req.GetPath())
+	}
+
+	frontmatter, err := structpb.NewStruct(doc.Metadata)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &obsvecv1.Document{
+		Path:       doc.Path,
+		Title:      doc.Title,
+		ModifiedAt: timestamppb.New(doc.ModifiedAt),
+		IndexedAt:  timestamppb.New(doc.IndexedAt),
+		Tags:       doc.Tags,
+		Metadata:   frontmatter,
+		Chunks:     make([]*obsvecv1.Chunk, len(doc.Chunks)),
+	}
+	for i, chunk := range doc.Chunks {
+		resp.Chunks[i] = &obsvecv1.Chunk{
+			Id:        chunk.ID,
+			Heading:   chunk.Heading,
+			StartLine: int32(chunk.StartLine),
+			EndLine:   int32(chunk.EndLine),
+			Content:   chunk.Content,
+		}
+	}
+	return resp, nil
+}
+
+// Index runs the reindex for as long as the client stays connected, sending
+// each progress update as it happens.
+func (g grpcService) Index(req *obsvecv1.IndexRequest, stream grpc.ServerStreamingServer[obsvecv1.IndexProgress]) error {
+	run, err := g.s.startRun(req.GetFull(), req.GetPaths())
+	switch {
+	case errors.Is(err, errIndexing):
+		return status.Error(codes.Aborted, err.Error())
+	case err != nil:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	g.s.wg.Add(1)
+	defer g.s.wg.Done()
+
+	// Closing the server cancels the run as well as the client hanging up
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	stop := context.AfterFunc(g.s.ctx, cancel)
+	defer stop()
+
+	var sendErr error
+	err = g.s.reindex(ctx, run, func(p indexer.Progress) {
+		if sendErr != nil {
+			return
+		}
+		sendErr = stream.Send(&obsvecv1.IndexProgress{
+			Phase:   string(p.Phase),
+			Current: int32(p.Current),
+			Total:   int32(p.Total),
+			Path:    p.FilePath,
+			Message: p.Message,
+			Chunks:  int32(p.Chunks),
+			Tokens:  int32(p.Tokens),
+		})
+	})
+	g.s.finishRun(run, err)
+
+	switch {
+	case err != nil:
+		return status.Error(codes.Internal, err.Error())
+	case sendErr != nil:
+		return sendErr
+	}
+	return nil
+}
+
+func resultProto(r search.Result) *obsvecv1.Result {
+	return &obsvecv1.Result{
+		Rank:       int32(r.Rank),
+		Score:      r.Score,
+		Path:       r.Path,
+		Heading:    r.Heading,
+		Content:    r.Content,
+		StartLine:  int32(r.StartLine),
+		EndLine:    int32(r.EndLine),
+		DocId:      r.DocID,
+		ChunkId:    r.ChunkID,
+		ModifiedAt: timestampProto(r.ModifiedAt),
+		LinkedFrom: r.LinkedFrom,
+	}
+}
+
+// timestampProto converts t, leaving the zero time unset.
+func timestampProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	obsvecv1 "github.com/mgomes/obsvec/api/obsvec/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T, s *Server) obsvecv1.ObsvecClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	g := grpc.NewServer(s.GRPCOptions()...)
+	s.RegisterGRPC(g)
+	go g.Serve(listener)
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return obsvecv1.NewObsvecClient(conn)
+}
+
+func TestGRPC_IndexAndFetch(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.WriteFile(filepath.Join(vault, "Plan.md"), []byte("---\ntags: [work]\n---\n# Plan\n"), 0644); err != nil {
+		t.Fatalf("failed to write note: %v", err)
+	}
+	client := newTestClient(t, newTestServer(t, vault))
+	ctx := context.Background()
+
+	_, err := client.GetDocument(ctx, &obsvecv1.GetDocumentRequest{Path: "Plan.md"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound before indexing, got %v", err)
+	}
+
+	stream, err := client.Index(ctx, &obsvecv1.IndexRequest{})
+	if err != nil {
+		t.Fatalf("failed to start index: %v", err)
+	}
+	var updates int
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("index failed: %v", err)
+		}
+		updates++
+	}
+	if updates == 0 {
+		t.Error("expected progress updates")
+	}
+
+	st, err := client.GetStatus(ctx, &obsvecv1.GetStatusRequest{})
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if st.GetDocuments() != 1 || st.GetIndexing() || st.GetLastIndex().GetFinishedAt() == nil {
+		t.Errorf("expected one document and a finished run, got %v", st)
+	}
+
+	doc, err := client.GetDocument(ctx, &obsvecv1.GetDocumentRequest{Path: "Plan.md"})
+	if err != nil {
+		t.Fatalf("failed to get document: %v", err)
+	}
+	if doc.GetTitle() != "Plan" || len(doc.GetTags()) != 1 || doc.GetTags()[0] != "work" {
+		t.Errorf("expected Plan tagged work, got %v", doc)
+	}
+}
+
+func TestGRPC_Token(t *testing.T) {
+	s := newTestServer(t, t.TempDir())
+	s.SetToken("secret")
+	client := newTestClient(t, s)
+
+	_, err := client.GetStatus(context.Background(), &obsvecv1.GetStatusRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without the token, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	if _, err := client.GetStatus(ctx, &obsvecv1.GetStatusRequest{}); err != nil {
+		t.Errorf("expected the token to be accepted, got %v", err)
+	}
+}
+
+func TestGRPC_InvalidRequests(t *testing.T) {
+	client := newTestClient(t, newTestServer(t, t.TempDir()))
+	ctx := context.Background()
+
+	if _, err := client.Search(ctx, &obsvecv1.SearchRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an empty query, got %v", err)
+	}
+
+	stream, err := client.Index(ctx, &obsvecv1.IndexRequest{Full: true, Paths: []string{"a.md"}})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for full with paths, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	_ "embed"
+	"log/slog"
+	"net/http"
+)
+
+// openAPISpec describes the HTTP API for client generators. /api/query is
+// left out: its request carries search.Options, and only ofind itself calls
+// it.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// publicPaths are served without the token, so monitors and the people
+// reading the docs needn't have one.
+var publicPaths = []string{"/api/health", "/api/openapi.json", "/docs"}
+
+// docsPage renders the OpenAPI document with Redoc, loaded from its CDN.
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>obsvec API</title>
+</head>
+<body>
+<redoc spec-url="/api/openapi.json"></redoc>
+<script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(openAPISpec); err != nil {
+		slog.Warn("writing response failed", "error", err)
+	}
+}
+
+func (s *Server) handleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(docsPage)); err != nil {
+		slog.Warn("writing response failed", "error", err)
+	}
+}
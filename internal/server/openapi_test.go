@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("failed to parse openapi.json: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("expected an OpenAPI 3 document, got version %q", spec.OpenAPI)
+	}
+
+	// Every documented operation has a route
+	s := newTestServer(t, t.TempDir())
+	for path, ops := range spec.Paths {
+		for method := range ops {
+			target := strings.ReplaceAll(path, "{path}", "Projects/Plan.md")
+			if _, pattern := s.mux.Handler(newRequest(strings.ToUpper(method), target)); pattern == "" {
+				t.Errorf("%s %s is documented but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+	for _, want := range []string{"/api/search", "/api/ask", "/api/index", "/api/events"} {
+		if _, ok := spec.Paths[want]; !ok {
+			t.Errorf("expected %s to be documented", want)
+		}
+	}
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	s := newTestServer(t, t.TempDir())
+	s.SetToken("secret")
+	handler := s.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest("GET", "/api/openapi.json"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 without a token, got %d", rec.Code)
+	}
+	if !json.Valid(rec.Body.Bytes()) {
+		t.Error("expected the document as JSON")
+	}
+}
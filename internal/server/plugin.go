@@ -0,0 +1,194 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/mgomes/obsvec/internal/config"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+// allowedOrigins may call the API from a browser context; app://obsidian.md
+// is the Obsidian desktop app, where the companion plugin runs.
+var allowedOrigins = []string{"app://obsidian.md"}
+
+// allowedHosts are the Host headers accepted, so that a web page can't reach
+// the API through a DNS name rebound to 127.0.0.1.
+var allowedHosts = []string{"127.0.0.1", "localhost", "::1"}
+
+// paneSnippetLen caps the snippet of each hit in a pane search.
+const paneSnippetLen = 200
+
+// SetToken requires every request but the health check and API docs to
+// carry token, or a client token, as "Authorization: Bearer <token>". An
+// empty token leaves the API open.
+func (s *Server) SetToken(token string) {
+	s.token = token
+}
+
+// AllowHost accepts requests addressed to host, by name or IP address, as
+// they are when the API listens on an address other than localhost, such as
+// a Tailscale one.
+func (s *Server) AllowHost(host string) {
+	s.hosts = append(s.hosts, strings.Trim(host, "[]"))
+}
+
+// protect rejects requests for other hosts and without the token, and
+// answers CORS preflight requests from allowed origins, before passing the
+// rest to next.
+func (s *Server) protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		host = strings.Trim(host, "[]")
+		if !slices.Contains(allowedHosts, host) && !slices.Contains(s.hosts, host) {
+			writeError(w, http.StatusForbidden, "host not allowed")
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); slices.Contains(allowedOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if !slices.Contains(publicPaths, r.URL.Path) && !s.authorized(authorization(r)) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or wrong API token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tokenParamPaths take the token as the token parameter too, since browsers
+// can't set headers on a WebSocket or an EventSource, and many widgets can't
+// set any.
+var tokenParamPaths = []string{"/api/events", "/api/ask", "/api/shortcut"}
+
+// authorization returns the request's Authorization header, or for the
+// routes in tokenParamPaths, a token parameter in its place.
+func authorization(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" && slices.Contains(tokenParamPaths, r.URL.Path) {
+		return "Bearer " + token
+	}
+	return r.Header.Get("Authorization")
+}
+
+// SetClientTokens also accepts the client tokens whose hashes, as made by
+// config.HashToken, lookup returns by client name. It is called for every
+// request, so a token revoked while the server runs stops working at once.
+func (s *Server) SetClientTokens(lookup func() map[string]string) {
+	s.clientTokens = lookup
+}
+
+// authorized reports whether an Authorization header value carries the token
+// or a client token.
+func (s *Server) authorized(header string) bool {
+	if s.token == "" {
+		return true
+	}
+	given, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) == 1 {
+		return true
+	}
+	if s.clientTokens == nil {
+		return false
+	}
+	hash := []byte(config.HashToken(given))
+	for _, clientHash := range s.clientTokens() {
+		if subtle.ConstantTimeCompare(hash, []byte(clientHash)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// paneResponse is the body of a pane search: results grouped by note, ready
+// to list in the plugin's search pane.
+type paneResponse struct {
+	Query string     `json:"query"`
+	Notes []paneNote `json:"notes"`
+}
+
+type paneNote struct {
+	Path  string    `json:"path"`
+	Title string    `json:"title"`
+	Score float64   `json:"score"`
+	Hits  []paneHit `json:"hits"`
+}
+
+// paneHit is a matching chunk. Heading is the innermost one, which Obsidian
+// links to as path#heading; Line is where the chunk starts, from 1.
+type paneHit struct {
+	Heading string  `json:"heading,omitempty"`
+	Line    int     `json:"line"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+// handlePaneSearch runs a search like handleSearch, but answers with the
+// results grouped by note and trimmed to short snippets.
+func (s *Server) handlePaneSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+
+	opts, err := s.searchOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := s.searcher.Search(r.Context(), query, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := paneResponse{Query: query, Notes: []paneNote{}}
+	for _, group := range search.GroupByDocument(results) {
+		note := paneNote{
+			Path:  group.Path,
+			Title: strings.TrimSuffix(path.Base(group.Path), ".md"),
+			Score: group.Score,
+		}
+		for _, hit := range group.Hits {
+			headings := strings.Split(hit.Heading, " > ")
+			note.Hits = append(note.Hits, paneHit{
+				Heading: strings.TrimSpace(headings[len(headings)-1]),
+				Line:    max(hit.StartLine, 1),
+				Score:   hit.Score,
+				Snippet: paneSnippet(hit.Content),
+			})
+		}
+		resp.Notes = append(resp.Notes, note)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// paneSnippet flattens content onto one line, cut at paneSnippetLen.
+func paneSnippet(content string) string {
+	snippet := strings.Join(strings.Fields(content), " ")
+	if runes := []rune(snippet); len(runes) > paneSnippetLen {
+		snippet = string(runes[:paneSnippetLen-1]) + "…"
+	}
+	return snippet
+}
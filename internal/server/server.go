@@ -0,0 +1,375 @@
+// Package server answers search requests over HTTP with JSON, for tools and
+// plugins that talk to a long-running ofind instead of running the CLI.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgomes/obsvec/internal/ask"
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+	"github.com/mgomes/obsvec/pkg/obsvec/events"
+	"github.com/mgomes/obsvec/pkg/obsvec/indexer"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+// maxLimit caps the n parameter of a search.
+const maxLimit = 100
+
+type Server struct {
+	db       *db.DB
+	searcher *search.Searcher
+	asker    *ask.Asker
+	indexer  *indexer.Indexer
+	opts     search.Options
+	mux      *http.ServeMux
+	token    string
+	events   *events.Bus
+
+	// noteURL links a result to its note, for shortcut searches.
+	noteURL func(path, heading string, line int) string
+
+	// hosts are the Host headers accepted besides allowedHosts.
+	hosts []string
+
+	// clientTokens returns the hashes of the client tokens by name.
+	clientTokens func() map[string]string
+
+	// ctx is canceled by Close, stopping a reindex in progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	indexing bool
+	lastRun  *indexRun
+}
+
+// indexRun describes a reindex started through the API.
+type indexRun struct {
+	Full       bool      `json:"full"`
+	Paths      []string  `json:"paths,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// New returns a server searching with searcher and reindexing with idx,
+// both over database. opts are the defaults for every search, such as
+// excluded paths.
+func New(database *db.DB, searcher *search.Searcher, idx *indexer.Indexer, opts search.Options) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		db:       database,
+		searcher: searcher,
+		indexer:  idx,
+		opts:     opts,
+		mux:      http.NewServeMux(),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	s.mux.HandleFunc("GET /api/health", s.handleHealth)
+	s.mux.HandleFunc("GET /api/search", s.handleSearch)
+	s.mux.HandleFunc("GET /api/pane/search", s.handlePaneSearch)
+	s.mux.HandleFunc("GET /api/ask", s.handleAsk)
+	s.mux.HandleFunc("GET /api/shortcut", s.handleShortcut)
+	s.mux.HandleFunc("GET /api/status", s.handleStatus)
+	s.mux.HandleFunc("GET /api/documents/{path...}", s.handleDocument)
+	s.mux.HandleFunc("POST /api/index", s.handleIndex)
+	s.mux.HandleFunc("POST /api/query", s.handleQuery)
+	s.mux.HandleFunc("GET /api/events", s.handleEvents)
+	s.mux.HandleFunc("GET /api/openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("GET /docs", s.handleDocs)
+	return s
+}
+
+// Close stops a reindex in progress and closes event streams, waiting for
+// both to return.
+func (s *Server) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Handler returns the server's routes, logging each request.
+func (s *Server) Handler() http.Handler {
+	handler := s.protect(s.mux)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(rec, r)
+		slog.Info("http request", "method", r.Method, "path", r.URL.Path, "status", rec.status, "duration", time.Since(start))
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// searchResponse is the body of a successful search.
+type searchResponse struct {
+	Query   string          `json:"query"`
+	Results []search.Result `json:"results"`
+}
+
+// handleSearch runs the query in q, returning up to n results (default as
+// configured).
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+
+	opts, err := s.searchOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := s.searcher.Search(r.Context(), query, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if results == nil {
+		results = []search.Result{}
+	}
+	writeJSON(w, http.StatusOK, searchResponse{Query: query, Results: results})
+}
+
+// searchOptions returns the server's search options with the limit from the
+// n parameter.
+func (s *Server) searchOptions(r *http.Request) (search.Options, error) {
+	opts := s.opts
+	if n := r.URL.Query().Get("n"); n != "" {
+		limit, err := strconv.Atoi(n)
+		if err != nil || limit <= 0 {
+			return opts, errors.New("n must be a positive number")
+		}
+		opts.Limit = min(limit, maxLimit)
+	}
+	return opts, nil
+}
+
+// statusResponse is the body of a status request.
+type statusResponse struct {
+	Documents int       `json:"documents"`
+	Chunks    int       `json:"chunks"`
+	Indexing  bool      `json:"indexing"`
+	LastIndex *indexRun `json:"last_index,omitempty"`
+}
+
+// handleStatus reports the size of the index and the last reindex started
+// through the API.
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.status()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) status() (statusResponse, error) {
+	docs, err := s.db.DocumentCount()
+	if err != nil {
+		return statusResponse{}, err
+	}
+	chunks, err := s.db.ChunkCount()
+	if err != nil {
+		return statusResponse{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := statusResponse{Documents: docs, Chunks: chunks, Indexing: s.indexing}
+	if s.lastRun != nil {
+		run := *s.lastRun
+		resp.LastIndex = &run
+	}
+	return resp, nil
+}
+
+// documentResponse is the body of a document request: the note as indexed.
+type documentResponse struct {
+	Path       string          `json:"path"`
+	Title      string          `json:"title"`
+	ModifiedAt time.Time       `json:"modified_at"`
+	IndexedAt  time.Time       `json:"indexed_at"`
+	Tags       []string        `json:"tags"`
+	Metadata   map[string]any  `json:"metadata,omitempty"`
+	Chunks     []documentChunk `json:"chunks"`
+}
+
+type documentChunk struct {
+	ID        int64  `json:"id"`
+	Heading   string `json:"heading,omitempty"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Content   string `json:"content"`
+}
+
+// handleDocument returns the indexed note at the vault-relative path, with
+// its tags, frontmatter and chunks.
+func (s *Server) handleDocument(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.document(r.PathValue("path"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if resp == nil {
+		writeError(w, http.StatusNotFound, "document not indexed")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// document returns the note at path as indexed, or nil if it isn't.
+func (s *Server) document(path string) (*documentResponse, error) {
+	doc, err := s.db.GetDocument(path)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+
+	tags, err := s.db.GetDocumentTags(doc.ID)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := s.db.GetDocumentMetadata(doc.ID)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := s.db.GetChunksForDocument(doc.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &documentResponse{
+		Path:       doc.Path,
+		Title:      doc.Title,
+		ModifiedAt: time.Unix(doc.ModifiedAt, 0),
+		IndexedAt:  time.Unix(doc.IndexedAt, 0),
+		Tags:       tags,
+		Metadata:   metadata,
+		Chunks:     make([]documentChunk, len(chunks)),
+	}
+	if resp.Tags == nil {
+		resp.Tags = []string{}
+	}
+	for i, chunk := range chunks {
+		resp.Chunks[i] = documentChunk{
+			ID:        chunk.ID,
+			Heading:   chunk.Heading,
+			StartLine: chunk.StartLine,
+			EndLine:   chunk.EndLine,
+			Content:   chunk.Content,
+		}
+	}
+	return resp, nil
+}
+
+// errIndexing is returned when a reindex is asked for while one is running.
+var errIndexing = errors.New("a reindex is already running")
+
+// handleIndex starts a reindex in the background: of the notes given as path
+// parameters, or of everything that changed, or everything with full=true.
+// Only one runs at a time; its outcome shows up in the status.
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	run, err := s.startRun(r.URL.Query().Get("full") == "true", r.URL.Query()["path"])
+	switch {
+	case errors.Is(err, errIndexing):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.finishRun(run, s.reindex(s.ctx, run, nil))
+	}()
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
+
+// startRun records the start of a reindex, unless one is already running.
+func (s *Server) startRun(full bool, paths []string) (*indexRun, error) {
+	if full && len(paths) > 0 {
+		return nil, errors.New("full and path can't be combined")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.indexing {
+		return nil, errIndexing
+	}
+	s.indexing = true
+	s.lastRun = &indexRun{Full: full, Paths: paths, StartedAt: time.Now()}
+	return s.lastRun, nil
+}
+
+// finishRun records the outcome of a reindex started with startRun.
+func (s *Server) finishRun(run *indexRun, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.indexing = false
+	run.FinishedAt = time.Now()
+	if err != nil {
+		run.Error = err.Error()
+	}
+}
+
+func (s *Server) reindex(ctx context.Context, run *indexRun, progress indexer.ProgressFunc) error {
+	var err error
+	if len(run.Paths) > 0 {
+		err = s.indexer.IndexFiles(ctx, run.Paths)
+	} else {
+		err = s.indexer.Index(ctx, run.Full, progress)
+	}
+
+	var skippedErr *indexer.SkippedFilesError
+	if errors.As(err, &skippedErr) {
+		for _, file := range skippedErr.Files {
+			slog.Warn("skipped file", "path", file.Path, "error", file.Err)
+		}
+	} else if err != nil {
+		slog.Error("indexing failed", "error", err)
+	}
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("writing response failed", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// statusRecorder remembers the status code written, for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets the event stream hijack the connection underneath.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mgomes/obsvec/internal/config"
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+	"github.com/mgomes/obsvec/pkg/obsvec/indexer"
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+func newTestServer(t *testing.T, vault string) *Server {
+	t.Helper()
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"), 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	s := New(database, search.New(database, nil), indexer.New(database, nil, vault), search.Options{})
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newRequest returns a request as a local client sends it.
+func newRequest(method, target string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.Host = "127.0.0.1:7700"
+	return req
+}
+
+func TestServer_Requests(t *testing.T) {
+	handler := newTestServer(t, t.TempDir()).Handler()
+
+	tests := []struct {
+		method, target string
+		status         int
+		body           string
+	}{
+		{"GET", "/api/health", http.StatusOK, `"status":"ok"`},
+		{"GET", "/api/search", http.StatusBadRequest, "missing query"},
+		{"GET", "/api/search?q=taxes&n=zero", http.StatusBadRequest, "positive number"},
+		{"GET", "/api/pane/search", http.StatusBadRequest, "missing query"},
+		{"GET", "/api/shortcut", http.StatusBadRequest, "missing query"},
+		{"GET", "/api/shortcut?q=taxes&n=-1", http.StatusBadRequest, "positive number"},
+		{"POST", "/api/search?q=taxes", http.StatusMethodNotAllowed, ""},
+		{"GET", "/api/status", http.StatusOK, `"documents":0`},
+		{"GET", "/api/documents/missing.md", http.StatusNotFound, "not indexed"},
+		{"POST", "/api/index?full=true&path=a.md", http.StatusBadRequest, "can't be combined"},
+		{"GET", "/api/index", http.StatusMethodNotAllowed, ""},
+		{"GET", "/api/events", http.StatusNotFound, "no event stream"},
+		{"GET", "/api/nothing", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newRequest(tt.method, tt.target))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.target, tt.status, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("%s %s: expected %q in the body, got %q", tt.method, tt.target, tt.body, rec.Body.String())
+		}
+	}
+}
+
+func TestServer_IndexAndFetch(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(vault, "Projects"), 0755); err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "Projects", "Plan.md"), []byte("---\ntags: [work]\n---\n# Plan\n"), 0644); err != nil {
+		t.Fatalf("failed to write note: %v", err)
+	}
+
+	s := newTestServer(t, vault)
+	handler := s.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest("POST", "/api/index"))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	s.wg.Wait()
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest("GET", "/api/status"))
+	var status statusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if status.Documents != 1 || status.Indexing || status.LastIndex == nil || status.LastIndex.FinishedAt.IsZero() {
+		t.Errorf("expected one document and a finished run, got %+v", status)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest("GET", "/api/documents/Projects/Plan.md"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var doc documentResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to decode document: %v", err)
+	}
+	if doc.Path != "Projects/Plan.md" || len(doc.Tags) != 1 || doc.Tags[0] != "work" {
+		t.Errorf("expected Projects/Plan.md tagged work, got %+v", doc)
+	}
+}
+
+func TestServer_Protect(t *testing.T) {
+	s := newTestServer(t, t.TempDir())
+	s.SetToken("secret")
+	clientTokens := map[string]string{"phone": config.HashToken("phone-secret")}
+	s.SetClientTokens(func() map[string]string { return clientTokens })
+	s.AllowHost("100.64.0.1")
+	handler := s.Handler()
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		host    string
+		headers map[string]string
+		status  int
+	}{
+		{"health needs no token", "GET", "/api/health", "localhost:7700", nil, http.StatusOK},
+		{"docs need no token", "GET", "/docs", "localhost:7700", nil, http.StatusOK},
+		{"missing token", "GET", "/api/status", "localhost:7700", nil, http.StatusUnauthorized},
+		{"wrong token", "GET", "/api/status", "localhost:7700", map[string]string{"Authorization": "Bearer guess"}, http.StatusUnauthorized},
+		{"token", "GET", "/api/status", "localhost:7700", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+		{"client token", "GET", "/api/status", "localhost:7700", map[string]string{"Authorization": "Bearer phone-secret"}, http.StatusOK},
+		{"client token hash", "GET", "/api/status", "localhost:7700", map[string]string{"Authorization": "Bearer " + config.HashToken("phone-secret")}, http.StatusUnauthorized},
+		{"other host", "GET", "/api/health", "attacker.example:7700", nil, http.StatusForbidden},
+		{"allowed host", "GET", "/api/status", "100.64.0.1:7700", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+		{"token parameter", "GET", "/api/shortcut?token=phone-secret", "100.64.0.1:7700", nil, http.StatusBadRequest},
+		{"token parameter elsewhere", "GET", "/api/status?token=secret", "localhost:7700", nil, http.StatusUnauthorized},
+		{"preflight", "OPTIONS", "/api/search", "127.0.0.1:7700", map[string]string{"Origin": "app://obsidian.md"}, http.StatusNoContent},
+		{"preflight from a web page", "OPTIONS", "/api/search", "127.0.0.1:7700", map[string]string{"Origin": "https://example.com"}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Host = tt.host
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+
+			origin := rec.Header().Get("Access-Control-Allow-Origin")
+			if allowed := tt.headers["Origin"] == "app://obsidian.md"; allowed != (origin != "") {
+				t.Errorf("unexpected Access-Control-Allow-Origin %q", origin)
+			}
+		})
+	}
+}
+
+func TestPaneSnippet(t *testing.T) {
+	if got := paneSnippet("line one\n\n  line two"); got != "line one line two" {
+		t.Errorf("expected the snippet on one line, got %q", got)
+	}
+	long := strings.Repeat("a", paneSnippetLen+10)
+	if got := []rune(paneSnippet(long)); len(got) != paneSnippetLen || got[len(got)-1] != '…' {
+		t.Errorf("expected a %d rune snippet ending in an ellipsis, got %d runes", paneSnippetLen, len(got))
+	}
+}
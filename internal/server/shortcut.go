@@ -0,0 +1,90 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// shortcutLimit is how many results a shortcut search returns without n;
+// a phone has room for few.
+const shortcutLimit = 5
+
+// SetNoteURL makes shortcut searches link each result with the URL that
+// noteURL returns for its vault-relative path, heading and start line,
+// such as an obsidian:// one opening the note in the app.
+func (s *Server) SetNoteURL(noteURL func(path, heading string, line int) string) {
+	s.noteURL = noteURL
+}
+
+// shortcutResponse is the body of a shortcut search: flat and small, for
+// Apple Shortcuts and home screen widgets to use without much parsing. Text
+// is every result as plain text, ready to show as it is.
+type shortcutResponse struct {
+	Query   string           `json:"query"`
+	Count   int              `json:"count"`
+	Text    string           `json:"text"`
+	Results []shortcutResult `json:"results"`
+}
+
+type shortcutResult struct {
+	Title   string `json:"title"`
+	Path    string `json:"path"`
+	Heading string `json:"heading,omitempty"`
+	Snippet string `json:"snippet"`
+	URL     string `json:"url,omitempty"`
+}
+
+// handleShortcut runs a search like handleSearch, answering with only what
+// a phone shows of each result: its note, heading, a one-line snippet and a
+// link to open it.
+func (s *Server) handleShortcut(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+
+	opts, err := s.searchOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.URL.Query().Get("n") == "" {
+		opts.Limit = shortcutLimit
+	}
+
+	results, err := s.searcher.Search(r.Context(), query, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := shortcutResponse{Query: query, Count: len(results), Results: []shortcutResult{}}
+	var text []string
+	for _, res := range results {
+		headings := strings.Split(res.Heading, " > ")
+		item := shortcutResult{
+			Title:   strings.TrimSuffix(path.Base(res.Path), ".md"),
+			Path:    res.Path,
+			Heading: strings.TrimSpace(headings[len(headings)-1]),
+			Snippet: paneSnippet(res.Content),
+		}
+		if s.noteURL != nil {
+			item.URL = s.noteURL(res.Path, res.Heading, res.StartLine)
+		}
+		resp.Results = append(resp.Results, item)
+
+		title := item.Title
+		if item.Heading != "" {
+			title += " › " + item.Heading
+		}
+		text = append(text, title+"\n"+item.Snippet)
+	}
+	resp.Text = strings.Join(text, "\n\n")
+	if len(results) == 0 {
+		resp.Text = fmt.Sprintf("No notes match %q", query)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/search"
+)
+
+// ErrNoDaemon is returned by Query when no daemon on the socket can take the
+// query.
+var ErrNoDaemon = errors.New("no daemon running")
+
+// QueryRequest is a search as the CLI runs it, sent to a daemon to run
+// instead. Options are used as given, not merged with the server's.
+type QueryRequest struct {
+	Queries []string       `json:"queries"`
+	Options search.Options `json:"options"`
+
+	// Context is how many neighboring chunks to add around each result.
+	Context int `json:"context,omitempty"`
+}
+
+// handleQuery runs a QueryRequest.
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	var req QueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	if len(req.Queries) == 0 {
+		writeError(w, http.StatusBadRequest, "missing queries")
+		return
+	}
+
+	results, err := s.searcher.SearchMulti(r.Context(), req.Queries, req.Options)
+	if err == nil && req.Context > 0 {
+		err = s.searcher.AddContext(results, req.Context)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if results == nil {
+		results = []search.Result{}
+	}
+	writeJSON(w, http.StatusOK, searchResponse{Query: strings.Join(req.Queries, " | "), Results: results})
+}
+
+// ListenSocket listens on the unix socket at path, readable only by the
+// user. A socket left behind by a daemon that didn't exit cleanly is
+// replaced; one another daemon is still answering on is an error.
+func ListenSocket(path string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("another daemon is listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// Query runs req on the daemon listening on the unix socket at path. It
+// returns ErrNoDaemon when nothing is listening there, or the daemon is too
+// old to take queries or has another API token.
+func Query(ctx context.Context, path, token string, req QueryRequest) ([]search.Result, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost/api/query", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, ErrNoDaemon
+		}
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var sr searchResponse
+		if err := json.NewDecoder(resp.Body).Decode(&sr); err != nil {
+			return nil, fmt.Errorf("invalid daemon response: %w", err)
+		}
+		return sr.Results, nil
+	case http.StatusNotFound, http.StatusUnauthorized:
+		return nil, ErrNoDaemon
+	}
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+		return nil, fmt.Errorf("daemon answered %s", resp.Status)
+	}
+	return nil, errors.New(errResp.Error)
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSocket_Query(t *testing.T) {
+	// Unix socket paths are short-lived and length-limited, so not t.TempDir
+	dir, err := os.MkdirTemp("", "obsvec")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.sock")
+
+	ctx := context.Background()
+	if _, err := Query(ctx, path, "", QueryRequest{Queries: []string{"taxes"}}); !errors.Is(err, ErrNoDaemon) {
+		t.Errorf("expected ErrNoDaemon without a socket, got %v", err)
+	}
+
+	listener, err := ListenSocket(path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := newTestServer(t, t.TempDir())
+	s.SetToken("secret")
+	srv := &http.Server{Handler: s.Handler()}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected the socket to be private, got %v, %v", info, err)
+	}
+	if _, err := ListenSocket(path); err == nil {
+		t.Error("expected a second listener on a live socket to fail")
+	}
+
+	if _, err := Query(ctx, path, "wrong", QueryRequest{Queries: []string{"taxes"}}); !errors.Is(err, ErrNoDaemon) {
+		t.Errorf("expected ErrNoDaemon with the wrong token, got %v", err)
+	}
+	_, err = Query(ctx, path, "secret", QueryRequest{})
+	if err == nil || err.Error() != "missing queries" {
+		t.Errorf("expected the daemon's error, got %v", err)
+	}
+}
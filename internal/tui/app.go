@@ -1,67 +1,294 @@
 package tui
 
 import (
+	"cmp"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mgomes/obsvec/internal/redact"
 )
 
+// summaryWidth is the widest the result summary is wrapped to.
+const summaryWidth = 80
+
 type SearchModel struct {
 	query    string
 	results  []SearchResult
+	summary  string
+	groups   []resultGroup
 	selected int
 	error    string
 	width    int
 	height   int
 	vaultDir string
+	redact   bool
+	grouped  bool
+	context  bool
+	keys     KeyMap
+
+	// editor opens results with e, and with enter too when editorFirst is
+	// set.
+	editor      string
+	editorFirst bool
+
+	// status reports the last copy or editor action until the next key,
+	// as an error when statusErr is set.
+	status    string
+	statusErr bool
+
+	// live searches as the query is typed into queryInput, which has focus
+	// while typing is set. searchSeq numbers edits so stale results are
+	// dropped.
+	live       LiveSearchFunc
+	queryInput textinput.Model
+	typing     bool
+	searchSeq  int
+	searching  bool
+
+	// preview shows the selected note beside a compact list, rendered as
+	// markdown and scrolled to the hit.
+	preview     bool
+	previewPane viewport.Model
+
+	// menu shows the actions for the selected note, and fullChunk its whole
+	// text in chunkPane. exclude, when set, adds an action hiding the note
+	// from future searches.
+	menu         bool
+	menuSelected int
+	fullChunk    bool
+	chunkPane    viewport.Model
+	exclude      ExcludeFunc
+
+	// filterInput narrows the current results locally; filtering is true
+	// while it has focus.
+	filterInput textinput.Model
+	filtering   bool
+}
+
+// resultGroup is one selectable entry in the list. Ungrouped results each get
+// their own group.
+type resultGroup struct {
+	Path  string
+	Score float64
+	Hits  []SearchResult
 }
 
 func NewSearchModel(query, vaultDir string) SearchModel {
+	filterInput := textinput.New()
+	filterInput.Prompt = "/"
+	filterInput.Placeholder = "filter results"
+
 	return SearchModel{
-		query:    query,
-		vaultDir: vaultDir,
+		query:       query,
+		vaultDir:    vaultDir,
+		keys:        DefaultKeyMap(),
+		filterInput: filterInput,
+		previewPane: viewport.New(0, 0),
+		chunkPane:   viewport.New(0, 0),
 	}
 }
 
+// WithRedaction hides note paths and all but the first snippet line so results
+// can be shown on screen without leaking note contents.
+func (m SearchModel) WithRedaction() SearchModel {
+	m.redact = true
+	return m
+}
+
+// WithGrouping shows one entry per note with its chunk hits nested below.
+func (m SearchModel) WithGrouping() SearchModel {
+	m.grouped = true
+	return m
+}
+
+// WithContext starts with neighboring chunks shown around each hit. The c key
+// toggles them either way.
+func (m SearchModel) WithContext() SearchModel {
+	m.context = true
+	return m
+}
+
+// WithEditor opens the selected result at its line in editor, a command such
+// as "nvim", with the e key, and with enter instead of Obsidian if onEnter is
+// set.
+func (m SearchModel) WithEditor(editor string, onEnter bool) SearchModel {
+	m.editor = editor
+	m.editorFirst = onEnter && editor != ""
+	return m
+}
+
+// WithKeyMap replaces the keys of the remappable actions.
+func (m SearchModel) WithKeyMap(keys KeyMap) SearchModel {
+	m.keys = keys
+	return m
+}
+
 func (m SearchModel) Init() tea.Cmd {
+	if m.live != nil {
+		return textinput.Blink
+	}
 	return nil
 }
 
 func (m SearchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "q":
+		key := msg.String()
+		if m.typing && !m.keys.navigation(key) {
+			return m.updateQuery(msg)
+		}
+		if m.filtering {
+			return m.updateFilter(msg)
+		}
+		m.status = ""
+		if m.menu {
+			return m.updateMenu(msg)
+		}
+		if m.fullChunk {
+			return m.updateFullChunk(msg)
+		}
+		hasSelection := len(m.groups) > 0 && m.selected < len(m.groups)
+
+		// Remapped keys take precedence over the fixed ones below
+		switch {
+		case key == "ctrl+c" || slices.Contains(m.keys.Quit, key):
 			return m, tea.Quit
 
-		case "up", "k":
+		case slices.Contains(m.keys.Up, key):
 			if m.selected > 0 {
 				m.selected--
+				return m, m.loadPreview()
 			}
 
-		case "down", "j":
-			if m.selected < len(m.results)-1 {
+		case slices.Contains(m.keys.Down, key):
+			if m.selected < len(m.groups)-1 {
 				m.selected++
+				return m, m.loadPreview()
+			}
+
+		case slices.Contains(m.keys.Open, key):
+			if hasSelection {
+				if m.editorFirst {
+					return m, m.editSelected()
+				}
+				hit := m.groups[m.selected].Hits[0]
+				OpenInObsidian(m.vaultDir, hit.Path, hit.Heading, hit.StartLine)
+			}
+
+		case slices.Contains(m.keys.Edit, key):
+			if hasSelection {
+				return m, m.editSelected()
+			}
+
+		case slices.Contains(m.keys.CopyPath, key):
+			if hasSelection {
+				return m, m.copySelected("path")
+			}
+
+		case slices.Contains(m.keys.CopyLink, key):
+			if hasSelection {
+				return m, m.copySelected("link")
+			}
+
+		case slices.Contains(m.keys.CopyText, key):
+			if hasSelection {
+				return m, m.copySelected("text")
+			}
+
+		case key == " ":
+			if hasSelection {
+				m.menu = true
+				m.menuSelected = 0
+			}
+
+		case key == "tab":
+			return m.togglePreview()
+
+		case key == "i":
+			if m.live != nil {
+				m.typing = true
+				return m, m.queryInput.Focus()
+			}
+
+		case key == "pgdown" || key == "ctrl+d":
+			if m.preview {
+				m.previewPane.HalfPageDown()
 			}
 
-		case "enter":
-			if len(m.results) > 0 && m.selected < len(m.results) {
-				result := m.results[m.selected]
-				openInObsidian(m.vaultDir, result.Path)
+		case key == "pgup" || key == "ctrl+u":
+			if m.preview {
+				m.previewPane.HalfPageUp()
 			}
+
+		case key == "/":
+			m.filtering = true
+			return m, m.filterInput.Focus()
+
+		case key == "c":
+			m.context = !m.context
+
+		case key == "esc":
+			if m.filterInput.Value() != "" {
+				m.filterInput.SetValue("")
+				m.applyFilter()
+				return m, m.loadPreview()
+			}
+			if m.live != nil {
+				m.typing = true
+				return m, m.queryInput.Focus()
+			}
+		}
+
+	case liveQueryMsg:
+		return m.runLiveQuery(msg)
+
+	case liveResultsMsg:
+		return m.showLiveResults(msg)
+
+	case copiedMsg:
+		m.status, m.statusErr = "copied "+msg.what, false
+		if msg.err != nil {
+			m.status, m.statusErr = fmt.Sprintf("copy %s: %v", msg.what, msg.err), true
 		}
 
+	case excludedMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = fmt.Sprintf("exclude %s: %v", msg.path, msg.err), true
+			return m, nil
+		}
+		m.status, m.statusErr = "excluded "+msg.path+" from future results", false
+		m.removeResults(msg.path)
+		return m, m.loadPreview()
+
+	case editorFinishedMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = msg.err.Error(), true
+		}
+
+	case previewMsg:
+		m = m.showPreview(msg)
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		return m, m.loadPreview()
 
 	case SearchResultsMsg:
 		m.results = msg.Results
-		m.selected = 0
+		m.summary = msg.Summary
+		m.applyFilter()
+		return m, m.loadPreview()
 
 	case SearchErrorMsg:
 		m.error = msg.Error
@@ -70,24 +297,223 @@ func (m SearchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// updateFilter handles keys while the filter input has focus. Enter keeps the
+// filter and returns to navigation; esc clears it.
+func (m SearchModel) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "enter":
+		m.filtering = false
+		m.filterInput.Blur()
+		return m, nil
+
+	case "esc":
+		m.filtering = false
+		m.filterInput.Blur()
+		m.filterInput.SetValue("")
+		m.applyFilter()
+		return m, m.loadPreview()
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	m.applyFilter()
+	return m, tea.Batch(cmd, m.loadPreview())
+}
+
+// togglePreview shows or hides the preview pane.
+func (m SearchModel) togglePreview() (tea.Model, tea.Cmd) {
+	m.preview = !m.preview
+	return m, m.loadPreview()
+}
+
+// editSelected opens the selected note in the editor at its first hit.
+func (m SearchModel) editSelected() tea.Cmd {
+	group := m.groups[m.selected]
+	return openInEditor(m.editor, m.vaultDir, group.Path, group.Hits[0].StartLine)
+}
+
+// copySelected copies the selected note's path, a wikilink to its first hit,
+// or that hit's text, for what "path", "link" or "text".
+func (m SearchModel) copySelected(what string) tea.Cmd {
+	group := m.groups[m.selected]
+	hit := group.Hits[0]
+	switch what {
+	case "path":
+		return copyToClipboard("path", group.Path)
+	case "link":
+		return copyToClipboard("link", Wikilink(group.Path, hit.Heading))
+	}
+	return copyToClipboard("text", hit.Snippet)
+}
+
+func (m *SearchModel) applyFilter() {
+	m.groups = groupResults(filterResults(m.results, m.filterInput.Value()), m.grouped)
+	m.selected = 0
+}
+
+// filterResults keeps results whose path, heading or snippet contains every
+// word of filter, ignoring case. Order is preserved.
+func filterResults(results []SearchResult, filter string) []SearchResult {
+	terms := strings.Fields(strings.ToLower(filter))
+	if len(terms) == 0 {
+		return results
+	}
+
+	var kept []SearchResult
+	for _, r := range results {
+		haystack := strings.ToLower(r.Path + "\n" + r.Heading + "\n" + r.Snippet)
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(haystack, term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// groupResults buckets results by note path in rank order, or wraps each
+// result in its own group when grouping is off.
+func groupResults(results []SearchResult, byDocument bool) []resultGroup {
+	var groups []resultGroup
+	byPath := make(map[string]int)
+	for _, r := range results {
+		if i, ok := byPath[r.Path]; ok && byDocument {
+			groups[i].Hits = append(groups[i].Hits, r)
+			groups[i].Score = max(groups[i].Score, r.Score)
+			continue
+		}
+		byPath[r.Path] = len(groups)
+		groups = append(groups, resultGroup{Path: r.Path, Score: r.Score, Hits: []SearchResult{r}})
+	}
+	return groups
+}
+
 func (m SearchModel) View() string {
 	var b strings.Builder
 
 	b.WriteString(titleStyle.Render("ofind") + " ")
-	b.WriteString(dimStyle.Render("\""+m.query+"\"") + "\n\n")
+	if m.live != nil {
+		b.WriteString(m.queryInput.View())
+		if m.searching {
+			b.WriteString(dimStyle.Render("  searching..."))
+		}
+		b.WriteString("\n\n")
+	} else {
+		b.WriteString(dimStyle.Render("\""+m.query+"\"") + "\n\n")
+	}
 
 	if m.error != "" {
 		b.WriteString(errorStyle.Render("Error: "+m.error) + "\n")
 		return b.String()
 	}
 
-	if len(m.results) == 0 {
+	if m.summary != "" {
+		summary := m.summary
+		if m.redact {
+			summary = redact.Snippet(summary)
+		}
+		width := summaryWidth
+		if m.width > 0 {
+			width = min(width, m.width)
+		}
+		b.WriteString(lipgloss.NewStyle().Width(width).Render(summary) + "\n\n")
+	}
+
+	if m.filtering || m.filterInput.Value() != "" {
+		b.WriteString(m.filterInput.View() + "\n\n")
+	}
+
+	if len(m.groups) == 0 {
+		if len(m.results) > 0 {
+			b.WriteString(dimStyle.Render("No results match the filter") + "\n")
+			b.WriteString("\n" + helpStyle.Render("esc clear filter  "+m.quitHelp()))
+			return b.String()
+		}
+		if m.live != nil {
+			if m.query != "" && !m.searching {
+				b.WriteString(dimStyle.Render("No results found") + "\n")
+			}
+			if m.statusErr {
+				b.WriteString(errorStyle.Render(m.status) + "\n")
+			}
+			b.WriteString("\n" + helpStyle.Render("type to search  esc quit"))
+			return b.String()
+		}
 		b.WriteString(dimStyle.Render("No results found") + "\n")
-		b.WriteString("\n" + helpStyle.Render("q quit"))
+		b.WriteString("\n" + helpStyle.Render(m.quitHelp()))
+		return b.String()
+	}
+
+	switch {
+	case m.menu:
+		m.viewMenu(&b)
 		return b.String()
+	case m.fullChunk:
+		m.viewFullChunk(&b)
+		return b.String()
+	case m.preview:
+		b.WriteString(m.viewSplit() + "\n\n")
+	default:
+		m.renderList(&b)
+	}
+
+	switch {
+	case m.statusErr && m.status != "":
+		b.WriteString(errorStyle.Render(m.status) + "\n")
+	case m.status != "":
+		b.WriteString(helpStyle.Render(m.status) + "\n")
+	}
+
+	open := keyName(m.keys.Open) + " open in Obsidian  " + keyName(m.keys.Edit) + " edit"
+	if m.editorFirst {
+		open = keyName(m.keys.Open) + " edit"
+	}
+	keys := []string{keyName(m.keys.Up) + "/" + keyName(m.keys.Down) + " navigate", open}
+	switch {
+	case m.typing:
+		keys = append(keys, "tab preview", "esc more keys")
+	case m.filtering:
+		keys = []string{"enter apply filter", "esc clear filter"}
+	default:
+		if m.preview {
+			keys = append(keys, "pgup/pgdn scroll")
+		}
+		keys = append(keys, "tab preview")
+		if m.filterInput.Value() != "" {
+			keys = append(keys, "/ edit filter", "esc clear filter")
+		} else {
+			keys = append(keys, "/ filter")
+		}
+		if !m.preview {
+			keys = append(keys, "c context")
+		}
+		keys = append(keys, keyName(m.keys.CopyPath)+"/"+keyName(m.keys.CopyLink)+"/"+keyName(m.keys.CopyText)+" copy path/link/text", "space actions")
+		if m.live != nil {
+			keys = append(keys, "i edit query")
+		}
+		keys = append(keys, m.quitHelp())
 	}
+	b.WriteString(helpStyle.Render(strings.Join(keys, "  ")))
+
+	return b.String()
+}
 
-	for i, result := range m.results {
+// quitHelp describes the quit key for the help line.
+func (m SearchModel) quitHelp() string {
+	return cmp.Or(keyName(m.keys.Quit), "ctrl+c") + " quit"
+}
+
+// renderList renders each result with its heading and snippet.
+func (m SearchModel) renderList(b *strings.Builder) {
+	for i, group := range m.groups {
 		isSelected := i == m.selected
 
 		var line strings.Builder
@@ -98,27 +524,78 @@ func (m SearchModel) View() string {
 			line.WriteString("  ")
 		}
 
-		scoreStr := fmt.Sprintf("[%.2f]", result.Score)
+		scoreStr := fmt.Sprintf("[%.2f]", group.Score)
 		line.WriteString(scoreStyle.Render(scoreStr) + " ")
 
-		line.WriteString(pathStyle.Render(result.Path))
-		b.WriteString(line.String() + "\n")
+		path := group.Path
+		if m.redact {
+			path = redact.Path(path)
+		}
+		line.WriteString(pathStyle.Render(path))
 
-		indent := "    "
-		if result.Heading != "" {
-			b.WriteString(indent + headingStyle.Render(result.Heading) + "\n")
+		if m.grouped && len(group.Hits) > 1 {
+			line.WriteString(dimStyle.Render(fmt.Sprintf(" (%d hits)", len(group.Hits))))
 		}
+		if from := group.Hits[0].LinkedFrom; from != "" {
+			if m.redact {
+				from = redact.Path(from)
+			}
+			line.WriteString(dimStyle.Render(" ← linked from " + from))
+		}
+		b.WriteString(line.String() + "\n")
 
-		snippetLines := wrapText(result.Snippet, 76, 3)
-		for _, line := range snippetLines {
-			b.WriteString(indent + snippetStyle.Render(line) + "\n")
+		for _, hit := range group.Hits {
+			indent := "    "
+			if m.grouped {
+				indent = "      "
+				if len(group.Hits) > 1 {
+					b.WriteString("    " + scoreStyle.Render(fmt.Sprintf("[%.2f]", hit.Score)) + "\n")
+				}
+			}
+			m.renderHit(b, hit, indent)
 		}
 		b.WriteString("\n")
 	}
+}
 
-	b.WriteString(helpStyle.Render("↑/↓ navigate  enter open in Obsidian  q quit"))
+func (m SearchModel) renderHit(b *strings.Builder, hit SearchResult, indent string) {
+	if hit.Heading != "" {
+		b.WriteString(indent + headingStyle.Render(hit.Heading) + "\n")
+	}
+	if hit.Explain != "" {
+		b.WriteString(indent + scoreStyle.Render(hit.Explain) + "\n")
+	}
 
-	return b.String()
+	if m.context {
+		for _, before := range hit.Before {
+			m.renderContext(b, before, indent)
+		}
+	}
+
+	snippet := hit.Snippet
+	if m.redact {
+		snippet = redact.Snippet(snippet)
+	}
+
+	snippetLines := wrapText(snippet, 76, 3)
+	for _, line := range snippetLines {
+		b.WriteString(indent + snippetStyle.Render(line) + "\n")
+	}
+
+	if m.context {
+		for _, after := range hit.After {
+			m.renderContext(b, after, indent)
+		}
+	}
+}
+
+func (m SearchModel) renderContext(b *strings.Builder, snippet, indent string) {
+	if m.redact {
+		snippet = redact.Snippet(snippet)
+	}
+	for _, line := range wrapText(snippet, 74, 2) {
+		b.WriteString(indent + dimStyle.Render("│ "+line) + "\n")
+	}
 }
 
 func wrapText(s string, width, maxLines int) []string {
@@ -168,24 +645,62 @@ func normalizeWhitespace(s string) string {
 	return strings.Join(fields, " ")
 }
 
-func openInObsidian(vaultDir, filePath string) {
-	vaultName := filepath.Base(vaultDir)
-
-	filePathWithoutExt := strings.TrimSuffix(filePath, ".md")
-
-	url := fmt.Sprintf("obsidian://open?vault=%s&file=%s", vaultName, filePathWithoutExt)
+// OpenInObsidian opens a vault-relative note in the Obsidian app, at heading
+// or line when given.
+func OpenInObsidian(vaultDir, filePath, heading string, line int) {
+	uri := ObsidianURL(vaultDir, filePath, heading, line)
 
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
 	case "darwin":
-		cmd = exec.Command("open", url)
+		cmd = exec.Command("open", uri)
 	case "linux":
-		cmd = exec.Command("xdg-open", url)
+		cmd = exec.Command("xdg-open", uri)
 	case "windows":
-		cmd = exec.Command("cmd", "/c", "start", url)
+		cmd = exec.Command("cmd", "/c", "start", uri)
 	}
 
 	if cmd != nil {
 		_ = cmd.Start()
 	}
 }
+
+// advancedURIPlugin is the ID of the Advanced URI community plugin, which can
+// open a note at a line.
+const advancedURIPlugin = "obsidian-advanced-uri"
+
+// ObsidianURL returns the URI opening a vault-relative note. A line, counted
+// from 1, is used when the vault has the Advanced URI plugin enabled;
+// otherwise the note opens at the innermost heading of a "A > B" breadcrumb.
+func ObsidianURL(vaultDir, filePath, heading string, line int) string {
+	vaultName := uriEscape(filepath.Base(vaultDir))
+
+	if line > 0 && pluginEnabled(vaultDir, advancedURIPlugin) {
+		return fmt.Sprintf("obsidian://advanced-uri?vault=%s&filepath=%s&line=%d", vaultName, uriEscape(filePath), line)
+	}
+
+	target := strings.TrimSuffix(filePath, ".md")
+	if heading != "" {
+		target += "#" + innermostHeading(heading)
+	}
+	return fmt.Sprintf("obsidian://open?vault=%s&file=%s", vaultName, uriEscape(target))
+}
+
+// pluginEnabled reports whether the vault's community-plugins.json lists id.
+func pluginEnabled(vaultDir, id string) bool {
+	data, err := os.ReadFile(filepath.Join(vaultDir, ".obsidian", "community-plugins.json"))
+	if err != nil {
+		return false
+	}
+	var enabled []string
+	if err := json.Unmarshal(data, &enabled); err != nil {
+		return false
+	}
+	return slices.Contains(enabled, id)
+}
+
+// uriEscape escapes s as a URI query value, with spaces as %20 since
+// Obsidian doesn't decode +.
+func uriEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -1,8 +1,12 @@
 package tui
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 func TestWrapText_ShortText(t *testing.T) {
@@ -78,3 +82,101 @@ func TestWrapText_WhitespaceCollapsed(t *testing.T) {
 		t.Errorf("expected whitespace to be collapsed, got '%s'", lines[0])
 	}
 }
+
+func TestGroupResults(t *testing.T) {
+	results := []SearchResult{
+		{Score: 0.9, Path: "a.md", ChunkID: 1},
+		{Score: 0.8, Path: "b.md", ChunkID: 2},
+		{Score: 0.7, Path: "a.md", ChunkID: 3},
+	}
+
+	groups := groupResults(results, true)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if len(groups[0].Hits) != 2 || groups[0].Score != 0.9 {
+		t.Errorf("expected a.md with 2 hits and best score 0.9, got %d hits, %v", len(groups[0].Hits), groups[0].Score)
+	}
+
+	ungrouped := groupResults(results, false)
+	if len(ungrouped) != 3 {
+		t.Errorf("expected one entry per result when ungrouped, got %d", len(ungrouped))
+	}
+}
+
+func TestFilterResults(t *testing.T) {
+	results := []SearchResult{
+		{Path: "Projects/Kubernetes.md", Heading: "Helm", Snippet: "Chart values"},
+		{Path: "Recipes/Bread.md", Snippet: "Kubernetes of dough"},
+		{Path: "Daily/2024-01-01.md", Snippet: "Nothing here"},
+	}
+
+	kept := filterResults(results, "KUBERNETES")
+	if len(kept) != 2 || kept[0].Path != "Projects/Kubernetes.md" || kept[1].Path != "Recipes/Bread.md" {
+		t.Errorf("expected both kubernetes results in order, got %v", kept)
+	}
+
+	kept = filterResults(results, "kubernetes helm")
+	if len(kept) != 1 || kept[0].Path != "Projects/Kubernetes.md" {
+		t.Errorf("expected all terms to be required, got %v", kept)
+	}
+
+	if kept := filterResults(results, "  "); len(kept) != 3 {
+		t.Errorf("expected an empty filter to keep everything, got %d", len(kept))
+	}
+}
+
+func TestSearchModel_FilterKeys(t *testing.T) {
+	model := NewSearchModel("query", "/vault")
+	updated, _ := model.Update(SearchResultsMsg{Results: []SearchResult{
+		{Path: "a.md", Snippet: "alpha"},
+		{Path: "b.md", Snippet: "beta"},
+	}})
+
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("/")},
+		{Type: tea.KeyRunes, Runes: []rune("beta")},
+		{Type: tea.KeyEnter},
+	} {
+		updated, _ = updated.Update(key)
+	}
+
+	m := updated.(SearchModel)
+	if m.filtering {
+		t.Error("expected enter to leave filter mode")
+	}
+	if len(m.groups) != 1 || m.groups[0].Path != "b.md" {
+		t.Errorf("expected only b.md after filtering, got %v", m.groups)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m := updated.(SearchModel); len(m.groups) != 2 {
+		t.Errorf("expected esc to clear the filter, got %d groups", len(m.groups))
+	}
+}
+
+func TestObsidianURL(t *testing.T) {
+	vault := filepath.Join(t.TempDir(), "My Vault")
+	if err := os.MkdirAll(filepath.Join(vault, ".obsidian"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := ObsidianURL(vault, "notes/Tax & Co.md", "2024 > Deductions", 12)
+	want := "obsidian://open?vault=My%20Vault&file=notes%2FTax%20%26%20Co%23Deductions"
+	if got != want {
+		t.Errorf("expected a heading link without Advanced URI, got %q", got)
+	}
+
+	plugins := `["dataview", "obsidian-advanced-uri"]`
+	if err := os.WriteFile(filepath.Join(vault, ".obsidian", "community-plugins.json"), []byte(plugins), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got = ObsidianURL(vault, "notes/Tax.md", "Deductions", 12)
+	want = "obsidian://advanced-uri?vault=My%20Vault&filepath=notes%2FTax.md&line=12"
+	if got != want {
+		t.Errorf("expected a line link with Advanced URI, got %q", got)
+	}
+	if got := ObsidianURL(vault, "notes/Tax.md", "", 0); got != "obsidian://open?vault=My%20Vault&file=notes%2FTax" {
+		t.Errorf("expected a plain link without a line, got %q", got)
+	}
+}
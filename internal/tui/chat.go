@@ -0,0 +1,232 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mgomes/obsvec/internal/redact"
+)
+
+// chatWidth is the widest answers are wrapped to when the terminal is wider.
+const chatWidth = 80
+
+type ChatModel struct {
+	input    textinput.Model
+	turns    []chatTurn
+	waiting  bool
+	width    int
+	height   int
+	vaultDir string
+	redact   bool
+
+	// browsing is true while the sources of the last answer have focus
+	// instead of the input; selected is the highlighted source.
+	browsing bool
+	selected int
+}
+
+type chatTurn struct {
+	question string
+	answer   string
+	sources  []ChatSource
+	error    string
+}
+
+func NewChatModel(vaultDir string) ChatModel {
+	input := textinput.New()
+	input.Prompt = "› "
+	input.Placeholder = "Ask about your notes..."
+	input.Focus()
+
+	return ChatModel{
+		input:    input,
+		vaultDir: vaultDir,
+	}
+}
+
+// WithRedaction hides the paths of cited notes.
+func (m ChatModel) WithRedaction() ChatModel {
+	m.redact = true
+	return m
+}
+
+func (m ChatModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.browsing {
+			return m.updateSources(msg)
+		}
+
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			return m, tea.Quit
+
+		case "tab":
+			if len(m.lastSources()) > 0 {
+				m.browsing = true
+				m.selected = 0
+				m.input.Blur()
+			}
+			return m, nil
+
+		case "enter":
+			question := strings.TrimSpace(m.input.Value())
+			if question == "" || m.waiting {
+				return m, nil
+			}
+			m.input.SetValue("")
+			m.turns = append(m.turns, chatTurn{question: question})
+			m.waiting = true
+			return m, func() tea.Msg {
+				return ChatSubmitMsg{Question: question}
+			}
+		}
+
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case ChatAnswerMsg:
+		if len(m.turns) > 0 {
+			turn := &m.turns[len(m.turns)-1]
+			turn.answer = msg.Text
+			turn.sources = msg.Sources
+		}
+		m.waiting = false
+
+	case ChatErrorMsg:
+		if len(m.turns) > 0 {
+			m.turns[len(m.turns)-1].error = msg.Error
+		}
+		m.waiting = false
+	}
+
+	return m, nil
+}
+
+// updateSources handles keys while the last answer's sources have focus.
+// Digits open a source directly by its footnote number.
+func (m ChatModel) updateSources(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	sources := m.lastSources()
+
+	switch key := msg.String(); key {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "tab", "esc":
+		m.browsing = false
+		return m, m.input.Focus()
+
+	case "up", "k":
+		if m.selected > 0 {
+			m.selected--
+		}
+
+	case "down", "j":
+		if m.selected < len(sources)-1 {
+			m.selected++
+		}
+
+	case "enter":
+		if m.selected < len(sources) {
+			OpenInObsidian(m.vaultDir, sources[m.selected].Path, sources[m.selected].Heading, 0)
+		}
+
+	default:
+		if len(key) == 1 && key[0] >= '1' && key[0] <= '9' {
+			n := int(key[0] - '0')
+			for i, src := range sources {
+				if src.Number == n {
+					m.selected = i
+					OpenInObsidian(m.vaultDir, src.Path, src.Heading, 0)
+				}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// lastSources returns the sources of the most recent answer.
+func (m ChatModel) lastSources() []ChatSource {
+	if len(m.turns) == 0 {
+		return nil
+	}
+	return m.turns[len(m.turns)-1].sources
+}
+
+func (m ChatModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("ofind chat") + "\n\n")
+
+	width := chatWidth
+	if m.width > 0 {
+		width = min(width, m.width)
+	}
+	answerStyle := lipgloss.NewStyle().Width(width)
+
+	for i, turn := range m.turns {
+		last := i == len(m.turns)-1
+
+		b.WriteString(activeStyle.Render("› "+turn.question) + "\n\n")
+
+		switch {
+		case turn.error != "":
+			b.WriteString(errorStyle.Render("Error: "+turn.error) + "\n\n")
+			continue
+		case turn.answer == "" && last && m.waiting:
+			b.WriteString(dimStyle.Render("Searching your notes...") + "\n\n")
+			continue
+		}
+
+		b.WriteString(answerStyle.Render(turn.answer) + "\n")
+		if len(turn.sources) > 0 {
+			b.WriteString("\n")
+		}
+		for j, src := range turn.sources {
+			line := fmt.Sprintf("[%d] %s", src.Number, m.sourcePath(src))
+			if src.Heading != "" {
+				line += " (" + src.Heading + ")"
+			}
+			if last && m.browsing && j == m.selected {
+				b.WriteString(selectedStyle.Render("> "+line) + "\n")
+			} else {
+				b.WriteString(dimStyle.Render("  "+line) + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	if m.browsing {
+		b.WriteString(helpStyle.Render("↑/↓ navigate  enter or 1-9 open in Obsidian  tab back to chat  ctrl+c quit"))
+		return b.String()
+	}
+
+	b.WriteString(m.input.View() + "\n\n")
+	if len(m.lastSources()) > 0 {
+		b.WriteString(helpStyle.Render("enter ask  tab open cited notes  esc quit"))
+	} else {
+		b.WriteString(helpStyle.Render("enter ask  esc quit"))
+	}
+
+	return b.String()
+}
+
+func (m ChatModel) sourcePath(src ChatSource) string {
+	if m.redact {
+		return redact.Path(src.Path)
+	}
+	return src.Path
+}
@@ -0,0 +1,48 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestChatModel_Turns(t *testing.T) {
+	var updated tea.Model = NewChatModel("/vault")
+
+	updated, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("when is it due?")})
+	updated, cmd := updated.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if cmd == nil {
+		t.Fatal("expected enter to submit the question")
+	}
+	if msg, ok := cmd().(ChatSubmitMsg); !ok || msg.Question != "when is it due?" {
+		t.Errorf("expected ChatSubmitMsg for the question, got %#v", msg)
+	}
+
+	m := updated.(ChatModel)
+	if !m.waiting || m.input.Value() != "" {
+		t.Error("expected the input to clear while waiting for an answer")
+	}
+
+	updated, _ = m.Update(ChatAnswerMsg{
+		Text:    "March 2026[1].",
+		Sources: []ChatSource{{Number: 1, Path: "Passport.md"}},
+	})
+	updated, _ = updated.Update(tea.KeyMsg{Type: tea.KeyTab})
+
+	m = updated.(ChatModel)
+	if m.waiting {
+		t.Error("expected the answer to end waiting")
+	}
+	if len(m.turns) != 1 || m.turns[0].answer != "March 2026[1]." {
+		t.Errorf("expected the answer on the first turn, got %+v", m.turns)
+	}
+	if !m.browsing {
+		t.Error("expected tab to focus the cited notes")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m := updated.(ChatModel); m.browsing {
+		t.Error("expected esc to return to the input")
+	}
+}
@@ -0,0 +1,39 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copiedMsg reports the outcome of copying part of a result.
+type copiedMsg struct {
+	what string
+	err  error
+}
+
+// Wikilink returns an Obsidian link to a vault-relative note, pointing at the
+// innermost heading of a "A > B" breadcrumb when there is one.
+func Wikilink(filePath, heading string) string {
+	target := strings.TrimSuffix(filePath, ".md")
+	if heading == "" {
+		return "[[" + target + "]]"
+	}
+	return fmt.Sprintf("[[%s#%s]]", target, innermostHeading(heading))
+}
+
+// innermostHeading returns the last heading of a "A > B" breadcrumb.
+func innermostHeading(heading string) string {
+	parts := strings.Split(heading, " > ")
+	return strings.TrimSpace(parts[len(parts)-1])
+}
+
+// copyToClipboard writes text to the system clipboard, describing it as what
+// in the status line.
+func copyToClipboard(what, text string) tea.Cmd {
+	return func() tea.Msg {
+		return copiedMsg{what: what, err: clipboard.WriteAll(text)}
+	}
+}
@@ -0,0 +1,19 @@
+package tui
+
+import "testing"
+
+func TestWikilink(t *testing.T) {
+	tests := []struct {
+		path, heading, want string
+	}{
+		{"notes/Tax.md", "", "[[notes/Tax]]"},
+		{"notes/Tax.md", "2024", "[[notes/Tax#2024]]"},
+		{"notes/Tax.md", "2024 > Deductions", "[[notes/Tax#Deductions]]"},
+	}
+
+	for _, tt := range tests {
+		if got := Wikilink(tt.path, tt.heading); got != tt.want {
+			t.Errorf("Wikilink(%q, %q) = %q, want %q", tt.path, tt.heading, got, tt.want)
+		}
+	}
+}
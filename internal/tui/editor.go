@@ -0,0 +1,61 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editorFinishedMsg is sent when the editor opened from the results exits.
+type editorFinishedMsg struct {
+	err error
+}
+
+// EditorCommand returns the command that opens a vault-relative note in
+// editor at line. editor is a command line such as "nvim" or "code --wait";
+// the line is passed the way the editor expects, +line for vi, nano, emacs
+// and most other terminal editors.
+func EditorCommand(editor, vaultDir, filePath string, line int) (*exec.Cmd, error) {
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no editor configured: set $EDITOR or editor in config.json")
+	}
+	path := filepath.Join(vaultDir, filePath)
+	line = max(line, 1)
+
+	switch strings.TrimSuffix(filepath.Base(args[0]), ".exe") {
+	case "code", "code-insiders", "codium", "cursor":
+		args = append(args, "--goto", fmt.Sprintf("%s:%d", path, line))
+	case "subl", "zed", "hx", "helix":
+		args = append(args, fmt.Sprintf("%s:%d", path, line))
+	default:
+		args = append(args, fmt.Sprintf("+%d", line), path)
+	}
+	return exec.Command(args[0], args[1:]...), nil
+}
+
+// OpenInEditor runs editor on a vault-relative note at line and waits for
+// it to exit, handing it the terminal.
+func OpenInEditor(editor, vaultDir, filePath string, line int) error {
+	cmd, err := EditorCommand(editor, vaultDir, filePath, line)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// openInEditor suspends the TUI while the editor runs.
+func openInEditor(editor, vaultDir, filePath string, line int) tea.Cmd {
+	cmd, err := EditorCommand(editor, vaultDir, filePath, line)
+	if err != nil {
+		return func() tea.Msg { return editorFinishedMsg{err: err} }
+	}
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{err: err}
+	})
+}
@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestEditorCommand(t *testing.T) {
+	path := filepath.Join("/vault", "notes", "a.md")
+	tests := []struct {
+		editor string
+		want   []string
+	}{
+		{"nvim", []string{"nvim", "+12", path}},
+		{"emacs -nw", []string{"emacs", "-nw", "+12", path}},
+		{"code --wait", []string{"code", "--wait", "--goto", path + ":12"}},
+		{"/usr/local/bin/hx", []string{"/usr/local/bin/hx", path + ":12"}},
+	}
+
+	for _, tt := range tests {
+		cmd, err := EditorCommand(tt.editor, "/vault", "notes/a.md", 12)
+		if err != nil {
+			t.Fatalf("EditorCommand(%q) failed: %v", tt.editor, err)
+		}
+		if !slices.Equal(cmd.Args, tt.want) {
+			t.Errorf("EditorCommand(%q) = %q, want %q", tt.editor, cmd.Args, tt.want)
+		}
+	}
+
+	if cmd, _ := EditorCommand("vi", "/vault", "notes/a.md", 0); cmd.Args[1] != "+1" {
+		t.Errorf("expected a missing line to open at +1, got %q", cmd.Args)
+	}
+	if _, err := EditorCommand(" ", "/vault", "notes/a.md", 1); err == nil {
+		t.Error("expected an error without an editor")
+	}
+}
@@ -0,0 +1,95 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// KeyMap binds the remappable actions of the results view to keys, named as
+// bubbletea names them: "k", "ctrl+p", "down", "enter". ctrl+c always quits.
+type KeyMap struct {
+	Up       []string
+	Down     []string
+	Open     []string
+	Edit     []string
+	CopyPath []string
+	CopyLink []string
+	CopyText []string
+	Quit     []string
+}
+
+// DefaultKeyMap returns the keys the results view uses unless remapped.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Up:       []string{"up", "k"},
+		Down:     []string{"down", "j"},
+		Open:     []string{"enter"},
+		Edit:     []string{"e"},
+		CopyPath: []string{"p"},
+		CopyLink: []string{"w"},
+		CopyText: []string{"y"},
+		Quit:     []string{"q"},
+	}
+}
+
+// KeyActions lists the action names Rebind accepts.
+func KeyActions() []string {
+	return []string{"up", "down", "open", "edit", "copy_path", "copy_link", "copy_text", "quit"}
+}
+
+// Rebind replaces the keys of each action in bindings, which maps action
+// names to space-separated keys, e.g. "up": "k ctrl+p". Actions left out keep
+// their keys.
+func (k KeyMap) Rebind(bindings map[string]string) (KeyMap, error) {
+	for action, keys := range bindings {
+		fields := strings.Fields(keys)
+		if len(fields) == 0 {
+			return k, fmt.Errorf("keybindings.%s has no keys", action)
+		}
+		switch action {
+		case "up":
+			k.Up = fields
+		case "down":
+			k.Down = fields
+		case "open":
+			k.Open = fields
+		case "edit":
+			k.Edit = fields
+		case "copy_path":
+			k.CopyPath = fields
+		case "copy_link":
+			k.CopyLink = fields
+		case "copy_text":
+			k.CopyText = fields
+		case "quit":
+			k.Quit = fields
+		default:
+			return k, fmt.Errorf("unknown keybinding action %q (known: %s)", action, strings.Join(KeyActions(), ", "))
+		}
+	}
+	return k, nil
+}
+
+// navigation reports whether key moves or opens the selection without being
+// a printable character, so it also works while the query is being typed.
+func (k KeyMap) navigation(key string) bool {
+	if len([]rune(key)) == 1 {
+		return false
+	}
+	return slices.Contains(k.Up, key) || slices.Contains(k.Down, key) || slices.Contains(k.Open, key)
+}
+
+// keyName returns the first key bound to an action, as shown in the help.
+func keyName(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	switch keys[0] {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	}
+	return keys[0]
+}
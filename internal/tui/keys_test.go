@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestKeyMap_Rebind(t *testing.T) {
+	keys, err := DefaultKeyMap().Rebind(map[string]string{"down": "ctrl+n  n", "quit": "ctrl+g"})
+	if err != nil {
+		t.Fatalf("Rebind failed: %v", err)
+	}
+	if !slices.Equal(keys.Down, []string{"ctrl+n", "n"}) || !slices.Equal(keys.Quit, []string{"ctrl+g"}) {
+		t.Errorf("expected down and quit remapped, got %v and %v", keys.Down, keys.Quit)
+	}
+	if !slices.Equal(keys.Up, DefaultKeyMap().Up) {
+		t.Errorf("expected up to keep its keys, got %v", keys.Up)
+	}
+
+	for _, bindings := range []map[string]string{{"jump": "g"}, {"up": " "}} {
+		if _, err := DefaultKeyMap().Rebind(bindings); err == nil {
+			t.Errorf("expected %v to be rejected", bindings)
+		}
+	}
+}
+
+func TestSearchModel_KeyMap(t *testing.T) {
+	keys, _ := DefaultKeyMap().Rebind(map[string]string{"up": "ctrl+p", "down": "ctrl+n", "quit": "ctrl+g"})
+	var m tea.Model = NewLiveSearchModel("/vault", func(string) ([]SearchResult, error) { return nil, nil }).WithKeyMap(keys)
+	m, _ = m.Update(SearchResultsMsg{Results: []SearchResult{{Path: "a.md"}, {Path: "b.md"}}})
+
+	// Bound keys that aren't characters navigate while typing
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlN})
+	if got := m.(SearchModel).selected; got != 1 {
+		t.Errorf("expected ctrl+n to select the second result, got %d", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	if got := m.(SearchModel).selected; got != 1 {
+		t.Errorf("expected k to be unbound, got selection %d", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	if got := m.(SearchModel).selected; got != 0 {
+		t.Errorf("expected ctrl+p to select the first result, got %d", got)
+	}
+
+	if view := m.View(); !strings.Contains(view, "ctrl+p/ctrl+n navigate") || !strings.Contains(view, "ctrl+g quit") {
+		t.Errorf("expected the help to show the remapped keys:\n%s", view)
+	}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}); cmd != nil {
+		t.Error("expected q to be unbound")
+	}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlG}); cmd == nil {
+		t.Error("expected ctrl+g to quit")
+	}
+}
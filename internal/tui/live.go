@@ -0,0 +1,125 @@
+package tui
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// liveSearchDelay is how long typing must pause before the query is
+// searched.
+const liveSearchDelay = 300 * time.Millisecond
+
+// LiveSearchFunc runs a search for the live search screen. It is called off
+// the UI goroutine.
+type LiveSearchFunc func(query string) ([]SearchResult, error)
+
+// liveQueryMsg fires liveSearchDelay after an edit; seq tells whether the
+// query changed again since.
+type liveQueryMsg struct {
+	seq int
+}
+
+type liveResultsMsg struct {
+	seq     int
+	results []SearchResult
+	err     error
+}
+
+// NewLiveSearchModel returns a results view with a query input at the top.
+// Results refresh through search as the query is typed.
+func NewLiveSearchModel(vaultDir string, search LiveSearchFunc) SearchModel {
+	queryInput := textinput.New()
+	queryInput.Prompt = "› "
+	queryInput.Placeholder = "Search your notes..."
+	queryInput.Focus()
+
+	m := NewSearchModel("", vaultDir)
+	m.live = search
+	m.queryInput = queryInput
+	m.typing = true
+	return m
+}
+
+// updateQuery handles keys while the query input has focus. Esc moves focus
+// to the results so their single-letter keys work, and i or esc brings it
+// back. With no results esc clears the query, or quits when it is empty.
+func (m SearchModel) updateQuery(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "esc":
+		switch {
+		case len(m.groups) > 0:
+			m.typing = false
+			m.queryInput.Blur()
+			return m, nil
+		case m.queryInput.Value() == "":
+			return m, tea.Quit
+		}
+		m.queryInput.SetValue("")
+		return m, m.queueSearch()
+
+	case "tab":
+		return m.togglePreview()
+	}
+
+	before := m.queryInput.Value()
+	var cmd tea.Cmd
+	m.queryInput, cmd = m.queryInput.Update(msg)
+	if m.queryInput.Value() != before {
+		return m, tea.Batch(cmd, m.queueSearch())
+	}
+	return m, cmd
+}
+
+// queueSearch searches for the query once typing pauses.
+func (m *SearchModel) queueSearch() tea.Cmd {
+	m.searchSeq++
+	seq := m.searchSeq
+	return tea.Tick(liveSearchDelay, func(time.Time) tea.Msg {
+		return liveQueryMsg{seq: seq}
+	})
+}
+
+// runLiveQuery starts the search for msg if the query hasn't changed since.
+func (m SearchModel) runLiveQuery(msg liveQueryMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.searchSeq {
+		return m, nil
+	}
+
+	query := strings.TrimSpace(m.queryInput.Value())
+	m.query = query
+	if query == "" {
+		m.results, m.searching = nil, false
+		m.applyFilter()
+		return m, nil
+	}
+
+	m.searching = true
+	search := m.live
+	return m, func() tea.Msg {
+		results, err := search(query)
+		return liveResultsMsg{seq: msg.seq, results: results, err: err}
+	}
+}
+
+// showLiveResults replaces the results unless a newer query has been typed.
+func (m SearchModel) showLiveResults(msg liveResultsMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.searchSeq {
+		return m, nil
+	}
+
+	m.searching = false
+	if msg.err != nil {
+		m.status, m.statusErr = msg.err.Error(), true
+		return m, nil
+	}
+	m.status = ""
+	m.results = msg.results
+	m.applyFilter()
+	return m, m.loadPreview()
+}
@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSearchModel_LiveSearch(t *testing.T) {
+	var searched []string
+	m := NewLiveSearchModel("/vault", func(query string) ([]SearchResult, error) {
+		searched = append(searched, query)
+		return []SearchResult{{Path: query + ".md", Snippet: "found " + query}}, nil
+	})
+
+	update := func(msg tea.Msg) tea.Cmd {
+		t.Helper()
+		updated, cmd := m.Update(msg)
+		m = updated.(SearchModel)
+		return cmd
+	}
+	typeRunes := func(s string) {
+		for _, r := range s {
+			update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+
+	typeRunes("tax")
+	if m.searchSeq != 3 {
+		t.Fatalf("expected each edit to queue a search, got seq %d", m.searchSeq)
+	}
+	if cmd := update(liveQueryMsg{seq: 2}); cmd != nil {
+		t.Error("expected a stale query to be dropped")
+	}
+
+	cmd := update(liveQueryMsg{seq: 3})
+	if cmd == nil || !m.searching {
+		t.Fatal("expected the settled query to be searched")
+	}
+	typeRunes("es")
+	update(cmd())
+	if len(m.results) != 0 {
+		t.Error("expected results for an outdated query to be dropped")
+	}
+
+	cmd = update(liveQueryMsg{seq: m.searchSeq})
+	update(cmd())
+	if len(m.groups) != 1 || m.groups[0].Path != "taxes.md" {
+		t.Fatalf("expected results for the current query, got %+v", m.groups)
+	}
+	if !slices.Equal(searched, []string{"tax", "taxes"}) {
+		t.Errorf("expected searches for tax and taxes, got %q", searched)
+	}
+
+	// q is typed into the query until esc moves focus to the results
+	typeRunes("q")
+	if got := m.queryInput.Value(); got != "taxesq" {
+		t.Errorf("expected q to be typed, got query %q", got)
+	}
+	update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.typing {
+		t.Fatal("expected esc to move focus to the results")
+	}
+	if !strings.Contains(m.View(), "i edit query") {
+		t.Error("expected the help to offer returning to the query")
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")})
+	if !m.typing {
+		t.Error("expected i to return focus to the query")
+	}
+}
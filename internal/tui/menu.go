@@ -0,0 +1,218 @@
+package tui
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mgomes/obsvec/internal/redact"
+)
+
+// ExcludeFunc keeps a vault-relative note out of future results.
+type ExcludeFunc func(path string) error
+
+// menuAction is an entry in the action menu, chosen with enter or its key.
+type menuAction struct {
+	key   string
+	label string
+}
+
+var menuActions = []menuAction{
+	{"o", "Open in Obsidian"},
+	{"e", "Open in editor"},
+	{"r", "Reveal in file manager"},
+	{"l", "Copy link"},
+	{"f", "Show full chunk"},
+	{"x", "Exclude note from future results"},
+}
+
+// excludedMsg reports the outcome of excluding a note.
+type excludedMsg struct {
+	path string
+	err  error
+}
+
+// WithExclude adds an action menu entry that hides the selected note from
+// future searches through exclude.
+func (m SearchModel) WithExclude(exclude ExcludeFunc) SearchModel {
+	m.exclude = exclude
+	return m
+}
+
+// actions returns the menu entries available for this view.
+func (m SearchModel) actions() []menuAction {
+	if m.exclude != nil {
+		return menuActions
+	}
+	return menuActions[:len(menuActions)-1]
+}
+
+// updateMenu handles keys while the action menu is open.
+func (m SearchModel) updateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	actions := m.actions()
+	key := msg.String()
+	switch {
+	case key == "ctrl+c":
+		return m, tea.Quit
+
+	case key == "esc" || key == " " || slices.Contains(m.keys.Quit, key):
+		m.menu = false
+
+	case slices.Contains(m.keys.Up, key):
+		m.menuSelected = (m.menuSelected + len(actions) - 1) % len(actions)
+
+	case slices.Contains(m.keys.Down, key):
+		m.menuSelected = (m.menuSelected + 1) % len(actions)
+
+	case key == "enter":
+		return m.runAction(actions[m.menuSelected].key)
+
+	default:
+		for _, action := range actions {
+			if action.key == key {
+				return m.runAction(key)
+			}
+		}
+	}
+	return m, nil
+}
+
+// runAction closes the menu and applies the action with key to the selected
+// note.
+func (m SearchModel) runAction(key string) (tea.Model, tea.Cmd) {
+	m.menu = false
+	group := m.groups[m.selected]
+	hit := group.Hits[0]
+
+	switch key {
+	case "o":
+		OpenInObsidian(m.vaultDir, hit.Path, hit.Heading, hit.StartLine)
+	case "e":
+		return m, m.editSelected()
+	case "r":
+		if err := RevealInFileManager(m.vaultDir, group.Path); err != nil {
+			m.status, m.statusErr = err.Error(), true
+		}
+	case "l":
+		return m, m.copySelected("link")
+	case "f":
+		m.fullChunk = true
+		m.chunkPane.Width, m.chunkPane.Height = m.chunkSize()
+		m.chunkPane.SetContent(m.chunkText(group))
+		m.chunkPane.GotoTop()
+	case "x":
+		exclude := m.exclude
+		return m, func() tea.Msg {
+			return excludedMsg{path: group.Path, err: exclude(group.Path)}
+		}
+	}
+	return m, nil
+}
+
+// removeResults drops a note's results after it has been excluded.
+func (m *SearchModel) removeResults(path string) {
+	m.results = slices.DeleteFunc(m.results, func(r SearchResult) bool { return r.Path == path })
+	m.applyFilter()
+}
+
+// updateFullChunk handles keys while the full chunk is shown.
+func (m SearchModel) updateFullChunk(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	switch {
+	case key == "ctrl+c":
+		return m, tea.Quit
+	case key == "esc" || key == " " || slices.Contains(m.keys.Quit, key):
+		m.fullChunk = false
+	case slices.Contains(m.keys.Up, key):
+		m.chunkPane.ScrollUp(1)
+	case slices.Contains(m.keys.Down, key):
+		m.chunkPane.ScrollDown(1)
+	case key == "pgup" || key == "ctrl+u":
+		m.chunkPane.HalfPageUp()
+	case key == "pgdown" || key == "ctrl+d":
+		m.chunkPane.HalfPageDown()
+	}
+	return m, nil
+}
+
+// chunkSize returns the size of the full chunk view for the terminal size.
+func (m SearchModel) chunkSize() (int, int) {
+	width := summaryWidth
+	if m.width > 0 {
+		width = min(width, m.width)
+	}
+	height := 20
+	if m.height > 0 {
+		height = max(5, m.height-6)
+	}
+	return width, height
+}
+
+// chunkText returns the full text of a note's hits, under their headings.
+func (m SearchModel) chunkText(group resultGroup) string {
+	width, _ := m.chunkSize()
+	var parts []string
+	for _, hit := range group.Hits {
+		text := hit.Snippet
+		if m.redact {
+			text = redact.Snippet(text)
+		}
+		if hit.Heading != "" {
+			text = headingStyle.Render(hit.Heading) + "\n" + text
+		}
+		parts = append(parts, lipgloss.NewStyle().Width(width).Render(text))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// viewMenu renders the action menu for the selected note.
+func (m SearchModel) viewMenu(b *strings.Builder) {
+	path := m.groups[m.selected].Path
+	if m.redact {
+		path = redact.Path(path)
+	}
+	b.WriteString(pathStyle.Render(path) + "\n\n")
+	for i, action := range m.actions() {
+		marker := "  "
+		if i == m.menuSelected {
+			marker = selectedStyle.Render("> ")
+		}
+		b.WriteString(fmt.Sprintf("%s%s %s\n", marker, scoreStyle.Render(action.key), action.label))
+	}
+	b.WriteString("\n" + helpStyle.Render("enter choose  esc close"))
+}
+
+// viewFullChunk renders the selected note's full chunk text.
+func (m SearchModel) viewFullChunk(b *strings.Builder) {
+	path := m.groups[m.selected].Path
+	if m.redact {
+		path = redact.Path(path)
+	}
+	b.WriteString(pathStyle.Render(path) + "\n\n")
+	b.WriteString(m.chunkPane.View() + "\n\n")
+	b.WriteString(helpStyle.Render("pgup/pgdn scroll  esc back"))
+}
+
+// RevealInFileManager shows a vault-relative note in the system file manager,
+// selected where the platform allows it.
+func RevealInFileManager(vaultDir, filePath string) error {
+	fullPath := filepath.Join(vaultDir, filepath.FromSlash(filePath))
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", "-R", fullPath)
+	case "linux":
+		cmd = exec.Command("xdg-open", filepath.Dir(fullPath))
+	case "windows":
+		cmd = exec.Command("explorer", "/select,"+fullPath)
+	default:
+		return fmt.Errorf("revealing files isn't supported on %s", runtime.GOOS)
+	}
+	return cmd.Start()
+}
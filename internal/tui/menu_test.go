@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSearchModel_ActionMenu(t *testing.T) {
+	var excluded []string
+	long := strings.Repeat("All the receipts are in the shoebox. ", 10) + "The end."
+
+	var m tea.Model = NewSearchModel("taxes", "/vault").WithExclude(func(path string) error {
+		excluded = append(excluded, path)
+		return nil
+	})
+	m, _ = m.Update(SearchResultsMsg{Results: []SearchResult{
+		{Path: "taxes.md", Heading: "Receipts", Snippet: long},
+		{Path: "budget.md", Snippet: "Groceries"},
+	}})
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	if view := m.View(); !strings.Contains(view, "Show full chunk") || !strings.Contains(view, "Exclude note") {
+		t.Fatalf("expected space to open the action menu:\n%s", view)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	if view := m.View(); !strings.Contains(view, "The end.") {
+		t.Errorf("expected the full chunk to be shown:\n%s", view)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.(SearchModel).fullChunk {
+		t.Error("expected esc to return to the results")
+	}
+
+	// Choose the last entry by wrapping around from the top
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected enter to exclude the note")
+	}
+	m, _ = m.Update(cmd())
+	if len(excluded) != 1 || excluded[0] != "taxes.md" {
+		t.Errorf("expected taxes.md to be excluded, got %v", excluded)
+	}
+	sm := m.(SearchModel)
+	if len(sm.groups) != 1 || sm.groups[0].Path != "budget.md" {
+		t.Errorf("expected the excluded note to leave the results, got %+v", sm.groups)
+	}
+	if !strings.Contains(sm.View(), "excluded taxes.md") {
+		t.Errorf("expected a status line:\n%s", sm.View())
+	}
+}
+
+func TestSearchModel_ActionMenuWithoutExclude(t *testing.T) {
+	var m tea.Model = NewSearchModel("taxes", "/vault")
+	m, _ = m.Update(SearchResultsMsg{Results: []SearchResult{{Path: "taxes.md"}}})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	if view := m.View(); strings.Contains(view, "Exclude note") {
+		t.Errorf("expected no exclude action without a handler:\n%s", view)
+	}
+}
@@ -3,6 +3,9 @@ package tui
 type SetupSubmitMsg struct {
 	APIKey      string
 	ObsidianDir string
+
+	// Keychain asks for the API key to be kept in the OS keychain.
+	Keychain bool
 }
 
 type SetupErrorMsg struct {
@@ -11,6 +14,9 @@ type SetupErrorMsg struct {
 
 type SearchResultsMsg struct {
 	Results []SearchResult
+
+	// Summary is an optional synthesis of the results shown above them.
+	Summary string
 }
 
 type SearchErrorMsg struct {
@@ -25,4 +31,58 @@ type SearchResult struct {
 	Snippet  string
 	DocID    int64
 	ChunkID  int64
+
+	// StartLine is where the chunk begins in the note, for opening it in
+	// an editor.
+	StartLine int
+
+	// LinkedFrom is the path of the result linking to this one, for
+	// secondary results found by following links.
+	LinkedFrom string
+
+	// Before and After are snippets of the neighboring chunks in the note,
+	// shown when context is toggled on.
+	Before []string
+	After  []string
+
+	// Explain is a one-line score breakdown, shown when set.
+	Explain string
+}
+
+// ChatSubmitMsg is sent when the user asks a question in the chat session.
+type ChatSubmitMsg struct {
+	Question string
+}
+
+type ChatAnswerMsg struct {
+	Text    string
+	Sources []ChatSource
 }
+
+type ChatErrorMsg struct {
+	Error string
+}
+
+// ChatSource is a note cited by an answer, referenced as [Number] in its text.
+type ChatSource struct {
+	Number  int
+	Path    string
+	Heading string
+}
+
+// IndexProgressMsg reports how far indexing has got. Messages without a
+// Total are status lines, printed above the progress bar.
+type IndexProgressMsg struct {
+	Phase   string
+	Current int
+	Total   int
+	Message string
+
+	// Chunks and Tokens count the chunks embedded so far and the estimated
+	// tokens sent for them.
+	Chunks int
+	Tokens int
+}
+
+// IndexDoneMsg is sent when indexing finishes, closing the progress view.
+type IndexDoneMsg struct{}
@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mgomes/obsvec/internal/redact"
+)
+
+// previewMsg carries a note rendered for the preview pane. offset is the
+// rendered line the hit starts on.
+type previewMsg struct {
+	path    string
+	line    int
+	width   int
+	content string
+	offset  int
+}
+
+// previewMargin is the pane's border and padding plus the margins glamour
+// adds on both sides of the document.
+const previewMargin = 2 + 4
+
+var previewBorder = lipgloss.NewStyle().
+	Border(lipgloss.NormalBorder(), false, false, false, true).
+	BorderForeground(lipgloss.Color("241")).
+	PaddingLeft(1)
+
+// previewStyle is the glamour style for previews: $GLAMOUR_STYLE, a style
+// name or JSON file, else dark. Auto-detection would query the terminal
+// while the program owns it.
+func previewStyle() string {
+	return cmp.Or(os.Getenv("GLAMOUR_STYLE"), styles.DarkStyle)
+}
+
+// renderPreview renders the vault-relative note as markdown wrapped to width,
+// finding where line starts by rendering the lines before it.
+func renderPreview(vaultDir, path string, line, width int) tea.Cmd {
+	return func() tea.Msg {
+		msg := previewMsg{path: path, line: line, width: width}
+
+		data, err := os.ReadFile(filepath.Join(vaultDir, path))
+		if err != nil {
+			msg.content = errorStyle.Render(err.Error())
+			return msg
+		}
+		renderer, err := glamour.NewTermRenderer(glamour.WithStylePath(previewStyle()), glamour.WithWordWrap(width))
+		if err != nil {
+			msg.content = errorStyle.Render(err.Error())
+			return msg
+		}
+		content, err := renderer.Render(string(data))
+		if err != nil {
+			// Show the raw note rather than nothing
+			content = string(data)
+		}
+		msg.content = content
+
+		if lines := strings.SplitAfter(string(data), "\n"); line > 1 && line <= len(lines) {
+			if before, err := renderer.Render(strings.Join(lines[:line-1], "")); err == nil {
+				msg.offset = strings.Count(strings.TrimRight(before, "\n"), "\n")
+			}
+		}
+		return msg
+	}
+}
+
+// previewSize returns the preview's width and height for the terminal size,
+// leaving the rest of the width to the result list.
+func (m SearchModel) previewSize() (int, int) {
+	width, height := cmp.Or(m.width, 100), cmp.Or(m.height, 30)
+	return max(previewMargin+10, width-m.listWidth()-2), max(5, height-5)
+}
+
+func (m SearchModel) listWidth() int {
+	return cmp.Or(m.width, 100) * 2 / 5
+}
+
+// loadPreview renders the selected note into the preview pane when it is
+// shown.
+func (m *SearchModel) loadPreview() tea.Cmd {
+	if !m.preview || m.redact || len(m.groups) == 0 {
+		return nil
+	}
+	width, height := m.previewSize()
+	m.previewPane.Width, m.previewPane.Height = width, height
+
+	group := m.groups[m.selected]
+	return renderPreview(m.vaultDir, group.Path, group.Hits[0].StartLine, width-previewMargin)
+}
+
+// showPreview fills the pane unless the selection moved on while rendering.
+func (m SearchModel) showPreview(msg previewMsg) SearchModel {
+	if !m.preview || len(m.groups) == 0 {
+		return m
+	}
+	hit := m.groups[m.selected].Hits[0]
+	if msg.path != hit.Path || msg.line != hit.StartLine || msg.width != m.previewPane.Width-previewMargin {
+		return m
+	}
+	m.previewPane.SetContent(msg.content)
+	m.previewPane.SetYOffset(msg.offset)
+	return m
+}
+
+// viewSplit renders the result list, one line per note, beside the preview.
+func (m SearchModel) viewSplit() string {
+	_, height := m.previewSize()
+	listWidth := m.listWidth()
+
+	// Keep the selection in view
+	start := max(0, m.selected-height+1)
+	end := min(len(m.groups), start+height)
+
+	var list strings.Builder
+	for i := start; i < end; i++ {
+		group := m.groups[i]
+		path := group.Path
+		if m.redact {
+			path = redact.Path(path)
+		}
+		marker := "  "
+		if i == m.selected {
+			marker = selectedStyle.Render("> ")
+		}
+		line := marker + scoreStyle.Render(fmt.Sprintf("[%.2f]", group.Score)) + " " + pathStyle.Render(path)
+		list.WriteString(lipgloss.NewStyle().MaxWidth(listWidth).Render(line) + "\n")
+	}
+
+	pane := dimStyle.Render("Preview hidden in demo mode")
+	if !m.redact {
+		pane = m.previewPane.View()
+	}
+	listPane := lipgloss.NewStyle().Width(listWidth).Height(height).Render(list.String())
+	return lipgloss.JoinHorizontal(lipgloss.Top, listPane, previewBorder.Height(height).Render(pane))
+}
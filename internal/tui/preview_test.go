@@ -0,0 +1,50 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestSearchModel_Preview(t *testing.T) {
+	vault := t.TempDir()
+	var note strings.Builder
+	note.WriteString("# Taxes\n\n")
+	for i := range 30 {
+		fmt.Fprintf(&note, "Filler paragraph %d.\n\n", i)
+	}
+	note.WriteString("## Deductions\n\nHome office expenses.\n")
+	if err := os.WriteFile(filepath.Join(vault, "taxes.md"), []byte(note.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var m tea.Model = NewSearchModel("taxes", vault)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	m, _ = m.Update(SearchResultsMsg{Results: []SearchResult{
+		{Path: "taxes.md", Heading: "Taxes > Deductions", Snippet: "Home office expenses.", StartLine: 63},
+	}})
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if cmd == nil {
+		t.Fatal("expected tab to render the preview")
+	}
+	m, _ = m.Update(cmd())
+
+	sm := m.(SearchModel)
+	if sm.previewPane.YOffset == 0 {
+		t.Error("expected the preview to scroll to the hit")
+	}
+	if view := ansi.Strip(sm.View()); !strings.Contains(view, "Home office expenses") {
+		t.Errorf("expected the hit in the preview:\n%s", view)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if m.(SearchModel).preview {
+		t.Error("expected tab to hide the preview again")
+	}
+}
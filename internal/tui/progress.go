@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxBarWidth is the widest the progress bar gets on wide terminals.
+const maxBarWidth = 50
+
+// IndexModel shows indexing progress: the current phase with a bar, files per
+// second or chunks and tokens embedded, and an estimate of the time left in
+// the phase.
+type IndexModel struct {
+	bar        progress.Model
+	last       IndexProgressMsg
+	phaseStart time.Time
+	now        func() time.Time
+
+	interrupted bool
+}
+
+func NewIndexModel() IndexModel {
+	return IndexModel{
+		bar: progress.New(progress.WithDefaultGradient(), progress.WithWidth(maxBarWidth)),
+		now: time.Now,
+	}
+}
+
+// Interrupted reports whether the user quit with ctrl+c before indexing
+// finished.
+func (m IndexModel) Interrupted() bool {
+	return m.interrupted
+}
+
+func (m IndexModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m IndexModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			m.interrupted = true
+			return m, tea.Quit
+		}
+
+	case tea.WindowSizeMsg:
+		m.bar.Width = max(10, min(maxBarWidth, msg.Width-30))
+
+	case IndexProgressMsg:
+		if msg.Total == 0 {
+			if msg.Message == "" {
+				return m, nil
+			}
+			return m, tea.Println(msg.Message)
+		}
+		if msg.Phase != m.last.Phase {
+			m.phaseStart = m.now()
+		}
+		m.last = msg
+
+	case IndexDoneMsg:
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+func (m IndexModel) View() string {
+	p := m.last
+	if p.Total == 0 {
+		return ""
+	}
+
+	unit := "files"
+	if p.Phase == "Embedding" {
+		unit = "batches"
+	}
+	percent := float64(p.Current) / float64(p.Total)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-10s %s %d/%d %s\n", p.Phase, m.bar.ViewAs(percent), p.Current, p.Total, unit)
+
+	elapsed := m.now().Sub(m.phaseStart)
+	var stats []string
+	if unit == "batches" {
+		stats = append(stats, fmt.Sprintf("%d chunks embedded", p.Chunks), fmt.Sprintf("~%s tokens sent", approxCount(p.Tokens)))
+	} else if secs := elapsed.Seconds(); secs > 0 {
+		stats = append(stats, fmt.Sprintf("%.0f files/s", float64(p.Current)/secs))
+	}
+	if left, ok := eta(elapsed, p.Current, p.Total); ok {
+		stats = append(stats, "ETA "+left.String())
+	}
+	b.WriteString(dimStyle.Render(strings.Join(stats, " · ")) + "\n")
+	b.WriteString(helpStyle.Render("ctrl+c stop") + "\n")
+
+	return b.String()
+}
+
+// eta extrapolates the time left from the time taken for done of total
+// steps, rounded to the second. It has no estimate before the first step.
+func eta(elapsed time.Duration, done, total int) (time.Duration, bool) {
+	if done <= 0 || done >= total {
+		return 0, false
+	}
+	left := time.Duration(float64(elapsed) / float64(done) * float64(total-done))
+	return left.Round(time.Second), true
+}
+
+// approxCount formats n with a k or M suffix once it passes a thousand.
+func approxCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	}
+	return fmt.Sprint(n)
+}
@@ -0,0 +1,41 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexModel_View(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewIndexModel()
+	m.now = func() time.Time { return start }
+
+	updated, _ := m.Update(IndexProgressMsg{Phase: "Embedding", Current: 2, Total: 4, Chunks: 192, Tokens: 48_000})
+	m = updated.(IndexModel)
+	m.now = func() time.Time { return start.Add(10 * time.Second) }
+
+	view := m.View()
+	for _, want := range []string{"Embedding", "2/4 batches", "192 chunks embedded", "~48.0k tokens sent", "ETA 10s"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in view:\n%s", want, view)
+		}
+	}
+
+	_, cmd := m.Update(IndexDoneMsg{})
+	if cmd == nil {
+		t.Error("expected IndexDoneMsg to quit")
+	}
+}
+
+func TestETA(t *testing.T) {
+	if _, ok := eta(time.Second, 0, 10); ok {
+		t.Error("expected no estimate before the first step")
+	}
+	if left, ok := eta(30*time.Second, 3, 4); !ok || left != 10*time.Second {
+		t.Errorf("eta = %v, %t; want 10s", left, ok)
+	}
+	if _, ok := eta(time.Minute, 4, 4); ok {
+		t.Error("expected no estimate once done")
+	}
+}
@@ -15,6 +15,15 @@ type SetupModel struct {
 	error       string
 	width       int
 	height      int
+
+	// vaults were found on disk; up and down pick one into dirInput.
+	vaults        []string
+	vaultSelected int
+
+	// keychainAvailable offers ctrl+t to toggle keychain, keeping the API
+	// key in the OS keychain instead of the config file.
+	keychainAvailable bool
+	keychain          bool
 }
 
 const inputWidth = 60
@@ -34,6 +43,22 @@ func NewSetupModel() SetupModel {
 	}
 }
 
+// WithVaults lists detected vault directories under the directory field,
+// starting with the first one filled in.
+func (m SetupModel) WithVaults(vaults []string) SetupModel {
+	m.vaults = vaults
+	if len(vaults) > 0 {
+		m.dirInput.SetValue(vaults[0])
+	}
+	return m
+}
+
+// WithKeychain offers to keep the API key in the OS keychain.
+func (m SetupModel) WithKeychain() SetupModel {
+	m.keychainAvailable = true
+	return m
+}
+
 func newSetupInput(placeholder string) textinput.Model {
 	input := textinput.New()
 	input.Placeholder = placeholder
@@ -50,10 +75,20 @@ func (m SetupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if key := msg.String(); m.focus == 1 && len(m.vaults) > 0 && (key == "up" || key == "down") {
+			return m.pickVault(key), nil
+		}
+
 		switch msg.String() {
 		case "ctrl+c":
 			return m, tea.Quit
 
+		case "ctrl+t":
+			if m.keychainAvailable {
+				m.keychain = !m.keychain
+			}
+			return m, nil
+
 		case "tab", "down":
 			if m.focus == 0 {
 				m.focus = 1
@@ -95,6 +130,7 @@ func (m SetupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return SetupSubmitMsg{
 					APIKey:      apiKey,
 					ObsidianDir: dir,
+					Keychain:    m.keychain,
 				}
 			}
 		}
@@ -115,6 +151,19 @@ func (m SetupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// pickVault moves the detected vault selection up or down, filling the
+// directory field with it.
+func (m SetupModel) pickVault(key string) SetupModel {
+	if key == "up" {
+		m.vaultSelected = (m.vaultSelected + len(m.vaults) - 1) % len(m.vaults)
+	} else {
+		m.vaultSelected = (m.vaultSelected + 1) % len(m.vaults)
+	}
+	m.dirInput.SetValue(m.vaults[m.vaultSelected])
+	m.dirInput.CursorEnd()
+	return m
+}
+
 func (m SetupModel) updateFocusedInput(msg tea.Msg) (SetupModel, tea.Cmd) {
 	var cmd tea.Cmd
 	if m.focus == 0 {
@@ -147,7 +196,15 @@ func (m SetupModel) View() string {
 		BorderForeground(lipgloss.Color("63")).
 		Padding(0, 1)
 
-	b.WriteString(style.Render(m.apiKeyInput.View()) + "\n\n")
+	b.WriteString(style.Render(m.apiKeyInput.View()) + "\n")
+	if m.keychainAvailable {
+		check := "[ ]"
+		if m.keychain {
+			check = selectedStyle.Render("[x]")
+		}
+		b.WriteString("  " + check + " Store the key in the OS keychain, not the config file\n")
+	}
+	b.WriteString("\n")
 
 	dirLabel := "Obsidian Vault Directory:"
 	if m.focus == 1 {
@@ -158,11 +215,29 @@ func (m SetupModel) View() string {
 	b.WriteString(dirLabel + "\n")
 	b.WriteString(style.Render(m.dirInput.View()) + "\n")
 
+	if len(m.vaults) > 0 {
+		b.WriteString("\n  " + dimStyle.Render("Vaults found:") + "\n")
+		for _, vault := range m.vaults {
+			if vault == strings.TrimSpace(m.dirInput.Value()) {
+				b.WriteString("  " + selectedStyle.Render("> "+vault) + "\n")
+			} else {
+				b.WriteString("    " + vault + "\n")
+			}
+		}
+	}
+
 	if m.error != "" {
 		b.WriteString("\n" + errorStyle.Render("Error: "+m.error) + "\n")
 	}
 
-	b.WriteString("\n" + helpStyle.Render("tab switch field  enter submit  ctrl+c quit"))
+	help := "tab switch field  enter submit  ctrl+c quit"
+	if m.keychainAvailable {
+		help = "ctrl+t keychain  " + help
+	}
+	if len(m.vaults) > 0 && m.focus == 1 {
+		help = "↑/↓ choose vault  " + help
+	}
+	b.WriteString("\n" + helpStyle.Render(help))
 
 	return b.String()
 }
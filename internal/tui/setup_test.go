@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSetupModel_Vaults(t *testing.T) {
+	var m tea.Model = NewSetupModel().WithVaults([]string{"/home/me/Notes", "/home/me/Work"})
+	if got := m.(SetupModel).dirInput.Value(); got != "/home/me/Notes" {
+		t.Errorf("expected the first vault filled in, got %q", got)
+	}
+
+	// Once the directory has focus, down picks the next vault
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if got := m.(SetupModel).dirInput.Value(); got != "/home/me/Work" {
+		t.Errorf("expected down to pick the next vault, got %q", got)
+	}
+	if view := m.View(); !strings.Contains(view, "> /home/me/Work") {
+		t.Errorf("expected the picked vault to be highlighted:\n%s", view)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		t.Fatal("expected the missing API key to block submitting")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("key")})
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	msg, ok := cmd().(SetupSubmitMsg)
+	if !ok || msg.ObsidianDir != "/home/me/Workx" || msg.APIKey != "key" {
+		t.Errorf("expected the edited vault path to be submitted, got %+v", msg)
+	}
+}
+
+func TestSetupModel_Keychain(t *testing.T) {
+	var m tea.Model = NewSetupModel()
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	if strings.Contains(m.View(), "keychain") {
+		t.Error("expected no keychain option without a keychain")
+	}
+
+	m = NewSetupModel().WithKeychain()
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("key")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	if !strings.Contains(m.View(), "[x]") {
+		t.Errorf("expected ctrl+t to check the keychain option:\n%s", m.View())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/vault")})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if msg, ok := cmd().(SetupSubmitMsg); !ok || !msg.Keychain || msg.APIKey != "key" {
+		t.Errorf("expected the keychain choice submitted, got %+v", msg)
+	}
+}
@@ -0,0 +1,199 @@
+package vectorstore
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+// Pgvector stores vectors in a PostgreSQL table using the pgvector
+// extension, keyed by chunk id.
+type Pgvector struct {
+	conn  *sql.DB
+	table string
+}
+
+// OpenPgvector connects to PostgreSQL with dsn and creates the extension,
+// table and HNSW index if they don't exist. An existing table must hold
+// dim-dimensional vectors.
+func OpenPgvector(dsn, table string, dim int) (*Pgvector, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("pgvector needs a url")
+	}
+	conn, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pgvector{conn: conn, table: table}
+	if err := p.init(dim); err != nil {
+		conn.Close() //nolint:errcheck
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Pgvector) init(dim int) error {
+	if _, err := p.conn.Exec("CREATE EXTENSION IF NOT EXISTS vector"); err != nil {
+		return fmt.Errorf("enable pgvector: %w", err)
+	}
+	_, err := p.conn.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			chunk_id BIGINT PRIMARY KEY,
+			embedding vector(%d) NOT NULL
+		)
+	`, p.table, dim))
+	if err != nil {
+		return fmt.Errorf("create pgvector table %s: %w", p.table, err)
+	}
+	_, err = p.conn.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_embedding_idx ON %[1]s USING hnsw (embedding vector_l2_ops)", p.table))
+	if err != nil {
+		return fmt.Errorf("create pgvector index: %w", err)
+	}
+
+	// The type modifier of a vector column is its dimension
+	var existing int
+	err = p.conn.QueryRow(`
+		SELECT atttypmod FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attname = 'embedding'
+	`, p.table).Scan(&existing)
+	if err != nil {
+		return err
+	}
+	if existing != dim {
+		return fmt.Errorf("pgvector table %s holds %d-dimensional vectors, expected %d", p.table, existing, dim)
+	}
+	return nil
+}
+
+func (p *Pgvector) Insert(chunkID int64, embedding []float32) error {
+	_, err := p.conn.Exec(`
+		INSERT INTO `+p.table+` (chunk_id, embedding) VALUES ($1, $2::vector)
+		ON CONFLICT (chunk_id) DO UPDATE SET embedding = excluded.embedding
+	`, chunkID, formatVector(embedding))
+	return err
+}
+
+func (p *Pgvector) Search(query []float32, k int, allowed []int64) ([]db.VectorHit, error) {
+	if allowed == nil {
+		rows, err := p.conn.Query(`
+			SELECT chunk_id, embedding <-> $1::vector AS distance
+			FROM `+p.table+`
+			ORDER BY distance
+			LIMIT $2
+		`, formatVector(query), k)
+		if err != nil {
+			return nil, err
+		}
+		return scanHits(rows)
+	}
+
+	// The approximate index filters after its scan and can come back short,
+	// so a filtered search reads the allowed rows by key and sorts them.
+	tx, err := p.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if _, err := tx.Exec("SET LOCAL enable_indexscan = off"); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(`
+		SELECT chunk_id, embedding <-> $1::vector AS distance
+		FROM `+p.table+`
+		WHERE chunk_id = ANY($3)
+		ORDER BY distance
+		LIMIT $2
+	`, formatVector(query), k, allowed)
+	if err != nil {
+		return nil, err
+	}
+	return scanHits(rows)
+}
+
+func scanHits(rows *sql.Rows) ([]db.VectorHit, error) {
+	defer rows.Close() //nolint:errcheck
+
+	var hits []db.VectorHit
+	for rows.Next() {
+		var hit db.VectorHit
+		if err := rows.Scan(&hit.ChunkID, &hit.Distance); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+func (p *Pgvector) Get(chunkIDs []int64) (map[int64][]float32, error) {
+	rows, err := p.conn.Query("SELECT chunk_id, embedding::text FROM "+p.table+" WHERE chunk_id = ANY($1)", chunkIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	embeddings := make(map[int64][]float32, len(chunkIDs))
+	for rows.Next() {
+		var id int64
+		var text string
+		if err := rows.Scan(&id, &text); err != nil {
+			return nil, err
+		}
+		vector, err := parseVector(text)
+		if err != nil {
+			return nil, err
+		}
+		embeddings[id] = vector
+	}
+	return embeddings, rows.Err()
+}
+
+func (p *Pgvector) Delete(chunkIDs []int64) error {
+	_, err := p.conn.Exec("DELETE FROM "+p.table+" WHERE chunk_id = ANY($1)", chunkIDs)
+	return err
+}
+
+func (p *Pgvector) Close() error {
+	return p.conn.Close()
+}
+
+// formatVector writes a vector in pgvector's text form, e.g. [1,0.5,-2].
+func formatVector(vector []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range vector {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// parseVector reads pgvector's text form.
+func parseVector(text string) ([]float32, error) {
+	inner, opened := strings.CutPrefix(text, "[")
+	inner, closed := strings.CutSuffix(inner, "]")
+	if !opened || !closed {
+		return nil, fmt.Errorf("invalid vector %q", text)
+	}
+	if inner == "" {
+		return []float32{}, nil
+	}
+
+	fields := strings.Split(inner, ",")
+	vector := make([]float32, len(fields))
+	for i, field := range fields {
+		x, err := strconv.ParseFloat(strings.TrimSpace(field), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector %q: %w", text, err)
+		}
+		vector[i] = float32(x)
+	}
+	return vector, nil
+}
@@ -0,0 +1,28 @@
+package vectorstore
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVectorText(t *testing.T) {
+	vector := []float32{1, 0.5, -2.25, 1e-7}
+	text := formatVector(vector)
+	if text != "[1,0.5,-2.25,1e-07]" {
+		t.Errorf("unexpected vector text %q", text)
+	}
+
+	parsed, err := parseVector(text)
+	if err != nil {
+		t.Fatalf("parseVector failed: %v", err)
+	}
+	if !slices.Equal(parsed, vector) {
+		t.Errorf("expected %v, got %v", vector, parsed)
+	}
+
+	for _, bad := range []string{"", "1,2", "[1,2", "[1,x]"} {
+		if _, err := parseVector(bad); err == nil {
+			t.Errorf("expected an error parsing %q", bad)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package vectorstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+// qdrantBatchSize caps the points sent in one request.
+const qdrantBatchSize = 1000
+
+// Qdrant stores vectors in a Qdrant collection through its REST API, with
+// chunk ids as point ids.
+type Qdrant struct {
+	baseURL    string
+	apiKey     string
+	collection string
+	client     *http.Client
+}
+
+type qdrantPoint struct {
+	ID     int64     `json:"id"`
+	Vector []float32 `json:"vector,omitempty"`
+	Score  float64   `json:"score,omitempty"`
+}
+
+// OpenQdrant connects to the Qdrant server at baseURL and creates the
+// collection if it doesn't exist. An existing collection must hold
+// dim-dimensional vectors.
+func OpenQdrant(baseURL, apiKey, collection string, dim int) (*Qdrant, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("qdrant needs a url")
+	}
+	q := &Qdrant{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		collection: collection,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+
+	var info struct {
+		Config struct {
+			Params struct {
+				Vectors struct {
+					Size     int    `json:"size"`
+					Distance string `json:"distance"`
+				} `json:"vectors"`
+			} `json:"params"`
+		} `json:"config"`
+	}
+	status, err := q.call(http.MethodGet, "", nil, &info)
+	if status == http.StatusNotFound {
+		body := map[string]any{"vectors": map[string]any{"size": dim, "distance": "Euclid"}}
+		if _, err := q.call(http.MethodPut, "", body, nil); err != nil {
+			return nil, fmt.Errorf("create qdrant collection %s: %w", collection, err)
+		}
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := info.Config.Params.Vectors
+	if vectors.Size != dim || vectors.Distance != "Euclid" {
+		return nil, fmt.Errorf("qdrant collection %s holds %d-dimensional %s vectors, expected %d-dimensional Euclid", collection, vectors.Size, vectors.Distance, dim)
+	}
+	return q, nil
+}
+
+func (q *Qdrant) Insert(chunkID int64, embedding []float32) error {
+	body := map[string]any{"points": []qdrantPoint{{ID: chunkID, Vector: embedding}}}
+	_, err := q.call(http.MethodPut, "/points?wait=true", body, nil)
+	return err
+}
+
+func (q *Qdrant) Search(query []float32, k int, allowed []int64) ([]db.VectorHit, error) {
+	body := map[string]any{"vector": query, "limit": k}
+	if allowed != nil {
+		body["filter"] = map[string]any{"must": []any{map[string]any{"has_id": allowed}}}
+	}
+
+	var points []qdrantPoint
+	if _, err := q.call(http.MethodPost, "/points/search", body, &points); err != nil {
+		return nil, err
+	}
+
+	hits := make([]db.VectorHit, len(points))
+	for i, p := range points {
+		// With Euclid distance the score is the distance itself
+		hits[i] = db.VectorHit{ChunkID: p.ID, Distance: p.Score}
+	}
+	return hits, nil
+}
+
+func (q *Qdrant) Get(chunkIDs []int64) (map[int64][]float32, error) {
+	embeddings := make(map[int64][]float32, len(chunkIDs))
+	for batch := range slices.Chunk(chunkIDs, qdrantBatchSize) {
+		body := map[string]any{"ids": batch, "with_vector": true, "with_payload": false}
+		var points []qdrantPoint
+		if _, err := q.call(http.MethodPost, "/points", body, &points); err != nil {
+			return nil, err
+		}
+		for _, p := range points {
+			embeddings[p.ID] = p.Vector
+		}
+	}
+	return embeddings, nil
+}
+
+func (q *Qdrant) Delete(chunkIDs []int64) error {
+	for batch := range slices.Chunk(chunkIDs, qdrantBatchSize) {
+		if _, err := q.call(http.MethodPost, "/points/delete?wait=true", map[string]any{"points": batch}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *Qdrant) Close() error {
+	q.client.CloseIdleConnections()
+	return nil
+}
+
+// call sends a request to path under the collection and decodes the result
+// field of the response into result. It returns the HTTP status alongside
+// any error.
+func (q *Qdrant) call(method, path string, body, result any) (int, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return 0, err
+		}
+	}
+
+	req, err := http.NewRequest(method, q.baseURL+"/collections/"+url.PathEscape(q.collection)+path, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if q.apiKey != "" {
+		req.Header.Set("api-key", q.apiKey)
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("qdrant request failed: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Status json.RawMessage `json:"status"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&envelope)
+
+	if resp.StatusCode/100 != 2 {
+		var status struct {
+			Error string `json:"error"`
+		}
+		if decodeErr == nil && json.Unmarshal(envelope.Status, &status) == nil && status.Error != "" {
+			return resp.StatusCode, fmt.Errorf("qdrant: %s", status.Error)
+		}
+		return resp.StatusCode, fmt.Errorf("qdrant: %s", resp.Status)
+	}
+	if decodeErr != nil {
+		return resp.StatusCode, fmt.Errorf("qdrant: invalid response: %w", decodeErr)
+	}
+	if result != nil {
+		if err := json.Unmarshal(envelope.Result, result); err != nil {
+			return resp.StatusCode, fmt.Errorf("qdrant: invalid response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
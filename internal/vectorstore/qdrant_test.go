@@ -0,0 +1,205 @@
+package vectorstore
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mgomes/obsvec/internal/config"
+)
+
+// fakeQdrant serves the parts of the Qdrant REST API the store uses.
+type fakeQdrant struct {
+	mu          sync.Mutex
+	dim         int
+	points      map[int64][]float32
+	apiKeys     []string
+	collections []string
+}
+
+func (f *fakeQdrant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.apiKeys = append(f.apiKeys, r.Header.Get("api-key"))
+
+	var body map[string]json.RawMessage
+	json.NewDecoder(r.Body).Decode(&body)
+	reply := func(result any) {
+		json.NewEncoder(w).Encode(map[string]any{"result": result, "status": "ok"})
+	}
+
+	collection, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/collections/"), "/")
+	if path != "" {
+		path = "/" + path
+	}
+	f.collections = append(f.collections, collection)
+
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		if f.points == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"status": map[string]string{"error": "Not found"}})
+			return
+		}
+		reply(map[string]any{"config": map[string]any{"params": map[string]any{
+			"vectors": map[string]any{"size": f.dim, "distance": "Euclid"},
+		}}})
+	case path == "" && r.Method == http.MethodPut:
+		var params struct {
+			Vectors struct {
+				Size int `json:"size"`
+			} `json:"vectors"`
+		}
+		json.Unmarshal(body["vectors"], &params.Vectors)
+		f.dim = params.Vectors.Size
+		f.points = make(map[int64][]float32)
+		reply(true)
+	case path == "/points" && r.Method == http.MethodPut:
+		var points []qdrantPoint
+		json.Unmarshal(body["points"], &points)
+		for _, p := range points {
+			f.points[p.ID] = p.Vector
+		}
+		reply(map[string]string{"status": "completed"})
+	case path == "/points" && r.Method == http.MethodPost:
+		var ids []int64
+		json.Unmarshal(body["ids"], &ids)
+		var points []qdrantPoint
+		for _, id := range ids {
+			if v, ok := f.points[id]; ok {
+				points = append(points, qdrantPoint{ID: id, Vector: v})
+			}
+		}
+		reply(points)
+	case path == "/points/search":
+		var query []float32
+		var limit int
+		json.Unmarshal(body["vector"], &query)
+		json.Unmarshal(body["limit"], &limit)
+		var filter struct {
+			Must []struct {
+				HasID []int64 `json:"has_id"`
+			} `json:"must"`
+		}
+		json.Unmarshal(body["filter"], &filter)
+
+		var points []qdrantPoint
+		for id, v := range f.points {
+			if len(filter.Must) > 0 && !slices.Contains(filter.Must[0].HasID, id) {
+				continue
+			}
+			var sum float64
+			for i := range v {
+				d := float64(v[i] - query[i])
+				sum += d * d
+			}
+			points = append(points, qdrantPoint{ID: id, Score: math.Sqrt(sum)})
+		}
+		sort.Slice(points, func(i, j int) bool { return points[i].Score < points[j].Score })
+		reply(points[:min(limit, len(points))])
+	case path == "/points/delete":
+		var ids []int64
+		json.Unmarshal(body["points"], &ids)
+		for _, id := range ids {
+			delete(f.points, id)
+		}
+		reply(map[string]string{"status": "completed"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestQdrant(t *testing.T) {
+	fake := &fakeQdrant{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := Open(config.VectorStoreConfig{Type: "qdrant", URL: server.URL + "/", APIKey: "secret", Collection: "notes"}, "", 2)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer store.Close()
+	if fake.dim != 2 {
+		t.Fatalf("expected a 2-dimensional collection to be created, got %d", fake.dim)
+	}
+
+	for id, v := range map[int64][]float32{1: {1, 0}, 2: {0, 1}, 3: {0.6, 0.8}} {
+		if err := store.Insert(id, v); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	hits, err := store.Search([]float32{0, 1}, 2, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(hits) != 2 || hits[0].ChunkID != 2 || hits[1].ChunkID != 3 {
+		t.Errorf("expected chunks 2 then 3, got %+v", hits)
+	}
+
+	hits, err = store.Search([]float32{0, 1}, 2, []int64{1})
+	if err != nil {
+		t.Fatalf("filtered Search failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ChunkID != 1 {
+		t.Errorf("expected only chunk 1, got %+v", hits)
+	}
+
+	if err := store.Delete([]int64{2}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	embeddings, err := store.Get([]int64{1, 2})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(embeddings) != 1 || !slices.Equal(embeddings[1], []float32{1, 0}) {
+		t.Errorf("expected only chunk 1's vector, got %v", embeddings)
+	}
+
+	for _, key := range fake.apiKeys {
+		if key != "secret" {
+			t.Fatalf("expected every request to carry the api key, got %q", key)
+		}
+	}
+
+	// Reopening with another dimension must not reuse the collection
+	if _, err := OpenQdrant(server.URL, "secret", "notes", 3); err == nil {
+		t.Error("expected a dimension mismatch error")
+	}
+}
+
+func TestOpen_VaultCollection(t *testing.T) {
+	// Chunk ids of different vaults overlap, so each needs its own collection
+	for _, tc := range []struct {
+		collection, vault, want string
+	}{
+		{"", "", "obsvec"},
+		{"", "work notes", "obsvec_work_notes"},
+		{"notes", "work", "notes_work"},
+	} {
+		fake := &fakeQdrant{}
+		server := httptest.NewServer(fake)
+		if _, err := Open(config.VectorStoreConfig{Type: "qdrant", URL: server.URL, Collection: tc.collection}, tc.vault, 2); err != nil {
+			t.Errorf("Open failed: %v", err)
+		}
+		server.Close()
+		if len(fake.collections) == 0 || fake.collections[0] != tc.want {
+			t.Errorf("collection %q, vault %q: expected collection %s, got %v", tc.collection, tc.vault, tc.want, fake.collections)
+		}
+	}
+}
+
+func TestOpen_InvalidConfig(t *testing.T) {
+	if _, err := Open(config.VectorStoreConfig{Type: "qdrant", URL: "http://localhost", Collection: "bad name"}, "", 2); err == nil {
+		t.Error("expected an error for an invalid collection name")
+	}
+	if _, err := Open(config.VectorStoreConfig{Type: "milvus"}, "notes", 2); err == nil {
+		t.Error("expected an error for an unknown type")
+	}
+}
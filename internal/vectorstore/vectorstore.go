@@ -0,0 +1,47 @@
+// Package vectorstore provides db.VectorStore backends that keep embeddings
+// on a server instead of in the local sqlite-vec table.
+package vectorstore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mgomes/obsvec/internal/config"
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+// namePattern restricts collection and table names to plain identifiers,
+// since they are spliced into URLs and SQL.
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Open connects to the configured backend, creating its collection or table
+// for dim-dimensional vectors if needed. Each vault numbers its chunks
+// independently, so a named vault gets its own collection, suffixed with the
+// vault name.
+func Open(cfg config.VectorStoreConfig, vault string, dim int) (db.VectorStore, error) {
+	name := cfg.Collection
+	if name == "" {
+		name = "obsvec"
+	}
+	if vault != "" {
+		name += "_" + strings.Map(func(r rune) rune {
+			if r < 128 && (r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+				return r
+			}
+			return '_'
+		}, vault)
+	}
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid vector store collection %q: use letters, digits and underscores", name)
+	}
+
+	switch cfg.Type {
+	case "qdrant":
+		return OpenQdrant(cfg.URL, cfg.APIKey, name, dim)
+	case "pgvector":
+		return OpenPgvector(cfg.URL, name, dim)
+	default:
+		return nil, fmt.Errorf("unknown vector store type %q: use qdrant or pgvector", cfg.Type)
+	}
+}
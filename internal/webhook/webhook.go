@@ -0,0 +1,135 @@
+// Package webhook POSTs index events to configured URLs as JSON, so that
+// home automation and note-processing pipelines can react to vault changes.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/events"
+)
+
+// Kinds are the events sent to webhooks. Searches are not among them.
+var Kinds = []events.Kind{
+	events.DocumentIndexed,
+	events.DocumentRemoved,
+	events.IndexCompleted,
+	events.IndexFailed,
+}
+
+// queueSize is how many events can wait for delivery before new ones are
+// dropped.
+const queueSize = 256
+
+// requestTimeout bounds each POST.
+const requestTimeout = 10 * time.Second
+
+// Payload is the body of every webhook request.
+type Payload struct {
+	events.Event
+
+	// Vault is the vault directory the event happened in.
+	Vault string `json:"vault"`
+}
+
+// Notifier delivers events to its URLs in the background, one at a time and
+// in order. Failed deliveries are logged, not retried.
+type Notifier struct {
+	urls   []string
+	vault  string
+	client *http.Client
+	done   chan struct{}
+
+	mu     sync.Mutex
+	queue  chan events.Event
+	closed bool
+}
+
+// New starts a notifier POSTing to urls the events of the vault at vaultDir.
+func New(urls []string, vaultDir string) *Notifier {
+	n := &Notifier{
+		urls:   urls,
+		vault:  vaultDir,
+		client: &http.Client{Timeout: requestTimeout},
+		queue:  make(chan events.Event, queueSize),
+		done:   make(chan struct{}),
+	}
+	go n.run()
+	return n
+}
+
+// Handle queues e for delivery, if it is one of Kinds. It never blocks; an
+// event arriving while the queue is full, or after Close, is dropped.
+func (n *Notifier) Handle(e events.Event) {
+	if !slices.Contains(Kinds, e.Kind) {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.queue <- e:
+	default:
+		slog.Warn("webhook queue full, dropping event", "kind", e.Kind, "path", e.Path)
+	}
+}
+
+// Close delivers the events still queued, waiting at most timeout for them,
+// and stops the notifier.
+func (n *Notifier) Close(timeout time.Duration) {
+	n.mu.Lock()
+	n.closed = true
+	close(n.queue)
+	n.mu.Unlock()
+
+	select {
+	case <-n.done:
+	case <-time.After(timeout):
+		slog.Warn("gave up delivering webhooks", "pending", len(n.queue))
+	}
+}
+
+func (n *Notifier) run() {
+	defer close(n.done)
+	for e := range n.queue {
+		body, err := json.Marshal(Payload{Event: e, Vault: n.vault})
+		if err != nil {
+			slog.Warn("encoding webhook failed", "error", err)
+			continue
+		}
+		for _, url := range n.urls {
+			if err := n.post(url, body); err != nil {
+				slog.Warn("webhook failed", "url", url, "kind", e.Kind, "error", err)
+			}
+		}
+	}
+}
+
+func (n *Notifier) post(url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("server answered %s", resp.Status)
+	}
+	return nil
+}
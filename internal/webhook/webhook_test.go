@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/events"
+)
+
+func TestNotifier_DeliversIndexEvents(t *testing.T) {
+	var mu sync.Mutex
+	var received []Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Payload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		mu.Lock()
+		received = append(received, p)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	n := New([]string{srv.URL}, "/vault")
+	n.Handle(events.Event{Kind: events.DocumentIndexed, Path: "a.md"})
+	n.Handle(events.Event{Kind: events.SearchExecuted, Query: "taxes"})
+	n.Handle(events.Event{Kind: events.IndexFailed, Path: "b.md", Error: "unreadable"})
+	n.Close(5 * time.Second)
+	n.Handle(events.Event{Kind: events.IndexCompleted})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("expected the two index events, got %+v", received)
+	}
+	if received[0].Kind != events.DocumentIndexed || received[0].Path != "a.md" || received[0].Vault != "/vault" {
+		t.Errorf("unexpected first payload %+v", received[0])
+	}
+	if received[1].Kind != events.IndexFailed || received[1].Error != "unreadable" {
+		t.Errorf("unexpected second payload %+v", received[1])
+	}
+}
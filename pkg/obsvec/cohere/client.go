@@ -0,0 +1,429 @@
+// Package cohere is the Cohere model provider: embeddings, reranking and
+// chat over Cohere's API.
+package cohere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cohere "github.com/cohere-ai/cohere-go/v2"
+	cohereclient "github.com/cohere-ai/cohere-go/v2/client"
+	"github.com/mgomes/obsvec/pkg/obsvec/provider"
+)
+
+const (
+	// maxRerankDocuments is the most documents sent in a single rerank call.
+	// Larger candidate sets are split into shards that are reranked concurrently.
+	maxRerankDocuments       = 1000
+	maxConcurrentRerankCalls = 4
+)
+
+type Client struct {
+	client      *cohereclient.Client
+	embedModel  string
+	rerankModel string
+	chatModel   string
+	embedDim    int
+}
+
+// The result types are the provider package's, so that a Client is a
+// provider.DocumentEmbedder and a provider.Searcher.
+type (
+	EmbeddingResult = provider.EmbeddingResult
+	RerankResult    = provider.RerankResult
+)
+
+func NewClient(apiKey, embedModel, rerankModel, chatModel string, embedDim int) *Client {
+	client := cohereclient.NewClient(cohereclient.WithToken(apiKey))
+	return &Client{
+		client:      client,
+		embedModel:  embedModel,
+		rerankModel: rerankModel,
+		chatModel:   chatModel,
+		embedDim:    embedDim,
+	}
+}
+
+func (c *Client) ValidateAPIKey(ctx context.Context) error {
+	_, err := c.client.Models.List(ctx, &cohere.ModelsListRequest{})
+	if err != nil {
+		return fmt.Errorf("invalid API key: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) EmbedDocuments(ctx context.Context, texts []string) ([]EmbeddingResult, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	embeddings, err := c.embed(ctx, texts, cohere.EmbedInputTypeSearchDocument)
+	if err != nil {
+		if errors.Is(err, errNoEmbeddings) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("embed request failed: %w", err)
+	}
+
+	results := make([]EmbeddingResult, len(embeddings))
+	for i, emb := range embeddings {
+		results[i] = EmbeddingResult{
+			Embedding: emb,
+		}
+	}
+
+	return results, nil
+}
+
+func (c *Client) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	embeddings, err := c.EmbedQueries(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedQueries embeds several short query texts in a single request.
+func (c *Client) EmbedQueries(ctx context.Context, queries []string) ([][]float32, error) {
+	embeddings, err := c.embed(ctx, queries, cohere.EmbedInputTypeSearchQuery)
+	if err != nil {
+		if errors.Is(err, errNoEmbeddings) {
+			return nil, fmt.Errorf("no embedding returned")
+		}
+		return nil, fmt.Errorf("embed query failed: %w", err)
+	}
+
+	if len(embeddings) != len(queries) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(queries), len(embeddings))
+	}
+
+	return embeddings, nil
+}
+
+func (c *Client) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankResult, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+
+	if len(documents) <= maxRerankDocuments {
+		return c.rerank(ctx, query, documents, topN)
+	}
+
+	return c.rerankSharded(ctx, query, documents, topN)
+}
+
+func (c *Client) rerankSharded(ctx context.Context, query string, documents []string, topN int) ([]RerankResult, error) {
+	numShards := (len(documents) + maxRerankDocuments - 1) / maxRerankDocuments
+	shardResults := make([][]RerankResult, numShards)
+	errs := make([]error, numShards)
+
+	sem := make(chan struct{}, maxConcurrentRerankCalls)
+	var wg sync.WaitGroup
+	for shard := 0; shard < numShards; shard++ {
+		start := shard * maxRerankDocuments
+		end := min(start+maxRerankDocuments, len(documents))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results, err := c.rerank(ctx, query, documents[start:end], min(topN, end-start))
+			if err != nil {
+				errs[shard] = fmt.Errorf("shard %d/%d: %w", shard+1, numShards, err)
+				return
+			}
+			for i := range results {
+				results[i].Index += start
+			}
+			shardResults[shard] = results
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return mergeRerankResults(shardResults, topN), nil
+}
+
+// mergeRerankResults combines per-shard results into a single list ordered by
+// relevance score and truncated to topN.
+func mergeRerankResults(shards [][]RerankResult, topN int) []RerankResult {
+	var merged []RerankResult
+	for _, results := range shards {
+		merged = append(merged, results...)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+
+	if topN > 0 && len(merged) > topN {
+		merged = merged[:topN]
+	}
+	return merged
+}
+
+func (c *Client) rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankResult, error) {
+	start := time.Now()
+	resp, err := c.client.V2.Rerank(ctx, &cohere.V2RerankRequest{
+		Model:     c.rerankModel,
+		Query:     query,
+		Documents: documents,
+		TopN:      &topN,
+	})
+	logRequest("rerank", start, err, "documents", len(documents))
+	if err != nil {
+		return nil, fmt.Errorf("rerank request failed: %w", err)
+	}
+
+	results := make([]RerankResult, len(resp.Results))
+	for i, r := range resp.Results {
+		results[i] = RerankResult{
+			Index: r.Index,
+			Score: r.RelevanceScore,
+		}
+	}
+
+	return results, nil
+}
+
+// ChatMessage is one turn of a conversation. Role is "user" or "assistant".
+type ChatMessage struct {
+	Role    string
+	Content string
+}
+
+// ChatDocument is a source the chat model can ground its answer in and cite.
+type ChatDocument struct {
+	ID    string
+	Title string
+	Text  string
+}
+
+// Citation ties the reply text between Start and End (rune offsets) to the
+// documents that support it.
+type Citation struct {
+	Start       int
+	End         int
+	DocumentIDs []string
+}
+
+type ChatReply struct {
+	Text      string
+	Citations []Citation
+}
+
+// Chat sends a single-turn conversation to the chat model and returns the
+// text of its reply.
+func (c *Client) Chat(ctx context.Context, system, prompt string) (string, error) {
+	reply, err := c.ChatWithDocuments(ctx, system, []ChatMessage{{Role: "user", Content: prompt}}, nil)
+	if err != nil {
+		return "", err
+	}
+	return reply.Text, nil
+}
+
+// ChatWithDocuments continues a conversation with the chat model, grounding
+// the reply in docs and returning citations to them.
+func (c *Client) ChatWithDocuments(ctx context.Context, system string, history []ChatMessage, docs []ChatDocument) (*ChatReply, error) {
+	messages := chatMessages(system, history)
+	req := &cohere.V2ChatRequest{
+		Model:    c.chatModel,
+		Messages: messages,
+	}
+	for _, d := range docs {
+		req.Documents = append(req.Documents, &cohere.V2ChatRequestDocumentsItem{Document: chatDocument(d)})
+	}
+
+	start := time.Now()
+	resp, err := c.client.V2.Chat(ctx, req)
+	logRequest("chat", start, err, "messages", len(messages), "documents", len(docs))
+	if err != nil {
+		return nil, fmt.Errorf("chat request failed: %w", err)
+	}
+
+	if resp.Message == nil {
+		return nil, fmt.Errorf("no chat response returned")
+	}
+
+	var reply ChatReply
+	var text strings.Builder
+	for _, item := range resp.Message.Content {
+		if item.Text != nil {
+			text.WriteString(item.Text.Text)
+		}
+	}
+	reply.Text = text.String()
+
+	for _, cit := range resp.Message.Citations {
+		if citation, ok := chatCitation(cit); ok {
+			reply.Citations = append(reply.Citations, citation)
+		}
+	}
+
+	return &reply, nil
+}
+
+// ChatWithDocumentsStream is ChatWithDocuments, passing each piece of the
+// reply text to onText as the model generates it. The returned reply has
+// the whole text and the citations, which arrive as the text does.
+func (c *Client) ChatWithDocumentsStream(ctx context.Context, system string, history []ChatMessage, docs []ChatDocument, onText func(string)) (*ChatReply, error) {
+	messages := chatMessages(system, history)
+	req := &cohere.V2ChatStreamRequest{
+		Model:    c.chatModel,
+		Messages: messages,
+	}
+	for _, d := range docs {
+		req.Documents = append(req.Documents, &cohere.V2ChatStreamRequestDocumentsItem{Document: chatDocument(d)})
+	}
+
+	start := time.Now()
+	stream, err := c.client.V2.ChatStream(ctx, req)
+	if err != nil {
+		logRequest("chat stream", start, err, "messages", len(messages), "documents", len(docs))
+		return nil, fmt.Errorf("chat request failed: %w", err)
+	}
+	defer stream.Close() //nolint:errcheck
+
+	var reply ChatReply
+	var text strings.Builder
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logRequest("chat stream", start, err, "messages", len(messages), "documents", len(docs))
+			return nil, fmt.Errorf("chat stream failed: %w", err)
+		}
+
+		switch {
+		case event.ContentDelta != nil:
+			delta := event.ContentDelta.GetDelta().GetMessage().GetContent().GetText()
+			if delta != nil && *delta != "" {
+				text.WriteString(*delta)
+				onText(*delta)
+			}
+		case event.CitationStart != nil:
+			if cit := event.CitationStart.GetDelta().GetMessage().GetCitations(); cit != nil {
+				if citation, ok := chatCitation(cit); ok {
+					reply.Citations = append(reply.Citations, citation)
+				}
+			}
+		}
+	}
+	logRequest("chat stream", start, nil, "messages", len(messages), "documents", len(docs))
+
+	reply.Text = text.String()
+	return &reply, nil
+}
+
+// chatMessages is the system prompt followed by the conversation, as the
+// chat API takes them.
+func chatMessages(system string, history []ChatMessage) cohere.ChatMessages {
+	messages := cohere.ChatMessages{
+		{Role: "system", System: &cohere.SystemMessageV2{Content: &cohere.SystemMessageV2Content{String: system}}},
+	}
+	for _, m := range history {
+		switch m.Role {
+		case "assistant":
+			messages = append(messages, &cohere.ChatMessageV2{
+				Role:      "assistant",
+				Assistant: &cohere.AssistantMessage{Content: &cohere.AssistantMessageV2Content{String: m.Content}},
+			})
+		default:
+			messages = append(messages, &cohere.ChatMessageV2{
+				Role: "user",
+				User: &cohere.UserMessageV2{Content: &cohere.UserMessageV2Content{String: m.Content}},
+			})
+		}
+	}
+	return messages
+}
+
+func chatDocument(d ChatDocument) *cohere.Document {
+	id := d.ID
+	return &cohere.Document{
+		Id:   &id,
+		Data: map[string]any{"title": d.Title, "snippet": d.Text},
+	}
+}
+
+// chatCitation converts a citation of the reply, reporting false for one
+// that has no span or cites no documents.
+func chatCitation(cit *cohere.Citation) (Citation, bool) {
+	if cit.Start == nil || cit.End == nil {
+		return Citation{}, false
+	}
+	citation := Citation{Start: *cit.Start, End: *cit.End}
+	for _, src := range cit.Sources {
+		if src.Document != nil && src.Document.Id != nil {
+			citation.DocumentIDs = append(citation.DocumentIDs, *src.Document.Id)
+		}
+	}
+	return citation, len(citation.DocumentIDs) > 0
+}
+
+func float64sToFloat32s(f64s []float64) []float32 {
+	f32s := make([]float32, len(f64s))
+	for i, v := range f64s {
+		f32s[i] = float32(v)
+	}
+	return f32s
+}
+
+var errNoEmbeddings = errors.New("no embeddings returned")
+
+func (c *Client) embed(ctx context.Context, texts []string, inputType cohere.EmbedInputType) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	embeddingTypes := []cohere.EmbeddingType{cohere.EmbeddingTypeFloat}
+	outputDim := c.embedDim
+
+	start := time.Now()
+	resp, err := c.client.V2.Embed(ctx, &cohere.V2EmbedRequest{
+		Texts:           texts,
+		Model:           c.embedModel,
+		InputType:       inputType,
+		EmbeddingTypes:  embeddingTypes,
+		OutputDimension: &outputDim,
+	})
+	logRequest("embed", start, err, "texts", len(texts), "input_type", inputType)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Embeddings == nil || resp.Embeddings.Float == nil {
+		return nil, errNoEmbeddings
+	}
+
+	results := make([][]float32, len(resp.Embeddings.Float))
+	for i, emb := range resp.Embeddings.Float {
+		results[i] = float64sToFloat32s(emb)
+	}
+
+	return results, nil
+}
+
+// logRequest records how long an API call took, for ofind -v.
+func logRequest(endpoint string, start time.Time, err error, attrs ...any) {
+	attrs = append([]any{"endpoint", endpoint, "duration", time.Since(start)}, attrs...)
+	if err != nil {
+		slog.Warn("cohere request failed", append(attrs, "error", err)...)
+		return
+	}
+	slog.Info("cohere request", attrs...)
+}
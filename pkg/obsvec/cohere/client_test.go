@@ -0,0 +1,36 @@
+package cohere
+
+import "testing"
+
+func TestMergeRerankResults_OrdersByScore(t *testing.T) {
+	shards := [][]RerankResult{
+		{{Index: 0, Score: 0.4}, {Index: 1, Score: 0.1}},
+		{{Index: 1000, Score: 0.9}, {Index: 1001, Score: 0.3}},
+	}
+
+	merged := mergeRerankResults(shards, 3)
+
+	if len(merged) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(merged))
+	}
+
+	wantIndexes := []int{1000, 0, 1001}
+	for i, want := range wantIndexes {
+		if merged[i].Index != want {
+			t.Errorf("result %d: expected index %d, got %d", i, want, merged[i].Index)
+		}
+	}
+}
+
+func TestMergeRerankResults_FewerThanTopN(t *testing.T) {
+	shards := [][]RerankResult{
+		{{Index: 0, Score: 0.5}},
+		nil,
+	}
+
+	merged := mergeRerankResults(shards, 10)
+
+	if len(merged) != 1 {
+		t.Errorf("expected 1 result, got %d", len(merged))
+	}
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// busyTimeoutMS is how long a connection waits for another one, possibly in
+// another process, to release its lock before failing with SQLITE_BUSY.
+const busyTimeoutMS = 5000
+
+// connector opens SQLite connections set up for sharing the file: each one
+// waits out other writers instead of failing, reader connections refuse to
+// write, and with a key each is unlocked before anything else touches the
+// file. database/sql may open several connections, and each needs this.
+type connector struct {
+	dsn      string
+	key      string
+	readOnly bool
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := sqliteDriver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	var pragmas []string
+	if c.key != "" {
+		// The key is hex, so quoting it this way is safe
+		pragmas = append(pragmas, `PRAGMA key = "x'`+c.key+`'"`)
+	}
+	pragmas = append(pragmas, "PRAGMA busy_timeout = "+strconv.Itoa(busyTimeoutMS))
+	if c.readOnly {
+		pragmas = append(pragmas, "PRAGMA query_only = 1")
+	}
+	for _, pragma := range pragmas {
+		if _, err := conn.(driver.ExecerContext).ExecContext(ctx, pragma, nil); err != nil {
+			conn.Close() //nolint:errcheck
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return sqliteDriver
+}
+
+// openPools opens the connection pools for path: a single writer connection,
+// so writes from this process queue up rather than contend for the lock, and
+// a pool of readers that run alongside it. In WAL mode readers see the last
+// commit and never block the writer, in this process or another.
+//
+// An in-memory database exists per connection, so there both pools are the
+// same single connection.
+func openPools(path, key string) (reader, writer *sql.DB) {
+	// Taking the write lock at BEGIN means a transaction that reads before
+	// it writes waits for another writer instead of failing on upgrade
+	writer = sql.OpenDB(connector{dsn: withParam(path, "_txlock=immediate"), key: key})
+	writer.SetMaxOpenConns(1)
+	if path == ":memory:" {
+		return writer, writer
+	}
+	return sql.OpenDB(connector{dsn: path, key: key, readOnly: true}), writer
+}
+
+// withParam appends a driver parameter to a DSN. Both drivers strip the
+// parameters from plain paths.
+func withParam(dsn, param string) string {
+	if strings.Contains(dsn, "?") {
+		return dsn + "&" + param
+	}
+	return dsn + "?" + param
+}
+
+func closePools(reader, writer *sql.DB) error {
+	if reader == writer {
+		return writer.Close()
+	}
+	return errors.Join(reader.Close(), writer.Close())
+}
@@ -0,0 +1,1047 @@
+// Package db stores an index of a vault in SQLite: notes, their chunks,
+// tags, links and properties, and the chunks' embeddings, searched with
+// sqlite-vec or, in pure-Go builds, by scanning. A VectorStore can keep the
+// embeddings elsewhere.
+package db
+
+import (
+	"cmp"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxQueryIDs caps how many ids are bound in one IN (...) list. Older SQLite
+// builds allow only 999 parameters per statement, so longer lists are split.
+const maxQueryIDs = 500
+
+// driverName is the SQLite driver with obsvec's SQL functions registered.
+const driverName = "sqlite3_obsvec"
+
+// DB is safe for concurrent use, and for use alongside other processes with
+// the same file open: reads run in parallel on conn while writes queue for
+// the single writer connection.
+type DB struct {
+	conn         *sql.DB
+	writer       *sql.DB
+	embedDim     int
+	embedModel   string
+	quantization Quantization
+	rebuild      bool
+	hasFTS       bool
+	vectors      VectorStore
+}
+
+// Options configures how a database is opened.
+type Options struct {
+	EmbedDim int
+
+	// EmbedModel is recorded with the index so vectors from another model
+	// are caught on open. Empty skips the check.
+	EmbedModel string
+
+	// Quantization selects the stored embedding type. It is fixed when the
+	// first vectors are written; switching later requires a rebuild.
+	Quantization Quantization
+
+	// Rebuild clears an index whose vectors don't match the options instead
+	// of failing, for callers about to reindex everything.
+	Rebuild bool
+
+	// Key encrypts the database with SQLCipher. It is 64 hex characters (a
+	// raw 256-bit key) and needs a binary linked against SQLCipher.
+	Key string
+
+	// VectorStore keeps embeddings outside the database, for example in a
+	// vector server. The database takes ownership and closes it. Nil uses
+	// the built-in sqlite-vec table.
+	VectorStore VectorStore
+}
+
+type Document struct {
+	ID         int64
+	Path       string
+	Title      string
+	ModifiedAt int64
+	IndexedAt  int64
+
+	// ContentHash is the checksum of the file as last indexed, empty for
+	// documents indexed before checksums were recorded.
+	ContentHash string
+}
+
+type Chunk struct {
+	ID        int64
+	DocID     int64
+	Content   string
+	StartLine int
+	EndLine   int
+	Heading   string
+}
+
+type ChunkWithScore struct {
+	Chunk
+	Distance   float64
+	Path       string
+	Title      string
+	ModifiedAt int64
+}
+
+func Open(path string, embedDim int) (*DB, error) {
+	return OpenWithOptions(path, Options{EmbedDim: embedDim})
+}
+
+func OpenWithOptions(path string, opts Options) (*DB, error) {
+	if opts.Quantization == QuantizeBit && opts.EmbedDim%8 != 0 {
+		return nil, fmt.Errorf("bit quantization needs an embedding dimension divisible by 8, got %d", opts.EmbedDim)
+	}
+	if opts.VectorStore != nil && opts.Quantization != QuantizeNone {
+		return nil, fmt.Errorf("quantization is only supported by the built-in vector store")
+	}
+
+	if opts.Key != "" && !validKey(opts.Key) {
+		return nil, fmt.Errorf("encryption key must be %d hex characters", keyHexLen)
+	}
+	conn, writer := openPools(path, opts.Key)
+	if opts.Key != "" {
+		if err := checkCipher(writer); err != nil {
+			closePools(conn, writer) //nolint:errcheck
+			return nil, err
+		}
+	}
+
+	db := &DB{
+		conn:         conn,
+		writer:       writer,
+		embedDim:     opts.EmbedDim,
+		embedModel:   opts.EmbedModel,
+		quantization: opts.Quantization,
+		rebuild:      opts.Rebuild,
+		vectors:      opts.VectorStore,
+	}
+	if db.vectors == nil {
+		db.vectors = newLocalVectorStore(conn, writer, opts.EmbedDim, opts.Quantization)
+	}
+	if err := db.init(); err != nil {
+		db.Close() //nolint:errcheck
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (db *DB) Close() error {
+	return errors.Join(db.vectors.Close(), closePools(db.conn, db.writer))
+}
+
+func (db *DB) init() error {
+	// Readers see the last commit while a write is in progress instead of
+	// waiting for it. The mode is stored in the file.
+	if _, err := db.writer.Exec("PRAGMA journal_mode = WAL"); err != nil {
+		return err
+	}
+
+	if err := checkVectorSupport(db.conn); err != nil {
+		return err
+	}
+
+	if err := db.migrate(); err != nil {
+		return err
+	}
+
+	if err := db.checkEmbeddings(); err != nil {
+		return err
+	}
+
+	return db.initFTS()
+}
+
+// initFTS creates the keyword index when SQLite was built with FTS5
+// (go build -tags sqlite_fts5). Without it, search falls back to vectors only.
+func (db *DB) initFTS() error {
+	var enabled bool
+	if err := db.conn.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled); err != nil {
+		return err
+	}
+
+	var exists int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'fts_chunks'").Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		// Chunks written from here on don't reach the keyword index, so
+		// the next binary with FTS5 rebuilds it
+		if exists > 0 {
+			return db.setMeta(ftsStaleKey, "1")
+		}
+		return nil
+	}
+
+	if exists == 0 {
+		// Backfill chunks indexed before keyword search was available
+		_, err := db.writer.Exec(`
+			CREATE VIRTUAL TABLE fts_chunks USING fts5(
+				content,
+				heading,
+				tokenize = "unicode61 tokenchars '_'"
+			);
+			INSERT INTO fts_chunks (rowid, content, heading) SELECT id, content, heading FROM chunks;
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create keyword index: %w", err)
+		}
+	} else if err := db.syncFTS(); err != nil {
+		return err
+	}
+
+	db.hasFTS = true
+	return nil
+}
+
+// ftsStaleKey is set in meta when a binary without FTS5 opened a database
+// that has a keyword index, which it can't keep up to date.
+const ftsStaleKey = "fts_stale"
+
+// syncFTS rebuilds the keyword index after a binary without FTS5 wrote to
+// the database: when one marked it stale, or when it holds other chunk ids
+// than the chunks table, for databases written before the mark existed.
+func (db *DB) syncFTS() error {
+	stale, err := db.getMeta(ftsStaleKey)
+	if err != nil {
+		return err
+	}
+	if stale == "" {
+		var drifted bool
+		err := db.conn.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM chunks WHERE id NOT IN (SELECT rowid FROM fts_chunks))
+				OR EXISTS (SELECT 1 FROM fts_chunks WHERE rowid NOT IN (SELECT id FROM chunks))
+		`).Scan(&drifted)
+		if err != nil || !drifted {
+			return err
+		}
+	}
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec(`
+		DELETE FROM fts_chunks;
+		INSERT INTO fts_chunks (rowid, content, heading) SELECT id, content, heading FROM chunks;
+	`); err != nil {
+		return fmt.Errorf("failed to rebuild keyword index: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM meta WHERE key = ?", ftsStaleKey); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// HasKeywordSearch reports whether the FTS5 keyword index is available.
+func (db *DB) HasKeywordSearch() bool {
+	return db.hasFTS
+}
+
+func (db *DB) GetDocument(path string) (*Document, error) {
+	var doc Document
+	err := db.conn.QueryRow(
+		"SELECT id, path, title, modified_at, indexed_at, content_hash FROM documents WHERE path = ?",
+		path,
+	).Scan(&doc.ID, &doc.Path, &doc.Title, &doc.ModifiedAt, &doc.IndexedAt, &doc.ContentHash)
+	return scanOptional(err, &doc)
+}
+
+func (db *DB) UpsertDocument(path, title string, modifiedAt, indexedAt int64) (int64, error) {
+	// LastInsertId isn't updated when the upsert updates an existing row
+	var docID int64
+	err := db.writer.QueryRow(`
+		INSERT INTO documents (path, title, modified_at, indexed_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			title = excluded.title,
+			modified_at = excluded.modified_at,
+			indexed_at = excluded.indexed_at
+		RETURNING id
+	`, path, title, modifiedAt, indexedAt).Scan(&docID)
+	return docID, err
+}
+
+// SetDocumentHash records the checksum of the content a document was indexed
+// from.
+func (db *DB) SetDocumentHash(docID int64, hash string) error {
+	_, err := db.writer.Exec("UPDATE documents SET content_hash = ? WHERE id = ?", hash, docID)
+	return err
+}
+
+// SetDocumentModified records a new modification time for a document whose
+// content hasn't changed.
+func (db *DB) SetDocumentModified(docID int64, modifiedAt int64) error {
+	_, err := db.writer.Exec("UPDATE documents SET modified_at = ? WHERE id = ?", modifiedAt, docID)
+	return err
+}
+
+// DeleteDocument removes a document with its chunks, vectors and metadata in
+// one transaction, so a failure never leaves orphaned chunks or vectors.
+func (db *DB) DeleteDocument(path string) error {
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return err
+	}
+
+	var docID int64
+	err = tx.QueryRow("SELECT id FROM documents WHERE path = ?", path).Scan(&docID)
+	if err == sql.ErrNoRows {
+		return tx.Rollback()
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := db.deleteDocumentTx(tx, docID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) deleteDocumentTx(tx *sql.Tx, docID int64) error {
+	if err := db.deleteChunksForDocumentTx(tx, docID); err != nil {
+		return err
+	}
+	for _, table := range []string{"document_tags", "document_links", "document_aliases"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE doc_id = ?", docID); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec("DELETE FROM documents WHERE id = ?", docID)
+	return err
+}
+
+// SetDocumentTags replaces the tags stored for a document.
+func (db *DB) SetDocumentTags(docID int64, tags []string) error {
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM document_tags WHERE doc_id = ?", docID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO document_tags (doc_id, tag) VALUES (?, ?)", docID, NormalizeTag(tag)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) GetDocumentTags(docID int64) ([]string, error) {
+	rows, err := db.conn.Query("SELECT tag FROM document_tags WHERE doc_id = ? ORDER BY tag", docID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// SetDocumentAliases replaces the frontmatter aliases stored for a document.
+func (db *DB) SetDocumentAliases(docID int64, aliases []string) error {
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM document_aliases WHERE doc_id = ?", docID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	for _, alias := range aliases {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO document_aliases (doc_id, alias) VALUES (?, ?)", docID, alias); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetDocumentMetadata replaces a document's frontmatter properties, stored
+// as a JSON object so SearchFilter.Properties can query them.
+func (db *DB) SetDocumentMetadata(docID int64, metadata map[string]any) error {
+	data, err := marshalMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	_, err = db.writer.Exec("UPDATE documents SET metadata = ? WHERE id = ?", data, docID)
+	return err
+}
+
+// GetDocumentMetadata returns a document's frontmatter properties.
+func (db *DB) GetDocumentMetadata(docID int64) (map[string]any, error) {
+	var data string
+	if err := db.conn.QueryRow("SELECT metadata FROM documents WHERE id = ?", docID).Scan(&data); err != nil {
+		return nil, err
+	}
+	var metadata map[string]any
+	if err := json.Unmarshal([]byte(data), &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// FindDocuments returns the documents matching every property filter,
+// ordered by path.
+func (db *DB) FindDocuments(filters []PropertyFilter) ([]Document, error) {
+	query := "SELECT id, path, title, modified_at, indexed_at, content_hash FROM documents d"
+	var args []any
+	if len(filters) > 0 {
+		var conds []string
+		for _, filter := range filters {
+			conds = append(conds, filter.condition(&args))
+		}
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	rows, err := db.conn.Query(query+" ORDER BY path", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var docs []Document
+	for rows.Next() {
+		var doc Document
+		if err := rows.Scan(&doc.ID, &doc.Path, &doc.Title, &doc.ModifiedAt, &doc.IndexedAt, &doc.ContentHash); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+func marshalMetadata(metadata map[string]any) (string, error) {
+	if len(metadata) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(metadata)
+	return string(data), err
+}
+
+// GetAllAliases returns the aliases of every document, keyed by document id.
+func (db *DB) GetAllAliases() (map[int64][]string, error) {
+	rows, err := db.conn.Query("SELECT doc_id, alias FROM document_aliases ORDER BY doc_id, alias")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	aliases := make(map[int64][]string)
+	for rows.Next() {
+		var docID int64
+		var alias string
+		if err := rows.Scan(&docID, &alias); err != nil {
+			return nil, err
+		}
+		aliases[docID] = append(aliases[docID], alias)
+	}
+	return aliases, rows.Err()
+}
+
+// DocumentLink is a resolved wikilink from one document to another.
+type DocumentLink struct {
+	FromID int64
+	ToID   int64
+}
+
+// SetDocumentLinks replaces the outgoing link targets stored for a document.
+// Targets are vault-relative paths or bare note names without the .md
+// extension, as written in [[wikilinks]].
+func (db *DB) SetDocumentLinks(docID int64, targets []string) error {
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM document_links WHERE doc_id = ?", docID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	for _, target := range targets {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO document_links (doc_id, target) VALUES (?, ?)", docID, strings.ToLower(target)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetLinkedDocuments resolves the outgoing links of the given documents. A
+// target matches a document with that exact path, or, like Obsidian's
+// shortest-path links, any document with that name in some folder. Links
+// that don't resolve and self-links are skipped.
+func (db *DB) GetLinkedDocuments(docIDs []int64) ([]DocumentLink, error) {
+	if len(docIDs) == 0 {
+		return nil, nil
+	}
+
+	var links []DocumentLink
+	for batch := range slices.Chunk(docIDs, maxQueryIDs) {
+		batchLinks, err := db.getLinkedDocumentsBatch(batch)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, batchLinks...)
+	}
+
+	slices.SortFunc(links, func(a, b DocumentLink) int {
+		return cmp.Or(cmp.Compare(a.FromID, b.FromID), cmp.Compare(a.ToID, b.ToID))
+	})
+	return links, nil
+}
+
+func (db *DB) getLinkedDocumentsBatch(docIDs []int64) ([]DocumentLink, error) {
+	var args []any
+	rows, err := db.conn.Query(`
+		SELECT DISTINCT l.doc_id, d.id
+		FROM document_links l
+		JOIN documents d
+			ON lower(d.path) = l.target || '.md'
+			OR substr(lower(d.path), -length(l.target) - 4) = '/' || l.target || '.md'
+		WHERE l.doc_id IN (`+idPlaceholders(docIDs, &args)+`) AND d.id != l.doc_id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var links []DocumentLink
+	for rows.Next() {
+		var link DocumentLink
+		if err := rows.Scan(&link.FromID, &link.ToID); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (db *DB) DeleteChunksForDocument(docID int64) error {
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := db.deleteChunksForDocumentTx(tx, docID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) deleteChunksForDocumentTx(tx *sql.Tx, docID int64) error {
+	if err := db.deleteVectorsTx(tx, "SELECT id FROM chunks WHERE doc_id = ?", docID); err != nil {
+		return err
+	}
+
+	if db.hasFTS {
+		if _, err := tx.Exec("DELETE FROM fts_chunks WHERE rowid IN (SELECT id FROM chunks WHERE doc_id = ?)", docID); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec("DELETE FROM chunks WHERE doc_id = ?", docID)
+	return err
+}
+
+func (db *DB) InsertChunk(docID int64, content string, startLine, endLine int, heading string) (int64, error) {
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO chunks (doc_id, content, start_line, end_line, heading, word_count, token_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, docID, content, startLine, endLine, heading, CountWords(content), EstimateTokens(content))
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+
+	chunkID, err := result.LastInsertId()
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+
+	if db.hasFTS {
+		_, err := tx.Exec("INSERT OR REPLACE INTO fts_chunks (rowid, content, heading) VALUES (?, ?, ?)", chunkID, content, heading)
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, err
+		}
+	}
+
+	return chunkID, tx.Commit()
+}
+
+// InsertEmbedding stores a float32 embedding serialized with
+// SerializeFloat32, quantizing it if the database is configured to.
+func (db *DB) InsertEmbedding(chunkID int64, embedding []byte) error {
+	return db.vectors.Insert(chunkID, DeserializeFloat32(embedding))
+}
+
+func (db *DB) SearchSimilar(queryEmbedding []byte, limit int, filter SearchFilter) (chunks []ChunkWithScore, err error) {
+	defer logQuery("vector search", time.Now(), &chunks, &err)
+
+	hits, err := db.searchVectors(DeserializeFloat32(queryEmbedding), limit, filter)
+	if err != nil || len(hits) == 0 {
+		return nil, err
+	}
+	return db.chunksForHits(hits)
+}
+
+// SearchKeyword returns chunks matching any of the query terms ordered by
+// BM25. Distance holds the BM25 score, where lower is better.
+func (db *DB) SearchKeyword(query string, limit int, filter SearchFilter) (chunks []ChunkWithScore, err error) {
+	if !db.hasFTS {
+		return nil, nil
+	}
+	defer logQuery("keyword search", time.Now(), &chunks, &err)
+
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	args := []any{match}
+	filterClause := ""
+	if subquery, filterArgs := filter.chunkIDQuery(); subquery != "" {
+		filterClause = "AND c.id IN (" + subquery + ")"
+		args = append(args, filterArgs...)
+	}
+	args = append(args, limit)
+
+	rows, err := db.conn.Query(`
+		SELECT
+			c.id,
+			bm25(fts_chunks),
+			c.doc_id,
+			c.content,
+			c.start_line,
+			c.end_line,
+			c.heading,
+			d.path,
+			COALESCE(d.title, ''),
+			d.modified_at
+		FROM fts_chunks f
+		JOIN chunks c ON c.id = f.rowid
+		JOIN documents d ON d.id = c.doc_id
+		WHERE fts_chunks MATCH ? `+filterClause+`
+		ORDER BY bm25(fts_chunks)
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	return scanChunksWithScore(rows)
+}
+
+// SearchTitles returns the best chunk of each document whose title or
+// heading path contains any of the terms, ordered by how many terms match.
+// Distance holds the negated match count, so lower is better as with the
+// other searches.
+func (db *DB) SearchTitles(terms []string, limit int, filter SearchFilter) (chunks []ChunkWithScore, err error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	defer logQuery("title search", time.Now(), &chunks, &err)
+
+	var matches, anyMatch []string
+	var termArgs []any
+	for _, term := range terms {
+		matches = append(matches, "(instr(lower(name), ?) > 0)")
+		anyMatch = append(anyMatch, "instr(lower(name), ?) > 0")
+		termArgs = append(termArgs, strings.ToLower(term))
+	}
+	score := strings.Join(matches, " + ")
+
+	// Title hits count for every chunk of the note, heading hits for the
+	// chunk under the heading
+	args := slices.Concat(termArgs, termArgs, termArgs)
+	filterClause := ""
+	if subquery, filterArgs := filter.chunkIDQuery(); subquery != "" {
+		filterClause = "AND c.id IN (" + subquery + ")"
+		args = append(args, filterArgs...)
+	}
+	args = append(args, limit)
+
+	// SQLite fills bare columns from the row holding the MAX() in each group
+	rows, err := db.conn.Query(`
+		SELECT id, -matched, doc_id, content, start_line, end_line, heading, path, title, modified_at
+		FROM (
+			SELECT
+				c.id,
+				MAX(COALESCE(nt.matched, 0) + COALESCE(ht.matched, 0)) AS matched,
+				c.doc_id,
+				c.content,
+				c.start_line,
+				c.end_line,
+				c.heading,
+				d.path,
+				COALESCE(d.title, '') AS title,
+				d.modified_at
+			FROM chunks c
+			JOIN documents d ON d.id = c.doc_id
+			LEFT JOIN (SELECT doc_id, `+score+` AS matched FROM titles WHERE kind = 'title') nt ON nt.doc_id = c.doc_id
+			LEFT JOIN (SELECT chunk_id, `+score+` AS matched FROM titles WHERE kind = 'heading') ht ON ht.chunk_id = c.id
+			WHERE c.doc_id IN (
+				SELECT doc_id FROM titles WHERE kind IN ('title', 'heading') AND (`+strings.Join(anyMatch, " OR ")+`)
+			) `+filterClause+`
+			GROUP BY c.doc_id
+		)
+		WHERE matched > 0
+		ORDER BY matched DESC, modified_at DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	return scanChunksWithScore(rows)
+}
+
+// logQuery logs how long a search query took and what it returned, for
+// ofind -vv. It is deferred, so it takes the results by pointer.
+func logQuery(name string, start time.Time, chunks *[]ChunkWithScore, err *error) {
+	if *err != nil {
+		slog.Debug(name, "duration", time.Since(start), "error", *err)
+		return
+	}
+	slog.Debug(name, "duration", time.Since(start), "rows", len(*chunks))
+}
+
+func scanChunksWithScore(rows *sql.Rows) ([]ChunkWithScore, error) {
+	var results []ChunkWithScore
+	for rows.Next() {
+		var chunk ChunkWithScore
+		err := rows.Scan(
+			&chunk.ID,
+			&chunk.Distance,
+			&chunk.DocID,
+			&chunk.Content,
+			&chunk.StartLine,
+			&chunk.EndLine,
+			&chunk.Heading,
+			&chunk.Path,
+			&chunk.Title,
+			&chunk.ModifiedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunk)
+	}
+
+	return results, rows.Err()
+}
+
+// ftsQuery turns free text into an FTS5 expression that ORs every term as a
+// quoted string, so punctuation in identifiers can't break the MATCH syntax.
+func ftsQuery(query string) string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		field = strings.ReplaceAll(field, `"`, "")
+		if field == "" {
+			continue
+		}
+		terms = append(terms, `"`+field+`"`)
+	}
+	return strings.Join(terms, " OR ")
+}
+
+func (db *DB) GetAllDocuments() ([]Document, error) {
+	rows, err := db.conn.Query("SELECT id, path, title, modified_at, indexed_at, content_hash FROM documents")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var docs []Document
+	for rows.Next() {
+		var doc Document
+		if err := rows.Scan(&doc.ID, &doc.Path, &doc.Title, &doc.ModifiedAt, &doc.IndexedAt, &doc.ContentHash); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// GetRecentDocuments returns up to limit of the most recently modified
+// documents that have at least one chunk, newest first.
+func (db *DB) GetRecentDocuments(limit int) ([]Document, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, path, title, modified_at, indexed_at, content_hash FROM documents d
+		WHERE EXISTS (SELECT 1 FROM chunks c WHERE c.doc_id = d.id)
+		ORDER BY modified_at DESC, id
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var docs []Document
+	for rows.Next() {
+		var doc Document
+		if err := rows.Scan(&doc.ID, &doc.Path, &doc.Title, &doc.ModifiedAt, &doc.IndexedAt, &doc.ContentHash); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+func (db *DB) GetChunk(id int64) (*Chunk, error) {
+	var chunk Chunk
+	err := db.conn.QueryRow(
+		"SELECT id, doc_id, content, start_line, end_line, heading FROM chunks WHERE id = ?",
+		id,
+	).Scan(&chunk.ID, &chunk.DocID, &chunk.Content, &chunk.StartLine, &chunk.EndLine, &chunk.Heading)
+	return scanOptional(err, &chunk)
+}
+
+// GetChunksForDocument returns a document's chunks in file order.
+func (db *DB) GetChunksForDocument(docID int64) ([]Chunk, error) {
+	return db.queryChunks(
+		"SELECT id, doc_id, content, start_line, end_line, heading FROM chunks WHERE doc_id = ? ORDER BY start_line, id",
+		docID,
+	)
+}
+
+// GetNeighborChunks returns up to n chunks on each side of chunkID within its
+// document, in document order.
+func (db *DB) GetNeighborChunks(chunkID int64, n int) (before, after []Chunk, err error) {
+	var docID int64
+	var startLine int
+	err = db.conn.QueryRow("SELECT doc_id, start_line FROM chunks WHERE id = ?", chunkID).Scan(&docID, &startLine)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before, err = db.queryChunks(`
+		SELECT id, doc_id, content, start_line, end_line, heading FROM chunks
+		WHERE doc_id = ? AND (start_line, id) < (?, ?)
+		ORDER BY start_line DESC, id DESC
+		LIMIT ?
+	`, docID, startLine, chunkID, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.Reverse(before)
+
+	after, err = db.queryChunks(`
+		SELECT id, doc_id, content, start_line, end_line, heading FROM chunks
+		WHERE doc_id = ? AND (start_line, id) > (?, ?)
+		ORDER BY start_line, id
+		LIMIT ?
+	`, docID, startLine, chunkID, n)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return before, after, nil
+}
+
+func (db *DB) queryChunks(query string, args ...any) ([]Chunk, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var chunks []Chunk
+	for rows.Next() {
+		var chunk Chunk
+		if err := rows.Scan(&chunk.ID, &chunk.DocID, &chunk.Content, &chunk.StartLine, &chunk.EndLine, &chunk.Heading); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+func (db *DB) GetChunksForRerank(chunkIDs []int64) ([]Chunk, error) {
+	if len(chunkIDs) == 0 {
+		return nil, nil
+	}
+
+	chunkMap := make(map[int64]Chunk, len(chunkIDs))
+	for batch := range slices.Chunk(chunkIDs, maxQueryIDs) {
+		var args []any
+		chunks, err := db.queryChunks(
+			"SELECT id, doc_id, content, start_line, end_line, heading FROM chunks WHERE id IN ("+idPlaceholders(batch, &args)+")",
+			args...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range chunks {
+			chunkMap[chunk.ID] = chunk
+		}
+	}
+
+	result := make([]Chunk, 0, len(chunkIDs))
+	for _, id := range chunkIDs {
+		if chunk, ok := chunkMap[id]; ok {
+			result = append(result, chunk)
+		}
+	}
+	return result, nil
+}
+
+// GetEmbeddings returns the stored vectors for the given chunks, keyed by
+// chunk id. Chunks without an embedding are omitted.
+func (db *DB) GetEmbeddings(chunkIDs []int64) (map[int64][]float32, error) {
+	if len(chunkIDs) == 0 {
+		return nil, nil
+	}
+
+	return db.vectors.Get(chunkIDs)
+}
+
+// GetDocumentEmbeddings returns the mean of each document's chunk embeddings,
+// keyed by document id. Documents without embedded chunks are omitted.
+func (db *DB) GetDocumentEmbeddings() (map[int64][]float32, error) {
+	chunkIDs, docIDs, err := db.chunkDocuments()
+	if err != nil {
+		return nil, err
+	}
+
+	sums := make(map[int64][]float32)
+	counts := make(map[int64]int)
+	for batch := range slices.Chunk(chunkIDs, maxQueryIDs) {
+		embeddings, err := db.vectors.Get(batch)
+		if err != nil {
+			return nil, err
+		}
+		for chunkID, vector := range embeddings {
+			docID := docIDs[chunkID]
+			sum, ok := sums[docID]
+			if !ok {
+				sum = make([]float32, len(vector))
+				sums[docID] = sum
+			}
+			for i, x := range vector {
+				sum[i] += x
+			}
+			counts[docID]++
+		}
+	}
+
+	for docID, sum := range sums {
+		n := float32(counts[docID])
+		for i := range sum {
+			sum[i] /= n
+		}
+	}
+	return sums, nil
+}
+
+// chunkDocuments returns every chunk id along with a map to its document id.
+func (db *DB) chunkDocuments() ([]int64, map[int64]int64, error) {
+	rows, err := db.conn.Query("SELECT id, doc_id FROM chunks")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var chunkIDs []int64
+	docIDs := make(map[int64]int64)
+	for rows.Next() {
+		var chunkID, docID int64
+		if err := rows.Scan(&chunkID, &docID); err != nil {
+			return nil, nil, err
+		}
+		chunkIDs = append(chunkIDs, chunkID)
+		docIDs[chunkID] = docID
+	}
+	return chunkIDs, docIDs, rows.Err()
+}
+
+// SerializeFloat32 encodes a vector as little-endian float32s, the blob
+// format sqlite-vec reads.
+func SerializeFloat32(vector []float32) []byte {
+	blob := make([]byte, len(vector)*4)
+	for i, x := range vector {
+		binary.LittleEndian.PutUint32(blob[i*4:], math.Float32bits(x))
+	}
+	return blob
+}
+
+// DeserializeFloat32 is the inverse of SerializeFloat32.
+func DeserializeFloat32(blob []byte) []float32 {
+	vector := make([]float32, len(blob)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(blob[i*4:]))
+	}
+	return vector
+}
+
+func (db *DB) DocumentCount() (int, error) {
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM documents").Scan(&count)
+	return count, err
+}
+
+func (db *DB) ChunkCount() (int, error) {
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM chunks").Scan(&count)
+	return count, err
+}
+
+func scanOptional[T any](err error, value *T) (*T, error) {
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,1801 @@
+package db
+
+import (
+	"bytes"
+	"cmp"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupTestDB(t *testing.T) (*DB, func()) {
+	tmpDir, err := os.MkdirTemp("", "obsvec-db-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := Open(dbPath, 4) // Small dimension for testing
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	cleanup := func() {
+		db.Close()
+		os.RemoveAll(tmpDir)
+	}
+
+	return db, cleanup
+}
+
+func TestDatabaseOpen(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if db == nil {
+		t.Fatal("expected non-nil database")
+	}
+}
+
+func TestDocumentCRUD(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Insert
+	docID, err := db.UpsertDocument("test/path.md", "Test Title", 1000, 2000)
+	if err != nil {
+		t.Fatalf("failed to insert document: %v", err)
+	}
+
+	if docID == 0 {
+		t.Error("expected non-zero document ID")
+	}
+
+	// Read
+	doc, err := db.GetDocument("test/path.md")
+	if err != nil {
+		t.Fatalf("failed to get document: %v", err)
+	}
+
+	if doc == nil {
+		t.Fatal("expected document, got nil")
+	}
+
+	if doc.Title != "Test Title" {
+		t.Errorf("expected title 'Test Title', got '%s'", doc.Title)
+	}
+
+	if doc.ModifiedAt != 1000 {
+		t.Errorf("expected modified_at 1000, got %d", doc.ModifiedAt)
+	}
+
+	// Update (upsert), after another insert so a stale last insert id shows
+	db.UpsertDocument("test/other.md", "Other", 1000, 2000)
+	updatedID, err := db.UpsertDocument("test/path.md", "Updated Title", 1500, 2500)
+	if err != nil {
+		t.Fatalf("failed to update document: %v", err)
+	}
+	if updatedID != docID {
+		t.Errorf("expected the update to return id %d, got %d", docID, updatedID)
+	}
+
+	doc, _ = db.GetDocument("test/path.md")
+	if doc.Title != "Updated Title" {
+		t.Errorf("expected updated title, got '%s'", doc.Title)
+	}
+
+	// Delete
+	err = db.DeleteDocument("test/path.md")
+	if err != nil {
+		t.Fatalf("failed to delete document: %v", err)
+	}
+
+	doc, _ = db.GetDocument("test/path.md")
+	if doc != nil {
+		t.Error("expected nil after delete")
+	}
+}
+
+func TestDeleteDocument_RemovesChunksAndVectors(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	keep, _ := db.UpsertDocument("keep.md", "", 1000, 2000)
+	drop, _ := db.UpsertDocument("drop.md", "", 1000, 2000)
+	emb := SerializeFloat32([]float32{1, 0, 0, 0})
+	for _, docID := range []int64{keep, drop, drop} {
+		chunkID, _ := db.InsertChunk(docID, "content", 1, 2, "")
+		db.InsertEmbedding(chunkID, emb)
+	}
+
+	if err := db.DeleteDocument("drop.md"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	if err := db.DeleteDocument("missing.md"); err != nil {
+		t.Errorf("expected deleting a missing document to be a no-op, got %v", err)
+	}
+
+	if n, _ := db.ChunkCount(); n != 1 {
+		t.Errorf("expected 1 chunk left, got %d", n)
+	}
+	var vectors int
+	db.conn.QueryRow("SELECT COUNT(*) FROM vec_chunks").Scan(&vectors)
+	if vectors != 1 {
+		t.Errorf("expected 1 vector left, got %d", vectors)
+	}
+}
+
+func TestChunkOperations(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Create a document first
+	docID, _ := db.UpsertDocument("test.md", "Test", 1000, 2000)
+
+	// Insert chunk
+	chunkID, err := db.InsertChunk(docID, "This is chunk content", 1, 10, "Heading")
+	if err != nil {
+		t.Fatalf("failed to insert chunk: %v", err)
+	}
+
+	if chunkID == 0 {
+		t.Error("expected non-zero chunk ID")
+	}
+
+	// Get chunk
+	chunk, err := db.GetChunk(chunkID)
+	if err != nil {
+		t.Fatalf("failed to get chunk: %v", err)
+	}
+
+	if chunk.Content != "This is chunk content" {
+		t.Errorf("expected content 'This is chunk content', got '%s'", chunk.Content)
+	}
+
+	if chunk.Heading != "Heading" {
+		t.Errorf("expected heading 'Heading', got '%s'", chunk.Heading)
+	}
+
+	// Delete chunks for document
+	err = db.DeleteChunksForDocument(docID)
+	if err != nil {
+		t.Fatalf("failed to delete chunks: %v", err)
+	}
+
+	chunk, _ = db.GetChunk(chunkID)
+	if chunk != nil {
+		t.Error("expected chunk to be deleted")
+	}
+}
+
+func TestEmbeddingOperations(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	docID, _ := db.UpsertDocument("test.md", "Test", 1000, 2000)
+	chunkID, _ := db.InsertChunk(docID, "Content", 1, 5, "")
+
+	// Insert embedding (4 dimensions as configured)
+	embedding := []float32{0.1, 0.2, 0.3, 0.4}
+	embBytes := SerializeFloat32(embedding)
+
+	err := db.InsertEmbedding(chunkID, embBytes)
+	if err != nil {
+		t.Fatalf("failed to insert embedding: %v", err)
+	}
+
+	// Search similar
+	queryEmb := []float32{0.1, 0.2, 0.3, 0.4}
+	queryBytes := SerializeFloat32(queryEmb)
+
+	results, err := db.SearchSimilar(queryBytes, 10, SearchFilter{})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Errorf("expected 1 result, got %d", len(results))
+	}
+
+	if len(results) > 0 && results[0].Content != "Content" {
+		t.Errorf("expected content 'Content', got '%s'", results[0].Content)
+	}
+}
+
+func TestDocumentCount(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	count, _ := db.DocumentCount()
+	if count != 0 {
+		t.Errorf("expected 0 documents, got %d", count)
+	}
+
+	_, _ = db.UpsertDocument("a.md", "A", 1000, 2000)
+	_, _ = db.UpsertDocument("b.md", "B", 1000, 2000)
+
+	count, _ = db.DocumentCount()
+	if count != 2 {
+		t.Errorf("expected 2 documents, got %d", count)
+	}
+}
+
+func TestChunkCount(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	docID, _ := db.UpsertDocument("test.md", "Test", 1000, 2000)
+
+	count, _ := db.ChunkCount()
+	if count != 0 {
+		t.Errorf("expected 0 chunks, got %d", count)
+	}
+
+	_, _ = db.InsertChunk(docID, "Chunk 1", 1, 5, "")
+	_, _ = db.InsertChunk(docID, "Chunk 2", 6, 10, "")
+
+	count, _ = db.ChunkCount()
+	if count != 2 {
+		t.Errorf("expected 2 chunks, got %d", count)
+	}
+}
+
+func TestGetAllDocuments(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	_, _ = db.UpsertDocument("a.md", "A", 1000, 2000)
+	_, _ = db.UpsertDocument("b.md", "B", 1000, 2000)
+	_, _ = db.UpsertDocument("c.md", "C", 1000, 2000)
+
+	docs, err := db.GetAllDocuments()
+	if err != nil {
+		t.Fatalf("failed to get all documents: %v", err)
+	}
+
+	if len(docs) != 3 {
+		t.Errorf("expected 3 documents, got %d", len(docs))
+	}
+}
+
+func TestKeywordSearch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if !db.HasKeywordSearch() {
+		t.Skip("SQLite built without FTS5 (use -tags sqlite_fts5)")
+	}
+
+	docID, _ := db.UpsertDocument("test.md", "Test", 1000, 2000)
+	_, _ = db.InsertChunk(docID, "The deploy failed with error code ERR_CONN_RESET", 1, 5, "")
+	_, _ = db.InsertChunk(docID, "Notes about gardening and tomatoes", 6, 10, "")
+
+	results, err := db.SearchKeyword("ERR_CONN_RESET", 10, SearchFilter{})
+	if err != nil {
+		t.Fatalf("keyword search failed: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	if results[0].Path != "test.md" {
+		t.Errorf("expected path 'test.md', got '%s'", results[0].Path)
+	}
+
+	// Deleting the document's chunks must remove them from the keyword index
+	if err := db.DeleteChunksForDocument(docID); err != nil {
+		t.Fatalf("failed to delete chunks: %v", err)
+	}
+
+	results, _ = db.SearchKeyword("ERR_CONN_RESET", 10, SearchFilter{})
+	if len(results) != 0 {
+		t.Errorf("expected no results after delete, got %d", len(results))
+	}
+}
+
+func TestKeywordIndexRebuiltWhenOutOfSync(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(dbPath, 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if !db.HasKeywordSearch() {
+		db.Close()
+		t.Skip("SQLite built without FTS5 (use -tags sqlite_fts5)")
+	}
+	reopen := func() {
+		t.Helper()
+		db.Close()
+		if db, err = Open(dbPath, 4); err != nil {
+			t.Fatalf("failed to reopen database: %v", err)
+		}
+	}
+	defer func() { db.Close() }()
+	found := func(query string) int {
+		t.Helper()
+		results, err := db.SearchKeyword(query, 10, SearchFilter{})
+		if err != nil {
+			t.Fatalf("keyword search failed: %v", err)
+		}
+		return len(results)
+	}
+	addNote := func(path, content string) int64 {
+		docID, _ := db.UpsertDocument(path, path, 1000, 2000)
+		_, _ = db.InsertChunk(docID, content, 1, 5, "")
+		return docID
+	}
+
+	// As written by a binary without FTS5
+	db.hasFTS = false
+	deployID := addNote("deploy.md", "The deploy failed with error code ERR_CONN_RESET")
+	addNote("garden.md", "Notes about gardening and tomatoes")
+	reopen()
+	if found("ERR_CONN_RESET") != 1 {
+		t.Error("expected missing chunks added by the rebuild")
+	}
+
+	// Replacing one note with another of as many chunks keeps the count
+	db.hasFTS = false
+	_ = db.DeleteChunksForDocument(deployID)
+	potatoID := addNote("potatoes.md", "Seed potatoes arrive in March")
+	reopen()
+	if found("ERR_CONN_RESET") != 0 || found("potatoes") != 1 {
+		t.Error("expected the replaced chunks swapped by the rebuild")
+	}
+
+	// Reused chunk ids are caught by the mark a binary without FTS5 leaves
+	db.hasFTS = false
+	_ = db.DeleteChunksForDocument(potatoID)
+	addNote("onions.md", "Onion sets go in after the frost")
+	_ = db.setMeta(ftsStaleKey, "1")
+	reopen()
+	if found("potatoes") != 0 || found("onion") != 1 {
+		t.Error("expected a database marked stale rebuilt")
+	}
+	if stale, _ := db.getMeta(ftsStaleKey); stale != "" {
+		t.Errorf("expected the stale mark cleared, got %q", stale)
+	}
+}
+
+func TestFTSQuery(t *testing.T) {
+	got := ftsQuery(`error "code" 137`)
+	want := `"error" OR "code" OR "137"`
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	if ftsQuery(`  "" `) != "" {
+		t.Error("expected empty expression for query without terms")
+	}
+}
+
+func TestSearchSimilar_TagFilter(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	emb := SerializeFloat32([]float32{0.1, 0.2, 0.3, 0.4})
+
+	workID, _ := db.UpsertDocument("work.md", "Work", 1000, 2000)
+	workChunk, _ := db.InsertChunk(workID, "Work content", 1, 5, "")
+	_ = db.InsertEmbedding(workChunk, emb)
+	_ = db.SetDocumentTags(workID, []string{"#Work", "project/x"})
+
+	homeID, _ := db.UpsertDocument("home.md", "Home", 1000, 2000)
+	homeChunk, _ := db.InsertChunk(homeID, "Home content", 1, 5, "")
+	_ = db.InsertEmbedding(homeChunk, emb)
+	_ = db.SetDocumentTags(homeID, []string{"home"})
+
+	tags, _ := db.GetDocumentTags(workID)
+	if len(tags) != 2 || tags[0] != "project/x" || tags[1] != "work" {
+		t.Errorf("expected normalized tags [project/x work], got %v", tags)
+	}
+
+	results, err := db.SearchSimilar(emb, 10, SearchFilter{Tags: []string{"work"}})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Path != "work.md" {
+		t.Errorf("expected only work.md, got %v", results)
+	}
+
+	// Parent tags match nested tags
+	results, _ = db.SearchSimilar(emb, 10, SearchFilter{Tags: []string{"project"}})
+	if len(results) != 1 || results[0].Path != "work.md" {
+		t.Errorf("expected 'project' to match 'project/x', got %v", results)
+	}
+
+	// Multiple tags must all match
+	results, _ = db.SearchSimilar(emb, 10, SearchFilter{Tags: []string{"work", "home"}})
+	if len(results) != 0 {
+		t.Errorf("expected no results for disjoint tags, got %d", len(results))
+	}
+}
+
+// addFilterNotes adds n notes under filtered/ whose chunks are nearest the
+// returned query, and n further away under matching/, calling setup on
+// each. A filtered search must look past the nearest chunks to fill its
+// limit.
+func addFilterNotes(t *testing.T, db *DB, n int, setup func(docID int64, matching bool)) []byte {
+	t.Helper()
+	add := func(path string, vec []float32, matching bool) {
+		docID, err := db.UpsertDocument(path, path, 1000, 2000)
+		if err != nil {
+			t.Fatalf("failed to insert document: %v", err)
+		}
+		chunkID, _ := db.InsertChunk(docID, "content", 1, 5, "")
+		_ = db.InsertEmbedding(chunkID, SerializeFloat32(vec))
+		setup(docID, matching)
+	}
+	for i := range n {
+		add(fmt.Sprintf("filtered/%02d.md", i), []float32{1, 0.01 * float32(i), 0, 0}, false)
+	}
+	for i := range n {
+		add(fmt.Sprintf("matching/%02d.md", i), []float32{0.2, 1, 0.01 * float32(i), 0}, true)
+	}
+	return SerializeFloat32([]float32{1, 0, 0, 0})
+}
+
+func TestSearchSimilar_TagFilterPastNearest(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	query := addFilterNotes(t, db, 30, func(docID int64, matching bool) {
+		if matching {
+			_ = db.SetDocumentTags(docID, []string{"work"})
+		}
+	})
+
+	results, err := db.SearchSimilar(query, 5, SearchFilter{Tags: []string{"work"}})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 tagged results behind the untagged ones, got %d", len(results))
+	}
+	for _, r := range results {
+		if !strings.HasPrefix(r.Path, "matching/") {
+			t.Errorf("expected only tagged notes, got %s", r.Path)
+		}
+	}
+}
+
+func TestPropertyFilter(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	emb := SerializeFloat32([]float32{0.1, 0.2, 0.3, 0.4})
+	for path, metadata := range map[string]map[string]any{
+		"done.md":  {"status": "Done", "priority": 1.0, "authors": []any{"ann", "bob"}, "draft": false},
+		"open.md":  {"status": "open", "priority": 3.0, "authors": []any{"cat"}},
+		"plain.md": nil,
+	} {
+		docID, _ := db.UpsertDocument(path, "", 1000, 2000)
+		chunkID, _ := db.InsertChunk(docID, "content", 1, 2, "")
+		_ = db.InsertEmbedding(chunkID, emb)
+		if err := db.SetDocumentMetadata(docID, metadata); err != nil {
+			t.Fatalf("SetDocumentMetadata failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		filter PropertyFilter
+		want   []string
+	}{
+		{PropertyFilter{Key: "Status", Op: PropertyEquals, Value: "done"}, []string{"done.md"}},
+		{PropertyFilter{Key: "status", Op: PropertyNotEquals, Value: "done"}, []string{"open.md", "plain.md"}},
+		{PropertyFilter{Key: "authors", Op: PropertyEquals, Value: "bob"}, []string{"done.md"}},
+		{PropertyFilter{Key: "priority", Op: PropertyGreaterOrEqual, Value: ParsePropertyValue("2")}, []string{"open.md"}},
+		{PropertyFilter{Key: "priority", Op: PropertyLess, Value: 2}, []string{"done.md"}},
+		{PropertyFilter{Key: "draft", Op: PropertyEquals, Value: ParsePropertyValue("false")}, []string{"done.md"}},
+		{PropertyFilter{Key: "authors", Op: PropertyExists}, []string{"done.md", "open.md"}},
+	}
+	for _, tt := range tests {
+		docs, err := db.FindDocuments([]PropertyFilter{tt.filter})
+		if err != nil {
+			t.Fatalf("FindDocuments(%v) failed: %v", tt.filter, err)
+		}
+		var paths []string
+		for _, doc := range docs {
+			paths = append(paths, doc.Path)
+		}
+		if !slices.Equal(paths, tt.want) {
+			t.Errorf("FindDocuments(%v) = %v, want %v", tt.filter, paths, tt.want)
+		}
+	}
+
+	results, err := db.SearchSimilar(emb, 10, SearchFilter{Properties: []PropertyFilter{{Key: "status", Op: PropertyEquals, Value: "open"}}})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Path != "open.md" {
+		t.Errorf("expected only open.md, got %v", results)
+	}
+}
+
+func TestMatchPathGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"Projects/**", "Projects/Idea.md", true},
+		{"Projects/**", "Projects/2024/Q1/Plan.md", true},
+		{"Projects/**", "Archive/Projects/Idea.md", false},
+		{"Projects", "Projects/Idea.md", true},
+		{"Projects", "ProjectsOld/Idea.md", false},
+		{"Daily/*.md", "Daily/2024-01-01.md", true},
+		{"Daily/*.md", "Daily/2024/01-01.md", false},
+		{"**/README.md", "README.md", true},
+		{"**/README.md", "a/b/README.md", true},
+		{"*.md", "a/b.md", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPathGlob(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPathGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSearchSimilar_PathFilter(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	emb := SerializeFloat32([]float32{0.1, 0.2, 0.3, 0.4})
+	for _, path := range []string{"Projects/a.md", "Projects/Daily/b.md", "Daily/c.md"} {
+		docID, _ := db.UpsertDocument(path, path, 1000, 2000)
+		chunkID, _ := db.InsertChunk(docID, "content", 1, 5, "")
+		_ = db.InsertEmbedding(chunkID, emb)
+	}
+
+	results, err := db.SearchSimilar(emb, 10, SearchFilter{
+		Paths:        []string{"Projects/**"},
+		ExcludePaths: []string{"**/Daily/**"},
+	})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+
+	if len(results) != 1 || results[0].Path != "Projects/a.md" {
+		t.Errorf("expected only Projects/a.md, got %v", results)
+	}
+}
+
+func TestSearchSimilar_PathFilterPastNearest(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	query := addFilterNotes(t, db, 30, func(int64, bool) {})
+
+	results, err := db.SearchSimilar(query, 5, SearchFilter{Paths: []string{"matching/**"}})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results behind the excluded ones, got %d", len(results))
+	}
+	for _, r := range results {
+		if !strings.HasPrefix(r.Path, "matching/") {
+			t.Errorf("expected only notes under matching/, got %s", r.Path)
+		}
+	}
+}
+
+func TestSearchSimilar_DateFilter(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	emb := SerializeFloat32([]float32{0.1, 0.2, 0.3, 0.4})
+	for path, modifiedAt := range map[string]int64{"old.md": 1000, "mid.md": 2000, "new.md": 3000} {
+		docID, _ := db.UpsertDocument(path, path, modifiedAt, modifiedAt)
+		chunkID, _ := db.InsertChunk(docID, "content", 1, 5, "")
+		_ = db.InsertEmbedding(chunkID, emb)
+	}
+
+	results, err := db.SearchSimilar(emb, 10, SearchFilter{ModifiedSince: 2000, ModifiedBefore: 3000})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+
+	if len(results) != 1 || results[0].Path != "mid.md" {
+		t.Errorf("expected only mid.md, got %v", results)
+	}
+}
+
+func TestSearchSimilar_DateFilterPastNearest(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	query := addFilterNotes(t, db, 30, func(docID int64, matching bool) {
+		if matching {
+			_ = db.SetDocumentModified(docID, 5000)
+		}
+	})
+
+	results, err := db.SearchSimilar(query, 5, SearchFilter{ModifiedSince: 4000})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 recent results behind the older ones, got %d", len(results))
+	}
+	for _, r := range results {
+		if !strings.HasPrefix(r.Path, "matching/") {
+			t.Errorf("expected only recently modified notes, got %s", r.Path)
+		}
+	}
+}
+
+func TestGetEmbeddings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	docID, _ := db.UpsertDocument("test.md", "Test", 1000, 2000)
+	chunkID, _ := db.InsertChunk(docID, "Content", 1, 5, "")
+	embedding := []float32{0.1, 0.2, 0.3, 0.4}
+	embBytes := SerializeFloat32(embedding)
+	_ = db.InsertEmbedding(chunkID, embBytes)
+
+	embeddings, err := db.GetEmbeddings([]int64{chunkID, chunkID + 100})
+	if err != nil {
+		t.Fatalf("failed to get embeddings: %v", err)
+	}
+
+	if len(embeddings) != 1 {
+		t.Fatalf("expected 1 embedding, got %d", len(embeddings))
+	}
+
+	for i, v := range embeddings[chunkID] {
+		if v != embedding[i] {
+			t.Errorf("component %d: expected %v, got %v", i, embedding[i], v)
+		}
+	}
+}
+
+func TestSearchSimilar_ExcludeDocIDs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	emb := SerializeFloat32([]float32{0.1, 0.2, 0.3, 0.4})
+
+	aID, _ := db.UpsertDocument("a.md", "A", 1000, 2000)
+	aChunk, _ := db.InsertChunk(aID, "A content", 1, 5, "")
+	_ = db.InsertEmbedding(aChunk, emb)
+
+	bID, _ := db.UpsertDocument("b.md", "B", 1000, 2000)
+	bChunk, _ := db.InsertChunk(bID, "B content", 1, 5, "")
+	_ = db.InsertEmbedding(bChunk, emb)
+
+	chunks, err := db.GetChunksForDocument(aID)
+	if err != nil {
+		t.Fatalf("failed to get chunks: %v", err)
+	}
+	if len(chunks) != 1 || chunks[0].ID != aChunk {
+		t.Errorf("expected chunk %d for a.md, got %v", aChunk, chunks)
+	}
+
+	results, err := db.SearchSimilar(emb, 10, SearchFilter{ExcludeDocIDs: []int64{aID}})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Path != "b.md" {
+		t.Errorf("expected only b.md, got %v", results)
+	}
+}
+
+func TestSearchTitles(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	taxID, _ := db.UpsertDocument("Finance/Tax Return 2024.md", "Tax Return 2024", 1000, 2000)
+	_, _ = db.InsertChunk(taxID, "Intro", 1, 2, "")
+	taxChunk, _ := db.InsertChunk(taxID, "Deductions", 3, 9, "Tax Return 2024 > Deductions")
+
+	budgetID, _ := db.UpsertDocument("budget.md", "Budget", 1000, 2000)
+	budgetChunk, _ := db.InsertChunk(budgetID, "Estimated tax", 1, 5, "Budget > Tax")
+
+	otherID, _ := db.UpsertDocument("other.md", "Other", 1000, 2000)
+	_, _ = db.InsertChunk(otherID, "Tax talk in the body only", 1, 5, "")
+
+	results, err := db.SearchTitles([]string{"Tax", "deductions"}, 10, SearchFilter{})
+	if err != nil {
+		t.Fatalf("failed to search titles: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].ID != taxChunk || results[0].Title != "Tax Return 2024" {
+		t.Errorf("expected the deductions chunk of the tax note first, got %+v", results[0])
+	}
+	if results[1].ID != budgetChunk {
+		t.Errorf("expected the budget chunk second, got %+v", results[1])
+	}
+}
+
+func TestMatchPhrase(t *testing.T) {
+	tests := []struct {
+		content string
+		phrase  string
+		want    bool
+	}{
+		{"Pod exited with Error Code 137.", "error code 137", true},
+		{"error code\n  137", "error code 137", true},
+		{"error 137", "error code 137", false},
+		{"anything", "  ", true},
+	}
+	for _, tt := range tests {
+		if got := MatchPhrase(tt.content, tt.phrase); got != tt.want {
+			t.Errorf("MatchPhrase(%q, %q) = %v, want %v", tt.content, tt.phrase, got, tt.want)
+		}
+	}
+}
+
+func TestSearchSimilar_PhraseFilter(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	emb := SerializeFloat32([]float32{0.1, 0.2, 0.3, 0.4})
+
+	docID, _ := db.UpsertDocument("k8s.md", "K8s", 1000, 2000)
+	oomChunk, _ := db.InsertChunk(docID, "The pod died with error code 137", 1, 5, "")
+	_ = db.InsertEmbedding(oomChunk, emb)
+	otherChunk, _ := db.InsertChunk(docID, "Some other error code", 6, 9, "")
+	_ = db.InsertEmbedding(otherChunk, emb)
+
+	results, err := db.SearchSimilar(emb, 10, SearchFilter{Phrases: []string{"Error Code 137"}})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != oomChunk {
+		t.Errorf("expected only the chunk containing the phrase, got %v", results)
+	}
+}
+
+func TestGetNeighborChunks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	docID, _ := db.UpsertDocument("note.md", "Note", 1000, 2000)
+	var ids []int64
+	for i := range 5 {
+		id, _ := db.InsertChunk(docID, fmt.Sprintf("chunk %d", i), i*10+1, i*10+9, "")
+		ids = append(ids, id)
+	}
+	otherID, _ := db.UpsertDocument("other.md", "Other", 1000, 2000)
+	_, _ = db.InsertChunk(otherID, "other", 1, 9, "")
+
+	before, after, err := db.GetNeighborChunks(ids[2], 1)
+	if err != nil {
+		t.Fatalf("failed to get neighbors: %v", err)
+	}
+	if len(before) != 1 || before[0].ID != ids[1] {
+		t.Errorf("expected chunk %d before, got %v", ids[1], before)
+	}
+	if len(after) != 1 || after[0].ID != ids[3] {
+		t.Errorf("expected chunk %d after, got %v", ids[3], after)
+	}
+
+	before, after, _ = db.GetNeighborChunks(ids[1], 2)
+	if len(before) != 1 || before[0].ID != ids[0] {
+		t.Errorf("expected only the first chunk before, got %v", before)
+	}
+	if len(after) != 2 || after[0].ID != ids[2] || after[1].ID != ids[3] {
+		t.Errorf("expected the next two chunks in order, got %v", after)
+	}
+}
+
+func TestGetLinkedDocuments(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	hubID, _ := db.UpsertDocument("Hub.md", "Hub", 1000, 2000)
+	ideaID, _ := db.UpsertDocument("Projects/Idea.md", "Idea", 1000, 2000)
+	notesID, _ := db.UpsertDocument("Meeting Notes.md", "Meeting Notes", 1000, 2000)
+
+	_ = db.SetDocumentLinks(hubID, []string{"idea", "meeting notes", "missing", "hub"})
+	_ = db.SetDocumentLinks(ideaID, []string{"projects/idea", "Hub"})
+
+	links, err := db.GetLinkedDocuments([]int64{hubID, ideaID})
+	if err != nil {
+		t.Fatalf("failed to get links: %v", err)
+	}
+
+	want := []DocumentLink{{hubID, ideaID}, {hubID, notesID}, {ideaID, hubID}}
+	if len(links) != len(want) {
+		t.Fatalf("expected %v, got %v", want, links)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("link %d: expected %v, got %v", i, want[i], links[i])
+		}
+	}
+}
+
+func TestGetRecentDocuments(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	oldID, _ := db.UpsertDocument("old.md", "Old", 1000, 2000)
+	_, _ = db.InsertChunk(oldID, "old", 1, 5, "")
+	newID, _ := db.UpsertDocument("new.md", "New", 3000, 4000)
+	_, _ = db.InsertChunk(newID, "new", 1, 5, "")
+	_, _ = db.UpsertDocument("empty.md", "Empty", 5000, 6000)
+
+	docs, err := db.GetRecentDocuments(10)
+	if err != nil {
+		t.Fatalf("failed to get recent documents: %v", err)
+	}
+	if len(docs) != 2 || docs[0].Path != "new.md" || docs[1].Path != "old.md" {
+		t.Errorf("expected [new.md old.md], got %v", docs)
+	}
+
+	docs, _ = db.GetRecentDocuments(1)
+	if len(docs) != 1 || docs[0].Path != "new.md" {
+		t.Errorf("expected limit to keep only new.md, got %v", docs)
+	}
+}
+
+func TestGetDocumentEmbeddings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	docID, _ := db.UpsertDocument("note.md", "Note", 1000, 2000)
+	for _, vector := range [][]float32{{1, 0, 0, 0}, {0, 1, 0, 0}} {
+		chunkID, _ := db.InsertChunk(docID, "content", 1, 5, "")
+		emb := SerializeFloat32(vector)
+		_ = db.InsertEmbedding(chunkID, emb)
+	}
+	_, _ = db.UpsertDocument("empty.md", "Empty", 1000, 2000)
+
+	embeddings, err := db.GetDocumentEmbeddings()
+	if err != nil {
+		t.Fatalf("failed to get document embeddings: %v", err)
+	}
+	if len(embeddings) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(embeddings))
+	}
+	if got := embeddings[docID]; got[0] != 0.5 || got[1] != 0.5 {
+		t.Errorf("expected the mean [0.5 0.5 0 0], got %v", got)
+	}
+}
+
+func TestChunkEmbeddings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	b, _ := db.UpsertDocument("b.md", "B", 1000, 2000)
+	bChunk, _ := db.InsertChunk(b, "second", 1, 2, "B")
+	_ = db.InsertEmbedding(bChunk, SerializeFloat32([]float32{0, 1, 0, 0}))
+	a, _ := db.UpsertDocument("a.md", "A", 1000, 2000)
+	aChunk, _ := db.InsertChunk(a, "first", 3, 4, "A > Part")
+	_ = db.InsertEmbedding(aChunk, SerializeFloat32([]float32{1, 0, 0, 0}))
+	_, _ = db.InsertChunk(a, "never embedded", 5, 6, "")
+
+	chunks, err := db.ChunkEmbeddings()
+	if err != nil {
+		t.Fatalf("ChunkEmbeddings failed: %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("expected the 2 embedded chunks, got %d", len(chunks))
+	}
+	first := chunks[0]
+	if first.ChunkID != aChunk || first.Path != "a.md" || first.Heading != "A > Part" || first.StartLine != 3 || first.EndLine != 4 {
+		t.Errorf("expected a.md's chunk first, got %+v", first)
+	}
+	if first.Hash != ChunkHash("first") || first.Embedding[0] != 1 {
+		t.Errorf("unexpected hash or embedding: %+v", first)
+	}
+}
+
+func TestImportEmbeddings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	docID, _ := db.UpsertDocument("a.md", "A", 1000, 2000)
+	chunkID, _ := db.InsertChunk(docID, "known text", 1, 2, "")
+	_ = db.InsertEmbedding(chunkID, SerializeFloat32([]float32{1, 0, 0, 0}))
+
+	applied, err := db.ImportEmbeddings(map[string][]float32{
+		ChunkHash("known text"):  {0, 2, 0, 0},
+		ChunkHash("future text"): {0, 0, 1, 0},
+	})
+	if err != nil {
+		t.Fatalf("ImportEmbeddings failed: %v", err)
+	}
+	if applied != 1 {
+		t.Errorf("expected 1 chunk to get an imported vector, got %d", applied)
+	}
+	if got, _ := db.GetEmbeddings([]int64{chunkID}); got[chunkID][1] != 1 {
+		t.Errorf("expected the chunk's embedding replaced at unit length, got %v", got[chunkID])
+	}
+
+	kept, err := db.ImportedEmbeddings([]string{ChunkHash("future text"), ChunkHash("unknown")})
+	if err != nil {
+		t.Fatalf("ImportedEmbeddings failed: %v", err)
+	}
+	if len(kept) != 1 || kept[ChunkHash("future text")][2] != 1 {
+		t.Errorf("expected the vector kept for later, got %v", kept)
+	}
+
+	if _, err := db.ImportEmbeddings(map[string][]float32{ChunkHash("x"): {1, 0}}); err == nil {
+		t.Error("expected an error for the wrong dimensions")
+	}
+}
+
+func TestDocumentAliases(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	docID, _ := db.UpsertDocument("Travel/Passport.md", "Passport", 1000, 2000)
+	if err := db.SetDocumentAliases(docID, []string{"Travel docs", "ID"}); err != nil {
+		t.Fatalf("SetDocumentAliases failed: %v", err)
+	}
+	if err := db.SetDocumentAliases(docID, []string{"Travel docs"}); err != nil {
+		t.Fatalf("SetDocumentAliases failed: %v", err)
+	}
+
+	aliases, err := db.GetAllAliases()
+	if err != nil {
+		t.Fatalf("GetAllAliases failed: %v", err)
+	}
+	if len(aliases[docID]) != 1 || aliases[docID][0] != "Travel docs" {
+		t.Errorf("expected aliases to be replaced, got %v", aliases[docID])
+	}
+
+	if err := db.DeleteDocument("Travel/Passport.md"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	aliases, _ = db.GetAllAliases()
+	if len(aliases) != 0 {
+		t.Errorf("expected aliases to be deleted with the document, got %v", aliases)
+	}
+}
+
+func TestTitleIndex(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	names := func(kinds ...TitleKind) []string {
+		t.Helper()
+		titles, err := db.GetTitles(kinds...)
+		if err != nil {
+			t.Fatalf("GetTitles failed: %v", err)
+		}
+		var names []string
+		for _, title := range titles {
+			names = append(names, string(title.Kind)+":"+title.Name)
+		}
+		return names
+	}
+
+	docID, _ := db.UpsertDocument("Travel/Passport.md", "Passport", 1000, 2000)
+	_ = db.SetDocumentAliases(docID, []string{"Travel docs"})
+	chunkID, _ := db.InsertChunk(docID, "Renewal steps", 1, 5, "Passport > Renewal")
+	_, _ = db.InsertChunk(docID, "No heading", 6, 8, "")
+
+	want := []string{"alias:Travel docs", "file:Passport", "heading:Passport > Renewal", "title:Passport"}
+	if got := names(); !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	_, _ = db.UpsertDocument("Travel/Passport.md", "My Passport", 1000, 3000)
+	_ = db.SetDocumentAliases(docID, []string{"ID"})
+	want = []string{"alias:ID", "file:Passport", "title:My Passport"}
+	if got := names(TitleNote, TitleFile, TitleAlias); !slices.Equal(got, want) {
+		t.Errorf("expected names to follow title and alias changes, got %v", got)
+	}
+
+	matches, err := db.LookupTitles("pass", 10)
+	if err != nil {
+		t.Fatalf("LookupTitles failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Name != "Passport" || matches[1].Kind != TitleHeading || matches[1].ChunkID != chunkID {
+		t.Errorf("expected the file name then the heading, got %+v", matches)
+	}
+	if matches, _ := db.LookupTitles("renewal", 10); len(matches) != 0 {
+		t.Errorf("expected only prefixes to match, got %+v", matches)
+	}
+
+	_ = db.DeleteChunksForDocument(docID)
+	if got := names(TitleHeading); len(got) != 0 {
+		t.Errorf("expected headings to go with their chunks, got %v", got)
+	}
+
+	if err := db.DeleteDocument("Travel/Passport.md"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	if got := names(); len(got) != 0 {
+		t.Errorf("expected names to go with the document, got %v", got)
+	}
+}
+
+func TestMigrate_LegacyDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+
+	// A database from before schema versioning, with only the original tables
+	conn, err := sql.Open(driverName, dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	_, err = conn.Exec(`
+		CREATE TABLE documents (id INTEGER PRIMARY KEY, path TEXT UNIQUE NOT NULL, title TEXT, modified_at INTEGER, indexed_at INTEGER);
+		CREATE TABLE chunks (id INTEGER PRIMARY KEY, doc_id INTEGER, content TEXT NOT NULL, start_line INTEGER, end_line INTEGER, heading TEXT);
+		INSERT INTO documents (path, title, modified_at, indexed_at) VALUES ('old.md', 'Old', 1, 2);
+		INSERT INTO chunks (doc_id, content, start_line, end_line, heading) VALUES (1, 'three old words', 1, 1, '');
+	`)
+	conn.Close()
+	if err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	db, err := Open(dbPath, 4)
+	if err != nil {
+		t.Fatalf("failed to migrate legacy database: %v", err)
+	}
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if want := migrations[len(migrations)-1].version; version != want {
+		t.Errorf("expected schema version %d, got %d", want, version)
+	}
+
+	if doc, err := db.GetDocument("old.md"); err != nil || doc == nil {
+		t.Errorf("expected existing documents to survive migration, got %v, %v", doc, err)
+	}
+	docID, _ := db.UpsertDocument("old.md", "Old", 1, 2)
+	if err := db.SetDocumentAliases(docID, []string{"legacy"}); err != nil {
+		t.Errorf("expected tables from later migrations to exist: %v", err)
+	}
+	if stats, err := db.Stats(1); err != nil || stats.Words != 3 {
+		t.Errorf("expected word counts backfilled for existing chunks, got %+v, %v", stats, err)
+	}
+}
+
+func TestMigrate_NewerSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "newer.db")
+	db, err := Open(dbPath, 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, err := db.writer.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (9999, 'future', 0)"); err != nil {
+		t.Fatalf("failed to bump schema version: %v", err)
+	}
+	db.Close()
+
+	if _, err := Open(dbPath, 4); err == nil {
+		t.Error("expected an error opening a database from a newer version")
+	}
+}
+
+func TestQuantizedSearch(t *testing.T) {
+	vectors := [][]float32{
+		{0.6, 0.4, -0.3, 0.2, 0.1, -0.5, 0.2, 0.2},
+		{-0.5, 0.1, 0.4, -0.3, 0.6, 0.2, -0.2, 0.1},
+		{0.1, -0.6, 0.2, 0.5, -0.3, 0.1, 0.4, -0.3},
+	}
+
+	for _, q := range []Quantization{QuantizeInt8, QuantizeBit} {
+		t.Run(q.String(), func(t *testing.T) {
+			db, err := OpenWithOptions(filepath.Join(t.TempDir(), "q.db"), Options{EmbedDim: 8, Quantization: q})
+			if err != nil {
+				t.Fatalf("failed to open database: %v", err)
+			}
+			defer db.Close()
+
+			var chunkIDs []int64
+			for i, v := range vectors {
+				docID, _ := db.UpsertDocument(fmt.Sprintf("%d.md", i), "", 1000, 2000)
+				chunkID, _ := db.InsertChunk(docID, "content", 1, 2, "")
+				emb := SerializeFloat32(normalize(append([]float32(nil), v...)))
+				if err := db.InsertEmbedding(chunkID, emb); err != nil {
+					t.Fatalf("InsertEmbedding failed: %v", err)
+				}
+				chunkIDs = append(chunkIDs, chunkID)
+			}
+
+			query := SerializeFloat32(normalize(append([]float32(nil), vectors[1]...)))
+			results, err := db.SearchSimilar(query, 2, SearchFilter{})
+			if err != nil {
+				t.Fatalf("SearchSimilar failed: %v", err)
+			}
+			if len(results) != 2 || results[0].ID != chunkIDs[1] {
+				t.Fatalf("expected the matching chunk first, got %+v", results)
+			}
+			if results[0].Distance > 0.8 || results[0].Distance > results[1].Distance {
+				t.Errorf("expected rescored distances in ascending order, got %v and %v", results[0].Distance, results[1].Distance)
+			}
+
+			embeddings, err := db.GetEmbeddings(chunkIDs[:1])
+			if err != nil {
+				t.Fatalf("GetEmbeddings failed: %v", err)
+			}
+			if got := embeddings[chunkIDs[0]]; len(got) != 8 || got[0] <= 0 || got[1] <= 0 || got[2] >= 0 {
+				t.Errorf("expected a decoded vector with the original signs, got %v", got)
+			}
+		})
+	}
+}
+
+func TestQuantizationMismatch(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "q.db")
+
+	db, err := Open(dbPath, 8)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.Close()
+
+	// An empty float index is switched over silently
+	db, err = OpenWithOptions(dbPath, Options{EmbedDim: 8, Quantization: QuantizeInt8})
+	if err != nil {
+		t.Fatalf("expected an empty index to switch quantization: %v", err)
+	}
+	docID, _ := db.UpsertDocument("a.md", "", 1000, 2000)
+	chunkID, _ := db.InsertChunk(docID, "content", 1, 2, "")
+	emb := SerializeFloat32([]float32{1, 0, 0, 0, 0, 0, 0, 0})
+	if err := db.InsertEmbedding(chunkID, emb); err != nil {
+		t.Fatalf("InsertEmbedding failed: %v", err)
+	}
+	db.Close()
+
+	if _, err := Open(dbPath, 8); err == nil {
+		t.Error("expected an error opening an int8 index as float")
+	}
+}
+
+func TestVacuum(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(dbPath, 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	content := strings.Repeat("filler text ", 200)
+	for i := range 50 {
+		docID, _ := db.UpsertDocument(fmt.Sprintf("%d.md", i), "", 1000, 2000)
+		chunkID, _ := db.InsertChunk(docID, content, 1, 2, "")
+		emb := SerializeFloat32([]float32{1, 0, 0, 0})
+		db.InsertEmbedding(chunkID, emb)
+	}
+	for i := range 50 {
+		if err := db.DeleteDocument(fmt.Sprintf("%d.md", i)); err != nil {
+			t.Fatalf("DeleteDocument failed: %v", err)
+		}
+	}
+
+	before, _ := os.Stat(dbPath)
+	if err := db.Vacuum(); err != nil {
+		t.Fatalf("Vacuum failed: %v", err)
+	}
+	after, _ := os.Stat(dbPath)
+
+	if after.Size() >= before.Size() {
+		t.Errorf("expected the file to shrink, got %d -> %d bytes", before.Size(), after.Size())
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	src, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	docID, _ := src.UpsertDocument("Notes/a.md", "A", 1000, 2000)
+	src.SetDocumentTags(docID, []string{"work"})
+	src.SetDocumentLinks(docID, []string{"b"})
+	src.SetDocumentAliases(docID, []string{"Alpha"})
+	src.SetDocumentMetadata(docID, map[string]any{"status": "done"})
+	chunkID, _ := src.InsertChunk(docID, "hello world", 1, 3, "Intro")
+	emb := SerializeFloat32([]float32{0.1, 0.2, 0.3, 0.4})
+	src.InsertEmbedding(chunkID, emb)
+	src.UpsertDocument("b.md", "B", 1500, 2500)
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	dst, cleanup2 := setupTestDB(t)
+	defer cleanup2()
+
+	count, err := dst.Import(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 documents imported, got %d", count)
+	}
+
+	doc, _ := dst.GetDocument("Notes/a.md")
+	if doc == nil || doc.Title != "A" || doc.ModifiedAt != 1000 {
+		t.Fatalf("expected the document to round-trip, got %+v", doc)
+	}
+	if tags, _ := dst.GetDocumentTags(doc.ID); len(tags) != 1 || tags[0] != "work" {
+		t.Errorf("expected tags to round-trip, got %v", tags)
+	}
+	if aliases, _ := dst.GetAllAliases(); len(aliases[doc.ID]) != 1 {
+		t.Errorf("expected aliases to round-trip, got %v", aliases)
+	}
+	if metadata, _ := dst.GetDocumentMetadata(doc.ID); metadata["status"] != "done" {
+		t.Errorf("expected metadata to round-trip, got %v", metadata)
+	}
+	if links, _ := dst.GetLinkedDocuments([]int64{doc.ID}); len(links) != 1 {
+		t.Errorf("expected links to round-trip, got %v", links)
+	}
+
+	chunks, _ := dst.GetChunksForDocument(doc.ID)
+	if len(chunks) != 1 || chunks[0].Heading != "Intro" || chunks[0].EndLine != 3 {
+		t.Fatalf("expected the chunk to round-trip, got %+v", chunks)
+	}
+	embeddings, _ := dst.GetEmbeddings([]int64{chunks[0].ID})
+	if got := embeddings[chunks[0].ID]; len(got) != 4 || got[3] != 0.4 {
+		t.Errorf("expected the embedding to round-trip, got %v", got)
+	}
+
+	// Importing again replaces rather than duplicates
+	if _, err := dst.Import(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("second Import failed: %v", err)
+	}
+	if n, _ := dst.ChunkCount(); n != 1 {
+		t.Errorf("expected 1 chunk after reimport, got %d", n)
+	}
+
+	wrongDim, err := Open(filepath.Join(t.TempDir(), "wrong.db"), 8)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer wrongDim.Close()
+	if _, err := wrongDim.Import(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("expected an error importing into a database with another dimension")
+	}
+
+	modelPath := filepath.Join(t.TempDir(), "model.db")
+	v4, err := OpenWithOptions(modelPath, Options{EmbedDim: 4, EmbedModel: "embed-v4.0"})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	buf.Reset()
+	if err := v4.Export(&buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	v4.Close()
+	wrongModel, err := OpenWithOptions(filepath.Join(t.TempDir(), "other.db"), Options{EmbedDim: 4, EmbedModel: "embed-english-v3.0"})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer wrongModel.Close()
+	if _, err := wrongModel.Import(bytes.NewReader(buf.Bytes())); err == nil || !strings.Contains(err.Error(), "embed-v4.0") {
+		t.Errorf("expected an error importing embeddings from another model, got %v", err)
+	}
+}
+
+func TestSyncExportApply(t *testing.T) {
+	src, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	docA, _ := src.UpsertDocument("a.md", "A", 1000, 1000)
+	chunkID, _ := src.InsertChunk(docA, "hello world", 1, 3, "Intro")
+	src.InsertEmbedding(chunkID, SerializeFloat32([]float32{0.1, 0.2, 0.3, 0.4}))
+	src.UpsertDocument("b.md", "B", 1000, 1000)
+	src.UpsertDocument("c.md", "C", 1000, 1000)
+
+	var full bytes.Buffer
+	stats, err := src.ExportSync(&full, time.Time{})
+	if err != nil {
+		t.Fatalf("ExportSync failed: %v", err)
+	}
+	if stats.Documents != 3 || !stats.Since.IsZero() {
+		t.Errorf("expected a full bundle of 3 documents, got %+v", stats)
+	}
+
+	dst, cleanup2 := setupTestDB(t)
+	defer cleanup2()
+	if _, err := dst.ApplySync(bytes.NewReader(full.Bytes())); err != nil {
+		t.Fatalf("ApplySync failed: %v", err)
+	}
+	if n, _ := dst.DocumentCount(); n != 3 {
+		t.Errorf("expected 3 documents after the full bundle, got %d", n)
+	}
+
+	// Change b, delete c and send only what changed
+	src.UpsertDocument("b.md", "B2", 3000, 3000)
+	src.DeleteDocument("c.md")
+
+	var delta bytes.Buffer
+	stats, err = src.ExportSync(&delta, time.Unix(2000, 0))
+	if err != nil {
+		t.Fatalf("ExportSync failed: %v", err)
+	}
+	if stats.Documents != 1 {
+		t.Errorf("expected the delta to carry 1 document, got %d", stats.Documents)
+	}
+
+	stats, err = dst.ApplySync(bytes.NewReader(delta.Bytes()))
+	if err != nil {
+		t.Fatalf("ApplySync of the delta failed: %v", err)
+	}
+	if stats.Documents != 1 || stats.Removed != 1 {
+		t.Errorf("expected 1 document applied and 1 removed, got %+v", stats)
+	}
+	if doc, _ := dst.GetDocument("b.md"); doc == nil || doc.Title != "B2" {
+		t.Errorf("expected b.md to be updated, got %+v", doc)
+	}
+	if doc, _ := dst.GetDocument("c.md"); doc != nil {
+		t.Errorf("expected c.md to be removed, got %+v", doc)
+	}
+	doc, _ := dst.GetDocument("a.md")
+	chunks, _ := dst.GetChunksForDocument(doc.ID)
+	if embeddings, _ := dst.GetEmbeddings([]int64{chunks[0].ID}); len(embeddings[chunks[0].ID]) != 4 {
+		t.Errorf("expected a.md's embedding to survive the delta, got %v", embeddings)
+	}
+
+	// A delta applied to an index that missed the earlier bundle leaves a gap
+	fresh, cleanup3 := setupTestDB(t)
+	defer cleanup3()
+	if _, err := fresh.ApplySync(bytes.NewReader(delta.Bytes())); err == nil {
+		t.Error("expected an error applying a delta to an index never synced")
+	}
+	if n, _ := fresh.DocumentCount(); n != 0 {
+		t.Errorf("expected the refused bundle to change nothing, got %d documents", n)
+	}
+
+	if err := src.SetSyncExported(stats.Until); err != nil {
+		t.Fatalf("SetSyncExported failed: %v", err)
+	}
+	if exported, _ := src.SyncExported(); !exported.Equal(stats.Until) {
+		t.Errorf("expected the export checkpoint %v, got %v", stats.Until, exported)
+	}
+}
+
+func TestEmbeddingMetadataMismatch(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := OpenWithOptions(dbPath, Options{EmbedDim: 4, EmbedModel: "embed-v4.0"})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	docID, _ := db.UpsertDocument("a.md", "", 1000, 2000)
+	chunkID, _ := db.InsertChunk(docID, "content", 1, 2, "")
+	emb := SerializeFloat32([]float32{1, 0, 0, 0})
+	db.InsertEmbedding(chunkID, emb)
+	db.Close()
+
+	_, err = OpenWithOptions(dbPath, Options{EmbedDim: 8, EmbedModel: "embed-v4.0"})
+	var mismatch *MismatchError
+	if !errors.As(err, &mismatch) || !strings.Contains(mismatch.Reason, "embed_dim is 8") {
+		t.Errorf("expected a dimension mismatch error, got %v", err)
+	}
+	if _, err := OpenWithOptions(dbPath, Options{EmbedDim: 4, EmbedModel: "embed-english-v3.0"}); err == nil || !strings.Contains(err.Error(), "embed-v4.0") {
+		t.Errorf("expected a model mismatch error, got %v", err)
+	}
+
+	db, err = OpenWithOptions(dbPath, Options{EmbedDim: 8, EmbedModel: "embed-v4.0", Rebuild: true})
+	if err != nil {
+		t.Fatalf("expected a rebuild to clear the index: %v", err)
+	}
+	defer db.Close()
+
+	if n, _ := db.DocumentCount(); n != 0 {
+		t.Errorf("expected the rebuilt index to be empty, got %d documents", n)
+	}
+	if dim, _ := db.getMeta("embed_dim"); dim != "8" {
+		t.Errorf("expected embed_dim 8 recorded, got %q", dim)
+	}
+	emb = SerializeFloat32(make([]float32, 8))
+	docID, _ = db.UpsertDocument("a.md", "", 1000, 2000)
+	chunkID, _ = db.InsertChunk(docID, "content", 1, 2, "")
+	if err := db.InsertEmbedding(chunkID, emb); err != nil {
+		t.Errorf("expected 8-dimensional embeddings to fit the rebuilt index: %v", err)
+	}
+}
+
+func TestOpen_EncryptionKey(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	if _, err := OpenWithOptions(dbPath, Options{EmbedDim: 4, Key: "not hex"}); err == nil {
+		t.Error("expected an error for a malformed key")
+	}
+
+	key, err := NewKey()
+	if err != nil {
+		t.Fatalf("NewKey failed: %v", err)
+	}
+	if !validKey(key) {
+		t.Fatalf("expected a valid key, got %q", key)
+	}
+
+	db, err := OpenWithOptions(dbPath, Options{EmbedDim: 4, Key: key})
+	if err != nil {
+		// Without SQLCipher the key must be refused rather than ignored
+		if !strings.Contains(err.Error(), "SQLCipher") {
+			t.Errorf("expected a missing SQLCipher error, got %v", err)
+		}
+		return
+	}
+	db.Close()
+}
+
+func TestEngineInfo(t *testing.T) {
+	engine, err := EngineInfo()
+	if err != nil {
+		t.Fatalf("EngineInfo failed: %v", err)
+	}
+	if engine.SQLite == "" {
+		t.Error("expected a SQLite version")
+	}
+	if nativeVec != (engine.SQLiteVec != "") {
+		t.Errorf("expected a sqlite-vec version only in cgo builds, got %q", engine.SQLiteVec)
+	}
+}
+
+func TestOpen_Memory(t *testing.T) {
+	db, err := Open(":memory:", 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// A second connection would see a fresh, empty database
+	if n := db.conn.Stats().MaxOpenConnections; n != 1 {
+		t.Errorf("expected an in-memory database to use one connection, got %d", n)
+	}
+
+	if _, err := db.UpsertDocument("a.md", "", 1000, 2000); err != nil {
+		t.Fatalf("UpsertDocument failed: %v", err)
+	}
+	if n, _ := db.DocumentCount(); n != 1 {
+		t.Errorf("expected 1 document, got %d", n)
+	}
+}
+
+func TestStats(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	jan := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC).Unix()
+	mar := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC).Unix()
+
+	short, _ := db.UpsertDocument("short.md", "", jan, jan)
+	db.InsertChunk(short, "just four words here", 1, 1, "")
+	long, _ := db.UpsertDocument("long.md", "", mar, mar)
+	db.InsertChunk(long, strings.Repeat("word ", 100), 1, 10, "")
+	db.InsertChunk(long, strings.Repeat("more ", 50), 11, 20, "")
+
+	stats, err := db.Stats(1)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+
+	if stats.Documents != 2 || stats.Chunks != 3 || stats.Words != 154 {
+		t.Errorf("expected 2 notes, 3 chunks and 154 words, got %+v", stats)
+	}
+	if stats.Tokens != EstimateTokens("just four words here")+EstimateTokens(strings.Repeat("word ", 100))+EstimateTokens(strings.Repeat("more ", 50)) {
+		t.Errorf("unexpected token total %d", stats.Tokens)
+	}
+	if stats.WordsPerNote() != 77 {
+		t.Errorf("expected 77 words per note, got %v", stats.WordsPerNote())
+	}
+	if len(stats.Largest) != 1 || stats.Largest[0].Path != "long.md" || stats.Largest[0].Words != 150 {
+		t.Errorf("expected long.md as the largest note, got %+v", stats.Largest)
+	}
+	want := []MonthStats{{Month: "2024-01", Notes: 1, Words: 4}, {Month: "2024-03", Notes: 1, Words: 150}}
+	if !slices.Equal(stats.Months, want) {
+		t.Errorf("expected months %+v, got %+v", want, stats.Months)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	emb := SerializeFloat32([]float32{1, 0, 0, 0})
+	for _, path := range []string{"kept.md", "gone.md"} {
+		docID, _ := db.UpsertDocument(path, "", 1000, 2000)
+		db.SetDocumentTags(docID, []string{"tag"})
+		chunkID, _ := db.InsertChunk(docID, "content", 1, 2, "")
+		db.InsertEmbedding(chunkID, emb)
+	}
+
+	// Leftovers from an interrupted index: a chunk whose document is gone
+	// and a vector whose chunk is gone
+	db.writer.Exec("INSERT INTO chunks (doc_id, content, start_line, end_line, heading) VALUES (999, 'orphan', 1, 1, '')")
+	db.InsertEmbedding(12345, emb)
+
+	result, err := db.Prune(func(path string) bool { return path == "kept.md" })
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if result != (PruneResult{Documents: 1, Chunks: 1, Vectors: 1}) {
+		t.Errorf("unexpected prune result %+v", result)
+	}
+
+	if n, _ := db.ChunkCount(); n != 1 {
+		t.Errorf("expected 1 chunk left, got %d", n)
+	}
+	var vectors int
+	db.conn.QueryRow("SELECT COUNT(*) FROM vec_chunks").Scan(&vectors)
+	if vectors != 1 {
+		t.Errorf("expected 1 vector left, got %d", vectors)
+	}
+	if doc, _ := db.GetDocument("kept.md"); doc == nil {
+		t.Error("expected the document with a file to be kept")
+	}
+}
+
+func TestLargeIDLists(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	docID, _ := db.UpsertDocument("a.md", "", 1000, 2000)
+	chunkID, _ := db.InsertChunk(docID, "content", 1, 2, "")
+	emb := SerializeFloat32([]float32{1, 0, 0, 0})
+	db.InsertEmbedding(chunkID, emb)
+
+	// More ids than any SQLite build allows as parameters in one statement
+	ids := make([]int64, 40000)
+	for i := range ids {
+		ids[i] = int64(i + 1000)
+	}
+	ids[len(ids)-1] = chunkID
+
+	chunks, err := db.GetChunksForRerank(ids)
+	if err != nil {
+		t.Fatalf("GetChunksForRerank failed: %v", err)
+	}
+	if len(chunks) != 1 || chunks[0].ID != chunkID {
+		t.Errorf("expected the one existing chunk, got %+v", chunks)
+	}
+
+	embeddings, err := db.GetEmbeddings(ids)
+	if err != nil {
+		t.Fatalf("GetEmbeddings failed: %v", err)
+	}
+	if len(embeddings) != 1 {
+		t.Errorf("expected 1 embedding, got %d", len(embeddings))
+	}
+
+	if _, err := db.GetLinkedDocuments(ids); err != nil {
+		t.Errorf("GetLinkedDocuments failed: %v", err)
+	}
+
+	results, err := db.SearchSimilar(emb, 5, SearchFilter{ExcludeDocIDs: ids[:len(ids)-1]})
+	if err != nil {
+		t.Fatalf("SearchSimilar failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected the chunk outside the excluded ids, got %d results", len(results))
+	}
+}
+
+// memVectorStore is a brute-force VectorStore standing in for a server.
+type memVectorStore struct {
+	vectors map[int64][]float32
+	closed  bool
+}
+
+func (s *memVectorStore) Insert(chunkID int64, embedding []float32) error {
+	s.vectors[chunkID] = embedding
+	return nil
+}
+
+func (s *memVectorStore) Search(query []float32, k int, allowed []int64) ([]VectorHit, error) {
+	var hits []VectorHit
+	for id, vector := range s.vectors {
+		if allowed != nil && !slices.Contains(allowed, id) {
+			continue
+		}
+		var sum float64
+		for i := range vector {
+			d := float64(vector[i] - query[i])
+			sum += d * d
+		}
+		hits = append(hits, VectorHit{ChunkID: id, Distance: math.Sqrt(sum)})
+	}
+	slices.SortFunc(hits, func(a, b VectorHit) int { return cmp.Compare(a.Distance, b.Distance) })
+	return hits[:min(k, len(hits))], nil
+}
+
+func (s *memVectorStore) Get(chunkIDs []int64) (map[int64][]float32, error) {
+	embeddings := make(map[int64][]float32)
+	for _, id := range chunkIDs {
+		if vector, ok := s.vectors[id]; ok {
+			embeddings[id] = vector
+		}
+	}
+	return embeddings, nil
+}
+
+func (s *memVectorStore) Delete(chunkIDs []int64) error {
+	for _, id := range chunkIDs {
+		delete(s.vectors, id)
+	}
+	return nil
+}
+
+func (s *memVectorStore) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestExternalVectorStore(t *testing.T) {
+	store := &memVectorStore{vectors: make(map[int64][]float32)}
+	db, err := OpenWithOptions(filepath.Join(t.TempDir(), "test.db"), Options{EmbedDim: 4, VectorStore: store})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	var chunkIDs []int64
+	for i, path := range []string{"a.md", "b.md"} {
+		docID, _ := db.UpsertDocument(path, "", 1000, 2000)
+		db.SetDocumentTags(docID, []string{path[:1]})
+		chunkID, _ := db.InsertChunk(docID, "content", 1, 2, "")
+		vector := make([]float32, 4)
+		vector[i] = 1
+		emb := SerializeFloat32(vector)
+		if err := db.InsertEmbedding(chunkID, emb); err != nil {
+			t.Fatalf("InsertEmbedding failed: %v", err)
+		}
+		chunkIDs = append(chunkIDs, chunkID)
+	}
+
+	var local int
+	db.conn.QueryRow("SELECT COUNT(*) FROM vec_chunks").Scan(&local)
+	if local != 0 || len(store.vectors) != 2 {
+		t.Fatalf("expected vectors only in the store, got %d local and %d stored", local, len(store.vectors))
+	}
+
+	query := SerializeFloat32([]float32{0, 1, 0, 0})
+	results, err := db.SearchSimilar(query, 2, SearchFilter{})
+	if err != nil {
+		t.Fatalf("SearchSimilar failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Path != "b.md" || results[0].Distance != 0 {
+		t.Errorf("expected b.md nearest, got %+v", results)
+	}
+
+	results, err = db.SearchSimilar(query, 2, SearchFilter{Tags: []string{"a"}})
+	if err != nil {
+		t.Fatalf("SearchSimilar with filter failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Path != "a.md" {
+		t.Errorf("expected only a.md for tag a, got %+v", results)
+	}
+
+	embeddings, err := db.GetEmbeddings(chunkIDs)
+	if err != nil || len(embeddings) != 2 {
+		t.Errorf("expected 2 embeddings, got %d (%v)", len(embeddings), err)
+	}
+
+	if err := db.DeleteDocument("a.md"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	if _, ok := store.vectors[chunkIDs[0]]; ok || len(store.vectors) != 1 {
+		t.Errorf("expected the deleted document's vector removed, got %v", store.vectors)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !store.closed {
+		t.Error("expected Close to close the vector store")
+	}
+}
+
+func TestOpen_PureGoIndex(t *testing.T) {
+	if !nativeVec {
+		t.Skip("sqlite-vec indexes can't be opened without sqlite-vec")
+	}
+	dbPath := filepath.Join(t.TempDir(), "purego.db")
+
+	db, err := Open(dbPath, 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	// What a pure-Go build creates in place of the sqlite-vec table
+	db.writer.Exec("DROP TABLE vec_chunks")
+	db.writer.Exec("CREATE TABLE vec_chunks (chunk_id INTEGER PRIMARY KEY, embedding float[4])")
+	db.writer.Exec("INSERT INTO vec_chunks (chunk_id, embedding) VALUES (1, ?)", SerializeFloat32([]float32{1, 0, 0, 0}))
+	db.Close()
+
+	if _, err := Open(dbPath, 4); err == nil || !strings.Contains(err.Error(), "pure-Go") {
+		t.Fatalf("expected an error about a pure-Go index, got %v", err)
+	}
+
+	db, err = OpenWithOptions(dbPath, Options{EmbedDim: 4, Rebuild: true})
+	if err != nil {
+		t.Fatalf("expected a rebuild to replace the table: %v", err)
+	}
+	defer db.Close()
+	if _, _, native, err := db.vecColumn(); err != nil || !native {
+		t.Errorf("expected a sqlite-vec table after the rebuild, got native=%v (%v)", native, err)
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+
+	// Two handles stand in for a watcher and a search in another terminal
+	watcher, err := Open(dbPath, 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer watcher.Close()
+	searcher, err := Open(dbPath, 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer searcher.Close()
+
+	emb := SerializeFloat32([]float32{1, 0, 0, 0})
+	errs := make(chan error, 16)
+	var wg sync.WaitGroup
+
+	// Writers on both handles, and several goroutines on one
+	for w, db := range []*DB{watcher, watcher, searcher} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 30 {
+				path := fmt.Sprintf("%d-%d.md", w, i)
+				docID, err := db.UpsertDocument(path, "", 1000, 2000)
+				if err != nil {
+					errs <- err
+					return
+				}
+				chunkID, err := db.InsertChunk(docID, "content", 1, 2, "")
+				if err != nil {
+					errs <- err
+					return
+				}
+				if err := db.InsertEmbedding(chunkID, emb); err != nil {
+					errs <- err
+					return
+				}
+				if i%3 == 0 {
+					if err := db.DeleteDocument(path); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+		}()
+	}
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 30 {
+				if _, err := searcher.SearchSimilar(emb, 5, SearchFilter{}); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := searcher.GetAllDocuments(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if n, _ := searcher.DocumentCount(); n != 60 {
+		t.Errorf("expected 60 documents, got %d", n)
+	}
+}
@@ -0,0 +1,295 @@
+package db
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// dumpFormat identifies an index dump and its layout version.
+const (
+	dumpFormat  = "obsvec-index"
+	dumpVersion = 1
+)
+
+// dumpHeader is the first line of a dump.
+type dumpHeader struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	EmbedDim   int    `json:"embed_dim"`
+	EmbedModel string `json:"embed_model,omitempty"`
+}
+
+// dumpDocument is one note with everything indexed for it.
+type dumpDocument struct {
+	Path        string         `json:"path"`
+	Title       string         `json:"title"`
+	ModifiedAt  int64          `json:"modified_at"`
+	IndexedAt   int64          `json:"indexed_at"`
+	ContentHash string         `json:"content_hash,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Links       []string       `json:"links,omitempty"`
+	Aliases     []string       `json:"aliases,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	Chunks      []dumpChunk    `json:"chunks"`
+}
+
+type dumpChunk struct {
+	Content   string    `json:"content"`
+	StartLine int       `json:"start_line"`
+	EndLine   int       `json:"end_line"`
+	Heading   string    `json:"heading"`
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// Export writes the whole index to w as JSON lines: a header followed by one
+// line per document with its chunks, embeddings, tags, links, aliases and
+// frontmatter properties. Quantized embeddings are written in their
+// dequantized float form.
+func (db *DB) Export(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(dumpHeader{Format: dumpFormat, Version: dumpVersion, EmbedDim: db.embedDim, EmbedModel: db.embedModel}); err != nil {
+		return err
+	}
+
+	docs, err := db.GetAllDocuments()
+	if err != nil {
+		return err
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Path < docs[j].Path })
+
+	aliases, err := db.GetAllAliases()
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		entry, err := db.exportDocument(doc, aliases[doc.ID])
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportDocument collects everything indexed for doc.
+func (db *DB) exportDocument(doc Document, aliases []string) (dumpDocument, error) {
+	entry := dumpDocument{
+		Path:        doc.Path,
+		Title:       doc.Title,
+		ModifiedAt:  doc.ModifiedAt,
+		IndexedAt:   doc.IndexedAt,
+		ContentHash: doc.ContentHash,
+		Aliases:     aliases,
+		Chunks:      []dumpChunk{},
+	}
+
+	var err error
+	if entry.Tags, err = db.GetDocumentTags(doc.ID); err != nil {
+		return entry, err
+	}
+	if entry.Links, err = db.documentLinkTargets(doc.ID); err != nil {
+		return entry, err
+	}
+	if entry.Metadata, err = db.GetDocumentMetadata(doc.ID); err != nil {
+		return entry, err
+	}
+
+	chunks, err := db.GetChunksForDocument(doc.ID)
+	if err != nil {
+		return entry, err
+	}
+	chunkIDs := make([]int64, len(chunks))
+	for i, chunk := range chunks {
+		chunkIDs[i] = chunk.ID
+	}
+	embeddings, err := db.GetEmbeddings(chunkIDs)
+	if err != nil {
+		return entry, err
+	}
+
+	for _, chunk := range chunks {
+		entry.Chunks = append(entry.Chunks, dumpChunk{
+			Content:   chunk.Content,
+			StartLine: chunk.StartLine,
+			EndLine:   chunk.EndLine,
+			Heading:   chunk.Heading,
+			Embedding: embeddings[chunk.ID],
+		})
+	}
+	return entry, nil
+}
+
+func (db *DB) documentLinkTargets(docID int64) ([]string, error) {
+	rows, err := db.conn.Query("SELECT target FROM document_links WHERE doc_id = ? ORDER BY target", docID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var targets []string
+	for rows.Next() {
+		var target string
+		if err := rows.Scan(&target); err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
+
+func (db *DB) documentAliases(docID int64) ([]string, error) {
+	rows, err := db.conn.Query("SELECT alias FROM document_aliases WHERE doc_id = ? ORDER BY alias", docID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var aliases []string
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases, rows.Err()
+}
+
+// Import loads a dump written by Export, replacing any documents with the
+// same paths. It runs in a single transaction, so a failed import leaves the
+// database unchanged. It returns the number of documents imported.
+func (db *DB) Import(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 256*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("empty index dump")
+	}
+	var header dumpHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format != dumpFormat {
+		return 0, fmt.Errorf("not an obsvec index dump")
+	}
+	if header.Version > dumpVersion {
+		return 0, fmt.Errorf("index dump version %d is newer than this build supports (%d)", header.Version, dumpVersion)
+	}
+	if header.EmbedDim != db.embedDim {
+		return 0, fmt.Errorf("index dump has %d-dimensional embeddings but the database expects %d", header.EmbedDim, db.embedDim)
+	}
+	if header.EmbedModel != "" && db.embedModel != "" && header.EmbedModel != db.embedModel {
+		return 0, fmt.Errorf("index dump was embedded with %s but the database uses %s", header.EmbedModel, db.embedModel)
+	}
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for scanner.Scan() {
+		var doc dumpDocument
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			_ = tx.Rollback()
+			return 0, fmt.Errorf("document %d: %w", count+1, err)
+		}
+		if err := db.importDocumentTx(tx, doc); err != nil {
+			_ = tx.Rollback()
+			return 0, fmt.Errorf("%s: %w", doc.Path, err)
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+func (db *DB) importDocumentTx(tx *sql.Tx, doc dumpDocument) error {
+	metadata, err := marshalMetadata(doc.Metadata)
+	if err != nil {
+		return err
+	}
+
+	var docID int64
+	err = tx.QueryRow(`
+		INSERT INTO documents (path, title, modified_at, indexed_at, content_hash, metadata)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			title = excluded.title,
+			modified_at = excluded.modified_at,
+			indexed_at = excluded.indexed_at,
+			content_hash = excluded.content_hash,
+			metadata = excluded.metadata
+		RETURNING id
+	`, doc.Path, doc.Title, doc.ModifiedAt, doc.IndexedAt, doc.ContentHash, metadata).Scan(&docID)
+	if err != nil {
+		return err
+	}
+
+	if err := db.deleteChunksForDocumentTx(tx, docID); err != nil {
+		return err
+	}
+	for _, table := range []string{"document_tags", "document_links", "document_aliases"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE doc_id = ?", docID); err != nil {
+			return err
+		}
+	}
+
+	for _, chunk := range doc.Chunks {
+		result, err := tx.Exec(`
+			INSERT INTO chunks (doc_id, content, start_line, end_line, heading, word_count, token_count)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, docID, chunk.Content, chunk.StartLine, chunk.EndLine, chunk.Heading, CountWords(chunk.Content), EstimateTokens(chunk.Content))
+		if err != nil {
+			return err
+		}
+		chunkID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		if db.hasFTS {
+			if _, err := tx.Exec("INSERT OR REPLACE INTO fts_chunks (rowid, content, heading) VALUES (?, ?, ?)", chunkID, chunk.Content, chunk.Heading); err != nil {
+				return err
+			}
+		}
+
+		if len(chunk.Embedding) == 0 {
+			continue
+		}
+		if len(chunk.Embedding) != db.embedDim {
+			return fmt.Errorf("chunk at line %d has %d dimensions, expected %d", chunk.StartLine, len(chunk.Embedding), db.embedDim)
+		}
+		if err := db.insertVectorTx(tx, chunkID, chunk.Embedding); err != nil {
+			return err
+		}
+	}
+
+	for _, tag := range doc.Tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO document_tags (doc_id, tag) VALUES (?, ?)", docID, NormalizeTag(tag)); err != nil {
+			return err
+		}
+	}
+	for _, target := range doc.Links {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO document_links (doc_id, target) VALUES (?, ?)", docID, target); err != nil {
+			return err
+		}
+	}
+	for _, alias := range doc.Aliases {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO document_aliases (doc_id, alias) VALUES (?, ?)", docID, alias); err != nil {
+			return err
+		}
+	}
+	return nil
+}
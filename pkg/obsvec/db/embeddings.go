@@ -0,0 +1,163 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ChunkEmbedding is a chunk's stored embedding with what identifies the
+// chunk, for exporting embeddings.
+type ChunkEmbedding struct {
+	ChunkID   int64
+	Path      string
+	Heading   string
+	StartLine int
+	EndLine   int
+
+	// Hash is ChunkHash of the chunk's content.
+	Hash string
+
+	Embedding []float32
+}
+
+// ChunkHash identifies chunk text by its SHA-256 in hex, so an embedding
+// can be matched to the same text wherever it is indexed.
+func ChunkHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChunkEmbeddings returns every embedded chunk, ordered by path and line.
+// Quantized embeddings are returned in their dequantized float form.
+func (db *DB) ChunkEmbeddings() ([]ChunkEmbedding, error) {
+	rows, err := db.conn.Query(`
+		SELECT c.id, d.path, c.heading, c.start_line, c.end_line, c.content
+		FROM chunks c JOIN documents d ON d.id = c.doc_id
+		ORDER BY d.path, c.start_line, c.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var chunks []ChunkEmbedding
+	for rows.Next() {
+		var chunk ChunkEmbedding
+		var content string
+		if err := rows.Scan(&chunk.ChunkID, &chunk.Path, &chunk.Heading, &chunk.StartLine, &chunk.EndLine, &content); err != nil {
+			return nil, err
+		}
+		chunk.Hash = ChunkHash(content)
+		chunks = append(chunks, chunk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close() //nolint:errcheck
+
+	for batch := range slices.Chunk(chunks, maxQueryIDs) {
+		ids := make([]int64, len(batch))
+		for i, chunk := range batch {
+			ids[i] = chunk.ChunkID
+		}
+		embeddings, err := db.vectors.Get(ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range batch {
+			batch[i].Embedding = embeddings[batch[i].ChunkID]
+		}
+	}
+
+	// Chunks whose embed failed have no vector to export
+	return slices.DeleteFunc(chunks, func(c ChunkEmbedding) bool { return c.Embedding == nil }), nil
+}
+
+// ImportEmbeddings keeps vectors, computed elsewhere for the chunk texts
+// whose ChunkHash they are keyed by, and stores them for the chunks in the
+// index with those hashes, replacing their embeddings. Indexing takes
+// embeddings from the kept vectors before asking the embedding API. Vectors
+// are scaled to unit length, as the API returns them. It returns how many
+// chunks got a vector.
+func (db *DB) ImportEmbeddings(vectors map[string][]float32) (int, error) {
+	for hash, vector := range vectors {
+		if len(vector) != db.embedDim {
+			return 0, fmt.Errorf("embedding for %s has %d dimensions, expected %d", hash, len(vector), db.embedDim)
+		}
+		vectors[hash] = normalize(vector)
+	}
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for hash, vector := range vectors {
+		if _, err := tx.Exec("INSERT OR REPLACE INTO imported_embeddings (hash, embedding) VALUES (?, ?)", hash, SerializeFloat32(vector)); err != nil {
+			return 0, err
+		}
+	}
+
+	rows, err := tx.Query("SELECT id, content FROM chunks")
+	if err != nil {
+		return 0, err
+	}
+	matched := make(map[int64][]float32)
+	for rows.Next() {
+		var id int64
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close() //nolint:errcheck
+			return 0, err
+		}
+		if vector, ok := vectors[ChunkHash(content)]; ok {
+			matched[id] = vector
+		}
+	}
+	rows.Close() //nolint:errcheck
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// The vector table can't replace rows, so the old ones go first
+	ids := slices.Collect(maps.Keys(matched))
+	var args []any
+	if err := db.deleteVectorsTx(tx, "SELECT id FROM chunks WHERE id IN ("+idList(ids, &args)+")", args...); err != nil {
+		return 0, err
+	}
+	for id, vector := range matched {
+		if err := db.insertVectorTx(tx, id, vector); err != nil {
+			return 0, err
+		}
+	}
+	return len(matched), tx.Commit()
+}
+
+// ImportedEmbeddings returns the imported vectors of the given chunk hashes,
+// skipping hashes without one.
+func (db *DB) ImportedEmbeddings(hashes []string) (map[string][]float32, error) {
+	list, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.conn.Query("SELECT hash, embedding FROM imported_embeddings WHERE hash IN (SELECT value FROM json_each(?))", string(list))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	found := make(map[string][]float32)
+	for rows.Next() {
+		var hash string
+		var blob []byte
+		if err := rows.Scan(&hash, &blob); err != nil {
+			return nil, err
+		}
+		found[hash] = DeserializeFloat32(blob)
+	}
+	return found, rows.Err()
+}
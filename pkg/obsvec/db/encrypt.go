@@ -0,0 +1,44 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+)
+
+// keyHexLen is the length of a raw 256-bit SQLCipher key in hex.
+const keyHexLen = 64
+
+// NewKey returns a random key for Options.Key.
+func NewKey() (string, error) {
+	key := make([]byte, keyHexLen/2)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func validKey(key string) bool {
+	_, err := hex.DecodeString(key)
+	return err == nil && len(key) == keyHexLen
+}
+
+// checkCipher makes sure the key took effect. SQLite without SQLCipher
+// silently ignores PRAGMA key and would write the notes in plain text.
+func checkCipher(conn *sql.DB) error {
+	var version string
+	err := conn.QueryRow("PRAGMA cipher_version").Scan(&version)
+	if err == sql.ErrNoRows || (err == nil && version == "") {
+		return fmt.Errorf("encryption needs ofind built with SQLCipher (make build-encrypted)")
+	}
+	if err != nil {
+		return err
+	}
+
+	// Reading the schema fails with the wrong key or a plain-text database
+	if _, err := conn.Exec("SELECT COUNT(*) FROM sqlite_master"); err != nil {
+		return fmt.Errorf("can't decrypt database (wrong key, or created without encryption?): %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import "database/sql"
+
+// Engine describes the SQLite library this build uses.
+type Engine struct {
+	SQLite string `json:"sqlite"`
+
+	// SQLiteVec is the sqlite-vec extension's version, empty in pure-Go
+	// builds, which scan vectors in Go instead.
+	SQLiteVec string `json:"sqlite_vec,omitempty"`
+
+	// SQLCipher is the SQLCipher version, empty unless the build links
+	// against it for encryption.
+	SQLCipher string `json:"sqlcipher,omitempty"`
+
+	FTS5 bool `json:"fts5"`
+}
+
+// EngineInfo reports the SQLite library, extensions and compile options
+// available in this build, from an in-memory database.
+func EngineInfo() (Engine, error) {
+	var engine Engine
+	conn, err := sql.Open(driverName, ":memory:")
+	if err != nil {
+		return engine, err
+	}
+	defer conn.Close() //nolint:errcheck
+
+	err = conn.QueryRow("SELECT sqlite_version(), sqlite_compileoption_used('ENABLE_FTS5')").Scan(&engine.SQLite, &engine.FTS5)
+	if err != nil {
+		return engine, err
+	}
+	if nativeVec {
+		if err := conn.QueryRow("SELECT vec_version()").Scan(&engine.SQLiteVec); err != nil {
+			return engine, err
+		}
+	}
+
+	// Plain SQLite ignores the pragma and returns no row
+	err = conn.QueryRow("PRAGMA cipher_version").Scan(&engine.SQLCipher)
+	if err != nil && err != sql.ErrNoRows {
+		return engine, err
+	}
+	return engine, nil
+}
@@ -0,0 +1,255 @@
+package db
+
+import (
+	"encoding/json"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// SearchFilter restricts which chunks are considered by SearchSimilar and
+// SearchKeyword. The zero value matches everything.
+type SearchFilter struct {
+	// Tags requires the document to carry every listed tag. A tag also
+	// matches its nested tags, so "project" matches "project/x".
+	Tags []string
+
+	// Paths keeps documents matching any of these globs; ExcludePaths drops
+	// documents matching any of them. See MatchPathGlob for the syntax.
+	Paths        []string
+	ExcludePaths []string
+
+	// ModifiedSince and ModifiedBefore bound the document's modified_at
+	// (unix seconds). ModifiedSince is inclusive, ModifiedBefore exclusive;
+	// zero means unbounded.
+	ModifiedSince  int64
+	ModifiedBefore int64
+
+	// DocIDs keeps only chunks from these documents when non-empty;
+	// ExcludeDocIDs drops chunks from these documents.
+	DocIDs        []int64
+	ExcludeDocIDs []int64
+
+	// Phrases requires every phrase to appear in the chunk's content. See
+	// MatchPhrase for the matching rules.
+	Phrases []string
+
+	// Properties requires the document's frontmatter to match every filter.
+	Properties []PropertyFilter
+}
+
+// PropertyOp compares a frontmatter property with a PropertyFilter's value.
+type PropertyOp string
+
+const (
+	PropertyEquals         PropertyOp = "="
+	PropertyNotEquals      PropertyOp = "!="
+	PropertyLess           PropertyOp = "<"
+	PropertyLessOrEqual    PropertyOp = "<="
+	PropertyGreater        PropertyOp = ">"
+	PropertyGreaterOrEqual PropertyOp = ">="
+	PropertyExists         PropertyOp = "exists"
+)
+
+// PropertyFilter matches documents by a frontmatter property. A list property
+// matches when any of its items does, so "tags = x" works on a list, and
+// PropertyNotEquals matches when none does, including when the property is
+// missing. Strings compare without case; numbers and booleans need a Value
+// of the same type, which ParsePropertyValue gives for user input.
+type PropertyFilter struct {
+	Key   string
+	Op    PropertyOp
+	Value any
+}
+
+// condition returns the SQL for the filter against documents aliased d and
+// appends its arguments to args.
+func (f PropertyFilter) condition(args *[]any) string {
+	path := PropertyPath(f.Key)
+	switch f.Op {
+	case PropertyExists:
+		*args = append(*args, path)
+		return "json_type(d.metadata, ?) IS NOT NULL"
+	case PropertyNotEquals:
+		*args = append(*args, path, f.Value)
+		return "NOT EXISTS (SELECT 1 FROM json_each(d.metadata, ?) WHERE value = ? COLLATE NOCASE)"
+	case PropertyLess, PropertyLessOrEqual, PropertyGreater, PropertyGreaterOrEqual:
+		*args = append(*args, path, f.Value)
+		return "EXISTS (SELECT 1 FROM json_each(d.metadata, ?) WHERE value " + string(f.Op) + " ?)"
+	default:
+		*args = append(*args, path, f.Value)
+		return "EXISTS (SELECT 1 FROM json_each(d.metadata, ?) WHERE value = ? COLLATE NOCASE)"
+	}
+}
+
+// PropertyPath returns the JSON path of a property in the metadata column.
+// Property names are stored lowercased, matching Obsidian, which treats them
+// without case.
+func PropertyPath(key string) string {
+	return `$."` + strings.ToLower(strings.TrimSpace(key)) + `"`
+}
+
+// ParsePropertyValue reads an unquoted YAML scalar the way frontmatter
+// properties are stored: true and false become booleans, numbers become
+// float64 and anything else stays a string. Quoted values are always strings.
+func ParsePropertyValue(s string) any {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	switch strings.ToLower(s) {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null", "~", "":
+		return nil
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+		return n
+	}
+	return s
+}
+
+func (f SearchFilter) IsEmpty() bool {
+	return len(f.Tags) == 0 && len(f.Paths) == 0 && len(f.ExcludePaths) == 0 &&
+		f.ModifiedSince == 0 && f.ModifiedBefore == 0 && len(f.DocIDs) == 0 && len(f.ExcludeDocIDs) == 0 &&
+		len(f.Phrases) == 0 && len(f.Properties) == 0
+}
+
+// chunkIDQuery returns a subquery selecting the ids of matching chunks, for use
+// in a "chunk_id IN (...)" constraint, or "" when the filter is empty.
+func (f SearchFilter) chunkIDQuery() (string, []any) {
+	if f.IsEmpty() {
+		return "", nil
+	}
+
+	var conds []string
+	var args []any
+	for _, tag := range f.Tags {
+		tag = NormalizeTag(tag)
+		conds = append(conds, `d.id IN (SELECT doc_id FROM document_tags WHERE tag = ? OR tag LIKE ? ESCAPE '\')`)
+		args = append(args, tag, escapeLike(tag)+"/%")
+	}
+
+	if len(f.Paths) > 0 {
+		var include []string
+		for _, pattern := range f.Paths {
+			include = append(include, "path_glob(?, d.path)")
+			args = append(args, pattern)
+		}
+		conds = append(conds, "("+strings.Join(include, " OR ")+")")
+	}
+
+	for _, pattern := range f.ExcludePaths {
+		conds = append(conds, "NOT path_glob(?, d.path)")
+		args = append(args, pattern)
+	}
+
+	if f.ModifiedSince != 0 {
+		conds = append(conds, "d.modified_at >= ?")
+		args = append(args, f.ModifiedSince)
+	}
+	if f.ModifiedBefore != 0 {
+		conds = append(conds, "d.modified_at < ?")
+		args = append(args, f.ModifiedBefore)
+	}
+
+	if len(f.DocIDs) > 0 {
+		conds = append(conds, "d.id IN ("+idList(f.DocIDs, &args)+")")
+	}
+	if len(f.ExcludeDocIDs) > 0 {
+		conds = append(conds, "d.id NOT IN ("+idList(f.ExcludeDocIDs, &args)+")")
+	}
+
+	for _, phrase := range f.Phrases {
+		conds = append(conds, "contains_phrase(c.content, ?)")
+		args = append(args, phrase)
+	}
+
+	for _, property := range f.Properties {
+		conds = append(conds, property.condition(&args))
+	}
+
+	query := "SELECT c.id FROM chunks c JOIN documents d ON d.id = c.doc_id WHERE " + strings.Join(conds, " AND ")
+	return query, args
+}
+
+// idPlaceholders returns a "?, ?, ..." list for ids and appends them to args.
+func idPlaceholders(ids []int64, args *[]any) string {
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		*args = append(*args, id)
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+// idList returns a subquery over ids bound as a single JSON array parameter,
+// for id lists inside larger queries that can't be split into batches.
+func idList(ids []int64, args *[]any) string {
+	data, _ := json.Marshal(ids)
+	*args = append(*args, string(data))
+	return "SELECT value FROM json_each(?)"
+}
+
+// NormalizeTag lowercases a tag and strips a leading '#', matching Obsidian's
+// case-insensitive tag semantics.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// MatchPhrase reports whether phrase appears in content, ignoring case and
+// treating any run of whitespace as a single space so phrases still match
+// across line breaks.
+func MatchPhrase(content, phrase string) bool {
+	phrase = strings.ToLower(strings.Join(strings.Fields(phrase), " "))
+	if phrase == "" {
+		return true
+	}
+	content = strings.ToLower(strings.Join(strings.Fields(content), " "))
+	return strings.Contains(content, phrase)
+}
+
+// MatchPathGlob reports whether a vault-relative path matches pattern. Segments
+// use path.Match syntax, and a "**" segment matches any number of directories.
+// A pattern without glob characters matches that file or everything under
+// that directory, so "Projects" behaves like "Projects/**".
+func MatchPathGlob(pattern, p string) bool {
+	pattern = strings.Trim(strings.ReplaceAll(pattern, "\\", "/"), "/")
+	p = strings.Trim(strings.ReplaceAll(p, "\\", "/"), "/")
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		return p == pattern || strings.HasPrefix(p, pattern+"/")
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
@@ -0,0 +1,134 @@
+package db
+
+import "slices"
+
+// Vacuum rebuilds the database file to reclaim space left by deleted and
+// rewritten chunks, after rebuilding every index and merging the keyword
+// index's segments.
+func (db *DB) Vacuum() error {
+	if _, err := db.writer.Exec("REINDEX"); err != nil {
+		return err
+	}
+
+	if db.hasFTS {
+		if _, err := db.writer.Exec("INSERT INTO fts_chunks (fts_chunks) VALUES ('optimize')"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.writer.Exec("VACUUM"); err != nil {
+		return err
+	}
+
+	// In WAL mode the rebuilt pages land in the log; copy them back so the
+	// file itself shrinks
+	_, err := db.writer.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	return err
+}
+
+// PruneResult counts the rows Prune removed.
+type PruneResult struct {
+	Documents int
+	Chunks    int
+	Vectors   int
+}
+
+// pruneStep deletes orphaned rows, optionally counting them.
+type pruneStep struct {
+	count *int
+	query string
+}
+
+// Prune removes documents whose files are gone, according to exists, along
+// with anything left behind by an interrupted index: chunks without a
+// document, and vectors, keyword entries and metadata without a chunk or
+// document.
+func (db *DB) Prune(exists func(path string) bool) (PruneResult, error) {
+	var result PruneResult
+
+	docs, err := db.GetAllDocuments()
+	if err != nil {
+		return result, err
+	}
+	for _, doc := range docs {
+		if exists(doc.Path) {
+			continue
+		}
+		if err := db.DeleteDocument(doc.Path); err != nil {
+			return result, err
+		}
+		result.Documents++
+	}
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return result, err
+	}
+
+	const orphanChunks = "FROM chunks WHERE doc_id IS NULL OR doc_id NOT IN (SELECT id FROM documents)"
+
+	var steps []pruneStep
+	if _, ok := db.vectors.(localVectorStore); ok {
+		steps = []pruneStep{
+			{&result.Chunks, "DELETE " + orphanChunks},
+			{&result.Vectors, "DELETE FROM vec_chunks WHERE chunk_id NOT IN (SELECT id FROM chunks)"},
+		}
+	} else {
+		// Vectors in other stores can only be found through their chunks
+		if err := db.deleteVectorsTx(tx, "SELECT id "+orphanChunks); err != nil {
+			_ = tx.Rollback()
+			return result, err
+		}
+		steps = []pruneStep{{&result.Chunks, "DELETE " + orphanChunks}}
+	}
+	steps = append(steps,
+		pruneStep{nil, "DELETE FROM document_tags WHERE doc_id NOT IN (SELECT id FROM documents)"},
+		pruneStep{nil, "DELETE FROM document_links WHERE doc_id NOT IN (SELECT id FROM documents)"},
+		pruneStep{nil, "DELETE FROM document_aliases WHERE doc_id NOT IN (SELECT id FROM documents)"},
+	)
+	if db.hasFTS {
+		steps = append(steps, pruneStep{nil, "DELETE FROM fts_chunks WHERE rowid NOT IN (SELECT id FROM chunks)"})
+	}
+
+	for _, step := range steps {
+		res, err := tx.Exec(step.query)
+		if err != nil {
+			_ = tx.Rollback()
+			return result, err
+		}
+		if step.count != nil {
+			n, err := res.RowsAffected()
+			if err != nil {
+				_ = tx.Rollback()
+				return result, err
+			}
+			*step.count = int(n)
+		}
+	}
+
+	return result, tx.Commit()
+}
+
+// ChunksWithoutEmbeddings counts each document's chunks that have no stored
+// vector, keyed by document id, as left by an interrupted or failed embed.
+// Documents whose chunks all have vectors are omitted.
+func (db *DB) ChunksWithoutEmbeddings() (map[int64]int, error) {
+	chunkIDs, docIDs, err := db.chunkDocuments()
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make(map[int64]int)
+	for batch := range slices.Chunk(chunkIDs, maxQueryIDs) {
+		embeddings, err := db.vectors.Get(batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, chunkID := range batch {
+			if _, ok := embeddings[chunkID]; !ok {
+				missing[docIDs[chunkID]]++
+			}
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// vecColumnPattern matches the embedding declaration in vec_chunks' schema.
+var vecColumnPattern = regexp.MustCompile(`embedding (float|int8|bit)\[(\d+)\]`)
+
+// getMeta returns a value recorded in the meta table, or "" if unset.
+func (db *DB) getMeta(key string) (string, error) {
+	var value string
+	err := db.conn.QueryRow("SELECT value FROM meta WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func (db *DB) setMeta(key, value string) error {
+	_, err := db.writer.Exec(`
+		INSERT INTO meta (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, key, value)
+	return err
+}
+
+// vecColumn reads the embedding type and dimension vec_chunks was created
+// with, and whether it is a sqlite-vec table rather than the plain table of
+// pure-Go builds.
+func (db *DB) vecColumn() (Quantization, int, bool, error) {
+	var schema string
+	err := db.conn.QueryRow("SELECT sql FROM sqlite_master WHERE name = 'vec_chunks'").Scan(&schema)
+	if err != nil {
+		return "", 0, false, err
+	}
+	native := strings.Contains(schema, "USING vec0")
+
+	m := vecColumnPattern.FindStringSubmatch(schema)
+	if m == nil {
+		return "", 0, false, fmt.Errorf("unrecognized vec_chunks schema: %s", schema)
+	}
+	dim, err := strconv.Atoi(m[2])
+	if err != nil {
+		return "", 0, false, err
+	}
+	quantization, err := ParseQuantization(m[1])
+	return quantization, dim, native, err
+}
+
+// MismatchError is returned by Open when the index holds vectors that don't
+// match the configured embeddings. Opening with Options.Rebuild clears them.
+type MismatchError struct {
+	Reason string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("index holds %s; rebuild it with ofind index -full", e.Reason)
+}
+
+// checkEmbeddings makes sure the stored vectors match the configured model,
+// dimension and quantization, then records them in meta. An index without
+// vectors is adapted silently; one holding vectors fails to open, unless
+// Options.Rebuild asks for it to be cleared.
+func (db *DB) checkEmbeddings() error {
+	quantization, dim, native, err := db.vecColumn()
+	if err != nil {
+		return err
+	}
+	if native && !nativeVec {
+		// Without sqlite-vec the table can't even be dropped
+		return fmt.Errorf("index was built with sqlite-vec, which this pure-Go build can't read; delete it or use a cgo build")
+	}
+	model, err := db.getMeta("embed_model")
+	if err != nil {
+		return err
+	}
+
+	var mismatch string
+	switch {
+	case !native && nativeVec:
+		mismatch = "vectors from a pure-Go build"
+	case dim != db.embedDim:
+		mismatch = fmt.Sprintf("%d-dimensional embeddings but embed_dim is %d", dim, db.embedDim)
+	case db.embedModel != "" && model != "" && model != db.embedModel:
+		mismatch = fmt.Sprintf("embeddings from %s but embed_model is %s", model, db.embedModel)
+	case quantization != db.quantization:
+		mismatch = fmt.Sprintf("%s vectors but %s quantization is configured", quantization, db.quantization)
+	}
+
+	if mismatch != "" {
+		// Other stores can't be counted, so any chunk is taken to have a vector
+		countQuery := "SELECT COUNT(*) FROM chunks"
+		if _, ok := db.vectors.(localVectorStore); ok {
+			countQuery = "SELECT COUNT(*) FROM vec_chunks"
+		}
+		var count int
+		if err := db.conn.QueryRow(countQuery).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 && !db.rebuild {
+			return &MismatchError{Reason: mismatch}
+		}
+		if err := db.resetIndex(); err != nil {
+			return err
+		}
+	}
+
+	if db.embedModel != "" {
+		if err := db.setMeta("embed_model", db.embedModel); err != nil {
+			return err
+		}
+	}
+	return db.setMeta("embed_dim", strconv.Itoa(db.embedDim))
+}
+
+// resetIndex removes every document, trashed ones included, and recreates
+// vec_chunks for the configured embeddings. The keyword index is dropped with
+// the chunks and recreated by initFTS.
+func (db *DB) resetIndex() error {
+	if _, ok := db.vectors.(localVectorStore); !ok {
+		tx, err := db.writer.Begin()
+		if err != nil {
+			return err
+		}
+		if err := db.deleteVectorsTx(tx, "SELECT id FROM chunks"); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.writer.Exec(`
+		DELETE FROM trash;
+		DELETE FROM imported_embeddings;
+		DELETE FROM titles;
+		DELETE FROM document_aliases;
+		DELETE FROM document_links;
+		DELETE FROM document_tags;
+		DELETE FROM chunks;
+		DELETE FROM documents;
+		DROP TABLE IF EXISTS fts_chunks;
+		DROP TABLE vec_chunks;
+	` + vecTableSQL(db.quantization.columnType(db.embedDim)))
+	return err
+}
@@ -0,0 +1,229 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// migration is one versioned schema change. Steps use IF NOT EXISTS so they
+// also apply cleanly to databases created before versioning, which already
+// have some of the tables.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx, db *DB) error
+}
+
+// migrations are applied in order; append new steps with the next version
+// and never edit a released one.
+var migrations = []migration{
+	{1, "initial schema", func(tx *sql.Tx, db *DB) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS documents (
+				id INTEGER PRIMARY KEY,
+				path TEXT UNIQUE NOT NULL,
+				title TEXT,
+				modified_at INTEGER,
+				indexed_at INTEGER
+			);
+
+			CREATE TABLE IF NOT EXISTS chunks (
+				id INTEGER PRIMARY KEY,
+				doc_id INTEGER REFERENCES documents(id) ON DELETE CASCADE,
+				content TEXT NOT NULL,
+				start_line INTEGER,
+				end_line INTEGER,
+				heading TEXT
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_chunks_doc_id ON chunks(doc_id);
+			CREATE INDEX IF NOT EXISTS idx_documents_path ON documents(path);
+		` + vecTableSQL(db.quantization.columnType(db.embedDim)))
+		return err
+	}},
+	{2, "document tags", execStep(`
+		CREATE TABLE IF NOT EXISTS document_tags (
+			doc_id INTEGER REFERENCES documents(id) ON DELETE CASCADE,
+			tag TEXT NOT NULL,
+			PRIMARY KEY (doc_id, tag)
+		);
+		CREATE INDEX IF NOT EXISTS idx_document_tags_tag ON document_tags(tag);
+	`)},
+	{3, "document links", execStep(`
+		CREATE TABLE IF NOT EXISTS document_links (
+			doc_id INTEGER REFERENCES documents(id) ON DELETE CASCADE,
+			target TEXT NOT NULL,
+			PRIMARY KEY (doc_id, target)
+		);
+	`)},
+	{4, "document aliases", execStep(`
+		CREATE TABLE IF NOT EXISTS document_aliases (
+			doc_id INTEGER REFERENCES documents(id) ON DELETE CASCADE,
+			alias TEXT NOT NULL,
+			PRIMARY KEY (doc_id, alias)
+		);
+	`)},
+	{5, "index metadata", execStep(`
+		CREATE TABLE IF NOT EXISTS meta (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		);
+	`)},
+	{6, "chunk word and token counts", func(tx *sql.Tx, _ *DB) error {
+		_, err := tx.Exec(`
+			ALTER TABLE chunks ADD COLUMN word_count INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE chunks ADD COLUMN token_count INTEGER NOT NULL DEFAULT 0;
+		`)
+		if err != nil {
+			return err
+		}
+		return backfillChunkCounts(tx)
+	}},
+	{7, "document content hashes", execStep(`
+		ALTER TABLE documents ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+	`)},
+	{8, "document metadata", execStep(`
+		ALTER TABLE documents ADD COLUMN metadata TEXT NOT NULL DEFAULT '{}';
+	`)},
+	{9, "trash", execStep(`
+		CREATE TABLE IF NOT EXISTS trash (
+			id INTEGER PRIMARY KEY,
+			path TEXT NOT NULL,
+			content_hash TEXT NOT NULL,
+			trashed_at INTEGER NOT NULL,
+			data TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_trash_content_hash ON trash(content_hash);
+	`)},
+	{10, "title index", execStep(`
+		CREATE TABLE IF NOT EXISTS titles (
+			doc_id INTEGER NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+			chunk_id INTEGER,
+			kind TEXT NOT NULL,
+			name TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_titles_doc_id ON titles(doc_id);
+		CREATE INDEX IF NOT EXISTS idx_titles_chunk_id ON titles(chunk_id);
+		CREATE INDEX IF NOT EXISTS idx_titles_name ON titles(name COLLATE NOCASE);
+
+		-- The file name is the path after its last slash, without .md
+		CREATE VIEW IF NOT EXISTS document_file_names AS
+			SELECT id, substr(base, 1, length(base) - (CASE WHEN lower(base) LIKE '%.md' THEN 3 ELSE 0 END)) AS name
+			FROM (
+				SELECT id, substr(p, length(rtrim(p, replace(p, '/', ''))) + 1) AS base
+				FROM (SELECT id, replace(path, '\', '/') AS p FROM documents)
+			);
+
+		CREATE TRIGGER IF NOT EXISTS titles_document_insert AFTER INSERT ON documents BEGIN
+			INSERT INTO titles (doc_id, kind, name) SELECT NEW.id, 'title', NEW.title WHERE COALESCE(NEW.title, '') != '';
+			INSERT INTO titles (doc_id, kind, name) SELECT id, 'file', name FROM document_file_names WHERE id = NEW.id;
+		END;
+		CREATE TRIGGER IF NOT EXISTS titles_document_update AFTER UPDATE OF title, path ON documents BEGIN
+			DELETE FROM titles WHERE doc_id = NEW.id AND kind IN ('title', 'file');
+			INSERT INTO titles (doc_id, kind, name) SELECT NEW.id, 'title', NEW.title WHERE COALESCE(NEW.title, '') != '';
+			INSERT INTO titles (doc_id, kind, name) SELECT id, 'file', name FROM document_file_names WHERE id = NEW.id;
+		END;
+		CREATE TRIGGER IF NOT EXISTS titles_document_delete AFTER DELETE ON documents BEGIN
+			DELETE FROM titles WHERE doc_id = OLD.id;
+		END;
+		CREATE TRIGGER IF NOT EXISTS titles_alias_insert AFTER INSERT ON document_aliases BEGIN
+			INSERT INTO titles (doc_id, kind, name) VALUES (NEW.doc_id, 'alias', NEW.alias);
+		END;
+		CREATE TRIGGER IF NOT EXISTS titles_alias_delete AFTER DELETE ON document_aliases BEGIN
+			DELETE FROM titles WHERE doc_id = OLD.doc_id AND kind = 'alias' AND name = OLD.alias;
+		END;
+		CREATE TRIGGER IF NOT EXISTS titles_chunk_insert AFTER INSERT ON chunks WHEN COALESCE(NEW.heading, '') != '' BEGIN
+			INSERT INTO titles (doc_id, chunk_id, kind, name) VALUES (NEW.doc_id, NEW.id, 'heading', NEW.heading);
+		END;
+		CREATE TRIGGER IF NOT EXISTS titles_chunk_delete AFTER DELETE ON chunks BEGIN
+			DELETE FROM titles WHERE chunk_id = OLD.id;
+		END;
+
+		DELETE FROM titles;
+		INSERT INTO titles (doc_id, kind, name) SELECT id, 'title', title FROM documents WHERE COALESCE(title, '') != '';
+		INSERT INTO titles (doc_id, kind, name) SELECT id, 'file', name FROM document_file_names;
+		INSERT INTO titles (doc_id, kind, name) SELECT doc_id, 'alias', alias FROM document_aliases;
+		INSERT INTO titles (doc_id, chunk_id, kind, name) SELECT doc_id, id, 'heading', heading FROM chunks WHERE COALESCE(heading, '') != '';
+	`)},
+	{11, "imported embeddings", execStep(`
+		CREATE TABLE IF NOT EXISTS imported_embeddings (
+			hash TEXT PRIMARY KEY,
+			embedding BLOB NOT NULL
+		);
+	`)},
+}
+
+func execStep(query string) func(tx *sql.Tx, db *DB) error {
+	return func(tx *sql.Tx, _ *DB) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// migrate applies every migration newer than the database's schema version,
+// each in its own transaction.
+func (db *DB) migrate() error {
+	_, err := db.writer.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if latest := migrations[len(migrations)-1].version; current > latest {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d); upgrade ofind", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := db.writer.Begin()
+		if err != nil {
+			return err
+		}
+		// Another process may have applied it while this one waited for the
+		// write lock
+		var applied bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_version WHERE version = ?)", m.version).Scan(&applied); err != nil || applied {
+			_ = tx.Rollback()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		start := time.Now()
+		if err := m.up(tx, db); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		if _, err := tx.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)", m.version, m.name, time.Now().Unix()); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		slog.Debug("applied migration", "version", m.version, "name", m.name, "duration", time.Since(start))
+	}
+	return nil
+}
+
+// SchemaVersion returns the version of the last applied migration, or 0 for
+// an empty database.
+func (db *DB) SchemaVersion() (int, error) {
+	var version int
+	err := db.conn.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	return version, err
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Quantization selects how embeddings are stored in vec_chunks. Quantized
+// vectors make the index much smaller; SearchSimilar fetches extra candidates
+// and rescores them against the full-precision query to recover accuracy.
+type Quantization string
+
+const (
+	QuantizeNone Quantization = ""
+	QuantizeInt8 Quantization = "int8"
+	QuantizeBit  Quantization = "bit"
+)
+
+// rescoreMultiplier is how many quantized candidates are fetched per result
+// before rescoring with the float query.
+const rescoreMultiplier = 4
+
+// maxKNN is the largest k sqlite-vec accepts for a KNN query.
+const maxKNN = 4096
+
+// ParseQuantization accepts "float" (or empty), "int8" and "bit".
+func ParseQuantization(s string) (Quantization, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "float", "float32":
+		return QuantizeNone, nil
+	case "int8":
+		return QuantizeInt8, nil
+	case "bit", "binary":
+		return QuantizeBit, nil
+	}
+	return "", fmt.Errorf("unknown quantization %q: use float, int8 or bit", s)
+}
+
+func (q Quantization) String() string {
+	if q == QuantizeNone {
+		return "float"
+	}
+	return string(q)
+}
+
+// columnType is the vec0 column declaration for embeddings of dim dimensions.
+func (q Quantization) columnType(dim int) string {
+	return fmt.Sprintf("%s[%d]", q.String(), dim)
+}
+
+// param wraps a placeholder for a float32 vector so it is quantized to the
+// column's type.
+func (q Quantization) param() string {
+	switch q {
+	case QuantizeInt8:
+		return "vec_quantize_int8(?, 'unit')"
+	case QuantizeBit:
+		return "vec_quantize_binary(?)"
+	}
+	return "?"
+}
+
+// decode converts a stored vector back to a unit-length float32 vector of dim
+// dimensions.
+func (q Quantization) decode(blob []byte, dim int) []float32 {
+	switch q {
+	case QuantizeInt8:
+		vector := make([]float32, len(blob))
+		for i, b := range blob {
+			vector[i] = float32(int8(b))
+		}
+		return normalize(vector)
+	case QuantizeBit:
+		vector := make([]float32, dim)
+		for i := range vector {
+			if blob[i/8]&(1<<(i%8)) != 0 {
+				vector[i] = 1
+			} else {
+				vector[i] = -1
+			}
+		}
+		return normalize(vector)
+	}
+	return DeserializeFloat32(blob)
+}
+
+func normalize(vector []float32) []float32 {
+	var norm float64
+	for _, x := range vector {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return vector
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vector {
+		vector[i] *= scale
+	}
+	return vector
+}
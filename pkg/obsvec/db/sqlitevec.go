@@ -0,0 +1,220 @@
+//go:build !purego
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
+	"github.com/mattn/go-sqlite3"
+)
+
+// nativeVec reports whether vec_chunks is a sqlite-vec table in this build.
+const nativeVec = true
+
+var sqliteDriver = &sqlite3.SQLiteDriver{
+	ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+		if err := conn.RegisterFunc("path_glob", MatchPathGlob, true); err != nil {
+			return err
+		}
+		return conn.RegisterFunc("contains_phrase", MatchPhrase, true)
+	},
+}
+
+func init() {
+	sqlite_vec.Auto()
+	sql.Register(driverName, sqliteDriver)
+}
+
+func checkVectorSupport(conn *sql.DB) error {
+	var vecVersion string
+	if err := conn.QueryRow("SELECT vec_version()").Scan(&vecVersion); err != nil {
+		return fmt.Errorf("sqlite-vec not available: %w", err)
+	}
+	return nil
+}
+
+// vecTableSQL creates vec_chunks with the given embedding column type.
+func vecTableSQL(columnType string) string {
+	return fmt.Sprintf(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS vec_chunks USING vec0(
+			chunk_id INTEGER PRIMARY KEY,
+			embedding %s
+		);
+	`, columnType)
+}
+
+func newLocalVectorStore(conn, writer *sql.DB, dim int, quantization Quantization) localVectorStore {
+	return &sqliteVecStore{conn: conn, writer: writer, dim: dim, quantization: quantization}
+}
+
+// sqliteVecStore is the default VectorStore: the vec_chunks sqlite-vec table
+// in the database itself, optionally quantized.
+type sqliteVecStore struct {
+	conn         *sql.DB
+	writer       *sql.DB
+	dim          int
+	quantization Quantization
+}
+
+func (s *sqliteVecStore) Insert(chunkID int64, embedding []float32) error {
+	return s.insert(s.writer, chunkID, embedding)
+}
+
+func (s *sqliteVecStore) insertTx(tx *sql.Tx, chunkID int64, embedding []float32) error {
+	return s.insert(tx, chunkID, embedding)
+}
+
+func (s *sqliteVecStore) insert(conn execer, chunkID int64, embedding []float32) error {
+	_, err := conn.Exec("INSERT INTO vec_chunks (chunk_id, embedding) VALUES (?, "+s.quantization.param()+")", chunkID, SerializeFloat32(embedding))
+	return err
+}
+
+func (s *sqliteVecStore) Search(query []float32, k int, allowed []int64) ([]VectorHit, error) {
+	if allowed == nil {
+		return s.search(query, k, "")
+	}
+	var args []any
+	return s.search(query, k, "AND chunk_id IN ("+idList(allowed, &args)+")", args...)
+}
+
+func (s *sqliteVecStore) searchFiltered(query []float32, k int, filter SearchFilter) ([]VectorHit, error) {
+	subquery, args := filter.chunkIDQuery()
+	if subquery == "" {
+		return s.search(query, k, "")
+	}
+	// Constraining chunk_id lets sqlite-vec filter before the KNN match
+	return s.search(query, k, "AND chunk_id IN ("+subquery+")", args...)
+}
+
+// search runs a KNN query with an extra WHERE constraint. Quantized indexes
+// fetch extra candidates and rescore them against the float query.
+func (s *sqliteVecStore) search(query []float32, k int, constraint string, constraintArgs ...any) ([]VectorHit, error) {
+	fetch := k
+	vectorColumn := ""
+	if s.quantization != QuantizeNone {
+		fetch = min(k*rescoreMultiplier, maxKNN)
+		vectorColumn = ", embedding"
+	}
+
+	args := append([]any{SerializeFloat32(query), fetch}, constraintArgs...)
+	rows, err := s.conn.Query(`
+		SELECT chunk_id, distance`+vectorColumn+`
+		FROM vec_chunks
+		WHERE embedding MATCH `+s.quantization.param()+` AND k = ? `+constraint+`
+		ORDER BY distance
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var hits []VectorHit
+	var vectors [][]byte
+	for rows.Next() {
+		var hit VectorHit
+		dest := []any{&hit.ChunkID, &hit.Distance}
+		var vector []byte
+		if vectorColumn != "" {
+			dest = append(dest, &vector)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+		vectors = append(vectors, vector)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if s.quantization != QuantizeNone {
+		hits = s.rescore(query, hits, vectors, k)
+	}
+	return hits, nil
+}
+
+// rescore reorders quantized candidates by their distance to the float query,
+// keeping the best limit. Distances are converted to the L2 distance between
+// unit vectors so they compare with unquantized results.
+func (s *sqliteVecStore) rescore(query []float32, hits []VectorHit, vectors [][]byte, limit int) []VectorHit {
+	for i := range hits {
+		vector := s.quantization.decode(vectors[i], s.dim)
+		var dot float64
+		for j := range min(len(query), len(vector)) {
+			dot += float64(query[j]) * float64(vector[j])
+		}
+		hits[i].Distance = math.Sqrt(max(0, 2-2*dot))
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Distance < hits[j].Distance
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+func (s *sqliteVecStore) Get(chunkIDs []int64) (map[int64][]float32, error) {
+	embeddings := make(map[int64][]float32, len(chunkIDs))
+	for batch := range slices.Chunk(chunkIDs, maxQueryIDs) {
+		if err := s.getBatch(batch, embeddings); err != nil {
+			return nil, err
+		}
+	}
+	return embeddings, nil
+}
+
+func (s *sqliteVecStore) getBatch(chunkIDs []int64, embeddings map[int64][]float32) error {
+	var args []any
+	rows, err := s.conn.Query(
+		"SELECT chunk_id, embedding FROM vec_chunks WHERE chunk_id IN ("+idPlaceholders(chunkIDs, &args)+")",
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	for rows.Next() {
+		var id int64
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return err
+		}
+		embeddings[id] = s.quantization.decode(blob, s.dim)
+	}
+	return rows.Err()
+}
+
+func (s *sqliteVecStore) Delete(chunkIDs []int64) error {
+	tx, err := s.writer.Begin()
+	if err != nil {
+		return err
+	}
+	if err := s.deleteTx(tx, chunkIDs); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteVecStore) deleteTx(tx *sql.Tx, chunkIDs []int64) error {
+	for batch := range slices.Chunk(chunkIDs, maxQueryIDs) {
+		var args []any
+		if _, err := tx.Exec("DELETE FROM vec_chunks WHERE chunk_id IN ("+idPlaceholders(batch, &args)+")", args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close is a no-op; the table closes with the database.
+func (s *sqliteVecStore) Close() error {
+	return nil
+}
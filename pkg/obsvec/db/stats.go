@@ -0,0 +1,163 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// charsPerToken approximates how many characters of English prose make up
+// one embedding token.
+const charsPerToken = 4
+
+// EstimateTokens approximates the number of embedding tokens in text.
+func EstimateTokens(text string) int {
+	return len(text) / charsPerToken
+}
+
+// CountWords counts whitespace-separated words in text.
+func CountWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+// Stats summarizes the indexed vault.
+type Stats struct {
+	Documents int `json:"documents"`
+	Chunks    int `json:"chunks"`
+	Words     int `json:"words"`
+	Tokens    int `json:"tokens"`
+
+	// Largest are the notes with the most tokens, largest first.
+	Largest []NoteStats `json:"largest"`
+
+	// Months counts notes by the month they were last modified, oldest
+	// first, as a rough view of how the vault has grown.
+	Months []MonthStats `json:"months"`
+}
+
+// NoteStats is the size of one note.
+type NoteStats struct {
+	Path   string `json:"path"`
+	Words  int    `json:"words"`
+	Tokens int    `json:"tokens"`
+}
+
+// MonthStats is the notes last modified in one month (YYYY-MM).
+type MonthStats struct {
+	Month string `json:"month"`
+	Notes int    `json:"notes"`
+	Words int    `json:"words"`
+}
+
+// WordsPerNote is the mean word count of a note.
+func (s *Stats) WordsPerNote() float64 {
+	if s.Documents == 0 {
+		return 0
+	}
+	return float64(s.Words) / float64(s.Documents)
+}
+
+// TokensPerNote is the mean token count of a note.
+func (s *Stats) TokensPerNote() float64 {
+	if s.Documents == 0 {
+		return 0
+	}
+	return float64(s.Tokens) / float64(s.Documents)
+}
+
+// Stats computes vault analytics from the index, listing up to largest of
+// the biggest notes.
+func (db *DB) Stats(largest int) (*Stats, error) {
+	var stats Stats
+	err := db.conn.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM documents),
+			COUNT(*),
+			COALESCE(SUM(word_count), 0),
+			COALESCE(SUM(token_count), 0)
+		FROM chunks
+	`).Scan(&stats.Documents, &stats.Chunks, &stats.Words, &stats.Tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT d.path, SUM(c.word_count), SUM(c.token_count)
+		FROM documents d
+		JOIN chunks c ON c.doc_id = d.id
+		GROUP BY d.id
+		ORDER BY SUM(c.token_count) DESC, d.path
+		LIMIT ?
+	`, largest)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	for rows.Next() {
+		var note NoteStats
+		if err := rows.Scan(&note.Path, &note.Words, &note.Tokens); err != nil {
+			return nil, err
+		}
+		stats.Largest = append(stats.Largest, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.conn.Query(`
+		SELECT
+			strftime('%Y-%m', d.modified_at, 'unixepoch') AS month,
+			COUNT(*),
+			SUM(COALESCE((SELECT SUM(word_count) FROM chunks c WHERE c.doc_id = d.id), 0))
+		FROM documents d
+		GROUP BY month
+		ORDER BY month
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	for rows.Next() {
+		var month MonthStats
+		if err := rows.Scan(&month.Month, &month.Notes, &month.Words); err != nil {
+			return nil, err
+		}
+		stats.Months = append(stats.Months, month)
+	}
+	return &stats, rows.Err()
+}
+
+// backfillChunkCounts fills in word and token counts for chunks indexed
+// before they were recorded.
+func backfillChunkCounts(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, content FROM chunks")
+	if err != nil {
+		return err
+	}
+
+	type chunkContent struct {
+		id      int64
+		content string
+	}
+	var chunks []chunkContent
+	for rows.Next() {
+		var c chunkContent
+		if err := rows.Scan(&c.id, &c.content); err != nil {
+			rows.Close() //nolint:errcheck
+			return err
+		}
+		chunks = append(chunks, c)
+	}
+	rows.Close() //nolint:errcheck
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range chunks {
+		if _, err := tx.Exec("UPDATE chunks SET word_count = ?, token_count = ? WHERE id = ?", CountWords(c.content), EstimateTokens(c.content), c.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syncFormat identifies a sync bundle and its layout version.
+const (
+	syncFormat  = "obsvec-sync"
+	syncVersion = 1
+)
+
+// Meta keys of the sync checkpoints: the end of the last bundle exported
+// from this index, and of the last one applied to it, in Unix seconds.
+const (
+	syncExportedKey = "sync_exported"
+	syncAppliedKey  = "sync_applied"
+)
+
+// syncHeader is the first line of a sync bundle. Paths lists every document
+// in the exporting index, so the receiving one can drop the others.
+type syncHeader struct {
+	Format     string   `json:"format"`
+	Version    int      `json:"version"`
+	EmbedDim   int      `json:"embed_dim"`
+	EmbedModel string   `json:"embed_model,omitempty"`
+	Since      int64    `json:"since"`
+	Until      int64    `json:"until"`
+	Paths      []string `json:"paths"`
+}
+
+// SyncStats describes a sync bundle exported or applied.
+type SyncStats struct {
+	// Since and Until bound when the documents in the bundle were indexed;
+	// a Since of zero marks a bundle of the whole index.
+	Since, Until time.Time
+
+	// Documents is how many documents the bundle carries, and Removed how
+	// many applying it deleted.
+	Documents, Removed int
+}
+
+// SyncExported returns when the last sync bundle exported from the index
+// ended, the zero time if none was.
+func (db *DB) SyncExported() (time.Time, error) {
+	return db.syncCheckpoint(syncExportedKey)
+}
+
+// SetSyncExported records the end of a bundle written by ExportSync, once
+// it is safely saved, so the next one starts there.
+func (db *DB) SetSyncExported(until time.Time) error {
+	return db.setMeta(syncExportedKey, strconv.FormatInt(until.Unix(), 10))
+}
+
+func (db *DB) syncCheckpoint(key string) (time.Time, error) {
+	value, err := db.getMeta(key)
+	if err != nil || value == "" {
+		return time.Time{}, err
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s checkpoint %q", key, value)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// ExportSync writes a sync bundle to w: the documents indexed since the
+// given time, all of them for the zero time, in the format of Export, and
+// the paths of every document, so ApplySync can remove deleted ones.
+func (db *DB) ExportSync(w io.Writer, since time.Time) (SyncStats, error) {
+	header := syncHeader{
+		Format:     syncFormat,
+		Version:    syncVersion,
+		EmbedDim:   db.embedDim,
+		EmbedModel: db.embedModel,
+		Until:      time.Now().Unix(),
+		Paths:      []string{},
+	}
+	if !since.IsZero() {
+		header.Since = since.Unix()
+	}
+
+	docs, err := db.GetAllDocuments()
+	if err != nil {
+		return SyncStats{}, err
+	}
+	slices.SortFunc(docs, func(a, b Document) int { return strings.Compare(a.Path, b.Path) })
+	aliases, err := db.GetAllAliases()
+	if err != nil {
+		return SyncStats{}, err
+	}
+
+	var changed []Document
+	for _, doc := range docs {
+		header.Paths = append(header.Paths, doc.Path)
+		// Indexed in the second the last bundle ended counts again, as it
+		// may have been after the bundle was written
+		if doc.IndexedAt >= header.Since {
+			changed = append(changed, doc)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(header); err != nil {
+		return SyncStats{}, err
+	}
+	for _, doc := range changed {
+		entry, err := db.exportDocument(doc, aliases[doc.ID])
+		if err != nil {
+			return SyncStats{}, err
+		}
+		if err := enc.Encode(entry); err != nil {
+			return SyncStats{}, err
+		}
+	}
+	return SyncStats{Since: unixOrZero(header.Since), Until: time.Unix(header.Until, 0), Documents: len(changed)}, nil
+}
+
+// ApplySync applies a bundle written by ExportSync in one transaction:
+// its documents replace those with the same paths, and documents the
+// exporting index no longer has are removed. A bundle starting after the
+// end of the last one applied would leave a gap and is refused, unless it
+// holds the whole index.
+func (db *DB) ApplySync(r io.Reader) (SyncStats, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 256*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return SyncStats{}, err
+		}
+		return SyncStats{}, fmt.Errorf("empty sync bundle")
+	}
+	var header syncHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format != syncFormat {
+		return SyncStats{}, fmt.Errorf("not an obsvec sync bundle")
+	}
+	if header.Version > syncVersion {
+		return SyncStats{}, fmt.Errorf("sync bundle version %d is newer than this build supports (%d)", header.Version, syncVersion)
+	}
+	if header.EmbedDim != db.embedDim {
+		return SyncStats{}, fmt.Errorf("sync bundle has %d-dimensional embeddings but the database expects %d", header.EmbedDim, db.embedDim)
+	}
+	if header.EmbedModel != "" && db.embedModel != "" && header.EmbedModel != db.embedModel {
+		return SyncStats{}, fmt.Errorf("sync bundle was embedded with %s but the database uses %s", header.EmbedModel, db.embedModel)
+	}
+
+	applied, err := db.syncCheckpoint(syncAppliedKey)
+	if err != nil {
+		return SyncStats{}, err
+	}
+	if header.Since != 0 && header.Since > applied.Unix() {
+		synced := "has never been synced"
+		if !applied.IsZero() {
+			synced = "was last synced up to " + applied.Format(time.DateTime)
+		}
+		return SyncStats{}, fmt.Errorf("the bundle holds changes since %s, but this index %s; apply the bundles in between, or export one with -full",
+			time.Unix(header.Since, 0).Format(time.DateTime), synced)
+	}
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return SyncStats{}, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	stats := SyncStats{Since: unixOrZero(header.Since), Until: time.Unix(header.Until, 0)}
+	for scanner.Scan() {
+		var doc dumpDocument
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			return SyncStats{}, fmt.Errorf("document %d: %w", stats.Documents+1, err)
+		}
+		if err := db.importDocumentTx(tx, doc); err != nil {
+			return SyncStats{}, fmt.Errorf("%s: %w", doc.Path, err)
+		}
+		stats.Documents++
+	}
+	if err := scanner.Err(); err != nil {
+		return SyncStats{}, err
+	}
+
+	keep := make(map[string]bool, len(header.Paths))
+	for _, path := range header.Paths {
+		keep[path] = true
+	}
+	docs, err := db.GetAllDocuments()
+	if err != nil {
+		return SyncStats{}, err
+	}
+	for _, doc := range docs {
+		if keep[doc.Path] {
+			continue
+		}
+		if err := db.deleteDocumentTx(tx, doc.ID); err != nil {
+			return SyncStats{}, fmt.Errorf("%s: %w", doc.Path, err)
+		}
+		stats.Removed++
+	}
+
+	if header.Until > applied.Unix() {
+		if _, err := tx.Exec(`
+			INSERT INTO meta (key, value) VALUES (?, ?)
+			ON CONFLICT(key) DO UPDATE SET value = excluded.value
+		`, syncAppliedKey, strconv.FormatInt(header.Until, 10)); err != nil {
+			return SyncStats{}, err
+		}
+	}
+	return stats, tx.Commit()
+}
+
+func unixOrZero(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
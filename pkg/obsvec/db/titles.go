@@ -0,0 +1,77 @@
+package db
+
+import "strings"
+
+// TitleKind is what a name in the title index comes from.
+type TitleKind string
+
+const (
+	TitleNote    TitleKind = "title"
+	TitleFile    TitleKind = "file"
+	TitleAlias   TitleKind = "alias"
+	TitleHeading TitleKind = "heading"
+)
+
+// Title is a name a note, or one of its chunks, can be found by. The title
+// index is kept up to date by triggers as documents, aliases and chunks
+// change, so lookups never touch chunk content or embeddings.
+type Title struct {
+	DocID int64
+
+	// ChunkID is the chunk under a heading; zero for note-level names.
+	ChunkID int64
+
+	Path string
+	Kind TitleKind
+	Name string
+}
+
+// GetTitles returns every name of the given kinds, all kinds when none are
+// given, ordered by path.
+func (db *DB) GetTitles(kinds ...TitleKind) ([]Title, error) {
+	query := `
+		SELECT t.doc_id, COALESCE(t.chunk_id, 0), d.path, t.kind, t.name
+		FROM titles t
+		JOIN documents d ON d.id = t.doc_id
+	`
+	var args []any
+	if len(kinds) > 0 {
+		query += "WHERE t.kind IN (?" + strings.Repeat(", ?", len(kinds)-1) + ")"
+		for _, kind := range kinds {
+			args = append(args, string(kind))
+		}
+	}
+	return db.queryTitles(query+" ORDER BY d.path, t.kind, t.name", args...)
+}
+
+// LookupTitles returns up to limit names starting with prefix, ignoring ASCII
+// case, shortest first. It is a range scan of the index on names, so it
+// stays fast on large vaults.
+func (db *DB) LookupTitles(prefix string, limit int) ([]Title, error) {
+	return db.queryTitles(`
+		SELECT t.doc_id, COALESCE(t.chunk_id, 0), d.path, t.kind, t.name
+		FROM titles t
+		JOIN documents d ON d.id = t.doc_id
+		WHERE t.name >= ? COLLATE NOCASE AND t.name < ? COLLATE NOCASE
+		ORDER BY length(t.name), t.name, d.path
+		LIMIT ?
+	`, prefix, prefix+"\U0010FFFF", limit)
+}
+
+func (db *DB) queryTitles(query string, args ...any) ([]Title, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var titles []Title
+	for rows.Next() {
+		var title Title
+		if err := rows.Scan(&title.DocID, &title.ChunkID, &title.Path, &title.Kind, &title.Name); err != nil {
+			return nil, err
+		}
+		titles = append(titles, title)
+	}
+	return titles, rows.Err()
+}
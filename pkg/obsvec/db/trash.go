@@ -0,0 +1,110 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// TrashDocument moves a document out of the index into the trash table, with
+// its chunks and embeddings saved in the export format, so it stops showing
+// up in searches but can come back through RestoreDocument without being
+// embedded again. It reports false when there is no document at path.
+func (db *DB) TrashDocument(path string, trashedAt int64) (bool, error) {
+	doc, err := db.GetDocument(path)
+	if err != nil || doc == nil {
+		return false, err
+	}
+
+	aliases, err := db.documentAliases(doc.ID)
+	if err != nil {
+		return false, err
+	}
+	entry, err := db.exportDocument(*doc, aliases)
+	if err != nil {
+		return false, err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO trash (path, content_hash, trashed_at, data) VALUES (?, ?, ?, ?)",
+		doc.Path, doc.ContentHash, trashedAt, string(data),
+	); err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+	if err := db.deleteDocumentTx(tx, doc.ID); err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RestoreDocument brings back the most recently trashed document with the
+// given content hash at path, which may differ from where it was trashed
+// from. It reports false when nothing in the trash matches.
+func (db *DB) RestoreDocument(contentHash, path string, modifiedAt int64) (bool, error) {
+	if contentHash == "" {
+		return false, nil
+	}
+
+	var id int64
+	var data string
+	err := db.conn.QueryRow(
+		"SELECT id, data FROM trash WHERE content_hash = ? ORDER BY trashed_at DESC LIMIT 1",
+		contentHash,
+	).Scan(&id, &data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var entry dumpDocument
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return false, err
+	}
+	entry.Path = path
+	entry.ModifiedAt = modifiedAt
+	entry.IndexedAt = time.Now().Unix()
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return false, err
+	}
+	if err := db.importDocumentTx(tx, entry); err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM trash WHERE id = ?", id); err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// PurgeTrash permanently drops documents trashed before the given time and
+// returns how many were removed.
+func (db *DB) PurgeTrash(before int64) (int, error) {
+	result, err := db.writer.Exec("DELETE FROM trash WHERE trashed_at < ?", before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// TrashCount returns the number of documents in the trash.
+func (db *DB) TrashCount() (int, error) {
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM trash").Scan(&count)
+	return count, err
+}
@@ -0,0 +1,228 @@
+//go:build purego
+
+package db
+
+import (
+	"cmp"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"slices"
+
+	"modernc.org/sqlite"
+)
+
+// nativeVec reports whether vec_chunks is a sqlite-vec table in this build.
+// Pure-Go builds store vectors in a plain table and scan it instead.
+const nativeVec = false
+
+// sqliteDriver is the driver modernc.org/sqlite registers as "sqlite". Its
+// functions are registered on that shared instance, so it is reused rather
+// than a new sqlite.Driver.
+var sqliteDriver = sharedDriver()
+
+func sharedDriver() driver.Driver {
+	conn, err := sql.Open("sqlite", "")
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close() //nolint:errcheck
+	return conn.Driver()
+}
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("path_glob", 2, stringFunc(MatchPathGlob))
+	sqlite.MustRegisterDeterministicScalarFunction("contains_phrase", 2, stringFunc(MatchPhrase))
+	sql.Register(driverName, sqliteDriver)
+}
+
+// stringFunc adapts a two-string predicate to a SQL function. NULL arguments
+// don't match.
+func stringFunc(match func(a, b string) bool) func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+	return func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		a, aOK := args[0].(string)
+		b, bOK := args[1].(string)
+		return aOK && bOK && match(a, b), nil
+	}
+}
+
+func checkVectorSupport(*sql.DB) error {
+	return nil
+}
+
+// vecTableSQL creates vec_chunks with the given embedding column type. The
+// type is only recorded for checkEmbeddings; SQLite doesn't enforce it.
+func vecTableSQL(columnType string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS vec_chunks (
+			chunk_id INTEGER PRIMARY KEY,
+			embedding %s
+		);
+	`, columnType)
+}
+
+func newLocalVectorStore(conn, writer *sql.DB, dim int, quantization Quantization) localVectorStore {
+	return &scanVecStore{conn: conn, writer: writer, dim: dim, quantization: quantization}
+}
+
+// scanVecStore keeps vectors in a plain vec_chunks table, in the same
+// encodings sqlite-vec uses, and searches by comparing the query with every
+// candidate in Go. That is exact, and fast enough for a personal vault.
+type scanVecStore struct {
+	conn         *sql.DB
+	writer       *sql.DB
+	dim          int
+	quantization Quantization
+}
+
+func (s *scanVecStore) Insert(chunkID int64, embedding []float32) error {
+	return s.insert(s.writer, chunkID, embedding)
+}
+
+func (s *scanVecStore) insertTx(tx *sql.Tx, chunkID int64, embedding []float32) error {
+	return s.insert(tx, chunkID, embedding)
+}
+
+func (s *scanVecStore) insert(conn execer, chunkID int64, embedding []float32) error {
+	if len(embedding) != s.dim {
+		return fmt.Errorf("embedding has %d dimensions, expected %d", len(embedding), s.dim)
+	}
+	_, err := conn.Exec("INSERT INTO vec_chunks (chunk_id, embedding) VALUES (?, ?)", chunkID, s.quantization.encode(embedding))
+	return err
+}
+
+func (s *scanVecStore) Search(query []float32, k int, allowed []int64) ([]VectorHit, error) {
+	if allowed == nil {
+		return s.search(query, k, "")
+	}
+	var args []any
+	return s.search(query, k, "WHERE chunk_id IN ("+idList(allowed, &args)+")", args...)
+}
+
+func (s *scanVecStore) searchFiltered(query []float32, k int, filter SearchFilter) ([]VectorHit, error) {
+	subquery, args := filter.chunkIDQuery()
+	if subquery == "" {
+		return s.search(query, k, "")
+	}
+	return s.search(query, k, "WHERE chunk_id IN ("+subquery+")", args...)
+}
+
+func (s *scanVecStore) search(query []float32, k int, where string, args ...any) ([]VectorHit, error) {
+	rows, err := s.conn.Query("SELECT chunk_id, embedding FROM vec_chunks "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var hits []VectorHit
+	for rows.Next() {
+		var hit VectorHit
+		var blob []byte
+		if err := rows.Scan(&hit.ChunkID, &blob); err != nil {
+			return nil, err
+		}
+		hit.Distance = l2Distance(query, s.quantization.decode(blob, s.dim))
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(hits, func(a, b VectorHit) int {
+		return cmp.Compare(a.Distance, b.Distance)
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+func l2Distance(a, b []float32) float64 {
+	var sum float64
+	for i := range min(len(a), len(b)) {
+		d := float64(a[i]) - float64(b[i])
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+func (s *scanVecStore) Get(chunkIDs []int64) (map[int64][]float32, error) {
+	embeddings := make(map[int64][]float32, len(chunkIDs))
+	for batch := range slices.Chunk(chunkIDs, maxQueryIDs) {
+		var args []any
+		rows, err := s.conn.Query(
+			"SELECT chunk_id, embedding FROM vec_chunks WHERE chunk_id IN ("+idPlaceholders(batch, &args)+")",
+			args...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int64
+			var blob []byte
+			if err := rows.Scan(&id, &blob); err != nil {
+				rows.Close() //nolint:errcheck
+				return nil, err
+			}
+			embeddings[id] = s.quantization.decode(blob, s.dim)
+		}
+		rows.Close() //nolint:errcheck
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return embeddings, nil
+}
+
+func (s *scanVecStore) Delete(chunkIDs []int64) error {
+	tx, err := s.writer.Begin()
+	if err != nil {
+		return err
+	}
+	if err := s.deleteTx(tx, chunkIDs); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *scanVecStore) deleteTx(tx *sql.Tx, chunkIDs []int64) error {
+	for batch := range slices.Chunk(chunkIDs, maxQueryIDs) {
+		var args []any
+		if _, err := tx.Exec("DELETE FROM vec_chunks WHERE chunk_id IN ("+idPlaceholders(batch, &args)+")", args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close is a no-op; the table closes with the database.
+func (s *scanVecStore) Close() error {
+	return nil
+}
+
+// encode converts a float32 vector to the stored form, matching sqlite-vec's
+// vec_quantize_int8(v, 'unit') and vec_quantize_binary(v).
+func (q Quantization) encode(vector []float32) []byte {
+	switch q {
+	case QuantizeInt8:
+		// In float32 like sqlite-vec, so values round the same way
+		step := float32(2.0 / 255)
+		blob := make([]byte, len(vector))
+		for i, x := range vector {
+			v := (x+1)/step - 128
+			blob[i] = byte(int8(max(-128, min(127, v))))
+		}
+		return blob
+	case QuantizeBit:
+		blob := make([]byte, (len(vector)+7)/8)
+		for i, x := range vector {
+			if x > 0 {
+				blob[i/8] |= 1 << (i % 8)
+			}
+		}
+		return blob
+	}
+	return SerializeFloat32(vector)
+}
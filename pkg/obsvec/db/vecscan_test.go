@@ -0,0 +1,23 @@
+//go:build purego
+
+package db
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestQuantizationEncode(t *testing.T) {
+	// The same bytes sqlite-vec's vec_quantize_int8(v, 'unit') and
+	// vec_quantize_binary(v) produce
+	vector := []float32{1, -1, 0, 0.5, -0.25, 0, 0, 1, 0.1, 0, 0, 0, 0, 0, 0, -0.3}
+	if got := QuantizeInt8.encode(vector); !bytes.Equal(got, []byte{126, 128, 0, 63, 224, 0, 0, 126, 12, 0, 0, 0, 0, 0, 0, 218}) {
+		t.Errorf("unexpected int8 encoding %v", got)
+	}
+	if got := QuantizeBit.encode(vector); !bytes.Equal(got, []byte{0b10001001, 0b1}) {
+		t.Errorf("unexpected bit encoding %08b", got)
+	}
+	if got := QuantizeNone.encode(vector); !bytes.Equal(got, SerializeFloat32(vector)) {
+		t.Error("expected float vectors to be stored as is")
+	}
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"database/sql"
+	"slices"
+)
+
+// VectorHit is a chunk found by a vector search.
+type VectorHit struct {
+	ChunkID  int64
+	Distance float64
+}
+
+// VectorStore holds the embedding of each chunk. The database keeps chunk
+// text and metadata itself and defaults to storing vectors in its own
+// sqlite-vec table; Options.VectorStore moves them to another backend.
+// Embeddings are unit vectors and distances are Euclidean, as in sqlite-vec.
+type VectorStore interface {
+	// Insert stores the embedding of a chunk, replacing any existing one.
+	Insert(chunkID int64, embedding []float32) error
+
+	// Search returns the k chunks nearest to query, nearest first. A
+	// non-nil allowed restricts the search to those chunk ids.
+	Search(query []float32, k int, allowed []int64) ([]VectorHit, error)
+
+	// Get returns the stored embeddings of chunkIDs, skipping missing ones.
+	Get(chunkIDs []int64) (map[int64][]float32, error)
+
+	// Delete removes the embeddings of chunkIDs.
+	Delete(chunkIDs []int64) error
+
+	Close() error
+}
+
+// localVectorStore is a VectorStore inside the SQLite database itself. Its
+// writes join the caller's transaction, and searches apply filters as a
+// subquery instead of listing every allowed chunk.
+type localVectorStore interface {
+	VectorStore
+	insertTx(tx *sql.Tx, chunkID int64, embedding []float32) error
+	deleteTx(tx *sql.Tx, chunkIDs []int64) error
+	searchFiltered(query []float32, k int, filter SearchFilter) ([]VectorHit, error)
+}
+
+// execer is the Exec method shared by *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// insertVectorTx stores an embedding as part of tx where the store allows.
+// Remote stores can't join SQLite transactions, so a rolled-back tx leaves
+// their vector in place; searches skip vectors whose chunk is gone.
+func (db *DB) insertVectorTx(tx *sql.Tx, chunkID int64, embedding []float32) error {
+	if local, ok := db.vectors.(localVectorStore); ok {
+		return local.insertTx(tx, chunkID, embedding)
+	}
+	return db.vectors.Insert(chunkID, embedding)
+}
+
+// deleteVectorsTx removes the embeddings of the chunks selected by query as
+// part of tx where the store allows.
+func (db *DB) deleteVectorsTx(tx *sql.Tx, query string, args ...any) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	var chunkIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close() //nolint:errcheck
+			return err
+		}
+		chunkIDs = append(chunkIDs, id)
+	}
+	rows.Close() //nolint:errcheck
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+
+	if local, ok := db.vectors.(localVectorStore); ok {
+		return local.deleteTx(tx, chunkIDs)
+	}
+	return db.vectors.Delete(chunkIDs)
+}
+
+// searchVectors runs a filtered vector search against the store.
+func (db *DB) searchVectors(query []float32, k int, filter SearchFilter) ([]VectorHit, error) {
+	if local, ok := db.vectors.(localVectorStore); ok {
+		return local.searchFiltered(query, k, filter)
+	}
+
+	var allowed []int64
+	if !filter.IsEmpty() {
+		subquery, args := filter.chunkIDQuery()
+		rows, err := db.conn.Query(subquery, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close() //nolint:errcheck
+
+		allowed = []int64{}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return nil, err
+			}
+			allowed = append(allowed, id)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(allowed) == 0 {
+			return nil, nil
+		}
+	}
+	return db.vectors.Search(query, k, allowed)
+}
+
+// chunksForHits loads the chunks of vector search hits in hit order, with
+// Distance set from the hit. Hits whose chunk no longer exists are dropped.
+func (db *DB) chunksForHits(hits []VectorHit) ([]ChunkWithScore, error) {
+	ids := make([]int64, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ChunkID
+	}
+
+	chunks := make(map[int64]ChunkWithScore, len(hits))
+	for batch := range slices.Chunk(ids, maxQueryIDs) {
+		var args []any
+		rows, err := db.conn.Query(`
+			SELECT
+				c.id,
+				0.0,
+				c.doc_id,
+				c.content,
+				c.start_line,
+				c.end_line,
+				c.heading,
+				d.path,
+				COALESCE(d.title, ''),
+				d.modified_at
+			FROM chunks c
+			JOIN documents d ON d.id = c.doc_id
+			WHERE c.id IN (`+idPlaceholders(batch, &args)+`)
+		`, args...)
+		if err != nil {
+			return nil, err
+		}
+		batchChunks, err := scanChunksWithScore(rows)
+		rows.Close() //nolint:errcheck
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range batchChunks {
+			chunks[chunk.ID] = chunk
+		}
+	}
+
+	results := make([]ChunkWithScore, 0, len(hits))
+	for _, hit := range hits {
+		if chunk, ok := chunks[hit.ChunkID]; ok {
+			chunk.Distance = hit.Distance
+			results = append(results, chunk)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,103 @@
+// Package events provides a small in-process event bus. The indexer, watcher
+// and searcher publish to it so that server mode, hooks, metrics and other
+// extensions can observe a single stream instead of wiring ad-hoc callbacks.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+type Kind string
+
+const (
+	DocumentIndexed Kind = "document_indexed"
+	DocumentRemoved Kind = "document_removed"
+	SearchExecuted  Kind = "search_executed"
+
+	// IndexCompleted follows every indexing run that didn't fail, even if
+	// some files were skipped. IndexFailed reports each skipped file, or
+	// with no Path, a run that failed as a whole.
+	IndexCompleted Kind = "index_completed"
+	IndexFailed    Kind = "index_failed"
+
+	// IndexProgress reports each step of an indexing run as it happens.
+	IndexProgress Kind = "index_progress"
+)
+
+type Event struct {
+	Kind Kind      `json:"kind"`
+	Time time.Time `json:"time"`
+
+	// Path is set for document events, files that failed to index, and
+	// progress on a single file.
+	Path string `json:"path,omitempty"`
+
+	// Query, Results and Duration are set for search events, and Duration
+	// for index completed events too.
+	Query    string        `json:"query,omitempty"`
+	Results  int           `json:"results,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// Error is set for index failed events.
+	Error string `json:"error,omitempty"`
+
+	// Phase, Current, Total and Message are set for index progress events,
+	// with Current counting up to Total within the phase.
+	Phase   string `json:"phase,omitempty"`
+	Current int    `json:"current,omitempty"`
+	Total   int    `json:"total,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Handler receives published events. Handlers are called synchronously on the
+// publishing goroutine and should hand off any slow work.
+type Handler func(Event)
+
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[int]Handler
+	nextID   int
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[int]Handler)}
+}
+
+// Subscribe registers h for all events and returns a function that removes it.
+func (b *Bus) Subscribe(h Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = h
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish delivers e to every subscriber. A nil Bus discards events, so
+// publishers don't need to check whether one was configured.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers))
+	for _, h := range b.handlers {
+		handlers = append(handlers, h)
+	}
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+}
@@ -0,0 +1,36 @@
+package events
+
+import "testing"
+
+func TestBusPublishSubscribe(t *testing.T) {
+	bus := NewBus()
+
+	var received []Event
+	unsubscribe := bus.Subscribe(func(e Event) {
+		received = append(received, e)
+	})
+
+	bus.Publish(Event{Kind: DocumentIndexed, Path: "a.md"})
+
+	if len(received) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(received))
+	}
+	if received[0].Path != "a.md" {
+		t.Errorf("expected path 'a.md', got '%s'", received[0].Path)
+	}
+	if received[0].Time.IsZero() {
+		t.Error("expected publish to stamp the event time")
+	}
+
+	unsubscribe()
+	bus.Publish(Event{Kind: DocumentRemoved, Path: "a.md"})
+
+	if len(received) != 1 {
+		t.Errorf("expected no events after unsubscribe, got %d", len(received))
+	}
+}
+
+func TestNilBusPublish(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Event{Kind: SearchExecuted})
+}
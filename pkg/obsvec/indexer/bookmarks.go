@@ -0,0 +1,60 @@
+package indexer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// bookmarkItem is an entry of Obsidian's bookmarks.json, or of the
+// starred.json that the Starred plugin wrote before bookmarks replaced it.
+type bookmarkItem struct {
+	Type  string         `json:"type"`
+	Path  string         `json:"path"`
+	Items []bookmarkItem `json:"items"`
+}
+
+// Bookmarks returns the vault-relative paths of the notes and folders
+// bookmarked in Obsidian, including those inside bookmark groups. Headings
+// and blocks count as their note; bookmarked searches, graphs and URLs are
+// left out. A vault without bookmarks returns none and no error.
+func Bookmarks(vaultDir string) ([]string, error) {
+	var file struct {
+		Items []bookmarkItem `json:"items"`
+	}
+	for _, name := range []string{"bookmarks.json", "starred.json"} {
+		data, err := os.ReadFile(filepath.Join(vaultDir, ".obsidian", name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		break
+	}
+
+	var paths []string
+	var walk func(items []bookmarkItem)
+	walk = func(items []bookmarkItem) {
+		for _, item := range items {
+			switch item.Type {
+			case "group":
+				walk(item.Items)
+			case "file", "folder", "heading", "block":
+				if p := strings.Trim(path.Clean("/"+item.Path), "/"); p != "" && !slices.Contains(paths, p) {
+					paths = append(paths, p)
+				}
+			}
+		}
+	}
+	walk(file.Items)
+	return paths, nil
+}
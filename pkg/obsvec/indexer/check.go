@@ -0,0 +1,98 @@
+package indexer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+const (
+	// largeFileThreshold flags markdown files that are unusually big for a note
+	// and will produce a disproportionate number of chunks.
+	largeFileThreshold = 1 << 20
+
+	// embedCostPerMillionTokens is the approximate Cohere embed-v4 list price
+	// in USD, used only for the pre-flight estimate.
+	embedCostPerMillionTokens = 0.12
+)
+
+type FileSize struct {
+	Path  string
+	Bytes int64
+}
+
+// VaultReport summarizes a vault before it is indexed.
+type VaultReport struct {
+	Dir             string
+	IsObsidianVault bool
+	MarkdownFiles   int
+	TotalBytes      int64
+	LargeFiles      []FileSize
+	Unreadable      []FileError
+	EstimatedChunks int
+	EstimatedTokens int
+}
+
+// EstimatedIndexBytes approximates the database size for the given embedding
+// dimension: chunk text plus one float32 vector per chunk.
+func (r *VaultReport) EstimatedIndexBytes(embedDim int) int64 {
+	return r.TotalBytes + int64(r.EstimatedChunks)*int64(embedDim)*4
+}
+
+// EstimatedCost approximates the USD cost of embedding the whole vault once.
+func (r *VaultReport) EstimatedCost() float64 {
+	return float64(r.EstimatedTokens) / 1_000_000 * embedCostPerMillionTokens
+}
+
+// CheckVault inspects dir without touching the index or calling any API.
+func CheckVault(dir string) (*VaultReport, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	report := &VaultReport{Dir: dir}
+	if info, err := os.Stat(filepath.Join(dir, ".obsidian")); err == nil && info.IsDir() {
+		report.IsObsidianVault = true
+	}
+
+	idx := &Indexer{dir: dir}
+	files, skipped, err := idx.findMarkdownFiles()
+	if err != nil {
+		return nil, err
+	}
+	report.Unreadable = skipped
+
+	for _, relPath := range files {
+		content, err := os.ReadFile(filepath.Join(dir, relPath))
+		if err != nil {
+			report.Unreadable = append(report.Unreadable, FileError{Path: relPath, Err: err})
+			continue
+		}
+
+		size := int64(len(content))
+		report.MarkdownFiles++
+		report.TotalBytes += size
+		if size >= largeFileThreshold {
+			report.LargeFiles = append(report.LargeFiles, FileSize{Path: relPath, Bytes: size})
+		}
+
+		_, chunks := parseMarkdown(string(content), relPath)
+		report.EstimatedChunks += len(chunks)
+		for _, chunk := range chunks {
+			report.EstimatedTokens += db.EstimateTokens(chunk.Content)
+		}
+	}
+
+	sort.Slice(report.LargeFiles, func(i, j int) bool {
+		return report.LargeFiles[i].Bytes > report.LargeFiles[j].Bytes
+	})
+
+	return report, nil
+}
@@ -0,0 +1,703 @@
+// Package indexer keeps an index in step with a vault of Markdown notes:
+// it finds new, changed and deleted notes, splits them into chunks by
+// heading and embeds the chunks. A Watcher does so as files change.
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+	"github.com/mgomes/obsvec/pkg/obsvec/events"
+	"github.com/mgomes/obsvec/pkg/obsvec/provider"
+)
+
+const (
+	maxChunkTokens   = 500
+	batchSize        = 96
+	avgCharsPerToken = 4
+)
+
+type Indexer struct {
+	db             *db.DB
+	embedder       provider.DocumentEmbedder
+	dir            string
+	events         *events.Bus
+	trashRetention time.Duration
+	compareContent bool
+	excludePaths   []string
+}
+
+type Chunk struct {
+	Content   string
+	StartLine int
+	EndLine   int
+	Heading   string
+}
+
+type pendingChunk struct {
+	chunkID int64
+	content string
+}
+
+// Phase names the stage of indexing a Progress report belongs to.
+type Phase string
+
+const (
+	PhaseRemove Phase = "Removing"
+	PhaseCheck  Phase = "Checking"
+	PhaseParse  Phase = "Parsing"
+	PhaseEmbed  Phase = "Embedding"
+)
+
+type Progress struct {
+	Phase    Phase
+	Current  int
+	Total    int
+	FilePath string
+	Message  string
+
+	// Chunks and Tokens count the chunks embedded so far in PhaseEmbed and
+	// the estimated tokens sent for them.
+	Chunks int
+	Tokens int
+}
+
+type ProgressFunc func(Progress)
+
+// FileError records a file that was skipped because it could not be indexed.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+// SkippedFilesError is returned by Index when the run completed but some
+// files had to be skipped.
+type SkippedFilesError struct {
+	Files []FileError
+}
+
+func (e *SkippedFilesError) Error() string {
+	return fmt.Sprintf("%d file(s) could not be indexed", len(e.Files))
+}
+
+var headingRegex = regexp.MustCompile(`^(#{1,6})\s+(.+)$`)
+
+// New returns an Indexer of the notes in obsidianDir into database, which
+// embeds them with embedder, usually a *cohere.Client.
+func New(database *db.DB, embedder provider.DocumentEmbedder, obsidianDir string) *Indexer {
+	return &Indexer{
+		db:             database,
+		embedder:       embedder,
+		dir:            obsidianDir,
+		trashRetention: defaultTrashRetention,
+	}
+}
+
+// SetEventBus publishes indexing events to bus: documents indexed and
+// removed, progress, and how each run ended.
+func (idx *Indexer) SetEventBus(bus *events.Bus) {
+	idx.events = bus
+}
+
+// SetExcludePaths leaves notes matching any of these vault-relative globs
+// (see db.MatchPathGlob) out of the index, removing those already in it.
+func (idx *Indexer) SetExcludePaths(globs []string) {
+	idx.excludePaths = globs
+}
+
+func (idx *Indexer) excluded(relPath string) bool {
+	for _, glob := range idx.excludePaths {
+		if db.MatchPathGlob(glob, filepath.ToSlash(relPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCompareContent makes indexing check every indexed note's content
+// against its checksum instead of trusting an unchanged modification time,
+// for directories such as git snapshots whose files all share one.
+func (idx *Indexer) SetCompareContent(compare bool) {
+	idx.compareContent = compare
+}
+
+func (idx *Indexer) Index(ctx context.Context, fullReindex bool, progress ProgressFunc) error {
+	start := time.Now()
+	var skipped []FileError
+	err := idx.index(ctx, fullReindex, idx.withProgressEvents(progress), &skipped)
+	idx.publishOutcome(ctx, start, skipped, err)
+	if err != nil {
+		return err
+	}
+	if len(skipped) > 0 {
+		return &SkippedFilesError{Files: skipped}
+	}
+	return nil
+}
+
+// IndexFiles reindexes the given notes, paths relative to the vault, whether
+// or not they changed and without scanning the rest of the vault. Notes
+// that no longer exist are removed from the index.
+func (idx *Indexer) IndexFiles(ctx context.Context, relPaths []string) error {
+	start := time.Now()
+	var toIndex []string
+	var skipped []FileError
+	for _, relPath := range relPaths {
+		relPath = filepath.Clean(relPath)
+		if !filepath.IsLocal(relPath) || isHiddenRelPath(relPath) || !isMarkdownFile(relPath) {
+			skipped = append(skipped, FileError{Path: relPath, Err: fmt.Errorf("not a note in the vault")})
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(idx.dir, relPath)); os.IsNotExist(err) || idx.excluded(relPath) {
+			if err := idx.removeDocument(relPath); err != nil {
+				idx.publishOutcome(ctx, start, skipped, err)
+				return err
+			}
+			continue
+		}
+		toIndex = append(toIndex, relPath)
+	}
+
+	failed, err := idx.indexFiles(ctx, toIndex)
+	skipped = append(skipped, failed...)
+	idx.publishOutcome(ctx, start, skipped, err)
+	if err != nil {
+		return err
+	}
+	if len(skipped) > 0 {
+		return &SkippedFilesError{Files: skipped}
+	}
+	return nil
+}
+
+func (idx *Indexer) index(ctx context.Context, fullReindex bool, progress ProgressFunc, skipped *[]FileError) error {
+	files, walkErrs, err := idx.findMarkdownFiles()
+	if err != nil {
+		return fmt.Errorf("failed to find markdown files: %w", err)
+	}
+	*skipped = append(*skipped, walkErrs...)
+
+	existingDocs, err := idx.db.GetAllDocuments()
+	if err != nil {
+		return fmt.Errorf("failed to get existing documents: %w", err)
+	}
+
+	existingByPath := make(map[string]*db.Document, len(existingDocs))
+	for i := range existingDocs {
+		existingByPath[existingDocs[i].Path] = &existingDocs[i]
+	}
+
+	currentPaths := make(map[string]bool)
+	for _, f := range files {
+		currentPaths[f] = true
+	}
+
+	if err := idx.purgeTrash(); err != nil {
+		return fmt.Errorf("failed to empty trash: %w", err)
+	}
+
+	var trashed map[string]bool
+	for _, doc := range existingDocs {
+		if !currentPaths[doc.Path] {
+			if trashed == nil {
+				trashed = idx.trashedHashes()
+			}
+			wasTrashed, err := idx.removeOrTrash(doc.Path, trashed)
+			if err != nil {
+				return fmt.Errorf("failed to delete document %s: %w", doc.Path, err)
+			}
+			if progress != nil {
+				verb := "Removed deleted"
+				if wasTrashed {
+					verb = "Moved to trash"
+				}
+				progress(Progress{Phase: PhaseRemove, Message: fmt.Sprintf("%s: %s", verb, filepath.Base(doc.Path))})
+			}
+		}
+	}
+
+	var filesToIndex []string
+	for i, filePath := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(Progress{Phase: PhaseCheck, Current: i + 1, Total: len(files), FilePath: filePath, Message: "Checking files..."})
+		}
+
+		needsIndex, err := idx.needsIndexing(filePath, fullReindex, existingByPath[filePath])
+		if err != nil {
+			*skipped = append(*skipped, FileError{Path: filePath, Err: err})
+			continue
+		}
+		if needsIndex {
+			filesToIndex = append(filesToIndex, filePath)
+		}
+	}
+
+	slog.Info("scanned vault", "files", len(files), "changed", len(filesToIndex), "full", fullReindex)
+	if len(filesToIndex) == 0 {
+		if progress != nil {
+			progress(Progress{Message: "Index is up to date"})
+		}
+		return nil
+	}
+
+	// Phase 1: Parse all files and collect chunks
+	var allPending []pendingChunk
+	var parsed []string
+	for i, filePath := range filesToIndex {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(Progress{
+				Phase:    PhaseParse,
+				Current:  i + 1,
+				Total:    len(filesToIndex),
+				FilePath: filePath,
+				Message:  fmt.Sprintf("Parsing %s", filepath.Base(filePath)),
+			})
+		}
+
+		pending, err := idx.parseFile(filePath)
+		if err != nil {
+			*skipped = append(*skipped, FileError{Path: filePath, Err: err})
+			continue
+		}
+		allPending = append(allPending, pending...)
+		parsed = append(parsed, filePath)
+	}
+
+	if len(allPending) == 0 {
+		if progress != nil {
+			progress(Progress{Message: "No chunks to embed"})
+		}
+		idx.publishIndexed(parsed)
+		return nil
+	}
+
+	// Phase 2: Batch embed all chunks across files
+	slog.Info("parsed notes", "notes", len(parsed), "chunks", len(allPending))
+	err = idx.embedPending(ctx, allPending, func(done, totalBatches, chunks, tokens int) {
+		if progress != nil {
+			msg := fmt.Sprintf("Embedded batch %d/%d (%d chunks)", done, totalBatches, chunks)
+			if done == 0 {
+				msg = fmt.Sprintf("Embedding %d chunks", len(allPending))
+			}
+			progress(Progress{
+				Phase:   PhaseEmbed,
+				Current: done,
+				Total:   totalBatches,
+				Message: msg,
+				Chunks:  chunks,
+				Tokens:  tokens,
+			})
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	idx.publishIndexed(parsed)
+	return nil
+}
+
+func (idx *Indexer) removeDocument(relPath string) error {
+	if err := idx.db.DeleteDocument(relPath); err != nil {
+		return err
+	}
+	idx.events.Publish(events.Event{Kind: events.DocumentRemoved, Path: relPath})
+	return nil
+}
+
+func (idx *Indexer) publishIndexed(relPaths []string) {
+	for _, relPath := range relPaths {
+		idx.events.Publish(events.Event{Kind: events.DocumentIndexed, Path: relPath})
+	}
+}
+
+// withProgressEvents returns a ProgressFunc that publishes each update as an
+// index progress event before passing it on to progress, if set.
+func (idx *Indexer) withProgressEvents(progress ProgressFunc) ProgressFunc {
+	if idx.events == nil {
+		return progress
+	}
+	return func(p Progress) {
+		idx.events.Publish(events.Event{
+			Kind:    events.IndexProgress,
+			Path:    p.FilePath,
+			Phase:   string(p.Phase),
+			Current: p.Current,
+			Total:   p.Total,
+			Message: p.Message,
+		})
+		if progress != nil {
+			progress(p)
+		}
+	}
+}
+
+// publishOutcome publishes how an indexing run that started at start ended:
+// a failed event for each skipped file, then completed, or failed if err is
+// set. A run stopped by ctx publishes nothing more.
+func (idx *Indexer) publishOutcome(ctx context.Context, start time.Time, skipped []FileError, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	for _, fileErr := range skipped {
+		idx.events.Publish(events.Event{Kind: events.IndexFailed, Path: fileErr.Path, Error: fileErr.Err.Error()})
+	}
+	if err != nil {
+		idx.events.Publish(events.Event{Kind: events.IndexFailed, Error: err.Error()})
+		return
+	}
+	idx.events.Publish(events.Event{Kind: events.IndexCompleted, Duration: time.Since(start)})
+}
+
+// findMarkdownFiles walks the vault and returns relative markdown paths.
+// Unreadable entries are reported as FileErrors rather than aborting the walk.
+func (idx *Indexer) findMarkdownFiles() ([]string, []FileError, error) {
+	var files []string
+	var skipped []FileError
+	err := filepath.Walk(idx.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == idx.dir {
+				return err
+			}
+			relPath, relErr := filepath.Rel(idx.dir, path)
+			if relErr != nil {
+				relPath = path
+			}
+			skipped = append(skipped, FileError{Path: relPath, Err: err})
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			if isHiddenDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if isMarkdownFile(info.Name()) {
+			relPath, err := filepath.Rel(idx.dir, path)
+			if err != nil {
+				return err
+			}
+			if !idx.excluded(relPath) {
+				files = append(files, relPath)
+			}
+		}
+
+		return nil
+	})
+
+	return files, skipped, err
+}
+
+func (idx *Indexer) needsIndexing(relPath string, fullReindex bool, doc *db.Document) (bool, error) {
+	if fullReindex {
+		return true, nil
+	}
+
+	if doc == nil {
+		return true, nil
+	}
+
+	absPath := filepath.Join(idx.dir, relPath)
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return false, err
+	}
+
+	if info.ModTime().Unix() <= doc.ModifiedAt && !idx.compareContent {
+		return false, nil
+	}
+
+	// A note touched without changes, as sync tools do when they copy a
+	// vault or an index applied from another machine, keeps its chunks
+	if doc.ContentHash == "" {
+		return true, nil
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return false, err
+	}
+	if ContentHash(content) != doc.ContentHash {
+		return true, nil
+	}
+	if info.ModTime().Unix() <= doc.ModifiedAt {
+		return false, nil
+	}
+	return false, idx.db.SetDocumentModified(doc.ID, info.ModTime().Unix())
+}
+
+// parseFile parses a file, stores chunks in DB, and returns pending chunks for embedding
+func (idx *Indexer) parseFile(relPath string) ([]pendingChunk, error) {
+	absPath := filepath.Join(idx.dir, relPath)
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// A note moved back out of the trash keeps its embeddings
+	hash := ContentHash(content)
+	restored, err := idx.db.RestoreDocument(hash, relPath, info.ModTime().Unix())
+	if err != nil || restored {
+		return nil, err
+	}
+
+	title, chunks := parseMarkdown(string(content), relPath)
+
+	docID, err := idx.db.UpsertDocument(relPath, title, info.ModTime().Unix(), time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := idx.db.SetDocumentHash(docID, hash); err != nil {
+		return nil, err
+	}
+
+	if err := idx.db.SetDocumentTags(docID, extractTags(string(content))); err != nil {
+		return nil, err
+	}
+
+	if err := idx.db.SetDocumentLinks(docID, extractLinks(string(content))); err != nil {
+		return nil, err
+	}
+
+	if err := idx.db.SetDocumentAliases(docID, extractAliases(string(content))); err != nil {
+		return nil, err
+	}
+
+	if err := idx.db.SetDocumentMetadata(docID, extractProperties(string(content))); err != nil {
+		return nil, err
+	}
+
+	if err := idx.db.DeleteChunksForDocument(docID); err != nil {
+		return nil, err
+	}
+
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+
+	var pending []pendingChunk
+	for _, chunk := range chunks {
+		chunkID, err := idx.db.InsertChunk(docID, chunk.Content, chunk.StartLine, chunk.EndLine, chunk.Heading)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, pendingChunk{
+			chunkID: chunkID,
+			content: chunk.Content,
+		})
+	}
+
+	return pending, nil
+}
+
+// indexFiles is used by the watcher to index a batch of changed files with a
+// single round of embed calls. Files that fail to parse are returned as
+// FileErrors; the remaining files are still embedded.
+func (idx *Indexer) indexFiles(ctx context.Context, relPaths []string) ([]FileError, error) {
+	var failed []FileError
+	var allPending []pendingChunk
+	var parsed []string
+	for _, relPath := range relPaths {
+		pending, err := idx.parseFile(relPath)
+		if err != nil {
+			failed = append(failed, FileError{Path: relPath, Err: err})
+			continue
+		}
+		allPending = append(allPending, pending...)
+		parsed = append(parsed, relPath)
+	}
+
+	if err := idx.embedPending(ctx, allPending, nil); err != nil {
+		return failed, err
+	}
+
+	idx.publishIndexed(parsed)
+	return failed, nil
+}
+
+// batchProgressFunc is called before the first batch and after each one,
+// with the chunks and estimated tokens embedded so far.
+type batchProgressFunc func(done, totalBatches, chunks, tokens int)
+
+func (idx *Indexer) embedPending(ctx context.Context, pending []pendingChunk, onBatch batchProgressFunc) error {
+	pending, err := idx.useImported(pending)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	totalBatches := (len(pending) + batchSize - 1) / batchSize
+	if onBatch != nil {
+		onBatch(0, totalBatches, 0, 0)
+	}
+
+	var chunks, tokens int
+	for i := 0; i < len(pending); i += batchSize {
+		end := i + batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[i:end]
+		batchNum := (i / batchSize) + 1
+
+		texts := make([]string, len(batch))
+		for j, p := range batch {
+			texts[j] = p.content
+			tokens += len(p.content) / avgCharsPerToken
+		}
+
+		slog.Info("embedding batch", "batch", batchNum, "of", totalBatches, "chunks", len(batch))
+		embeddings, err := idx.embedder.EmbedDocuments(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to generate embeddings for batch %d: %w", batchNum, err)
+		}
+
+		for j, p := range batch {
+			embBytes := db.SerializeFloat32(embeddings[j].Embedding)
+
+			if err := idx.db.InsertEmbedding(p.chunkID, embBytes); err != nil {
+				return fmt.Errorf("failed to insert embedding: %w", err)
+			}
+		}
+
+		chunks += len(batch)
+		if onBatch != nil {
+			onBatch(batchNum, totalBatches, chunks, tokens)
+		}
+	}
+
+	return nil
+}
+
+// useImported stores the embeddings imported with ofind db
+// import-embeddings for the pending chunks with matching text, returning
+// the chunks still to embed.
+func (idx *Indexer) useImported(pending []pendingChunk) ([]pendingChunk, error) {
+	hashes := make([]string, len(pending))
+	for i, p := range pending {
+		hashes[i] = db.ChunkHash(p.content)
+	}
+	imported, err := idx.db.ImportedEmbeddings(hashes)
+	if err != nil || len(imported) == 0 {
+		return pending, err
+	}
+
+	var rest []pendingChunk
+	for i, p := range pending {
+		vector, ok := imported[hashes[i]]
+		if !ok {
+			rest = append(rest, p)
+			continue
+		}
+		if err := idx.db.InsertEmbedding(p.chunkID, db.SerializeFloat32(vector)); err != nil {
+			return nil, fmt.Errorf("failed to insert embedding: %w", err)
+		}
+	}
+	slog.Info("used imported embeddings", "chunks", len(pending)-len(rest))
+	return rest, nil
+}
+
+func parseMarkdown(content, relPath string) (string, []Chunk) {
+	lines := strings.Split(content, "\n")
+	var chunks []Chunk
+	var currentChunk strings.Builder
+	var currentHeading string
+	var headingStack []string
+	startLine := 1
+	currentLine := 1
+	var title string
+
+	flushChunk := func() {
+		text := strings.TrimSpace(currentChunk.String())
+		if text != "" && len(text) > 20 {
+			chunks = append(chunks, Chunk{
+				Content:   text,
+				StartLine: startLine,
+				EndLine:   currentLine - 1,
+				Heading:   currentHeading,
+			})
+		}
+		currentChunk.Reset()
+		startLine = currentLine
+	}
+
+	for _, line := range lines {
+		if title == "" {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "# ") {
+				title = strings.TrimPrefix(trimmed, "# ")
+			}
+		}
+
+		if match := headingRegex.FindStringSubmatch(line); match != nil {
+			flushChunk()
+
+			level := len(match[1])
+			headingText := match[2]
+
+			for len(headingStack) >= level {
+				headingStack = headingStack[:len(headingStack)-1]
+			}
+			headingStack = append(headingStack, headingText)
+
+			currentHeading = strings.Join(headingStack, " > ")
+			startLine = currentLine
+		}
+
+		currentChunk.WriteString(line)
+		currentChunk.WriteString("\n")
+
+		if currentChunk.Len() > maxChunkTokens*avgCharsPerToken {
+			flushChunk()
+		}
+
+		currentLine++
+	}
+
+	flushChunk()
+
+	if title == "" && relPath != "" {
+		base := filepath.Base(relPath)
+		title = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	return title, chunks
+}
+
+func chunkMarkdown(content string) []Chunk {
+	_, chunks := parseMarkdown(content, "")
+	return chunks
+}
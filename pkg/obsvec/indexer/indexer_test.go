@@ -0,0 +1,754 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+	"github.com/mgomes/obsvec/pkg/obsvec/events"
+)
+
+func TestChunkMarkdown_SimpleDocument(t *testing.T) {
+	content := `# Title
+
+This is the introduction paragraph with some text.
+
+## Section One
+
+Content for section one goes here.
+
+## Section Two
+
+Content for section two goes here.
+`
+
+	chunks := chunkMarkdown(content)
+
+	if len(chunks) != 3 {
+		t.Errorf("expected 3 chunks, got %d", len(chunks))
+	}
+
+	if chunks[0].Heading != "Title" {
+		t.Errorf("expected heading 'Title', got '%s'", chunks[0].Heading)
+	}
+
+	if chunks[1].Heading != "Title > Section One" {
+		t.Errorf("expected heading 'Title > Section One', got '%s'", chunks[1].Heading)
+	}
+
+	if chunks[2].Heading != "Title > Section Two" {
+		t.Errorf("expected heading 'Title > Section Two', got '%s'", chunks[2].Heading)
+	}
+}
+
+func TestChunkMarkdown_NestedHeadings(t *testing.T) {
+	content := `# Main
+
+## Sub
+
+### SubSub
+
+Content here.
+
+## Another Sub
+
+More content.
+`
+
+	chunks := chunkMarkdown(content)
+
+	// Find the chunk with SubSub heading
+	var subsubChunk *Chunk
+	for i := range chunks {
+		if chunks[i].Heading == "Main > Sub > SubSub" {
+			subsubChunk = &chunks[i]
+			break
+		}
+	}
+
+	if subsubChunk == nil {
+		t.Error("expected to find chunk with nested heading 'Main > Sub > SubSub'")
+	}
+
+	// Check that heading stack resets properly
+	var anotherSubChunk *Chunk
+	for i := range chunks {
+		if chunks[i].Heading == "Main > Another Sub" {
+			anotherSubChunk = &chunks[i]
+			break
+		}
+	}
+
+	if anotherSubChunk == nil {
+		t.Error("expected heading stack to reset to 'Main > Another Sub'")
+	}
+}
+
+func TestChunkMarkdown_LongContent(t *testing.T) {
+	// Create content longer than maxChunkTokens * avgCharsPerToken (500 * 4 = 2000 chars)
+	// Use multiple lines since chunking happens per-line
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, "This is a line of text that adds up to create a long document.")
+	}
+	longContent := strings.Join(lines, "\n")
+
+	content := "# Title\n\n" + longContent
+
+	chunks := chunkMarkdown(content)
+
+	if len(chunks) < 2 {
+		t.Errorf("expected long content to be split into multiple chunks, got %d (len=%d chars)", len(chunks), len(longContent))
+	}
+}
+
+func TestChunkMarkdown_EmptyDocument(t *testing.T) {
+	chunks := chunkMarkdown("")
+
+	if len(chunks) != 0 {
+		t.Errorf("expected 0 chunks for empty document, got %d", len(chunks))
+	}
+}
+
+func TestChunkMarkdown_NoHeadings(t *testing.T) {
+	content := `Just some plain text without any headings.
+
+Another paragraph here.
+`
+
+	chunks := chunkMarkdown(content)
+
+	if len(chunks) != 1 {
+		t.Errorf("expected 1 chunk, got %d", len(chunks))
+	}
+
+	if chunks[0].Heading != "" {
+		t.Errorf("expected empty heading, got '%s'", chunks[0].Heading)
+	}
+}
+
+func TestChunkMarkdown_MinimumLength(t *testing.T) {
+	content := `# Title
+
+Hi
+`
+
+	chunks := chunkMarkdown(content)
+
+	// "Hi" is less than 20 chars, should be filtered out
+	if len(chunks) != 0 {
+		t.Errorf("expected 0 chunks (content too short), got %d", len(chunks))
+	}
+}
+
+func TestParseMarkdown_TitleWithH1(t *testing.T) {
+	content := `# My Document Title
+
+Some content here.
+`
+
+	title, _ := parseMarkdown(content, "fallback.md")
+
+	if title != "My Document Title" {
+		t.Errorf("expected 'My Document Title', got '%s'", title)
+	}
+}
+
+func TestParseMarkdown_TitleNoH1(t *testing.T) {
+	content := `Some content without a title.
+
+## Section
+`
+
+	title, _ := parseMarkdown(content, "my-note.md")
+
+	if title != "my-note" {
+		t.Errorf("expected 'my-note', got '%s'", title)
+	}
+}
+
+func TestParseMarkdown_TitleH1NotFirst(t *testing.T) {
+	content := `Some preamble text.
+
+# Actual Title
+
+Content.
+`
+
+	title, _ := parseMarkdown(content, "fallback.md")
+
+	// extractTitle finds first H1, even if not on first line
+	if title != "Actual Title" {
+		t.Errorf("expected 'Actual Title', got '%s'", title)
+	}
+}
+
+func TestIndex_SkipsUnreadableFiles(t *testing.T) {
+	vault := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(vault, "good.md"), []byte("# Good\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	// A dangling symlink looks like a markdown file but cannot be read
+	if err := os.Symlink(filepath.Join(vault, "missing"), filepath.Join(vault, "broken.md")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"), 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	bus := events.NewBus()
+	var indexed []string
+	bus.Subscribe(func(e events.Event) {
+		if e.Kind == events.DocumentIndexed {
+			indexed = append(indexed, e.Path)
+		}
+	})
+
+	idx := New(database, nil, vault)
+	idx.SetEventBus(bus)
+	err = idx.Index(context.Background(), false, nil)
+
+	var skippedErr *SkippedFilesError
+	if !errors.As(err, &skippedErr) {
+		t.Fatalf("expected SkippedFilesError, got %v", err)
+	}
+
+	if len(skippedErr.Files) != 1 || skippedErr.Files[0].Path != "broken.md" {
+		t.Errorf("expected broken.md to be skipped, got %v", skippedErr.Files)
+	}
+
+	doc, err := database.GetDocument("good.md")
+	if err != nil {
+		t.Fatalf("failed to get document: %v", err)
+	}
+	if doc == nil {
+		t.Error("expected good.md to be indexed despite the failure")
+	}
+
+	if len(indexed) != 1 || indexed[0] != "good.md" {
+		t.Errorf("expected a document indexed event for good.md, got %v", indexed)
+	}
+}
+
+func TestCheckVault(t *testing.T) {
+	vault := t.TempDir()
+
+	if err := os.Mkdir(filepath.Join(vault, ".obsidian"), 0755); err != nil {
+		t.Fatalf("failed to create .obsidian: %v", err)
+	}
+	note := "# Note\n\nSome content that is long enough to become a chunk.\n"
+	if err := os.WriteFile(filepath.Join(vault, "note.md"), []byte(note), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "image.png"), []byte("not markdown"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	report, err := CheckVault(vault)
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+
+	if !report.IsObsidianVault {
+		t.Error("expected vault to be detected as an Obsidian vault")
+	}
+
+	if report.MarkdownFiles != 1 {
+		t.Errorf("expected 1 markdown file, got %d", report.MarkdownFiles)
+	}
+
+	if report.EstimatedChunks != 1 {
+		t.Errorf("expected 1 estimated chunk, got %d", report.EstimatedChunks)
+	}
+
+	if report.EstimatedIndexBytes(4) <= report.TotalBytes {
+		t.Error("expected index estimate to include embedding storage")
+	}
+}
+
+func TestExtractTags(t *testing.T) {
+	content := "---\n" +
+		"title: Plan\n" +
+		"tags: [Work, project/x]\n" +
+		"---\n" +
+		"# Heading is not a tag\n" +
+		"Some text #meeting and #2024 and a url http://example.com/#anchor\n" +
+		"```\n" +
+		"#not-a-tag inside code\n" +
+		"```\n"
+
+	tags := extractTags(content)
+
+	want := []string{"meeting", "project/x", "work"}
+	if strings.Join(tags, ",") != strings.Join(want, ",") {
+		t.Errorf("expected tags %v, got %v", want, tags)
+	}
+}
+
+func TestExtractTags_FrontmatterList(t *testing.T) {
+	content := "---\ntags:\n  - alpha\n  - beta\naliases: [x]\n---\nBody\n"
+
+	tags := extractTags(content)
+
+	if strings.Join(tags, ",") != "alpha,beta" {
+		t.Errorf("expected [alpha beta], got %v", tags)
+	}
+}
+
+func TestExtractAliases(t *testing.T) {
+	inline := extractAliases("---\naliases: [Travel docs, \"ID card\"]\n---\nBody\n")
+	if strings.Join(inline, ",") != "Travel docs,ID card" {
+		t.Errorf("expected [Travel docs ID card], got %v", inline)
+	}
+
+	block := extractAliases("---\ntags: travel\naliases:\n  - Passport renewal\n  - 'Docs'\n---\n")
+	if strings.Join(block, ",") != "Passport renewal,Docs" {
+		t.Errorf("expected [Passport renewal Docs], got %v", block)
+	}
+
+	if aliases := extractAliases("No frontmatter\naliases: x\n"); aliases != nil {
+		t.Errorf("expected no aliases outside frontmatter, got %v", aliases)
+	}
+}
+
+func TestExtractProperties(t *testing.T) {
+	content := "---\n" +
+		"Status: done\n" +
+		"priority: 2\n" +
+		"published: true\n" +
+		"version: \"2\"\n" +
+		"due: 2024-03-01\n" +
+		"tags: [a, b]\n" +
+		"authors:\n  - Ann\n  - Bob\n" +
+		"summary: >\n  Two\n  lines\n" +
+		"nested:\n  key: value\n" +
+		"empty:\n" +
+		"---\nBody\n"
+
+	got := extractProperties(content)
+	want := map[string]any{
+		"status":    "done",
+		"priority":  float64(2),
+		"published": true,
+		"version":   "2",
+		"due":       "2024-03-01",
+		"tags":      []any{"a", "b"},
+		"authors":   []any{"Ann", "Bob"},
+		"summary":   "Two lines",
+		"empty":     nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if properties := extractProperties("No frontmatter\nstatus: done\n"); properties != nil {
+		t.Errorf("expected no properties outside frontmatter, got %v", properties)
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	content := "See [[Projects/Idea]] and [[Meeting Notes|the meeting]].\n" +
+		"Embed: ![[Diagram.md]] and [[Idea#Goals]] again.\n" +
+		"Markdown [link](Areas/Health%20Log.md#week) and [site](https://example.com/page.md).\n" +
+		"```\n" +
+		"[[Not A Link]]\n" +
+		"```\n"
+
+	links := extractLinks(content)
+
+	want := []string{"areas/health log", "diagram", "idea", "meeting notes", "projects/idea"}
+	if strings.Join(links, ",") != strings.Join(want, ",") {
+		t.Errorf("expected links %v, got %v", want, links)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	vault := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	for _, name := range []string{"stale.md", "deleted.md", "unembedded.md", "unhashed.md", "ok.md"} {
+		write(name, "# "+name+"\n")
+	}
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"), 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	idx := New(database, nil, vault)
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	// Rewritten with its old modification time, so only the checksum
+	// notices
+	info, _ := os.Stat(filepath.Join(vault, "stale.md"))
+	write("stale.md", "# Changed\n")
+	os.Chtimes(filepath.Join(vault, "stale.md"), info.ModTime(), info.ModTime())
+
+	os.Remove(filepath.Join(vault, "deleted.md"))
+	write("new.md", "# New\n")
+	doc, _ := database.GetDocument("unembedded.md")
+	database.InsertChunk(doc.ID, "a chunk whose embed never finished", 1, 1, "")
+	doc, _ = database.GetDocument("unhashed.md")
+	database.SetDocumentHash(doc.ID, "")
+
+	report, err := idx.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if report.Files != 5 || report.OK() {
+		t.Fatalf("expected drift across 5 files, got %+v", report)
+	}
+	for name, got := range map[string][]string{
+		"new.md":        report.NotIndexed,
+		"deleted.md":    report.Deleted,
+		"stale.md":      report.Stale,
+		"unembedded.md": report.MissingEmbeddings,
+		"unhashed.md":   report.Unhashed,
+	} {
+		if len(got) != 1 || got[0] != name {
+			t.Errorf("expected only %s, got %v", name, got)
+		}
+	}
+	if want := []string{"new.md", "stale.md", "unembedded.md"}; !slices.Equal(report.NeedsReindex(), want) {
+		t.Errorf("expected %v to need reindexing, got %v", want, report.NeedsReindex())
+	}
+
+	if err := idx.Repair(context.Background(), report); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	report, err = idx.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.OK() {
+		t.Errorf("expected a clean index after repair, got %+v", report)
+	}
+}
+
+func TestIndex_Trash(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.WriteFile(filepath.Join(vault, "note.md"), []byte("# Note\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"), 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	idx := New(database, nil, vault)
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	doc, _ := database.GetDocument("note.md")
+	chunkID, _ := database.InsertChunk(doc.ID, "embedded content", 1, 1, "")
+	database.InsertEmbedding(chunkID, db.SerializeFloat32([]float32{1, 0, 0, 0}))
+
+	os.Mkdir(filepath.Join(vault, ".trash"), 0755)
+	if err := os.Rename(filepath.Join(vault, "note.md"), filepath.Join(vault, ".trash", "note.md")); err != nil {
+		t.Fatalf("failed to trash note: %v", err)
+	}
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if doc, _ := database.GetDocument("note.md"); doc != nil {
+		t.Error("expected the trashed note out of the index")
+	}
+	if n, _ := database.TrashCount(); n != 1 {
+		t.Errorf("expected 1 trashed note, got %d", n)
+	}
+
+	// Restored somewhere else, without a client to embed it again
+	if err := os.Rename(filepath.Join(vault, ".trash", "note.md"), filepath.Join(vault, "restored.md")); err != nil {
+		t.Fatalf("failed to restore note: %v", err)
+	}
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	doc, _ = database.GetDocument("restored.md")
+	if doc == nil {
+		t.Fatal("expected the restored note back in the index")
+	}
+	chunks, _ := database.GetChunksForDocument(doc.ID)
+	if len(chunks) != 1 || chunks[0].Content != "embedded content" {
+		t.Fatalf("expected the trashed chunks back, got %v", chunks)
+	}
+	if embeddings, _ := database.GetEmbeddings([]int64{chunks[0].ID}); len(embeddings) != 1 {
+		t.Error("expected the trashed embedding back")
+	}
+	if n, _ := database.TrashCount(); n != 0 {
+		t.Errorf("expected the trash emptied by the restore, got %d", n)
+	}
+
+	// Without retention, trashed notes are dropped
+	idx.SetTrashRetention(0)
+	os.Rename(filepath.Join(vault, "restored.md"), filepath.Join(vault, ".trash", "restored.md"))
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if n, _ := database.TrashCount(); n != 0 {
+		t.Errorf("expected nothing kept without retention, got %d", n)
+	}
+}
+
+func TestIndexFiles(t *testing.T) {
+	vault := t.TempDir()
+	for _, name := range []string{"a.md", "b.md", "gone.md"} {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte("# "+name+"\n"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"), 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	idx := New(database, nil, vault)
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	// Same modification time, so only a forced reindex notices
+	info, _ := os.Stat(filepath.Join(vault, "a.md"))
+	os.WriteFile(filepath.Join(vault, "a.md"), []byte("# Renamed\n"), 0644)
+	os.Chtimes(filepath.Join(vault, "a.md"), info.ModTime(), info.ModTime())
+	os.WriteFile(filepath.Join(vault, "b.md"), []byte("# Also renamed\n"), 0644)
+	os.Chtimes(filepath.Join(vault, "b.md"), info.ModTime(), info.ModTime())
+	os.Remove(filepath.Join(vault, "gone.md"))
+
+	err = idx.IndexFiles(context.Background(), []string{"a.md", "gone.md", "../outside.md", "notes.txt"})
+	var skippedErr *SkippedFilesError
+	if !errors.As(err, &skippedErr) || len(skippedErr.Files) != 2 {
+		t.Fatalf("expected the paths outside the vault's notes to be skipped, got %v", err)
+	}
+
+	if doc, _ := database.GetDocument("a.md"); doc == nil || doc.Title != "Renamed" {
+		t.Errorf("expected a.md reindexed, got %+v", doc)
+	}
+	if doc, _ := database.GetDocument("b.md"); doc == nil || doc.Title != "b.md" {
+		t.Errorf("expected b.md left alone, got %+v", doc)
+	}
+	if doc, _ := database.GetDocument("gone.md"); doc != nil {
+		t.Error("expected the deleted note removed")
+	}
+}
+
+func TestIndex_UsesImportedEmbeddings(t *testing.T) {
+	vault := t.TempDir()
+	content := "# Plan\n\nShip the importer before the end of the quarter, then measure.\n"
+	if err := os.WriteFile(filepath.Join(vault, "plan.md"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"), 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	_, chunks := parseMarkdown(content, "plan.md")
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(chunks))
+	}
+	if _, err := database.ImportEmbeddings(map[string][]float32{db.ChunkHash(chunks[0].Content): {0, 3, 0, 4}}); err != nil {
+		t.Fatalf("ImportEmbeddings failed: %v", err)
+	}
+
+	// Without a client, any chunk left to embed would fail the run
+	idx := New(database, nil, vault)
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	embedded, err := database.ChunkEmbeddings()
+	if err != nil || len(embedded) != 1 {
+		t.Fatalf("expected the chunk embedded, got %v, %v", embedded, err)
+	}
+	if got := embedded[0].Embedding; got[1] != 0.6 || got[3] != 0.8 {
+		t.Errorf("expected the imported vector at unit length, got %v", got)
+	}
+}
+
+func TestIndex_SkipsTouchedNotes(t *testing.T) {
+	vault := t.TempDir()
+	for _, name := range []string{"same.md", "edited.md"} {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte("# "+name+"\n"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"), 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	idx := New(database, nil, vault)
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	before, _ := database.GetDocument("same.md")
+
+	// As a sync tool would leave them: newer, one with the same content
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(vault, "same.md"), later, later)
+	os.WriteFile(filepath.Join(vault, "edited.md"), []byte("# Edited\n"), 0644)
+	os.Chtimes(filepath.Join(vault, "edited.md"), later, later)
+	database.UpsertDocument("same.md", before.Title, before.ModifiedAt, 1)
+
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	doc, _ := database.GetDocument("same.md")
+	if doc.IndexedAt != 1 || doc.ModifiedAt != later.Unix() {
+		t.Errorf("expected same.md kept with its new modification time, got %+v", doc)
+	}
+	if doc, _ := database.GetDocument("edited.md"); doc == nil || doc.Title != "Edited" {
+		t.Errorf("expected edited.md reindexed, got %+v", doc)
+	}
+}
+
+func TestIndex_CompareContent(t *testing.T) {
+	vault := t.TempDir()
+	path := filepath.Join(vault, "note.md")
+	if err := os.WriteFile(path, []byte("# Draft\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"), 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	idx := New(database, nil, vault)
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	// An older file with other content, as in a snapshot of a commit
+	earlier := time.Now().Add(-time.Hour)
+	os.WriteFile(path, []byte("# Committed\n"), 0644)
+	os.Chtimes(path, earlier, earlier)
+
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if doc, _ := database.GetDocument("note.md"); doc.Title != "Draft" {
+		t.Fatalf("expected the older file skipped by default, got %+v", doc)
+	}
+
+	idx.SetCompareContent(true)
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if doc, _ := database.GetDocument("note.md"); doc.Title != "Committed" {
+		t.Errorf("expected the changed content reindexed, got %+v", doc)
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	vault := t.TempDir()
+	if bookmarks, err := Bookmarks(vault); err != nil || len(bookmarks) != 0 {
+		t.Fatalf("expected no bookmarks without .obsidian, got %v (%v)", bookmarks, err)
+	}
+
+	if err := os.Mkdir(filepath.Join(vault, ".obsidian"), 0755); err != nil {
+		t.Fatalf("failed to create .obsidian: %v", err)
+	}
+	starred := `{"items":[{"type":"file","title":"Old","path":"Old.md"}]}`
+	if err := os.WriteFile(filepath.Join(vault, ".obsidian", "starred.json"), []byte(starred), 0644); err != nil {
+		t.Fatalf("failed to write starred.json: %v", err)
+	}
+	if bookmarks, _ := Bookmarks(vault); !slices.Equal(bookmarks, []string{"Old.md"}) {
+		t.Errorf("expected the starred notes without bookmarks.json, got %v", bookmarks)
+	}
+
+	bookmarksJSON := `{"items":[
+		{"type":"file","ctime":1,"path":"Projects/Roadmap.md"},
+		{"type":"search","ctime":2,"query":"tag:#idea"},
+		{"type":"group","ctime":3,"title":"Reading","items":[
+			{"type":"folder","ctime":4,"path":"Books/"},
+			{"type":"heading","ctime":5,"path":"Projects/Roadmap.md","subpath":"#Q3"},
+			{"type":"block","ctime":6,"path":"Daily/2024-06-01.md","subpath":"#^abc"},
+			{"type":"url","ctime":7,"url":"https://obsidian.md"}
+		]}
+	]}`
+	if err := os.WriteFile(filepath.Join(vault, ".obsidian", "bookmarks.json"), []byte(bookmarksJSON), 0644); err != nil {
+		t.Fatalf("failed to write bookmarks.json: %v", err)
+	}
+	bookmarks, err := Bookmarks(vault)
+	if err != nil {
+		t.Fatalf("Bookmarks failed: %v", err)
+	}
+	want := []string{"Projects/Roadmap.md", "Books", "Daily/2024-06-01.md"}
+	if !slices.Equal(bookmarks, want) {
+		t.Errorf("expected %v, got %v", want, bookmarks)
+	}
+
+	os.WriteFile(filepath.Join(vault, ".obsidian", "bookmarks.json"), []byte("{"), 0644)
+	if _, err := Bookmarks(vault); err == nil {
+		t.Error("expected an error for a malformed bookmarks.json")
+	}
+}
+
+func TestIndex_ExcludePaths(t *testing.T) {
+	vault := t.TempDir()
+	for _, name := range []string{"keep.md", "Archive/old.md"} {
+		path := filepath.Join(vault, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte("# "+name+"\n"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"), 4)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	idx := New(database, nil, vault)
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	// Excluding a folder later removes what was indexed from it
+	idx.SetExcludePaths([]string{"Archive/**"})
+	if err := idx.Index(context.Background(), false, nil); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if doc, _ := database.GetDocument("Archive/old.md"); doc != nil {
+		t.Error("expected the excluded note removed from the index")
+	}
+	if doc, _ := database.GetDocument("keep.md"); doc == nil {
+		t.Error("expected the other note kept")
+	}
+
+	if err := idx.IndexFiles(context.Background(), []string{"Archive/old.md"}); err != nil {
+		t.Fatalf("IndexFiles failed: %v", err)
+	}
+	if doc, _ := database.GetDocument("Archive/old.md"); doc != nil {
+		t.Error("expected IndexFiles to leave the excluded note out")
+	}
+}
@@ -0,0 +1,70 @@
+package indexer
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// wikilinkRegex matches [[Target]], [[Target|alias]], [[Target#Heading]]
+	// and their ![[embed]] forms, capturing the target.
+	wikilinkRegex = regexp.MustCompile(`\[\[([^\]|#^]+)[^\]]*\]\]`)
+
+	// markdownLinkRegex matches [text](relative/note.md) links to notes.
+	markdownLinkRegex = regexp.MustCompile(`\[[^\]]*\]\(([^)\s]+\.md)(?:#[^)]*)?\)`)
+)
+
+// extractLinks returns the normalized, de-duplicated link targets of a note:
+// lowercase vault-relative paths or note names without the .md extension.
+// Links inside code fences and to external URLs are ignored.
+func extractLinks(content string) []string {
+	seen := make(map[string]bool)
+	add := func(target string) {
+		if target = normalizeLinkTarget(target); target != "" {
+			seen[target] = true
+		}
+	}
+
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, match := range wikilinkRegex.FindAllStringSubmatch(line, -1) {
+			add(match[1])
+		}
+		for _, match := range markdownLinkRegex.FindAllStringSubmatch(line, -1) {
+			if strings.Contains(match[1], "://") {
+				continue
+			}
+			if decoded, err := url.PathUnescape(match[1]); err == nil {
+				add(decoded)
+			}
+		}
+	}
+
+	links := make([]string, 0, len(seen))
+	for link := range seen {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	return links
+}
+
+func normalizeLinkTarget(target string) string {
+	target = strings.TrimSpace(strings.ReplaceAll(target, "\\", "/"))
+	if target == "" {
+		return ""
+	}
+	target = path.Clean("/" + target)[1:]
+	target = strings.ToLower(target)
+	return strings.TrimSuffix(target, ".md")
+}
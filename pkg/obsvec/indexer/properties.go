@@ -0,0 +1,86 @@
+package indexer
+
+import (
+	"strings"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+// extractProperties returns a note's frontmatter properties keyed by
+// lowercased name. Scalars are parsed with db.ParsePropertyValue, inline
+// ("[a, b]") and block lists become slices, block scalars ("|" or ">")
+// become strings and nested mappings are skipped.
+func extractProperties(content string) map[string]any {
+	fm, _, ok := splitFrontmatter(content)
+	if !ok {
+		return nil
+	}
+
+	properties := make(map[string]any)
+	lines := strings.Split(strings.ReplaceAll(fm, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		key, value, ok := strings.Cut(lines[i], ":")
+		if !ok || key == "" || strings.HasPrefix(key, " ") || strings.HasPrefix(key, "\t") || strings.HasPrefix(key, "#") {
+			continue
+		}
+		key = strings.ToLower(strings.Trim(strings.TrimSpace(key), `"'`))
+		if key == "" || strings.Contains(key, `"`) {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		// Indented lines below the key belong to it
+		var nested []string
+		for i+1 < len(lines) && (lines[i+1] == "" || strings.HasPrefix(lines[i+1], " ") || strings.HasPrefix(lines[i+1], "\t") || strings.HasPrefix(lines[i+1], "- ")) {
+			i++
+			nested = append(nested, lines[i])
+		}
+
+		switch {
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			items := []any{}
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, db.ParsePropertyValue(item))
+				}
+			}
+			properties[key] = items
+		case value == "|" || value == ">" || strings.HasPrefix(value, "|-") || strings.HasPrefix(value, ">-"):
+			var text []string
+			for _, line := range nested {
+				text = append(text, strings.TrimSpace(line))
+			}
+			sep := "\n"
+			if value[0] == '>' {
+				sep = " "
+			}
+			properties[key] = strings.TrimSpace(strings.Join(text, sep))
+		case value == "":
+			var items []any
+			isList := false
+			for _, line := range nested {
+				trimmed := strings.TrimSpace(line)
+				if item, ok := strings.CutPrefix(trimmed, "- "); ok {
+					items = append(items, db.ParsePropertyValue(item))
+					isList = true
+				} else if trimmed == "-" {
+					isList = true
+				} else if trimmed != "" {
+					isList = false
+					break
+				}
+			}
+			if isList {
+				if items == nil {
+					items = []any{}
+				}
+				properties[key] = items
+			} else if len(strings.TrimSpace(strings.Join(nested, ""))) == 0 {
+				properties[key] = nil
+			}
+		default:
+			properties[key] = db.ParsePropertyValue(value)
+		}
+	}
+	return properties
+}
@@ -0,0 +1,147 @@
+package indexer
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// inlineTagRegex matches Obsidian inline tags: '#' at the start of a line or
+// after whitespace, followed by letters, digits, '_', '-' or '/'.
+var inlineTagRegex = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]+)`)
+
+// extractTags returns the normalized, de-duplicated tags of a note, taken from
+// the frontmatter "tags"/"tag" property and inline #tags outside code fences.
+func extractTags(content string) []string {
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		tag = strings.ToLower(strings.Trim(strings.TrimSpace(tag), `#"'`))
+		tag = strings.Trim(tag, "/")
+		if tag == "" || isNumeric(tag) {
+			return
+		}
+		seen[tag] = true
+	}
+
+	body := content
+	if fm, rest, ok := splitFrontmatter(content); ok {
+		for _, tag := range frontmatterTags(fm) {
+			add(tag)
+		}
+		body = rest
+	}
+
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, match := range inlineTagRegex.FindAllStringSubmatch(line, -1) {
+			add(match[1])
+		}
+	}
+
+	tags := make([]string, 0, len(seen))
+	for tag := range seen {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// splitFrontmatter separates a leading YAML frontmatter block from the body.
+func splitFrontmatter(content string) (string, string, bool) {
+	if !strings.HasPrefix(content, "---\n") && !strings.HasPrefix(content, "---\r\n") {
+		return "", content, false
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			return strings.Join(lines[1:i], ""), strings.Join(lines[i+1:], ""), true
+		}
+	}
+	return "", content, false
+}
+
+// frontmatterTags reads the tags property in any of the forms Obsidian
+// accepts: "tags: a, b", "tags: [a, b]" or a YAML block list.
+func frontmatterTags(frontmatter string) []string {
+	return frontmatterList(frontmatter, []string{"tags", "tag"}, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// frontmatterAliases reads the aliases property in the same forms as tags.
+// Aliases may contain spaces, so inline values are only split on commas.
+func frontmatterAliases(frontmatter string) []string {
+	var aliases []string
+	for _, alias := range frontmatterList(frontmatter, []string{"aliases", "alias"}, func(r rune) bool { return r == ',' }) {
+		if alias = strings.Trim(strings.TrimSpace(alias), `"'`); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+// frontmatterList reads a list property stored under any of keys, splitting
+// inline values with sep.
+func frontmatterList(frontmatter string, keys []string, sep func(rune) bool) []string {
+	var values []string
+	inList := false
+	for _, line := range strings.Split(frontmatter, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if inList {
+			if strings.HasPrefix(trimmed, "- ") {
+				values = append(values, strings.TrimPrefix(trimmed, "- "))
+				continue
+			}
+			if trimmed == "" {
+				continue
+			}
+			inList = false
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(key, " ") {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !slices.Contains(keys, key) {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" {
+			inList = true
+			continue
+		}
+
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		values = append(values, strings.FieldsFunc(value, sep)...)
+	}
+	return values
+}
+
+// extractAliases returns the aliases declared in a note's frontmatter.
+func extractAliases(content string) []string {
+	fm, _, ok := splitFrontmatter(content)
+	if !ok {
+		return nil
+	}
+	return frontmatterAliases(fm)
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
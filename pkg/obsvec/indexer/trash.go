@@ -0,0 +1,65 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/events"
+)
+
+// trashDir is the folder Obsidian moves deleted notes to when it is set to
+// keep them in the vault.
+const trashDir = ".trash"
+
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// SetTrashRetention sets how long notes moved to the vault's .trash folder
+// are kept in the index, out of search results, so restoring one doesn't
+// need new embeddings. Zero removes them like any deleted note.
+func (idx *Indexer) SetTrashRetention(d time.Duration) {
+	idx.trashRetention = max(d, 0)
+}
+
+// trashedHashes returns the content hashes of the notes in the vault's trash.
+func (idx *Indexer) trashedHashes() map[string]bool {
+	hashes := make(map[string]bool)
+	if idx.trashRetention == 0 {
+		return hashes
+	}
+	_ = filepath.WalkDir(filepath.Join(idx.dir, trashDir), func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !isMarkdownFile(entry.Name()) {
+			return nil
+		}
+		if content, err := os.ReadFile(path); err == nil {
+			hashes[ContentHash(content)] = true
+		}
+		return nil
+	})
+	return hashes
+}
+
+// removeOrTrash drops a note that is gone from the vault. When a note with
+// the same content is in the trash, it was moved there, and it is kept in
+// the trash table instead. It reports whether the note was trashed.
+func (idx *Indexer) removeOrTrash(relPath string, trashed map[string]bool) (bool, error) {
+	doc, err := idx.db.GetDocument(relPath)
+	if err != nil {
+		return false, err
+	}
+	if doc == nil || doc.ContentHash == "" || !trashed[doc.ContentHash] {
+		return false, idx.removeDocument(relPath)
+	}
+
+	if _, err := idx.db.TrashDocument(relPath, time.Now().Unix()); err != nil {
+		return false, err
+	}
+	idx.events.Publish(events.Event{Kind: events.DocumentRemoved, Path: relPath})
+	return true, idx.purgeTrash()
+}
+
+// purgeTrash drops trashed notes older than the retention period.
+func (idx *Indexer) purgeTrash() error {
+	_, err := idx.db.PurgeTrash(time.Now().Add(-idx.trashRetention).Unix())
+	return err
+}
@@ -0,0 +1,165 @@
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+// ContentHash is the checksum recorded for a note's content when it is
+// indexed.
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// IntegrityReport lists the ways the index has drifted from the vault.
+type IntegrityReport struct {
+	Files int
+
+	// NotIndexed are notes on disk with no document in the index.
+	NotIndexed []string
+
+	// Deleted are indexed notes whose file is gone.
+	Deleted []string
+
+	// Stale are notes whose content changed since they were indexed, even
+	// if their modification time didn't.
+	Stale []string
+
+	// MissingEmbeddings are notes with chunks that have no vector, left by
+	// an interrupted or failed embed.
+	MissingEmbeddings []string
+
+	// Unhashed are notes indexed before checksums were recorded and not
+	// modified since. Repair records their checksum without reindexing.
+	Unhashed []string
+
+	Unreadable []FileError
+}
+
+// OK reports whether the index matches the vault.
+func (r *IntegrityReport) OK() bool {
+	return len(r.NotIndexed)+len(r.Deleted)+len(r.Stale)+len(r.MissingEmbeddings)+len(r.Unhashed) == 0
+}
+
+// NeedsReindex returns the notes that must be indexed again, sorted.
+func (r *IntegrityReport) NeedsReindex() []string {
+	paths := slices.Concat(r.NotIndexed, r.Stale, r.MissingEmbeddings)
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// Verify compares every note in the vault with the index without changing
+// either.
+func (idx *Indexer) Verify() (*IntegrityReport, error) {
+	files, skipped, err := idx.findMarkdownFiles()
+	if err != nil {
+		return nil, err
+	}
+	report := &IntegrityReport{Files: len(files), Unreadable: skipped}
+
+	docs, err := idx.db.GetAllDocuments()
+	if err != nil {
+		return nil, err
+	}
+	missing, err := idx.db.ChunksWithoutEmbeddings()
+	if err != nil {
+		return nil, err
+	}
+
+	onDisk := make(map[string]bool, len(files))
+	for _, relPath := range files {
+		onDisk[relPath] = true
+	}
+	byPath := make(map[string]db.Document, len(docs))
+	for _, doc := range docs {
+		byPath[doc.Path] = doc
+		if !onDisk[doc.Path] {
+			report.Deleted = append(report.Deleted, doc.Path)
+		}
+	}
+
+	for _, relPath := range files {
+		doc, ok := byPath[relPath]
+		if !ok {
+			report.NotIndexed = append(report.NotIndexed, relPath)
+			continue
+		}
+		if missing[doc.ID] > 0 {
+			report.MissingEmbeddings = append(report.MissingEmbeddings, relPath)
+		}
+
+		absPath := filepath.Join(idx.dir, relPath)
+		if doc.ContentHash == "" {
+			// Only the modification time can tell whether these changed
+			info, err := os.Stat(absPath)
+			if err != nil {
+				report.Unreadable = append(report.Unreadable, FileError{Path: relPath, Err: err})
+			} else if info.ModTime().Unix() > doc.ModifiedAt {
+				report.Stale = append(report.Stale, relPath)
+			} else {
+				report.Unhashed = append(report.Unhashed, relPath)
+			}
+			continue
+		}
+
+		content, err := os.ReadFile(absPath)
+		if err != nil {
+			report.Unreadable = append(report.Unreadable, FileError{Path: relPath, Err: err})
+			continue
+		}
+		if ContentHash(content) != doc.ContentHash {
+			report.Stale = append(report.Stale, relPath)
+		}
+	}
+
+	slices.Sort(report.Deleted)
+	return report, nil
+}
+
+// Repair brings the index back in line with a report from Verify: deleted
+// notes are removed, unhashed ones get a checksum and the rest of the
+// drifted notes are reindexed.
+func (idx *Indexer) Repair(ctx context.Context, report *IntegrityReport) error {
+	trashed := idx.trashedHashes()
+	for _, relPath := range report.Deleted {
+		if _, err := idx.removeOrTrash(relPath, trashed); err != nil {
+			return err
+		}
+	}
+
+	for _, relPath := range report.Unhashed {
+		if slices.Contains(report.MissingEmbeddings, relPath) {
+			continue // reindexed below
+		}
+		content, err := os.ReadFile(filepath.Join(idx.dir, relPath))
+		if err != nil {
+			return err
+		}
+		doc, err := idx.db.GetDocument(relPath)
+		if err != nil {
+			return err
+		}
+		if doc == nil {
+			continue
+		}
+		if err := idx.db.SetDocumentHash(doc.ID, ContentHash(content)); err != nil {
+			return err
+		}
+	}
+
+	failed, err := idx.indexFiles(ctx, report.NeedsReindex())
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return &SkippedFilesError{Files: failed}
+	}
+	return nil
+}
@@ -5,21 +5,28 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
-const debounceDelay = 2 * time.Second
+const (
+	defaultDebounce    = 2 * time.Second
+	defaultBatchWindow = 500 * time.Millisecond
+)
 
 type Watcher struct {
-	indexer   *Indexer
-	watcher   *fsnotify.Watcher
-	pending   map[string]time.Time
-	mu        sync.Mutex
-	stop      chan struct{}
-	onMessage func(string)
+	indexer     *Indexer
+	watcher     *fsnotify.Watcher
+	pending     map[string]time.Time
+	mu          sync.Mutex
+	stop        chan struct{}
+	onMessage   func(string)
+	quiet       bool
+	debounce    time.Duration
+	batchWindow time.Duration
 }
 
 func NewWatcher(indexer *Indexer) (*Watcher, error) {
@@ -29,10 +36,12 @@ func NewWatcher(indexer *Indexer) (*Watcher, error) {
 	}
 
 	return &Watcher{
-		indexer: indexer,
-		watcher: fsw,
-		pending: make(map[string]time.Time),
-		stop:    make(chan struct{}),
+		indexer:     indexer,
+		watcher:     fsw,
+		pending:     make(map[string]time.Time),
+		stop:        make(chan struct{}),
+		debounce:    defaultDebounce,
+		batchWindow: defaultBatchWindow,
 	}, nil
 }
 
@@ -40,6 +49,27 @@ func (w *Watcher) SetMessageHandler(fn func(string)) {
 	w.onMessage = fn
 }
 
+// SetQuiet drops status messages such as "Indexed: note.md", still
+// reporting errors.
+func (w *Watcher) SetQuiet(quiet bool) {
+	w.quiet = quiet
+}
+
+// SetDebounce sets how long a file must be quiet before it is reindexed.
+func (w *Watcher) SetDebounce(d time.Duration) {
+	if d > 0 {
+		w.debounce = d
+	}
+}
+
+// SetBatchWindow sets how often settled files are collected into a single
+// embed batch. Must be called before Start.
+func (w *Watcher) SetBatchWindow(d time.Duration) {
+	if d > 0 {
+		w.batchWindow = d
+	}
+}
+
 func (w *Watcher) Start(ctx context.Context) error {
 	if err := w.addWatchRecursive(w.indexer.dir); err != nil {
 		return err
@@ -92,7 +122,7 @@ func (w *Watcher) processEvents(ctx context.Context) {
 			if !ok {
 				return
 			}
-			w.message(fmt.Sprintf("Watch error: %v", err))
+			w.warn(fmt.Sprintf("Watch error: %v", err))
 		}
 	}
 }
@@ -107,7 +137,7 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 		return
 	}
 
-	if isHiddenRelPath(relPath) {
+	if isHiddenRelPath(relPath) || w.indexer.excluded(relPath) {
 		return
 	}
 
@@ -123,14 +153,17 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 	case event.Op&fsnotify.Remove == fsnotify.Remove,
 		event.Op&fsnotify.Rename == fsnotify.Rename:
 		delete(w.pending, relPath)
-		if err := w.indexer.db.DeleteDocument(relPath); err == nil {
+		trashed, err := w.indexer.removeOrTrash(relPath, w.indexer.trashedHashes())
+		if err == nil && trashed {
+			w.message(fmt.Sprintf("Moved to trash: %s", relPath))
+		} else if err == nil {
 			w.message(fmt.Sprintf("Removed from index: %s", relPath))
 		}
 	}
 }
 
 func (w *Watcher) processPending(ctx context.Context) {
-	ticker := time.NewTicker(500 * time.Millisecond)
+	ticker := time.NewTicker(w.batchWindow)
 	defer ticker.Stop()
 
 	for {
@@ -150,7 +183,7 @@ func (w *Watcher) indexPendingFiles(ctx context.Context) {
 	now := time.Now()
 	var toIndex []string
 	for path, timestamp := range w.pending {
-		if now.Sub(timestamp) >= debounceDelay {
+		if now.Sub(timestamp) >= w.debounce {
 			toIndex = append(toIndex, path)
 		}
 	}
@@ -159,17 +192,44 @@ func (w *Watcher) indexPendingFiles(ctx context.Context) {
 	}
 	w.mu.Unlock()
 
+	if len(toIndex) == 0 {
+		return
+	}
+
+	if len(toIndex) == 1 {
+		w.message(fmt.Sprintf("Indexing: %s", toIndex[0]))
+	} else {
+		w.message(fmt.Sprintf("Indexing %d files", len(toIndex)))
+	}
+
+	start := time.Now()
+	failed, err := w.indexer.indexFiles(ctx, toIndex)
+	w.indexer.publishOutcome(ctx, start, failed, err)
+	if err != nil {
+		w.warn(fmt.Sprintf("Error indexing %s: %v", strings.Join(toIndex, ", "), err))
+		return
+	}
+
+	failedPaths := make(map[string]bool, len(failed))
+	for _, fileErr := range failed {
+		failedPaths[fileErr.Path] = true
+		w.warn(fmt.Sprintf("Error indexing %s: %v", fileErr.Path, fileErr.Err))
+	}
 	for _, relPath := range toIndex {
-		w.message(fmt.Sprintf("Indexing: %s", relPath))
-		if err := w.indexer.indexFile(ctx, relPath); err != nil {
-			w.message(fmt.Sprintf("Error indexing %s: %v", relPath, err))
-		} else {
+		if !failedPaths[relPath] {
 			w.message(fmt.Sprintf("Indexed: %s", relPath))
 		}
 	}
 }
 
 func (w *Watcher) message(msg string) {
+	if !w.quiet {
+		w.warn(msg)
+	}
+}
+
+// warn reports msg even when the watcher is quiet.
+func (w *Watcher) warn(msg string) {
 	if w.onMessage != nil {
 		w.onMessage(msg)
 	} else {
@@ -0,0 +1,47 @@
+// Package provider defines what the indexer and searcher need from a model
+// provider: embeddings for notes and queries, reranking and short chat
+// completions. The cohere package implements them; other programs can
+// substitute their own, for example a local model server.
+package provider
+
+import "context"
+
+// EmbeddingResult is the embedding of one text.
+type EmbeddingResult struct {
+	Embedding []float32
+}
+
+// RerankResult scores a document by its index in the list reranked.
+type RerankResult struct {
+	Index int
+	Score float64
+}
+
+// DocumentEmbedder embeds note chunks for indexing. It returns one result
+// per text, in order, each with the index's embedding dimension.
+type DocumentEmbedder interface {
+	EmbedDocuments(ctx context.Context, texts []string) ([]EmbeddingResult, error)
+}
+
+// QueryEmbedder embeds search queries into the space of the documents.
+type QueryEmbedder interface {
+	EmbedQueries(ctx context.Context, queries []string) ([][]float32, error)
+}
+
+// Reranker orders documents by relevance to a query, returning at most
+// topN of them, best first.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankResult, error)
+}
+
+// Chatter completes a prompt; searches use it to paraphrase queries.
+type Chatter interface {
+	Chat(ctx context.Context, system, prompt string) (string, error)
+}
+
+// Searcher is everything a search needs.
+type Searcher interface {
+	QueryEmbedder
+	Reranker
+	Chatter
+}
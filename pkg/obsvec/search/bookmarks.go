@@ -0,0 +1,61 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+// isBookmarked reports whether a note is one of bookmarks or inside a
+// bookmarked folder.
+func isBookmarked(path string, bookmarks []string) bool {
+	for _, b := range bookmarks {
+		if path == b || strings.HasPrefix(path, b+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictToBookmarks narrows filter to the bookmarked notes when
+// opts.BookmarkedOnly is set, reporting false when none of them is indexed.
+func (s *Searcher) restrictToBookmarks(filter *db.SearchFilter, opts Options) (bool, error) {
+	if !opts.BookmarkedOnly {
+		return true, nil
+	}
+	docs, err := s.db.GetAllDocuments()
+	if err != nil {
+		return false, err
+	}
+	for _, doc := range docs {
+		if isBookmarked(doc.Path, opts.Bookmarks) {
+			filter.DocIDs = append(filter.DocIDs, doc.ID)
+		}
+	}
+	// An empty list of ids doesn't filter at all
+	return len(filter.DocIDs) > 0, nil
+}
+
+// boostBookmarked raises the scores of bookmarked notes by weight times their
+// magnitude, 1 + weight times for a positive score, and re-sorts by the new
+// score.
+func boostBookmarked(results []Result, bookmarks []string, weight float64) []Result {
+	boosted := make([]Result, len(results))
+	copy(boosted, results)
+
+	for i, r := range boosted {
+		if isBookmarked(r.Path, bookmarks) {
+			boosted[i].Score += math.Abs(r.Score) * weight
+		}
+	}
+
+	sort.SliceStable(boosted, func(i, j int) bool {
+		return boosted[i].Score > boosted[j].Score
+	})
+	for i := range boosted {
+		boosted[i].Rank = i + 1
+	}
+	return boosted
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// queryCache keeps the embeddings of the most recently searched texts, so a
+// long-running searcher doesn't embed a repeated query again.
+type queryCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // of *cachedQuery, most recent first
+	items map[string]*list.Element
+}
+
+type cachedQuery struct {
+	text string
+	emb  []float32
+}
+
+func newQueryCache(size int) *queryCache {
+	return &queryCache{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *queryCache) get(text string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[text]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cachedQuery).emb, true
+}
+
+func (c *queryCache) put(text string, emb []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[text]; ok {
+		elem.Value.(*cachedQuery).emb = emb
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[text] = c.order.PushFront(&cachedQuery{text: text, emb: emb})
+	if c.order.Len() > c.size {
+		oldest := c.order.Remove(c.order.Back()).(*cachedQuery)
+		delete(c.items, oldest.text)
+	}
+}
+
+// SetQueryCache remembers the embeddings of the last size query texts, for
+// searchers that answer many searches. 0 turns the cache off.
+func (s *Searcher) SetQueryCache(size int) {
+	s.queryCache = nil
+	if size > 0 {
+		s.queryCache = newQueryCache(size)
+	}
+}
+
+// embedQueries embeds texts as search queries, taking what it can from the
+// query cache and embedding the rest in one request.
+func (s *Searcher) embedQueries(ctx context.Context, texts []string) ([][]float32, error) {
+	if s.queryCache == nil {
+		return s.models.EmbedQueries(ctx, texts)
+	}
+
+	embs := make([][]float32, len(texts))
+	var missing []string
+	var missingIdx []int
+	for i, text := range texts {
+		if emb, ok := s.queryCache.get(text); ok {
+			embs[i] = emb
+			continue
+		}
+		missing = append(missing, text)
+		missingIdx = append(missingIdx, i)
+	}
+	if len(missing) == 0 {
+		return embs, nil
+	}
+
+	embedded, err := s.models.EmbedQueries(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for j, emb := range embedded {
+		embs[missingIdx[j]] = emb
+		s.queryCache.put(missing[j], emb)
+	}
+	return embs, nil
+}
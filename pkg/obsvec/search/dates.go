@@ -0,0 +1,85 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSince parses a lower date bound: an absolute date ("2024-01-01"), an
+// RFC 3339 timestamp, or a relative age such as "30d", "12h", "2w", "6m"
+// (months) or "1y", counted back from now.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	t, _, err := parseDateBound(value, now)
+	return t, err
+}
+
+// ParseUntil parses an upper date bound in the same forms as ParseSince. A
+// bare date includes that whole day, so "--until 2024-06-30" keeps notes
+// modified on June 30th.
+func ParseUntil(value string, now time.Time) (time.Time, error) {
+	t, dateOnly, err := parseDateBound(value, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if dateOnly {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// ParseHalfLife parses a recency half-life given as a relative age ("7d",
+// "2w", "6m") or a Go duration ("36h"). "0" disables recency boosting.
+func ParseHalfLife(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, nil
+	}
+
+	if value == "" || !strings.ContainsRune("hdwmy", rune(value[len(value)-1])) {
+		return 0, fmt.Errorf("invalid half-life %q: use a relative age like 7d", value)
+	}
+
+	// UTC so calendar units aren't skewed by DST transitions
+	now := time.Now().UTC()
+	t, _, err := parseDateBound(value, now)
+	if err != nil {
+		return 0, err
+	}
+	return now.Sub(t), nil
+}
+
+func parseDateBound(value string, now time.Time) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, fmt.Errorf("empty date")
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+
+	unit := value[len(value)-1]
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, false, fmt.Errorf("invalid date %q: use YYYY-MM-DD or a relative age like 30d", value)
+	}
+
+	switch unit {
+	case 'h':
+		return now.Add(-time.Duration(n) * time.Hour), false, nil
+	case 'd':
+		return now.AddDate(0, 0, -n), false, nil
+	case 'w':
+		return now.AddDate(0, 0, -7*n), false, nil
+	case 'm':
+		return now.AddDate(0, -n, 0), false, nil
+	case 'y':
+		return now.AddDate(-n, 0, 0), false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date %q: unknown unit %q (use h, d, w, m or y)", value, unit)
+}
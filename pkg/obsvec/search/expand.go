@@ -0,0 +1,46 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxExpansions is how many paraphrases the chat model is asked for.
+const maxExpansions = 3
+
+const expandSystemPrompt = `You rewrite search queries for a personal notes search engine.
+Given a query, write alternative phrasings that someone might have used in their notes: synonyms, related terms, or a fuller description.
+Reply with one phrasing per line and nothing else.`
+
+// expandQuery asks the chat model for paraphrases of query. The original
+// query is not included in the result.
+func (s *Searcher) expandQuery(ctx context.Context, query string) ([]string, error) {
+	prompt := fmt.Sprintf("Write %d alternative phrasings of this query:\n\n%s", maxExpansions, query)
+	reply, err := s.models.Chat(ctx, expandSystemPrompt, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("query expansion failed: %w", err)
+	}
+	return parseExpansions(reply, query, maxExpansions), nil
+}
+
+// parseExpansions takes up to n distinct lines from a chat reply, stripping
+// list markers and quotes and dropping repeats of the original query.
+func parseExpansions(reply, query string, n int) []string {
+	seen := map[string]bool{strings.ToLower(query): true}
+	var expansions []string
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimLeft(line, "-*•0123456789.) ")
+		line = strings.Trim(line, "\"'` ")
+		if line == "" || seen[strings.ToLower(line)] {
+			continue
+		}
+		seen[strings.ToLower(line)] = true
+		expansions = append(expansions, line)
+		if len(expansions) == n {
+			break
+		}
+	}
+	return expansions
+}
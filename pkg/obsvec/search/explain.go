@@ -0,0 +1,181 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+// Explanation records how a result was found and scored, for tuning the
+// ranking. It is only filled in when Options.Explain is set.
+type Explanation struct {
+	// VectorDistance is the smallest distance from the chunk to any query
+	// embedding, or nil when only keyword or title search found it.
+	VectorDistance *float64 `json:"vector_distance,omitempty"`
+
+	// KeywordRank and TitleRank are the chunk's best 1-based positions in
+	// the keyword and title candidate lists, 0 when absent.
+	KeywordRank int `json:"keyword_rank,omitempty"`
+	TitleRank   int `json:"title_rank,omitempty"`
+
+	// CandidateRank is the position in the fused pool sent to the reranker.
+	CandidateRank int `json:"candidate_rank"`
+
+	RerankScore float64 `json:"rerank_score"`
+	RerankRank  int     `json:"rerank_rank"`
+
+	// Adjustments lists score changes applied after reranking, in order.
+	Adjustments []Adjustment `json:"adjustments,omitempty"`
+
+	// Filters describes the active filters the result satisfied.
+	Filters []string `json:"filters,omitempty"`
+}
+
+type Adjustment struct {
+	Name   string  `json:"name"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+}
+
+// String formats the explanation on one line.
+func (e *Explanation) String() string {
+	var parts []string
+	if e.VectorDistance != nil {
+		parts = append(parts, fmt.Sprintf("distance %.3f", *e.VectorDistance))
+	}
+	if e.KeywordRank > 0 {
+		parts = append(parts, fmt.Sprintf("keyword #%d", e.KeywordRank))
+	}
+	if e.TitleRank > 0 {
+		parts = append(parts, fmt.Sprintf("title #%d", e.TitleRank))
+	}
+	parts = append(parts, fmt.Sprintf("pool #%d", e.CandidateRank))
+	parts = append(parts, fmt.Sprintf("rerank %.3f (#%d)", e.RerankScore, e.RerankRank))
+	for _, a := range e.Adjustments {
+		parts = append(parts, fmt.Sprintf("%s %.3f→%.3f", a.Name, a.Before, a.After))
+	}
+	if len(e.Filters) > 0 {
+		parts = append(parts, "filters "+strings.Join(e.Filters, ", "))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// explainTrace collects per-chunk retrieval signals while searching. A nil
+// trace records nothing.
+type explainTrace struct {
+	distances    map[int64]float64
+	keywordRanks map[int64]int
+	titleRanks   map[int64]int
+}
+
+func newExplainTrace() *explainTrace {
+	return &explainTrace{
+		distances:    make(map[int64]float64),
+		keywordRanks: make(map[int64]int),
+		titleRanks:   make(map[int64]int),
+	}
+}
+
+func (t *explainTrace) vectorHits(hits []db.ChunkWithScore) {
+	if t == nil {
+		return
+	}
+	for _, h := range hits {
+		if d, ok := t.distances[h.ID]; !ok || h.Distance < d {
+			t.distances[h.ID] = h.Distance
+		}
+	}
+}
+
+func (t *explainTrace) keywordHits(hits []db.ChunkWithScore) {
+	if t != nil {
+		bestRanks(t.keywordRanks, hits)
+	}
+}
+
+func (t *explainTrace) titleHits(hits []db.ChunkWithScore) {
+	if t != nil {
+		bestRanks(t.titleRanks, hits)
+	}
+}
+
+func bestRanks(ranks map[int64]int, hits []db.ChunkWithScore) {
+	for i, h := range hits {
+		if r, ok := ranks[h.ID]; !ok || i+1 < r {
+			ranks[h.ID] = i + 1
+		}
+	}
+}
+
+// explain attaches an Explanation to each freshly reranked result.
+func (t *explainTrace) explain(results []Result, candidates []db.ChunkWithScore, filter db.SearchFilter) {
+	if t == nil {
+		return
+	}
+
+	candidateRanks := make(map[int64]int, len(candidates))
+	for i, c := range candidates {
+		candidateRanks[c.ID] = i + 1
+	}
+
+	for i, r := range results {
+		e := &Explanation{
+			KeywordRank:   t.keywordRanks[r.ChunkID],
+			TitleRank:     t.titleRanks[r.ChunkID],
+			CandidateRank: candidateRanks[r.ChunkID],
+			RerankScore:   r.Score,
+			RerankRank:    r.Rank,
+			Filters:       matchedFilters(filter, r.Path),
+		}
+		if d, ok := t.distances[r.ChunkID]; ok {
+			e.VectorDistance = &d
+		}
+		results[i].Explanation = e
+	}
+}
+
+// explainAdjustment records the score change a ranking stage made to each
+// explained result.
+func explainAdjustment(name string, before, after []Result) {
+	scores := make(map[int64]float64, len(before))
+	for _, r := range before {
+		scores[r.ChunkID] = r.Score
+	}
+	for _, r := range after {
+		if r.Explanation == nil {
+			continue
+		}
+		if prev, ok := scores[r.ChunkID]; ok && prev != r.Score {
+			r.Explanation.Adjustments = append(r.Explanation.Adjustments, Adjustment{Name: name, Before: prev, After: r.Score})
+		}
+	}
+}
+
+// matchedFilters describes the active filters, naming the include globs the
+// path matched. Every candidate satisfies the filter, so the rest apply as-is.
+func matchedFilters(filter db.SearchFilter, path string) []string {
+	var matched []string
+	for _, tag := range filter.Tags {
+		matched = append(matched, "tag:"+db.NormalizeTag(tag))
+	}
+	for _, pattern := range filter.Paths {
+		if db.MatchPathGlob(pattern, path) {
+			matched = append(matched, "path:"+pattern)
+		}
+	}
+	for _, pattern := range filter.ExcludePaths {
+		matched = append(matched, "not path:"+pattern)
+	}
+	if filter.ModifiedSince != 0 {
+		matched = append(matched, "since:"+time.Unix(filter.ModifiedSince, 0).Format("2006-01-02"))
+	}
+	if filter.ModifiedBefore != 0 {
+		matched = append(matched, "until:"+time.Unix(filter.ModifiedBefore, 0).Format("2006-01-02"))
+	}
+	for _, phrase := range filter.Phrases {
+		matched = append(matched, fmt.Sprintf("%q", phrase))
+	}
+	return matched
+}
@@ -0,0 +1,45 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+// rrfK dampens the contribution of top ranks in reciprocal rank fusion. 60 is
+// the value from the original RRF paper and works well without tuning.
+const rrfK = 60
+
+// fuseRRF merges ranked candidate lists with reciprocal rank fusion: each chunk
+// scores the sum of 1/(rrfK+rank) over the lists it appears in. The fused list
+// is truncated to limit. When a chunk appears in several lists, the first
+// occurrence is kept so vector distances win over keyword scores.
+func fuseRRF(limit int, lists ...[]db.ChunkWithScore) []db.ChunkWithScore {
+	scores := make(map[int64]float64)
+	chunks := make(map[int64]db.ChunkWithScore)
+	var order []int64
+
+	for _, list := range lists {
+		for rank, c := range list {
+			if _, seen := chunks[c.ID]; !seen {
+				chunks[c.ID] = c
+				order = append(order, c.ID)
+			}
+			scores[c.ID] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	if limit > 0 && len(order) > limit {
+		order = order[:limit]
+	}
+
+	fused := make([]db.ChunkWithScore, len(order))
+	for i, id := range order {
+		fused[i] = chunks[id]
+	}
+	return fused
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+// linkedResults follows wikilinks out of results and returns the chunk of each
+// linked note closest to the query, up to limit notes. Notes already in
+// results are skipped. Scores are cosine similarities to the query, so they
+// aren't comparable to reranked scores.
+func (s *Searcher) linkedResults(results []Result, queryEmb []float32, filter db.SearchFilter, limit int) ([]Result, error) {
+	var docIDs []int64
+	pathByDoc := make(map[int64]string)
+	for _, r := range results {
+		if _, ok := pathByDoc[r.DocID]; !ok {
+			pathByDoc[r.DocID] = r.Path
+			docIDs = append(docIDs, r.DocID)
+		}
+	}
+
+	links, err := s.db.GetLinkedDocuments(docIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load links: %w", err)
+	}
+
+	linkedFrom := linkSources(links, docIDs)
+	if len(linkedFrom) == 0 {
+		return nil, nil
+	}
+
+	filter.DocIDs = nil
+	for id := range linkedFrom {
+		filter.DocIDs = append(filter.DocIDs, id)
+	}
+
+	embBytes := db.SerializeFloat32(queryEmb)
+
+	hits, err := s.db.SearchSimilar(embBytes, min(len(filter.DocIDs)*similarCandidatesPerResult, maxCandidates), filter)
+	if err != nil {
+		return nil, fmt.Errorf("linked note search failed: %w", err)
+	}
+
+	var linked []Result
+	seen := make(map[int64]bool)
+	for _, h := range hits {
+		if seen[h.DocID] {
+			continue
+		}
+		seen[h.DocID] = true
+
+		linked = append(linked, Result{
+			Rank:       len(results) + len(linked) + 1,
+			Score:      distanceToSimilarity(h.Distance),
+			Path:       h.Path,
+			Heading:    h.Heading,
+			Content:    h.Content,
+			StartLine:  h.StartLine,
+			EndLine:    h.EndLine,
+			DocID:      h.DocID,
+			ChunkID:    h.ID,
+			ModifiedAt: time.Unix(h.ModifiedAt, 0),
+			LinkedFrom: pathByDoc[linkedFrom[h.DocID]],
+		})
+		if len(linked) == limit {
+			break
+		}
+	}
+	return linked, nil
+}
+
+// linkSources maps each linked document to the best-ranked source linking to
+// it, dropping documents that are already sources themselves.
+func linkSources(links []db.DocumentLink, sources []int64) map[int64]int64 {
+	rank := make(map[int64]int, len(sources))
+	for i, id := range sources {
+		rank[id] = i
+	}
+
+	linkedFrom := make(map[int64]int64)
+	for _, link := range links {
+		if _, isSource := rank[link.ToID]; isSource {
+			continue
+		}
+		if from, ok := linkedFrom[link.ToID]; ok && rank[from] <= rank[link.FromID] {
+			continue
+		}
+		linkedFrom[link.ToID] = link.FromID
+	}
+	return linkedFrom
+}
@@ -0,0 +1,62 @@
+package search
+
+import "math"
+
+// mmrPoolMultiplier controls how many reranked results MMR chooses from per
+// requested result.
+const mmrPoolMultiplier = 3
+
+// selectMMR greedily picks k items by maximal marginal relevance: each step
+// takes the item maximizing lambda*relevance - (1-lambda)*similarity to the
+// items already picked. Items without a vector are treated as dissimilar to
+// everything. Returns indexes into relevance, in selection order.
+func selectMMR(relevance []float64, vectors [][]float32, lambda float64, k int) []int {
+	k = min(k, len(relevance))
+	selected := make([]int, 0, k)
+	used := make([]bool, len(relevance))
+
+	// maxSim[i] is the highest similarity between item i and any selected item
+	maxSim := make([]float64, len(relevance))
+
+	for len(selected) < k {
+		best := -1
+		bestScore := math.Inf(-1)
+		for i := range relevance {
+			if used[i] {
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*maxSim[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		used[best] = true
+		selected = append(selected, best)
+
+		for i := range relevance {
+			if !used[i] {
+				maxSim[i] = math.Max(maxSim[i], cosineSimilarity(vectors[i], vectors[best]))
+			}
+		}
+	}
+
+	return selected
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
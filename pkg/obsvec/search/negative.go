@@ -0,0 +1,59 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// negativeWeight scales how strongly similarity to an excluded term lowers a
+// result's score.
+const negativeWeight = 0.5
+
+// penalize embeds the excluded terms and down-ranks results whose chunks are
+// similar to any of them.
+func (s *Searcher) penalize(ctx context.Context, results []Result, terms []string) ([]Result, error) {
+	negatives, err := s.embedQueries(ctx, terms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed excluded terms: %w", err)
+	}
+
+	chunkIDs := make([]int64, len(results))
+	for i, r := range results {
+		chunkIDs[i] = r.ChunkID
+	}
+
+	embeddings, err := s.db.GetEmbeddings(chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embeddings for exclusions: %w", err)
+	}
+
+	return applyPenalty(results, embeddings, negatives), nil
+}
+
+// applyPenalty subtracts each result's strongest positive similarity to a
+// negative vector from its score and re-sorts by the adjusted score.
+func applyPenalty(results []Result, embeddings map[int64][]float32, negatives [][]float32) []Result {
+	penalized := make([]Result, len(results))
+	copy(penalized, results)
+
+	for i, r := range penalized {
+		vec, ok := embeddings[r.ChunkID]
+		if !ok {
+			continue
+		}
+		var worst float64
+		for _, neg := range negatives {
+			worst = max(worst, cosineSimilarity(vec, neg))
+		}
+		penalized[i].Score -= negativeWeight * worst
+	}
+
+	sort.SliceStable(penalized, func(i, j int) bool {
+		return penalized[i].Score > penalized[j].Score
+	})
+	for i := range penalized {
+		penalized[i].Rank = i + 1
+	}
+	return penalized
+}
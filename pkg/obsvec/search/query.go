@@ -0,0 +1,112 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// parsedQuery separates search operators from the free text that is embedded
+// and reranked.
+type parsedQuery struct {
+	Text      string
+	Tags      []string
+	Negatives []string
+
+	// Phrases are quoted parts of the query that must appear verbatim in a
+	// chunk. Their words are also kept in Text for semantic ranking.
+	Phrases []string
+}
+
+// parseQuery extracts "tag:name" operators, "-term" and -"some phrase"
+// exclusions, and "exact phrase" constraints from a query.
+func parseQuery(query string) parsedQuery {
+	var parsed parsedQuery
+	var text []string
+	for _, tok := range tokenizeQuery(query) {
+		switch {
+		case tok.quoted && tok.negated:
+			parsed.Negatives = append(parsed.Negatives, tok.text)
+			continue
+		case tok.quoted:
+			parsed.Phrases = append(parsed.Phrases, tok.text)
+			text = append(text, tok.text)
+			continue
+		}
+
+		if tag, ok := cutPrefixFold(tok.text, "tag:"); ok {
+			if tag != "" {
+				parsed.Tags = append(parsed.Tags, tag)
+			}
+			continue
+		}
+		if term, ok := strings.CutPrefix(tok.text, "-"); ok && term != "" && term[0] != '-' {
+			parsed.Negatives = append(parsed.Negatives, term)
+			continue
+		}
+		text = append(text, tok.text)
+	}
+	parsed.Text = strings.Join(text, " ")
+	return parsed
+}
+
+type queryToken struct {
+	text    string
+	quoted  bool
+	negated bool
+}
+
+// tokenizeQuery splits a query on whitespace, keeping double-quoted phrases
+// together. An unterminated quote runs to the end of the query.
+func tokenizeQuery(query string) []queryToken {
+	var tokens []queryToken
+	for {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		if query == "" {
+			return tokens
+		}
+
+		negated := strings.HasPrefix(query, `-"`)
+		if negated {
+			query = query[1:]
+		}
+
+		if rest, ok := strings.CutPrefix(query, `"`); ok {
+			phrase, after, _ := strings.Cut(rest, `"`)
+			query = after
+			if phrase = strings.TrimSpace(phrase); phrase != "" {
+				tokens = append(tokens, queryToken{text: phrase, quoted: true, negated: negated})
+			}
+			continue
+		}
+
+		end := strings.IndexFunc(query, unicode.IsSpace)
+		if end < 0 {
+			end = len(query)
+		}
+		tokens = append(tokens, queryToken{text: query[:end]})
+		query = query[end:]
+	}
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
+
+// minTitleTermLen skips short words that would match most titles.
+const minTitleTermLen = 3
+
+// titleTerms returns the words of query worth matching against note titles
+// and headings.
+func titleTerms(query string) []string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		field = strings.Trim(field, `"'.,:;!?()`)
+		if len([]rune(field)) >= minTitleTermLen {
+			terms = append(terms, field)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,34 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// recencyWeight is the largest boost recency can give: a note modified just
+// now scores up to (1 + recencyWeight) times its relevance.
+const recencyWeight = 0.5
+
+// boostRecent raises each result's score by how recently its note was
+// modified, halving the boost every halfLife, and re-sorts by the new score.
+// The boost is a share of the score's magnitude, so a score pushed below zero
+// by excluded terms still goes up rather than further down.
+func boostRecent(results []Result, halfLife time.Duration, now time.Time) []Result {
+	boosted := make([]Result, len(results))
+	copy(boosted, results)
+
+	for i, r := range boosted {
+		age := max(now.Sub(r.ModifiedAt), 0)
+		decay := math.Exp2(-float64(age) / float64(halfLife))
+		boosted[i].Score += math.Abs(r.Score) * recencyWeight * decay
+	}
+
+	sort.SliceStable(boosted, func(i, j int) bool {
+		return boosted[i].Score > boosted[j].Score
+	})
+	for i := range boosted {
+		boosted[i].Rank = i + 1
+	}
+	return boosted
+}
@@ -0,0 +1,509 @@
+// Package search answers queries against an index: it embeds the query,
+// fetches the nearest chunks with keyword matches fused in, reranks them
+// and applies filters, recency, link expansion and diversification.
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+	"github.com/mgomes/obsvec/pkg/obsvec/events"
+	"github.com/mgomes/obsvec/pkg/obsvec/provider"
+)
+
+const (
+	defaultLimit = 10
+	// candidateMultiplier controls how many vector candidates are fetched
+	// per requested result before reranking.
+	candidateMultiplier = 2
+	// maxCandidates is the largest k sqlite-vec accepts for a KNN query.
+	maxCandidates = 4096
+)
+
+type Searcher struct {
+	db     *db.DB
+	models provider.Searcher
+	events *events.Bus
+	onWarn func(string)
+
+	queryCache *queryCache
+}
+
+type Result struct {
+	Rank       int       `json:"rank"`
+	Score      float64   `json:"score"`
+	Path       string    `json:"path"`
+	Heading    string    `json:"heading,omitempty"`
+	Content    string    `json:"content"`
+	StartLine  int       `json:"start_line"`
+	EndLine    int       `json:"end_line"`
+	DocID      int64     `json:"doc_id"`
+	ChunkID    int64     `json:"chunk_id"`
+	ModifiedAt time.Time `json:"modified_at"`
+
+	// Vault names the vault the result came from in a search across
+	// vaults, and is empty otherwise.
+	Vault string `json:"vault,omitempty"`
+
+	// LinkedFrom is set on secondary results found by following links, and
+	// holds the path of the result that links to this note.
+	LinkedFrom string `json:"linked_from,omitempty"`
+
+	// Before and After hold neighboring chunks from the same note when
+	// requested with AddContext.
+	Before []ContextChunk `json:"before,omitempty"`
+	After  []ContextChunk `json:"after,omitempty"`
+
+	// Explanation breaks down the score when Options.Explain is set. Linked
+	// results have none.
+	Explanation *Explanation `json:"explanation,omitempty"`
+}
+
+// ContextChunk is a chunk adjacent to a result in its note.
+type ContextChunk struct {
+	Heading   string `json:"heading,omitempty"`
+	Content   string `json:"content"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// DocumentGroup collects the hits from one note. Score is the best hit's score.
+type DocumentGroup struct {
+	Path  string   `json:"path"`
+	DocID int64    `json:"doc_id"`
+	Score float64  `json:"score"`
+	Hits  []Result `json:"hits"`
+}
+
+type Options struct {
+	// Limit is the number of results returned after reranking.
+	Limit int
+
+	// Tags restricts candidates to documents carrying all of these tags, in
+	// addition to any "tag:" operators in the query.
+	Tags []string
+
+	// Paths and ExcludePaths are vault-relative globs ("Projects/**")
+	// restricting which documents are searched.
+	Paths        []string
+	ExcludePaths []string
+
+	// Since and Until bound the document's modification time; zero values
+	// are unbounded. Until is exclusive.
+	Since time.Time
+	Until time.Time
+
+	// Not lists terms to steer away from, in addition to any "-term"
+	// exclusions in the query. Results similar to them are down-ranked.
+	Not []string
+
+	// Expand asks the chat model for paraphrases of the query and searches
+	// with all of them, so notes phrased differently are still found.
+	Expand bool
+
+	// RecencyHalfLife boosts recently modified notes when positive. The
+	// boost halves for every half-life of age.
+	RecencyHalfLife time.Duration
+
+	// FollowLinks appends notes linked from the results, one wikilink hop
+	// away, as secondary results.
+	FollowLinks bool
+
+	// OnePerDocument keeps only the best chunk of each note.
+	OnePerDocument bool
+
+	// MMRLambda enables maximal marginal relevance diversification when in
+	// (0, 1]. Lower values favor diversity over relevance; 0 disables it.
+	MMRLambda float64
+
+	// Bookmarks are the vault-relative paths of bookmarked notes and
+	// folders, as indexer.Bookmarks returns them. BookmarkedOnly searches
+	// only those notes; a positive BookmarkBoost scales their scores by
+	// 1 + BookmarkBoost.
+	Bookmarks      []string
+	BookmarkedOnly bool
+	BookmarkBoost  float64
+
+	// Explain attaches an Explanation of its score to each result.
+	Explain bool
+}
+
+func (o Options) limit() int {
+	if o.Limit <= 0 {
+		return defaultLimit
+	}
+	return o.Limit
+}
+
+// filter converts the options into a db filter, adding any tags and phrases
+// parsed from the query.
+func (o Options) filter(q parsedQuery) db.SearchFilter {
+	filter := db.SearchFilter{
+		Tags:         append(append([]string(nil), o.Tags...), q.Tags...),
+		Paths:        o.Paths,
+		ExcludePaths: o.ExcludePaths,
+		Phrases:      q.Phrases,
+	}
+	if !o.Since.IsZero() {
+		filter.ModifiedSince = o.Since.Unix()
+	}
+	if !o.Until.IsZero() {
+		filter.ModifiedBefore = o.Until.Unix()
+	}
+	return filter
+}
+
+// New returns a Searcher of the index in database that embeds queries,
+// reranks and paraphrases with models, usually a *cohere.Client.
+func New(database *db.DB, models provider.Searcher) *Searcher {
+	return &Searcher{
+		db:     database,
+		models: models,
+	}
+}
+
+// SetEventBus publishes a search executed event for every successful search.
+func (s *Searcher) SetEventBus(bus *events.Bus) {
+	s.events = bus
+}
+
+// SetWarningHandler receives advice about a search, such as a query too
+// short to search well. Warnings are dropped when no handler is set.
+func (s *Searcher) SetWarningHandler(fn func(string)) {
+	s.onWarn = fn
+}
+
+func (s *Searcher) warn(msg string) {
+	if s.onWarn != nil {
+		s.onWarn(msg)
+	}
+}
+
+func (s *Searcher) Search(ctx context.Context, query string, opts Options) ([]Result, error) {
+	return s.SearchMulti(ctx, []string{query}, opts)
+}
+
+// SearchMulti runs several queries against the same filters and fuses their
+// candidate lists with RRF before a single rerank. Operators such as "tag:"
+// and "-term" from every query apply to the whole search.
+func (s *Searcher) SearchMulti(ctx context.Context, queries []string, opts Options) ([]Result, error) {
+	start := time.Now()
+	results, err := s.search(ctx, queries, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(events.Event{
+		Kind:     events.SearchExecuted,
+		Query:    strings.Join(queries, " | "),
+		Results:  len(results),
+		Duration: time.Since(start),
+	})
+	return results, nil
+}
+
+func (s *Searcher) search(ctx context.Context, rawQueries []string, opts Options) ([]Result, error) {
+	limit := opts.limit()
+
+	var texts []string
+	var operators parsedQuery
+	for _, raw := range rawQueries {
+		parsed := parseQuery(raw)
+		if parsed.Text != "" {
+			texts = append(texts, parsed.Text)
+		}
+		operators.Tags = append(operators.Tags, parsed.Tags...)
+		operators.Phrases = append(operators.Phrases, parsed.Phrases...)
+		operators.Negatives = append(operators.Negatives, parsed.Negatives...)
+	}
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("query has no search terms")
+	}
+
+	filter := opts.filter(operators)
+	negatives := append(operators.Negatives, opts.Not...)
+	if ok, err := s.restrictToBookmarks(&filter, opts); err != nil || !ok {
+		return nil, err
+	}
+	boostBookmarks := opts.BookmarkBoost > 0 && len(opts.Bookmarks) > 0
+
+	queries := append([]string(nil), texts...)
+	if opts.Expand {
+		for _, text := range texts {
+			expansions, err := s.expandQuery(ctx, text)
+			if err != nil {
+				return nil, err
+			}
+			queries = append(queries, expansions...)
+		}
+	}
+
+	queryEmbs, err := s.embedQueries(ctx, queries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	numCandidates := min(limit*candidateMultiplier, maxCandidates)
+
+	var trace *explainTrace
+	if opts.Explain {
+		trace = newExplainTrace()
+	}
+
+	var lists [][]db.ChunkWithScore
+	shortWithoutMatches := false
+	for i, queryEmb := range queryEmbs {
+		// Vectors miss exact identifiers and proper nouns, so fuse in BM25 hits
+		keywordHits, err := s.db.SearchKeyword(queries[i], numCandidates, filter)
+		if err != nil {
+			return nil, fmt.Errorf("keyword search failed: %w", err)
+		}
+
+		// A note titled after the query should make the pool even when its
+		// body reads differently
+		titleHits, err := s.db.SearchTitles(titleTerms(queries[i]), numCandidates, filter)
+		if err != nil {
+			return nil, fmt.Errorf("title search failed: %w", err)
+		}
+
+		embBytes := db.SerializeFloat32(queryEmb)
+
+		hits, err := s.db.SearchSimilar(embBytes, numCandidates, filter)
+		if err != nil {
+			return nil, fmt.Errorf("vector search failed: %w", err)
+		}
+
+		words := len(strings.Fields(queries[i]))
+		lexical := len(keywordHits) + len(titleHits)
+		hits = trimVectorHits(hits, words, lexical, limit)
+		if words <= shortQueryWords && lexical == 0 && i < len(texts) {
+			shortWithoutMatches = true
+		}
+
+		lists = append(lists, hits)
+		trace.vectorHits(hits)
+		if len(keywordHits) > 0 {
+			lists = append(lists, keywordHits)
+			trace.keywordHits(keywordHits)
+		}
+		if len(titleHits) > 0 {
+			lists = append(lists, titleHits)
+			trace.titleHits(titleHits)
+		}
+	}
+
+	if shortWithoutMatches && !opts.Expand {
+		s.warn("Short queries embed poorly and no note matched by keyword or title; results may be loosely related. Try a longer query or -expand.")
+	}
+
+	candidates := lists[0]
+	if len(lists) > 1 {
+		candidates = fuseRRF(numCandidates*len(queries), lists...)
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	docs := buildRerankDocs(candidates)
+
+	// Keep a larger pool when results may be reordered after reranking
+	rerankN := limit
+	if opts.MMRLambda > 0 || len(negatives) > 0 || opts.RecencyHalfLife > 0 || boostBookmarks {
+		rerankN = min(len(candidates), limit*mmrPoolMultiplier)
+	}
+	if opts.OnePerDocument {
+		// Every candidate is scored either way, so keep them all in case a
+		// few notes own most of the top chunks
+		rerankN = len(candidates)
+	}
+
+	rerankResults, err := s.models.Rerank(ctx, strings.Join(texts, "\n"), docs, rerankN)
+	if err != nil {
+		return nil, fmt.Errorf("rerank failed: %w", err)
+	}
+
+	results := buildResults(candidates, rerankResults)
+	trace.explain(results, candidates, filter)
+	if len(negatives) > 0 {
+		prev := results
+		results, err = s.penalize(ctx, results, negatives)
+		if err != nil {
+			return nil, err
+		}
+		explainAdjustment("negative", prev, results)
+	}
+	if opts.RecencyHalfLife > 0 {
+		prev := results
+		results = boostRecent(results, opts.RecencyHalfLife, time.Now())
+		explainAdjustment("recency", prev, results)
+	}
+	if boostBookmarks {
+		prev := results
+		results = boostBookmarked(results, opts.Bookmarks, opts.BookmarkBoost)
+		explainAdjustment("bookmark", prev, results)
+	}
+	if opts.OnePerDocument {
+		results = bestPerDocument(results)
+	}
+	if opts.MMRLambda > 0 && len(results) > limit {
+		results, err = s.diversify(results, opts.MMRLambda, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	if opts.FollowLinks {
+		linked, err := s.linkedResults(results, queryEmbs[0], filter, limit)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, linked...)
+	}
+	return results, nil
+}
+
+// diversify reorders reranked results with MMR so near-duplicate chunks don't
+// crowd out the rest of the list.
+func (s *Searcher) diversify(results []Result, lambda float64, limit int) ([]Result, error) {
+	chunkIDs := make([]int64, len(results))
+	for i, r := range results {
+		chunkIDs[i] = r.ChunkID
+	}
+
+	embeddings, err := s.db.GetEmbeddings(chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embeddings for diversification: %w", err)
+	}
+
+	relevance := make([]float64, len(results))
+	vectors := make([][]float32, len(results))
+	for i, r := range results {
+		relevance[i] = r.Score
+		vectors[i] = embeddings[r.ChunkID]
+	}
+
+	selected := selectMMR(relevance, vectors, min(lambda, 1), limit)
+	diversified := make([]Result, len(selected))
+	for i, idx := range selected {
+		diversified[i] = results[idx]
+		diversified[i].Rank = i + 1
+	}
+	return diversified, nil
+}
+
+// AddContext fills Before and After on each result with up to n neighboring
+// chunks from its note.
+func (s *Searcher) AddContext(results []Result, n int) error {
+	for i := range results {
+		before, after, err := s.db.GetNeighborChunks(results[i].ChunkID, n)
+		if err != nil {
+			return fmt.Errorf("failed to load context for %s: %w", results[i].Path, err)
+		}
+		results[i].Before = toContextChunks(before)
+		results[i].After = toContextChunks(after)
+	}
+	return nil
+}
+
+func toContextChunks(chunks []db.Chunk) []ContextChunk {
+	if len(chunks) == 0 {
+		return nil
+	}
+	context := make([]ContextChunk, len(chunks))
+	for i, c := range chunks {
+		context[i] = ContextChunk{
+			Heading:   c.Heading,
+			Content:   c.Content,
+			StartLine: c.StartLine,
+			EndLine:   c.EndLine,
+		}
+	}
+	return context
+}
+
+// bestPerDocument keeps the first, and so highest ranked, result of each
+// document and renumbers ranks.
+func bestPerDocument(results []Result) []Result {
+	var best []Result
+	seen := make(map[int64]bool)
+	for _, r := range results {
+		if seen[r.DocID] {
+			continue
+		}
+		seen[r.DocID] = true
+		r.Rank = len(best) + 1
+		best = append(best, r)
+	}
+	return best
+}
+
+// GroupByDocument buckets results per note. Groups are ordered by their best
+// hit and hits keep their original relative order.
+func GroupByDocument(results []Result) []DocumentGroup {
+	var groups []DocumentGroup
+	byDoc := make(map[int64]int)
+	for _, r := range results {
+		i, ok := byDoc[r.DocID]
+		if !ok {
+			i = len(groups)
+			byDoc[r.DocID] = i
+			groups = append(groups, DocumentGroup{Path: r.Path, DocID: r.DocID, Score: r.Score})
+		}
+		groups[i].Hits = append(groups[i].Hits, r)
+		groups[i].Score = max(groups[i].Score, r.Score)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Score > groups[j].Score
+	})
+	return groups
+}
+
+// buildRerankDocs prefixes each chunk with its note title and heading path so
+// the reranker can weigh them alongside the body text.
+func buildRerankDocs(candidates []db.ChunkWithScore) []string {
+	docs := make([]string, len(candidates))
+	for i, c := range candidates {
+		var b strings.Builder
+		if c.Title != "" {
+			b.WriteString("Title: " + c.Title + "\n")
+		}
+		if c.Heading != "" {
+			b.WriteString("Section: " + c.Heading + "\n")
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(c.Content)
+		docs[i] = b.String()
+	}
+	return docs
+}
+
+func buildResults(candidates []db.ChunkWithScore, rerankResults []provider.RerankResult) []Result {
+	results := make([]Result, len(rerankResults))
+	for i, rr := range rerankResults {
+		c := candidates[rr.Index]
+		results[i] = Result{
+			Rank:       i + 1,
+			Score:      rr.Score,
+			Path:       c.Path,
+			Heading:    c.Heading,
+			Content:    c.Content,
+			StartLine:  c.StartLine,
+			EndLine:    c.EndLine,
+			DocID:      c.DocID,
+			ChunkID:    c.ID,
+			ModifiedAt: time.Unix(c.ModifiedAt, 0),
+		}
+	}
+	return results
+}
@@ -0,0 +1,416 @@
+package search
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+func chunkWithID(id int64) db.ChunkWithScore {
+	return db.ChunkWithScore{Chunk: db.Chunk{ID: id}}
+}
+
+func TestFuseRRF_RewardsAgreement(t *testing.T) {
+	vector := []db.ChunkWithScore{chunkWithID(1), chunkWithID(2), chunkWithID(3)}
+	keyword := []db.ChunkWithScore{chunkWithID(3), chunkWithID(4)}
+
+	fused := fuseRRF(10, vector, keyword)
+
+	if len(fused) != 4 {
+		t.Fatalf("expected 4 fused candidates, got %d", len(fused))
+	}
+
+	// Chunk 3 appears in both lists so it outranks chunks found by only one
+	if fused[0].ID != 3 {
+		t.Errorf("expected chunk 3 first, got %d", fused[0].ID)
+	}
+}
+
+func TestFuseRRF_Limit(t *testing.T) {
+	list := []db.ChunkWithScore{chunkWithID(1), chunkWithID(2), chunkWithID(3)}
+
+	fused := fuseRRF(2, list)
+
+	if len(fused) != 2 {
+		t.Fatalf("expected 2 candidates, got %d", len(fused))
+	}
+	if fused[0].ID != 1 || fused[1].ID != 2 {
+		t.Errorf("expected original order preserved, got %d, %d", fused[0].ID, fused[1].ID)
+	}
+}
+
+func TestParseQuery_Tags(t *testing.T) {
+	parsed := parseQuery("tag:work quarterly planning TAG:project/x")
+
+	if parsed.Text != "quarterly planning" {
+		t.Errorf("expected text 'quarterly planning', got '%s'", parsed.Text)
+	}
+
+	if len(parsed.Tags) != 2 || parsed.Tags[0] != "work" || parsed.Tags[1] != "project/x" {
+		t.Errorf("expected tags [work project/x], got %v", parsed.Tags)
+	}
+}
+
+func TestParseQuery_Negatives(t *testing.T) {
+	parsed := parseQuery("kubernetes -helm self-hosted - --verbose")
+
+	if parsed.Text != "kubernetes self-hosted - --verbose" {
+		t.Errorf("expected text 'kubernetes self-hosted - --verbose', got '%s'", parsed.Text)
+	}
+
+	if len(parsed.Negatives) != 1 || parsed.Negatives[0] != "helm" {
+		t.Errorf("expected negatives [helm], got %v", parsed.Negatives)
+	}
+}
+
+func TestParseExpansions(t *testing.T) {
+	reply := "1. Feeling exhausted at work\n- \"burnout\"\n\n* Job stress and fatigue\nfeeling exhausted at work\nRecovering from overwork\nExtra line"
+
+	got := parseExpansions(reply, "Burnout", 3)
+
+	want := []string{"Feeling exhausted at work", "Job stress and fatigue", "Recovering from overwork"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expansion %d: expected '%s', got '%s'", i, want[i], got[i])
+		}
+	}
+}
+
+func TestApplyPenalty(t *testing.T) {
+	results := []Result{
+		{ChunkID: 1, Score: 0.9},
+		{ChunkID: 2, Score: 0.8},
+	}
+	embeddings := map[int64][]float32{
+		1: {1, 0},
+		2: {0, 1},
+	}
+
+	penalized := applyPenalty(results, embeddings, [][]float32{{1, 0}})
+
+	if penalized[0].ChunkID != 2 || penalized[0].Rank != 1 {
+		t.Errorf("expected chunk 2 to move to rank 1, got %+v", penalized[0])
+	}
+	if results[0].ChunkID != 1 {
+		t.Error("expected input slice to be left unchanged")
+	}
+}
+
+func TestParseQuery_Phrases(t *testing.T) {
+	parsed := parseQuery(`pod killed "error code 137" -"out of memory" "unterminated phrase`)
+
+	if parsed.Text != "pod killed error code 137 unterminated phrase" {
+		t.Errorf("expected phrase words kept in text, got '%s'", parsed.Text)
+	}
+	if len(parsed.Phrases) != 2 || parsed.Phrases[0] != "error code 137" || parsed.Phrases[1] != "unterminated phrase" {
+		t.Errorf("expected phrases [error code 137, unterminated phrase], got %v", parsed.Phrases)
+	}
+	if len(parsed.Negatives) != 1 || parsed.Negatives[0] != "out of memory" {
+		t.Errorf("expected negatives [out of memory], got %v", parsed.Negatives)
+	}
+}
+
+func TestTitleTerms(t *testing.T) {
+	terms := titleTerms("tax return of 2024?")
+
+	if len(terms) != 3 || terms[0] != "tax" || terms[1] != "return" || terms[2] != "2024" {
+		t.Errorf("expected [tax return 2024], got %v", terms)
+	}
+}
+
+func TestBuildRerankDocs(t *testing.T) {
+	candidates := []db.ChunkWithScore{
+		{Chunk: db.Chunk{Content: "Body", Heading: "Tax > Deductions"}, Title: "Tax Return 2024"},
+		{Chunk: db.Chunk{Content: "Plain"}},
+	}
+
+	docs := buildRerankDocs(candidates)
+
+	if docs[0] != "Title: Tax Return 2024\nSection: Tax > Deductions\n\nBody" {
+		t.Errorf("unexpected document: %q", docs[0])
+	}
+	if docs[1] != "Plain" {
+		t.Errorf("expected untitled chunk to be sent as-is, got %q", docs[1])
+	}
+}
+
+func TestParseDateBounds(t *testing.T) {
+	now := time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)
+
+	since, err := ParseSince("30d", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !since.Equal(now.AddDate(0, 0, -30)) {
+		t.Errorf("expected 30 days before now, got %v", since)
+	}
+
+	since, _ = ParseSince("2024-01-01", now)
+	if !since.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected start of 2024-01-01, got %v", since)
+	}
+
+	until, _ := ParseUntil("2024-06-30", now)
+	if !until.Equal(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected until to include all of 2024-06-30, got %v", until)
+	}
+
+	if _, err := ParseSince("yesterday", now); err == nil {
+		t.Error("expected error for unsupported date")
+	}
+	if _, err := ParseSince("3x", now); err == nil {
+		t.Error("expected error for unknown unit")
+	}
+}
+
+func TestParseHalfLife(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"36h", 36 * time.Hour},
+		{"0", 0},
+		{"2w", 14 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := ParseHalfLife(tt.value)
+		if err != nil {
+			t.Errorf("ParseHalfLife(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseHalfLife(%q): expected %v, got %v", tt.value, tt.want, got)
+		}
+	}
+
+	if _, err := ParseHalfLife("2024-01-01"); err == nil {
+		t.Error("expected error for an absolute date")
+	}
+}
+
+func TestBoostRecent(t *testing.T) {
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	results := []Result{
+		{ChunkID: 1, Score: 0.8, ModifiedAt: now.AddDate(-2, 0, 0)},
+		{ChunkID: 2, Score: 0.7, ModifiedAt: now.AddDate(0, 0, -1)},
+	}
+
+	boosted := boostRecent(results, 7*24*time.Hour, now)
+
+	if boosted[0].ChunkID != 2 || boosted[0].Rank != 1 {
+		t.Errorf("expected the recent note first, got %+v", boosted)
+	}
+	if math.Abs(boosted[1].Score-0.8) > 1e-6 {
+		t.Errorf("expected an old note to keep its score, got %v", boosted[1].Score)
+	}
+
+	// Scores below zero after excluded terms still go up
+	results = []Result{
+		{ChunkID: 1, Score: -0.1, ModifiedAt: now.AddDate(-1, 0, 0)},
+		{ChunkID: 2, Score: -0.1, ModifiedAt: now},
+	}
+
+	boosted = boostRecent(results, 7*24*time.Hour, now)
+
+	if boosted[0].ChunkID != 2 || math.Abs(boosted[0].Score+0.05) > 1e-6 {
+		t.Errorf("expected the recent note boosted ahead of the old one, got %+v", boosted)
+	}
+}
+
+func TestBoostBookmarked(t *testing.T) {
+	results := []Result{
+		{ChunkID: 1, Score: 0.8, Path: "Inbox/idea.md"},
+		{ChunkID: 2, Score: 0.7, Path: "Projects/Roadmap/q3.md"},
+		{ChunkID: 3, Score: 0.6, Path: "Projects/Roadmap.md"},
+	}
+
+	boosted := boostBookmarked(results, []string{"Projects/Roadmap"}, 0.5)
+
+	if boosted[0].ChunkID != 2 || boosted[0].Rank != 1 {
+		t.Errorf("expected the note in the bookmarked folder first, got %+v", boosted)
+	}
+	if boosted[1].Score != 0.8 || boosted[2].Score != 0.6 {
+		t.Errorf("expected notes outside the bookmarks to keep their scores, got %+v", boosted)
+	}
+
+	// A bookmarked note below zero still goes up
+	boosted = boostBookmarked([]Result{
+		{ChunkID: 1, Score: -0.1, Path: "Inbox/idea.md"},
+		{ChunkID: 2, Score: -0.1, Path: "Projects/Roadmap/q3.md"},
+	}, []string{"Projects/Roadmap"}, 0.5)
+
+	if boosted[0].ChunkID != 2 || math.Abs(boosted[0].Score+0.05) > 1e-6 {
+		t.Errorf("expected the bookmarked note boosted ahead, got %+v", boosted)
+	}
+}
+
+func TestSelectMMR_PrefersDiverseResults(t *testing.T) {
+	relevance := []float64{0.9, 0.85, 0.6}
+	vectors := [][]float32{
+		{1, 0},
+		{1, 0.01}, // near-duplicate of the first result
+		{0, 1},
+	}
+
+	selected := selectMMR(relevance, vectors, 0.5, 2)
+
+	if len(selected) != 2 {
+		t.Fatalf("expected 2 selections, got %d", len(selected))
+	}
+	if selected[0] != 0 || selected[1] != 2 {
+		t.Errorf("expected [0 2], got %v", selected)
+	}
+
+	// With lambda 1 MMR is plain relevance order
+	selected = selectMMR(relevance, vectors, 1, 2)
+	if selected[0] != 0 || selected[1] != 1 {
+		t.Errorf("expected [0 1] for lambda 1, got %v", selected)
+	}
+}
+
+func TestBestPerDocument(t *testing.T) {
+	results := []Result{
+		{Rank: 1, DocID: 1, ChunkID: 10},
+		{Rank: 2, DocID: 1, ChunkID: 11},
+		{Rank: 3, DocID: 2, ChunkID: 20},
+	}
+
+	best := bestPerDocument(results)
+
+	if len(best) != 2 || best[0].ChunkID != 10 || best[1].ChunkID != 20 {
+		t.Fatalf("expected chunks 10 and 20, got %+v", best)
+	}
+	if best[1].Rank != 2 {
+		t.Errorf("expected ranks to be renumbered, got %d", best[1].Rank)
+	}
+}
+
+func TestLinkSources(t *testing.T) {
+	links := []db.DocumentLink{
+		{FromID: 1, ToID: 3},
+		{FromID: 2, ToID: 3},
+		{FromID: 2, ToID: 1},
+		{FromID: 2, ToID: 4},
+	}
+
+	linkedFrom := linkSources(links, []int64{2, 1})
+
+	if len(linkedFrom) != 2 {
+		t.Fatalf("expected 2 linked documents, got %v", linkedFrom)
+	}
+	if linkedFrom[3] != 2 {
+		t.Errorf("expected doc 3 to be attributed to the better-ranked source 2, got %d", linkedFrom[3])
+	}
+	if _, ok := linkedFrom[1]; ok {
+		t.Error("expected sources to be excluded from linked documents")
+	}
+}
+
+func TestGroupByDocument(t *testing.T) {
+	results := []Result{
+		{Rank: 1, Score: 0.9, Path: "a.md", DocID: 1, ChunkID: 10},
+		{Rank: 2, Score: 0.8, Path: "b.md", DocID: 2, ChunkID: 20},
+		{Rank: 3, Score: 0.7, Path: "a.md", DocID: 1, ChunkID: 11},
+	}
+
+	groups := GroupByDocument(results)
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+
+	if groups[0].Path != "a.md" || len(groups[0].Hits) != 2 {
+		t.Errorf("expected a.md first with 2 hits, got %s with %d", groups[0].Path, len(groups[0].Hits))
+	}
+
+	if groups[0].Score != 0.9 {
+		t.Errorf("expected best score 0.9, got %v", groups[0].Score)
+	}
+
+	if groups[0].Hits[1].ChunkID != 11 {
+		t.Errorf("expected hits in original order, got chunk %d", groups[0].Hits[1].ChunkID)
+	}
+}
+
+func TestMeanVector(t *testing.T) {
+	mean := meanVector([][]float32{{1, 0}, {0, 1}})
+
+	want := float32(1 / math.Sqrt2)
+	if math.Abs(float64(mean[0]-want)) > 1e-6 || math.Abs(float64(mean[1]-want)) > 1e-6 {
+		t.Errorf("expected normalized mean [%v %v], got %v", want, want, mean)
+	}
+}
+
+func TestDistanceToSimilarity(t *testing.T) {
+	if got := distanceToSimilarity(0); got != 1 {
+		t.Errorf("expected identical vectors to have similarity 1, got %v", got)
+	}
+	if got := distanceToSimilarity(math.Sqrt2); math.Abs(got) > 1e-9 {
+		t.Errorf("expected orthogonal vectors to have similarity 0, got %v", got)
+	}
+}
+
+func TestExplainTrace(t *testing.T) {
+	trace := newExplainTrace()
+	trace.vectorHits([]db.ChunkWithScore{{Chunk: db.Chunk{ID: 1}, Distance: 0.4}})
+	trace.vectorHits([]db.ChunkWithScore{{Chunk: db.Chunk{ID: 1}, Distance: 0.3}})
+	trace.keywordHits([]db.ChunkWithScore{chunkWithID(2), chunkWithID(1)})
+	candidates := []db.ChunkWithScore{chunkWithID(2), chunkWithID(1)}
+
+	results := []Result{{Rank: 1, Score: 0.9, ChunkID: 1, Path: "Projects/a.md"}}
+	trace.explain(results, candidates, db.SearchFilter{Paths: []string{"Daily", "Projects/**"}})
+
+	e := results[0].Explanation
+	if e == nil || e.VectorDistance == nil || *e.VectorDistance != 0.3 {
+		t.Fatalf("expected the closest vector distance, got %+v", e)
+	}
+	if e.KeywordRank != 2 || e.CandidateRank != 2 || e.RerankScore != 0.9 {
+		t.Errorf("unexpected explanation: %+v", e)
+	}
+	if len(e.Filters) != 1 || e.Filters[0] != "path:Projects/**" {
+		t.Errorf("expected only the matching glob, got %v", e.Filters)
+	}
+
+	boosted := []Result{{ChunkID: 1, Score: 1.2, Explanation: e}}
+	explainAdjustment("recency", results, boosted)
+	if len(e.Adjustments) != 1 || e.Adjustments[0] != (Adjustment{Name: "recency", Before: 0.9, After: 1.2}) {
+		t.Errorf("expected a recency adjustment, got %+v", e.Adjustments)
+	}
+}
+
+func TestTrimVectorHits(t *testing.T) {
+	hits := []db.ChunkWithScore{chunkWithID(1), chunkWithID(2), chunkWithID(3)}
+
+	if got := trimVectorHits(hits, 1, 4, 2); len(got) != 2 {
+		t.Errorf("expected a short query with lexical matches to keep 2 vector hits, got %d", len(got))
+	}
+	if got := trimVectorHits(hits, 1, 0, 2); len(got) != 3 {
+		t.Errorf("expected a short query without lexical matches to keep every hit, got %d", len(got))
+	}
+	if got := trimVectorHits(hits, 5, 4, 2); len(got) != 3 {
+		t.Errorf("expected a long query to keep every hit, got %d", len(got))
+	}
+}
+
+func TestQueryCache_EvictsLeastRecent(t *testing.T) {
+	c := newQueryCache(2)
+	c.put("taxes", []float32{1})
+	c.put("travel", []float32{2})
+	c.get("taxes")
+	c.put("recipes", []float32{3})
+
+	if _, ok := c.get("travel"); ok {
+		t.Error("expected travel, the least recently used, to be evicted")
+	}
+	if emb, ok := c.get("taxes"); !ok || emb[0] != 1 {
+		t.Errorf("expected taxes to be kept, got %v, %v", emb, ok)
+	}
+	if _, ok := c.get("recipes"); !ok {
+		t.Error("expected recipes to be cached")
+	}
+}
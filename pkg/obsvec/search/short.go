@@ -0,0 +1,18 @@
+package search
+
+import "github.com/mgomes/obsvec/pkg/obsvec/db"
+
+// shortQueryWords is the most words a query can have and still be treated as
+// short. Such queries embed poorly, so lexical matches are favored for them.
+const shortQueryWords = 2
+
+// trimVectorHits limits the vector candidates of a short query to the top
+// limit when keyword or title search found anything, so the pool leans on
+// exact matches instead of loosely related chunks. Longer queries, and short
+// ones without lexical matches, keep every vector hit.
+func trimVectorHits(hits []db.ChunkWithScore, words, lexicalHits, limit int) []db.ChunkWithScore {
+	if words > shortQueryWords || lexicalHits == 0 || len(hits) <= limit {
+		return hits
+	}
+	return hits[:limit]
+}
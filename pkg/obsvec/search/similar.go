@@ -0,0 +1,167 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/mgomes/obsvec/pkg/obsvec/db"
+)
+
+// exploreRecentNotes is how many of the most recently modified notes Explore
+// picks its starting note from.
+const exploreRecentNotes = 50
+
+// similarCandidatesPerResult over-fetches chunks so that enough distinct notes
+// remain after keeping one chunk per note.
+const similarCandidatesPerResult = 5
+
+// SearchSimilarTo finds notes related to the note at path, using the average
+// of its chunk embeddings as the query. No API calls are made. Results hold
+// the best matching chunk of each related note, scored by cosine similarity.
+func (s *Searcher) SearchSimilarTo(ctx context.Context, path string, opts Options) ([]Result, error) {
+	doc, err := s.findDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := s.db.GetChunksForDocument(doc.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkIDs := make([]int64, len(chunks))
+	for i, c := range chunks {
+		chunkIDs[i] = c.ID
+	}
+
+	embeddings, err := s.db.GetEmbeddings(chunkIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("%s has no embedded chunks", doc.Path)
+	}
+
+	vectors := make([][]float32, 0, len(embeddings))
+	for _, v := range embeddings {
+		vectors = append(vectors, v)
+	}
+
+	embBytes := db.SerializeFloat32(meanVector(vectors))
+
+	limit := opts.limit()
+	filter := opts.filter(parsedQuery{})
+	filter.ExcludeDocIDs = append(filter.ExcludeDocIDs, doc.ID)
+	if ok, err := s.restrictToBookmarks(&filter, opts); err != nil || !ok {
+		return nil, err
+	}
+
+	candidates, err := s.db.SearchSimilar(embBytes, min(limit*similarCandidatesPerResult, maxCandidates), filter)
+	if err != nil {
+		return nil, fmt.Errorf("vector search failed: %w", err)
+	}
+
+	var results []Result
+	seen := make(map[int64]bool)
+	for _, c := range candidates {
+		if seen[c.DocID] {
+			continue
+		}
+		seen[c.DocID] = true
+
+		results = append(results, Result{
+			Rank:       len(results) + 1,
+			Score:      distanceToSimilarity(c.Distance),
+			Path:       c.Path,
+			Heading:    c.Heading,
+			Content:    c.Content,
+			StartLine:  c.StartLine,
+			EndLine:    c.EndLine,
+			DocID:      c.DocID,
+			ChunkID:    c.ID,
+			ModifiedAt: time.Unix(c.ModifiedAt, 0),
+		})
+		if len(results) == limit {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+// Explore picks a random note among the most recently modified and returns it
+// with its nearest neighbors, to resurface forgotten connections.
+func (s *Searcher) Explore(ctx context.Context, opts Options) (string, []Result, error) {
+	recent, err := s.db.GetRecentDocuments(exploreRecentNotes)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(recent) == 0 {
+		return "", nil, fmt.Errorf("no indexed notes to explore; run ofind index first")
+	}
+
+	seed := recent[rand.IntN(len(recent))]
+	results, err := s.SearchSimilarTo(ctx, seed.Path, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	return seed.Path, results, nil
+}
+
+// findDocument looks a note up by vault-relative path, allowing the .md
+// extension to be omitted.
+func (s *Searcher) findDocument(path string) (*db.Document, error) {
+	candidates := []string{path}
+	if !strings.HasSuffix(strings.ToLower(path), ".md") {
+		candidates = append(candidates, path+".md")
+	}
+
+	for _, p := range candidates {
+		doc, err := s.db.GetDocument(p)
+		if err != nil {
+			return nil, err
+		}
+		if doc != nil {
+			return doc, nil
+		}
+	}
+	return nil, fmt.Errorf("note not found in index: %s", path)
+}
+
+// meanVector averages vectors and normalizes the result to unit length.
+func meanVector(vectors [][]float32) []float32 {
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	mean := make([]float64, len(vectors[0]))
+	for _, v := range vectors {
+		for i, x := range v {
+			mean[i] += float64(x)
+		}
+	}
+
+	var norm float64
+	for _, x := range mean {
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+
+	result := make([]float32, len(mean))
+	for i, x := range mean {
+		if norm > 0 {
+			result[i] = float32(x / norm)
+		}
+	}
+	return result
+}
+
+// distanceToSimilarity converts sqlite-vec's L2 distance between unit vectors
+// into cosine similarity.
+func distanceToSimilarity(distance float64) float64 {
+	return 1 - distance*distance/2
+}